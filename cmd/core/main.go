@@ -20,7 +20,7 @@ import (
 
 func main() {
 	socket := flag.String("socket", "/tmp/nous-core.sock", "uds socket path")
-	providerName := flag.String("provider", "mock", "provider: mock|openai|gemini")
+	providerName := flag.String("provider", "mock", "provider: mock|openai|gemini|anthropic")
 	model := flag.String("model", "", "provider model name")
 	apiBase := flag.String("api-base", "", "optional provider API base URL")
 	workdir := flag.String("workdir", "", "working directory for builtin tools (default: current directory)")
@@ -28,6 +28,21 @@ func main() {
 	enableDemoExt := flag.Bool("enable-demo-extension", false, "register built-in demo extension command/tool")
 	extensionHookTimeout := flag.Duration("extension-hook-timeout", 0, "extension hook timeout (0 disables)")
 	extensionToolTimeout := flag.Duration("extension-tool-timeout", 0, "extension tool timeout (0 disables)")
+	systemPrompt := flag.String("system-prompt", "", "system prompt sent with every provider request")
+	systemPromptFile := flag.String("system-prompt-file", "", "read the system prompt from a file (overrides --system-prompt)")
+	contextFiles := flag.Bool("context-files", true, "load AGENTS.md and .nous/SYSTEM.md from --workdir up to the repo root")
+	toolApproval := flag.Bool("tool-approval", false, "pause selected tool calls until a client approves or rejects them")
+	toolApprovalTools := flag.String("tool-approval-tools", "bash,write,edit,apply_patch,git,process", "comma-separated tools that need approval (* = all)")
+	toolApprovalTimeout := flag.Duration("tool-approval-timeout", core.DefaultApprovalTimeout, "reject a pending approval after this long")
+	confineWorkdir := flag.Bool("confine-workdir", false, "reject builtin file tool paths outside --workdir and --allowed-roots")
+	allowedRoots := flag.String("allowed-roots", "", "comma-separated extra directories file tools may access when --confine-workdir is set")
+	shellPath := flag.String("shell", "", "shell for the bash tool (default: $SHELL, then /bin/bash, then /bin/sh)")
+	shellLogin := flag.Bool("shell-login", true, "run the bash tool shell as a login shell (false skips profile files)")
+	persistentBash := flag.Bool("persistent-bash", false, "run bash tool calls in one long-lived shell per session (keeps cd/export)")
+	policyFile := flag.String("policy", "", "JSON tool permission policy file (allow/deny/ask rules)")
+	execProfileName := flag.String("exec-profile", "default", "environment and limits for bash/process commands: "+strings.Join(builtins.ExecProfileNames(), "|"))
+	execProfileFile := flag.String("exec-profile-file", "", "JSON exec profile file (overrides --exec-profile)")
+	fetchAllowedDomains := flag.String("fetch-allowed-domains", "", "comma-separated hosts the fetch tool may access, subdomains included (default: any non-local host)")
 	flag.Parse()
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
@@ -42,7 +57,36 @@ func main() {
 	if err != nil {
 		log.Fatalf("resolve workdir failed: %v", err)
 	}
-	engine.SetTools(builtins.DefaultTools(cwd))
+	shell, err := builtins.ResolveShell(*shellPath, *shellLogin)
+	if err != nil {
+		log.Fatalf("shell init failed: %v", err)
+	}
+	execProfile, err := resolveExecProfile(*execProfileName, *execProfileFile)
+	if err != nil {
+		log.Fatalf("exec profile init failed: %v", err)
+	}
+	engine.SetTools(builtins.DefaultToolsWithConfig(builtins.Config{
+		Workdir:             cwd,
+		ConfineWorkdir:      *confineWorkdir,
+		AllowedRoots:        splitList(*allowedRoots),
+		PersistentBash:      *persistentBash,
+		Shell:               shell,
+		ExecProfile:         execProfile,
+		FetchAllowedDomains: splitList(*fetchAllowedDomains),
+	}))
+	if err := configureSystemPrompt(engine, *systemPrompt, *systemPromptFile, cwd, *contextFiles); err != nil {
+		log.Fatalf("system prompt init failed: %v", err)
+	}
+	if err := configureToolApproval(engine, *toolApproval, *toolApprovalTools, *toolApprovalTimeout); err != nil {
+		log.Fatalf("invalid tool approval config: %v", err)
+	}
+	if strings.TrimSpace(*policyFile) != "" {
+		policy, err := core.LoadPolicyFile(*policyFile, cwd)
+		if err != nil {
+			log.Fatalf("policy init failed: %v", err)
+		}
+		engine.SetPolicy(policy)
+	}
 	extMgr := extension.NewManager()
 	if err := configureExtensionTimeouts(extMgr, *extensionHookTimeout, *extensionToolTimeout); err != nil {
 		log.Fatalf("invalid extension timeout config: %v", err)
@@ -68,7 +112,17 @@ func registerDemoExtension(m *extension.Manager) {
 		text, _ := payload["text"].(string)
 		return map[string]any{"echo": text}, nil
 	})
-	_ = m.RegisterTool("demo.echo", func(args map[string]any) (string, error) {
+	_ = m.RegisterToolWithSpec(extension.ToolSpec{
+		Name:        "demo.echo",
+		Description: "Echo the given text back, prefixed with demo.echo.",
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"text": map[string]any{"type": "string", "description": "Text to echo."},
+			},
+			"required": []string{"text"},
+		},
+	}, func(args map[string]any) (string, error) {
 		text, _ := args["text"].(string)
 		return "demo.echo:" + text, nil
 	})
@@ -84,6 +138,47 @@ func configureExtensionTimeouts(m *extension.Manager, hookTimeout, toolTimeout t
 	return nil
 }
 
+func configureSystemPrompt(engine *core.Engine, prompt, promptFile, workdir string, loadContext bool) error {
+	if path := strings.TrimSpace(promptFile); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		prompt = string(data)
+	}
+	engine.SetSystemPrompt(prompt)
+	if !loadContext {
+		return nil
+	}
+	files, err := core.LoadContextFiles(workdir)
+	if err != nil {
+		return err
+	}
+	engine.SetContextFiles(files)
+	return nil
+}
+
+func configureToolApproval(engine *core.Engine, enabled bool, tools string, timeout time.Duration) error {
+	return engine.SetToolApproval(core.ApprovalConfig{Enabled: enabled, Tools: splitList(tools), Timeout: timeout})
+}
+
+func resolveExecProfile(name, file string) (builtins.ExecProfile, error) {
+	if strings.TrimSpace(file) != "" {
+		return builtins.LoadExecProfileFile(file)
+	}
+	return builtins.ExecProfileByName(name)
+}
+
+func splitList(raw string) []string {
+	out := make([]string, 0)
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 func resolveWorkDir(workdir string) (string, error) {
 	dir := strings.TrimSpace(workdir)
 	if dir == "" {
@@ -3,10 +3,13 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"nous/internal/core"
 	"nous/internal/extension"
+	"nous/internal/provider"
 )
 
 func TestConfigureExtensionTimeouts(t *testing.T) {
@@ -77,3 +80,73 @@ func TestResolveWorkDirRejectsFilePath(t *testing.T) {
 		t.Fatalf("expected file workdir to fail")
 	}
 }
+
+func TestConfigureSystemPromptLoadsFileAndContext(t *testing.T) {
+	root := t.TempDir()
+	promptPath := filepath.Join(root, "prompt.md")
+	if err := os.WriteFile(promptPath, []byte("from file"), 0o644); err != nil {
+		t.Fatalf("write fixture failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "AGENTS.md"), []byte("agents"), 0o644); err != nil {
+		t.Fatalf("write fixture failed: %v", err)
+	}
+	engine := core.NewEngine(core.NewRuntime(), provider.NewMockAdapter())
+	if err := configureSystemPrompt(engine, "from flag", promptPath, root, true); err != nil {
+		t.Fatalf("configure system prompt failed: %v", err)
+	}
+	got := engine.SystemPrompt()
+	if !strings.HasPrefix(got, "from file") || !strings.Contains(got, "agents") {
+		t.Fatalf("unexpected system prompt: %q", got)
+	}
+
+	engine = core.NewEngine(core.NewRuntime(), provider.NewMockAdapter())
+	if err := configureSystemPrompt(engine, "from flag", "", root, false); err != nil {
+		t.Fatalf("configure system prompt failed: %v", err)
+	}
+	if got := engine.SystemPrompt(); got != "from flag" {
+		t.Fatalf("expected context files to be skipped, got: %q", got)
+	}
+}
+
+func TestConfigureToolApproval(t *testing.T) {
+	engine := core.NewEngine(core.NewRuntime(), provider.NewMockAdapter())
+	if err := configureToolApproval(engine, true, " bash, write ,,edit", 30*time.Second); err != nil {
+		t.Fatalf("configure tool approval failed: %v", err)
+	}
+	cfg := engine.ToolApproval()
+	if !cfg.Enabled || cfg.Timeout != 30*time.Second {
+		t.Fatalf("unexpected approval config: %+v", cfg)
+	}
+	if strings.Join(cfg.Tools, ",") != "bash,write,edit" {
+		t.Fatalf("unexpected approval tools: %v", cfg.Tools)
+	}
+	if err := configureToolApproval(engine, true, "bash", -time.Second); err == nil {
+		t.Fatalf("expected negative approval timeout error")
+	}
+}
+
+func TestSplitListTrimsAndDropsEmpty(t *testing.T) {
+	if got := splitList(" /a, ,/b ,"); strings.Join(got, "|") != "/a|/b" {
+		t.Fatalf("unexpected split: %v", got)
+	}
+	if got := splitList(""); len(got) != 0 {
+		t.Fatalf("expected empty list, got %v", got)
+	}
+}
+
+func TestResolveExecProfile(t *testing.T) {
+	p, err := resolveExecProfile("strict", "")
+	if err != nil || p.Name != "strict" || p.Limits.CPUSeconds == 0 {
+		t.Fatalf("unexpected strict profile: %+v err=%v", p, err)
+	}
+	if _, err := resolveExecProfile("bogus", ""); err == nil {
+		t.Fatal("expected unknown profile error")
+	}
+	path := filepath.Join(t.TempDir(), "exec.json")
+	if err := os.WriteFile(path, []byte(`{"name":"ci","env_deny":["*"]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := resolveExecProfile("bogus", path); err != nil || p.Name != "ci" {
+		t.Fatalf("expected file profile to override name, got %+v err=%v", p, err)
+	}
+}
@@ -176,6 +176,11 @@ func parseArgs(args []string) (cmd string, payload map[string]any, err error) {
 			return "", nil, fmt.Errorf("get_state does not take arguments")
 		}
 		return string(protocol.CmdGetState), map[string]any{}, nil
+	case "get_policy":
+		if len(args) != 1 {
+			return "", nil, fmt.Errorf("get_policy does not take arguments")
+		}
+		return string(protocol.CmdGetPolicy), map[string]any{}, nil
 	case "get_messages":
 		if len(args) > 2 {
 			return "", nil, fmt.Errorf("get_messages takes at most one optional session id")
@@ -185,6 +190,27 @@ func parseArgs(args []string) (cmd string, payload map[string]any, err error) {
 			payload["session_id"] = args[1]
 		}
 		return string(protocol.CmdGetMessages), payload, nil
+	case "approve":
+		if len(args) < 2 || len(args) > 3 {
+			return "", nil, fmt.Errorf("approve requires tool_call_id and optional scope (once|session)")
+		}
+		payload := map[string]any{"tool_call_id": args[1]}
+		if len(args) == 3 {
+			if args[2] != "once" && args[2] != "session" {
+				return "", nil, fmt.Errorf("approve scope must be once or session")
+			}
+			payload["scope"] = args[2]
+		}
+		return string(protocol.CmdApproveToolCall), payload, nil
+	case "reject":
+		if len(args) < 2 {
+			return "", nil, fmt.Errorf("reject requires tool_call_id")
+		}
+		payload := map[string]any{"tool_call_id": args[1]}
+		if len(args) > 2 {
+			payload["reason"] = strings.Join(args[2:], " ")
+		}
+		return string(protocol.CmdRejectToolCall), payload, nil
 	case "ext":
 		if len(args) < 2 {
 			return "", nil, fmt.Errorf("ext requires command name")
@@ -234,6 +260,9 @@ func usage() {
 	fmt.Fprintln(os.Stderr, "  set_follow_up_mode <one-at-a-time|all>")
 	fmt.Fprintln(os.Stderr, "  get_state")
 	fmt.Fprintln(os.Stderr, "  get_messages [session_id]")
+	fmt.Fprintln(os.Stderr, "  get_policy")
+	fmt.Fprintln(os.Stderr, "  approve <tool_call_id> [once|session]")
+	fmt.Fprintln(os.Stderr, "  reject <tool_call_id> [reason]")
 	fmt.Fprintln(os.Stderr, "  ext <name> [json_payload]")
 }
 
@@ -31,6 +31,7 @@ func TestParseArgs(t *testing.T) {
 		{args: []string{"set_follow_up_mode", "one-at-a-time"}, wantCmd: "set_follow_up_mode"},
 		{args: []string{"get_state"}, wantCmd: "get_state"},
 		{args: []string{"get_messages"}, wantCmd: "get_messages"},
+		{args: []string{"get_policy"}, wantCmd: "get_policy"},
 		{args: []string{"get_messages", "sess-1"}, wantCmd: "get_messages"},
 		{args: []string{"ext", "hello"}, wantCmd: "extension_command"},
 		{args: []string{"ext", "hello", "{\"x\":1}"}, wantCmd: "extension_command"},
@@ -38,6 +39,12 @@ func TestParseArgs(t *testing.T) {
 		{args: []string{"prompt_async"}, wantErr: true},
 		{args: []string{"prompt_stream"}, wantErr: true},
 		{args: []string{"trace"}, wantErr: true},
+		{args: []string{"approve", "call-1"}, wantCmd: "approve_tool_call"},
+		{args: []string{"approve", "call-1", "session"}, wantCmd: "approve_tool_call"},
+		{args: []string{"reject", "call-1", "too", "risky"}, wantCmd: "reject_tool_call"},
+		{args: []string{"approve"}, wantErr: true},
+		{args: []string{"approve", "call-1", "forever"}, wantErr: true},
+		{args: []string{"reject"}, wantErr: true},
 	}
 
 	for _, tc := range tests {
@@ -157,3 +164,21 @@ func TestParseArgsGetMessagesOptionalSessionID(t *testing.T) {
 		t.Fatalf("expected session_id payload, got: %+v", payload)
 	}
 }
+
+func TestParseArgsApprovalPayloads(t *testing.T) {
+	_, payload, err := parseArgs([]string{"approve", "call-1", "session"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payload["tool_call_id"] != "call-1" || payload["scope"] != "session" {
+		t.Fatalf("unexpected approve payload: %+v", payload)
+	}
+
+	_, payload, err = parseArgs([]string{"reject", "call-2", "too", "risky"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payload["tool_call_id"] != "call-2" || payload["reason"] != "too risky" {
+		t.Fatalf("unexpected reject payload: %+v", payload)
+	}
+}
@@ -201,6 +201,34 @@ func parseInput(line string) (cmd string, payload map[string]any, quit bool, err
 			tools = append(tools, p)
 		}
 		return string(protocol.CmdSetActiveTools), map[string]any{"tools": tools}, false, nil
+	case strings.HasPrefix(line, "approve "):
+		fields := strings.Fields(strings.TrimPrefix(line, "approve "))
+		if len(fields) == 0 || len(fields) > 2 {
+			return "", nil, false, fmt.Errorf("usage: approve <tool_call_id> [once|session]")
+		}
+		payload := map[string]any{"tool_call_id": fields[0]}
+		if len(fields) == 2 {
+			if fields[1] != "once" && fields[1] != "session" {
+				return "", nil, false, fmt.Errorf("approve scope must be once or session")
+			}
+			payload["scope"] = fields[1]
+		}
+		return string(protocol.CmdApproveToolCall), payload, false, nil
+	case strings.HasPrefix(line, "reject "):
+		rest := strings.TrimSpace(strings.TrimPrefix(line, "reject "))
+		if rest == "" {
+			return "", nil, false, fmt.Errorf("tool call id is required")
+		}
+		id, reason := rest, ""
+		if idx := strings.IndexByte(rest, ' '); idx >= 0 {
+			id = rest[:idx]
+			reason = strings.TrimSpace(rest[idx+1:])
+		}
+		payload := map[string]any{"tool_call_id": id}
+		if reason != "" {
+			payload["reason"] = reason
+		}
+		return string(protocol.CmdRejectToolCall), payload, false, nil
 	case strings.HasPrefix(line, "ext "):
 		rest := strings.TrimSpace(strings.TrimPrefix(line, "ext "))
 		if rest == "" {
@@ -237,6 +265,8 @@ func printHelp() {
 	fmt.Println("  switch <session_id>")
 	fmt.Println("  branch <session_id>")
 	fmt.Println("  set_active_tools [tool...]   (no args = clear all)")
+	fmt.Println("  approve <tool_call_id> [once|session]")
+	fmt.Println("  reject <tool_call_id> [reason]")
 	fmt.Println("  ext <name> [json_payload]")
 	fmt.Println("  status")
 	fmt.Println("  help")
@@ -268,12 +298,28 @@ func renderEvent(ev map[string]any) {
 			fmt.Printf("assistant: %s\n", delta)
 		}
 	case "tool_execution_start", "tool_execution_update", "tool_execution_end":
+		if details, ok := ev["details"].(map[string]any); ok {
+			if diff, _ := details["diff"].(string); diff != "" {
+				renderDiff(toolName, details, diff)
+				break
+			}
+		}
 		delta, _ := ev["delta"].(string)
 		if delta != "" {
 			fmt.Printf("tool: %s name=%s run=%s turn=%s delta=%s\n", tp, toolName, runID, turnID, delta)
 			break
 		}
 		fmt.Printf("tool: %s name=%s run=%s turn=%s\n", tp, toolName, runID, turnID)
+	case "tool_approval_requested":
+		callID, _ := ev["tool_call_id"].(string)
+		args := ""
+		if raw, ok := ev["arguments"].(map[string]any); ok && len(raw) > 0 {
+			if b, err := json.Marshal(raw); err == nil {
+				args = " args=" + string(b)
+			}
+		}
+		fmt.Printf("approval: %s wants to run%s (id=%s)\n", toolName, args, callID)
+		fmt.Printf("approval: answer with: approve %s [session] | reject %s [reason]\n", callID, callID)
 	case "agent_start", "agent_end", "turn_start", "turn_end":
 		if runID != "" || turnID != "" {
 			fmt.Printf("status: %s run=%s turn=%s\n", tp, runID, turnID)
@@ -304,6 +350,20 @@ func renderEvent(ev map[string]any) {
 	}
 }
 
+// renderDiff prints the diff a write or edit published instead of the
+// plain result text.
+func renderDiff(toolName string, details map[string]any, diff string) {
+	path, _ := details["path"].(string)
+	line, _ := details["first_changed_line"].(float64)
+	adds, _ := details["additions"].(float64)
+	dels, _ := details["deletions"].(float64)
+	fmt.Printf("diff: %s %s first_changed_line=%d +%d -%d\n", toolName, path, int(line), int(adds), int(dels))
+	fmt.Print(diff)
+	if !strings.HasSuffix(diff, "\n") {
+		fmt.Println()
+	}
+}
+
 func streamRunEvents(socket, runID string, onEvent func(protocol.Envelope)) error {
 	conn, err := net.DialTimeout("unix", socket+".events", 500*time.Millisecond)
 	if err != nil {
@@ -28,6 +28,10 @@ func TestParseInput(t *testing.T) {
 		{in: "set_active_tools tool_a tool_b", wantCmd: "set_active_tools"},
 		{in: "ext hello", wantCmd: "extension_command"},
 		{in: "ext hello {\"x\":1}", wantCmd: "extension_command"},
+		{in: "approve call-1", wantCmd: "approve_tool_call"},
+		{in: "approve call-1 session", wantCmd: "approve_tool_call"},
+		{in: "reject call-1 not now", wantCmd: "reject_tool_call"},
+		{in: "approve call-1 always", wantErr: true},
 		{in: "quit", wantQ: true},
 		{in: "prompt ", wantErr: true},
 	}
@@ -203,6 +207,40 @@ func TestRenderResultRendersProgressFieldsForRunTurnTool(t *testing.T) {
 	}
 }
 
+func TestRenderEventShowsDiffDetails(t *testing.T) {
+	ev := map[string]any{
+		"type":      "tool_execution_update",
+		"tool_name": "edit",
+		"delta":     "edited a.txt",
+		"details": map[string]any{
+			"path":               "a.txt",
+			"diff":               "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+b\n",
+			"first_changed_line": float64(1),
+			"additions":          float64(1),
+			"deletions":          float64(1),
+		},
+	}
+
+	old := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe failed: %v", err)
+	}
+	os.Stdout = w
+	renderEvent(ev)
+	_ = w.Close()
+	os.Stdout = old
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		t.Fatalf("read stdout failed: %v", err)
+	}
+	want := "diff: edit a.txt first_changed_line=1 +1 -1\n--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+b\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("unexpected diff rendering:\n%s", got)
+	}
+}
+
 func TestParseInputBranchUsesSessionID(t *testing.T) {
 	_, payload, _, err := parseInput("branch sess-1")
 	if err != nil {
@@ -261,3 +299,44 @@ func TestRunQueueStateIgnoresMismatchedRun(t *testing.T) {
 		t.Fatalf("expected mismatched run event to be ignored")
 	}
 }
+
+func TestParseInputRejectReason(t *testing.T) {
+	_, payload, _, err := parseInput("reject call-1 touches prod config")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payload["tool_call_id"] != "call-1" || payload["reason"] != "touches prod config" {
+		t.Fatalf("unexpected reject payload: %+v", payload)
+	}
+}
+
+func TestRenderEventShowsApprovalRequest(t *testing.T) {
+	old := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe failed: %v", err)
+	}
+	os.Stdout = w
+	renderEvent(map[string]any{
+		"type":         "tool_approval_requested",
+		"tool_call_id": "call-7",
+		"tool_name":    "bash",
+		"arguments":    map[string]any{"command": "rm -rf build"},
+	})
+	_ = w.Close()
+	os.Stdout = old
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		t.Fatalf("read stdout failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`approval: bash wants to run args={"command":"rm -rf build"} (id=call-7)`,
+		"approve call-7 [session] | reject call-7 [reason]",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("render output missing %q in:\n%s", want, out)
+		}
+	}
+}
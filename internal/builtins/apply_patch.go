@@ -0,0 +1,709 @@
+package builtins
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"nous/internal/core"
+)
+
+const (
+	patchAdd    = "add"
+	patchUpdate = "update"
+	patchDelete = "delete"
+
+	// patchMaxFuzz is how many leading and trailing context lines a hunk may
+	// drop when it does not match as written.
+	patchMaxFuzz = 2
+)
+
+var unifiedHunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+type patchLine struct {
+	kind byte // ' ', '-' or '+'
+	text string
+}
+
+type patchHunk struct {
+	// at is the 0-based old line a unified hunk starts at; -1 when the patch
+	// gives no position.
+	at int
+	// anchor is the line a "*** Begin Patch" hunk follows ("@@ func f()").
+	anchor    string
+	lines     []patchLine
+	oldNoEOL  bool
+	newNoEOL  bool
+	endOfFile bool
+}
+
+type filePatch struct {
+	op     string
+	path   string
+	moveTo string
+	hunks  []patchHunk
+}
+
+// patchFile is the staged state of one file while a patch is checked.
+type patchFile struct {
+	abs      string
+	original []byte
+	existed  bool
+	content  []byte
+	exists   bool
+}
+
+type patchResult struct {
+	op    string
+	path  string
+	dest  string
+	diff  fileDiff
+	fuzzy []int
+}
+
+func newApplyPatchTool(paths *pathResolver) core.Tool {
+	return core.DetailedToolFunc{
+		ToolName:        "apply_patch",
+		ToolDescription: "Apply a patch to one or more files. Accepts unified diffs (as from git diff) or the *** Begin Patch format with *** Add File, *** Update File (optionally *** Move to) and *** Delete File sections. Every hunk is checked before any file is written; hunks whose context drifted are matched with fuzz.",
+		ToolSchema: objectSchema(map[string]any{
+			"patch": withAliases(requiredStringProperty("Patch text."), "input", "diff"),
+		}, "patch"),
+		Run: func(_ context.Context, args map[string]any) (core.ToolResult, error) {
+			text, _ := args["patch"].(string)
+			if strings.TrimSpace(text) == "" {
+				return core.ToolResult{}, fmt.Errorf("apply_patch_empty")
+			}
+			files, err := parsePatch(text)
+			if err != nil {
+				return core.ToolResult{}, err
+			}
+			results, staged, err := stagePatch(paths, files)
+			if err != nil {
+				return core.ToolResult{}, err
+			}
+			if err := writeStagedPatch(staged); err != nil {
+				return core.ToolResult{}, err
+			}
+			return patchToolResult(results), nil
+		},
+	}
+}
+
+// parsePatch reads either patch format into per-file operations.
+func parsePatch(text string) ([]filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.TrimSpace(line) == "*** Begin Patch" {
+			return parseCodexPatch(lines, i+1)
+		}
+		break
+	}
+	return parseUnifiedPatch(lines)
+}
+
+func patchSyntaxError(line int, format string, args ...any) error {
+	return fmt.Errorf("apply_patch_invalid: line %d: %s", line+1, fmt.Sprintf(format, args...))
+}
+
+func parseCodexPatch(lines []string, i int) ([]filePatch, error) {
+	var files []filePatch
+	for i < len(lines) {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "*** End Patch":
+			if len(files) == 0 {
+				return nil, fmt.Errorf("apply_patch_empty")
+			}
+			return files, nil
+		case strings.HasPrefix(line, "*** Add File: "):
+			fp := filePatch{op: patchAdd, path: strings.TrimSpace(strings.TrimPrefix(line, "*** Add File: "))}
+			h := patchHunk{at: -1}
+			for i++; i < len(lines) && !strings.HasPrefix(lines[i], "*** "); i++ {
+				if !strings.HasPrefix(lines[i], "+") {
+					return nil, patchSyntaxError(i, "added file lines must start with +")
+				}
+				h.lines = append(h.lines, patchLine{kind: '+', text: lines[i][1:]})
+			}
+			fp.hunks = []patchHunk{h}
+			files = append(files, fp)
+		case strings.HasPrefix(line, "*** Delete File: "):
+			files = append(files, filePatch{op: patchDelete, path: strings.TrimSpace(strings.TrimPrefix(line, "*** Delete File: "))})
+			i++
+		case strings.HasPrefix(line, "*** Update File: "):
+			fp := filePatch{op: patchUpdate, path: strings.TrimSpace(strings.TrimPrefix(line, "*** Update File: "))}
+			i++
+			if i < len(lines) && strings.HasPrefix(lines[i], "*** Move to: ") {
+				fp.moveTo = strings.TrimSpace(strings.TrimPrefix(lines[i], "*** Move to: "))
+				i++
+			}
+			h := patchHunk{at: -1}
+			started := false
+			for ; i < len(lines); i++ {
+				l := lines[i]
+				if l == "*** End of File" {
+					h.endOfFile = true
+					continue
+				}
+				if strings.HasPrefix(l, "*** ") {
+					break
+				}
+				if strings.HasPrefix(l, "@@") {
+					if len(h.lines) > 0 {
+						fp.hunks = append(fp.hunks, h)
+					}
+					h = patchHunk{at: -1}
+					if m := unifiedHunkHeader.FindStringSubmatch(l); m != nil {
+						h.at = unifiedHunkStart(m)
+					} else {
+						h.anchor = strings.TrimSpace(strings.TrimPrefix(l, "@@"))
+					}
+					started = true
+					continue
+				}
+				pl, ok := parsePatchLine(l)
+				if !ok {
+					return nil, patchSyntaxError(i, "unexpected %q in update of %s", l, fp.path)
+				}
+				h.lines = append(h.lines, pl)
+				started = true
+			}
+			if len(h.lines) > 0 {
+				fp.hunks = append(fp.hunks, h)
+			}
+			if !started && fp.moveTo == "" {
+				return nil, patchSyntaxError(i-1, "update of %s has no hunks", fp.path)
+			}
+			files = append(files, fp)
+		case strings.TrimSpace(line) == "":
+			i++
+		default:
+			return nil, patchSyntaxError(i, "unexpected %q", line)
+		}
+	}
+	return nil, fmt.Errorf("apply_patch_invalid: missing *** End Patch")
+}
+
+// parsePatchLine reads a hunk body line. A blank line counts as empty
+// context, since editors often strip the leading space.
+func parsePatchLine(l string) (patchLine, bool) {
+	if l == "" {
+		return patchLine{kind: ' '}, true
+	}
+	switch l[0] {
+	case ' ', '-', '+':
+		return patchLine{kind: l[0], text: l[1:]}, true
+	}
+	return patchLine{}, false
+}
+
+// unifiedHunkStart converts a hunk header's old range to the 0-based line the
+// hunk starts at. An empty range names the line before it.
+func unifiedHunkStart(m []string) int {
+	start, _ := strconv.Atoi(m[1])
+	if m[2] == "0" {
+		return start
+	}
+	return max(0, start-1)
+}
+
+func parseUnifiedPatch(lines []string) ([]filePatch, error) {
+	var files []filePatch
+	var renameFrom, renameTo string
+	sawFile := false
+	flushRename := func() {
+		// A pure git rename has rename headers but no ---/+++ pair.
+		if !sawFile && renameFrom != "" && renameTo != "" {
+			files = append(files, filePatch{op: patchUpdate, path: renameFrom, moveTo: renameTo})
+		}
+		renameFrom, renameTo, sawFile = "", "", false
+	}
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flushRename()
+			i++
+		case strings.HasPrefix(line, "rename from "):
+			renameFrom = strings.TrimSpace(strings.TrimPrefix(line, "rename from "))
+			i++
+		case strings.HasPrefix(line, "rename to "):
+			renameTo = strings.TrimSpace(strings.TrimPrefix(line, "rename to "))
+			i++
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			oldPath, newPath := unifiedPatchPaths(line[4:], lines[i+1][4:])
+			fp := filePatch{op: patchUpdate, path: oldPath}
+			switch {
+			case oldPath == "" && newPath == "":
+				return nil, patchSyntaxError(i, "both sides are /dev/null")
+			case oldPath == "":
+				fp = filePatch{op: patchAdd, path: newPath}
+			case newPath == "":
+				fp = filePatch{op: patchDelete, path: oldPath}
+			case newPath != oldPath:
+				fp.moveTo = newPath
+			}
+			sawFile = true
+			i += 2
+			for i < len(lines) && strings.HasPrefix(lines[i], "@@") {
+				h, next, err := parseUnifiedHunk(lines, i)
+				if err != nil {
+					return nil, err
+				}
+				fp.hunks = append(fp.hunks, h)
+				i = next
+			}
+			if fp.op == patchUpdate && len(fp.hunks) == 0 && fp.moveTo == "" {
+				return nil, patchSyntaxError(i-1, "%s has no hunks", fp.path)
+			}
+			files = append(files, fp)
+		case strings.HasPrefix(line, "@@"):
+			return nil, patchSyntaxError(i, "hunk without ---/+++ file header")
+		default:
+			// git metadata (index, mode lines) and commentary.
+			i++
+		}
+	}
+	flushRename()
+	if len(files) == 0 {
+		return nil, fmt.Errorf("apply_patch_invalid: no file headers found; expected a unified diff or *** Begin Patch")
+	}
+	return files, nil
+}
+
+// unifiedPatchPaths strips timestamps and, when both sides use them, the
+// a/ and b/ prefixes git adds. /dev/null becomes "".
+func unifiedPatchPaths(oldRaw, newRaw string) (string, string) {
+	clean := func(s string) string {
+		s, _, _ = strings.Cut(s, "\t")
+		s = strings.TrimSpace(s)
+		if s == "/dev/null" {
+			return ""
+		}
+		return s
+	}
+	oldPath, newPath := clean(oldRaw), clean(newRaw)
+	if (oldPath == "" || strings.HasPrefix(oldPath, "a/")) && (newPath == "" || strings.HasPrefix(newPath, "b/")) {
+		oldPath = strings.TrimPrefix(oldPath, "a/")
+		newPath = strings.TrimPrefix(newPath, "b/")
+	}
+	return oldPath, newPath
+}
+
+func parseUnifiedHunk(lines []string, i int) (patchHunk, int, error) {
+	m := unifiedHunkHeader.FindStringSubmatch(lines[i])
+	if m == nil {
+		return patchHunk{}, 0, patchSyntaxError(i, "invalid hunk header %q", lines[i])
+	}
+	count := func(s string) int {
+		if s == "" {
+			return 1
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	oldLeft, newLeft := count(m[2]), count(m[4])
+	h := patchHunk{at: unifiedHunkStart(m)}
+	for i++; oldLeft > 0 || newLeft > 0; i++ {
+		if i >= len(lines) {
+			return patchHunk{}, 0, patchSyntaxError(i-1, "hunk ends early")
+		}
+		if strings.HasPrefix(lines[i], `\`) {
+			continue
+		}
+		pl, ok := parsePatchLine(lines[i])
+		if !ok {
+			return patchHunk{}, 0, patchSyntaxError(i, "unexpected %q in hunk", lines[i])
+		}
+		if pl.kind != '+' {
+			oldLeft--
+		}
+		if pl.kind != '-' {
+			newLeft--
+		}
+		if oldLeft < 0 || newLeft < 0 {
+			return patchHunk{}, 0, patchSyntaxError(i, "hunk longer than its header")
+		}
+		h.lines = append(h.lines, pl)
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], `\`) {
+			if pl.kind != '+' {
+				h.oldNoEOL = true
+			}
+			if pl.kind != '-' {
+				h.newNoEOL = true
+			}
+		}
+	}
+	for i < len(lines) && strings.HasPrefix(lines[i], `\`) {
+		i++
+	}
+	return h, i, nil
+}
+
+// stagePatch applies every file operation in memory. Nothing is written; an
+// error means the patch does not apply.
+func stagePatch(paths *pathResolver, files []filePatch) ([]patchResult, []*patchFile, error) {
+	byAbs := map[string]*patchFile{}
+	var order []*patchFile
+	load := func(path string) (*patchFile, error) {
+		abs, err := paths.resolve(path)
+		if err != nil {
+			return nil, err
+		}
+		if f, ok := byAbs[abs]; ok {
+			return f, nil
+		}
+		f := &patchFile{abs: abs}
+		b, err := os.ReadFile(abs)
+		switch {
+		case err == nil:
+			f.original, f.existed = b, true
+			f.content, f.exists = b, true
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, fmt.Errorf("apply_patch_failed: %w", err)
+		}
+		byAbs[abs] = f
+		order = append(order, f)
+		return f, nil
+	}
+
+	results := make([]patchResult, 0, len(files))
+	for _, fp := range files {
+		if fp.path == "" {
+			return nil, nil, fmt.Errorf("apply_patch_invalid: empty file path")
+		}
+		f, err := load(fp.path)
+		if err != nil {
+			return nil, nil, err
+		}
+		res := patchResult{op: fp.op, path: fp.path}
+		switch fp.op {
+		case patchAdd:
+			if f.exists {
+				return nil, nil, fmt.Errorf("apply_patch_file_exists: %s", fp.path)
+			}
+			content := addedFileContent(fp.hunks)
+			f.content, f.exists = []byte(content), true
+			res.diff = unifiedDiff(fp.path, "", content, false)
+		case patchDelete:
+			if !f.exists {
+				return nil, nil, fmt.Errorf("apply_patch_file_not_found: %s", fp.path)
+			}
+			old, _, _ := decodeText(f.content)
+			f.content, f.exists = nil, false
+			res.diff = unifiedDiff(fp.path, old, "", true)
+			res.diff.Text = strings.Replace(res.diff.Text, "+++ b/"+fp.path+"\n", "+++ /dev/null\n", 1)
+		case patchUpdate:
+			if !f.exists {
+				return nil, nil, fmt.Errorf("apply_patch_file_not_found: %s", fp.path)
+			}
+			old, bom, crlf := decodeText(f.content)
+			updated, fuzzy, err := applyHunks(old, fp.hunks, fp.path)
+			if err != nil {
+				return nil, nil, err
+			}
+			res.fuzzy = fuzzy
+			target := f
+			shown := fp.path
+			if fp.moveTo != "" {
+				dest, err := load(fp.moveTo)
+				if err != nil {
+					return nil, nil, err
+				}
+				if dest != f {
+					if dest.exists {
+						return nil, nil, fmt.Errorf("apply_patch_file_exists: %s", fp.moveTo)
+					}
+					f.content, f.exists = nil, false
+					target = dest
+					res.dest = fp.moveTo
+					shown = fp.moveTo
+				}
+			}
+			target.content, target.exists = encodeText(updated, bom, crlf), true
+			res.diff = unifiedDiff(shown, old, updated, true)
+		}
+		results = append(results, res)
+	}
+	return results, order, nil
+}
+
+// addedFileContent joins the added lines of a new file.
+func addedFileContent(hunks []patchHunk) string {
+	var b strings.Builder
+	noEOL := false
+	for _, h := range hunks {
+		for _, l := range h.lines {
+			if l.kind == '-' {
+				continue
+			}
+			b.WriteString(l.text)
+			b.WriteByte('\n')
+		}
+		noEOL = h.newNoEOL
+	}
+	content := b.String()
+	if noEOL {
+		content = strings.TrimSuffix(content, "\n")
+	}
+	return content
+}
+
+// applyHunks applies hunks in order to LF text. Each hunk is matched at or
+// after the previous one, nearest its stated position; fuzzy lists the
+// 1-based hunks that needed whitespace or context fuzz.
+func applyHunks(text string, hunks []patchHunk, path string) (string, []int, error) {
+	var lines []string
+	if text != "" {
+		lines = strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	}
+	trailingNewline := text == "" || strings.HasSuffix(text, "\n")
+	var fuzzy []int
+	cursor, shift := 0, 0
+	for n, h := range hunks {
+		start := cursor
+		if h.anchor != "" {
+			idx := findPatchAnchor(lines, h.anchor, cursor)
+			if idx < 0 {
+				return "", nil, fmt.Errorf("apply_patch_hunk_failed: %s hunk %d: context %q not found", path, n+1, h.anchor)
+			}
+			start = idx + 1
+		}
+		hint := -1
+		if h.at >= 0 {
+			hint = h.at + shift
+		}
+		pos, body, fuzz := locateHunk(lines, h, start, hint)
+		if pos < 0 {
+			return "", nil, fmt.Errorf("apply_patch_hunk_failed: %s hunk %d: lines to change not found; re-read the file and regenerate the patch", path, n+1)
+		}
+		if fuzz {
+			fuzzy = append(fuzzy, n+1)
+		}
+
+		var replaced []string
+		fi := pos
+		for _, l := range body {
+			switch l.kind {
+			case ' ':
+				replaced = append(replaced, lines[fi])
+				fi++
+			case '-':
+				fi++
+			case '+':
+				replaced = append(replaced, l.text)
+			}
+		}
+		next := append([]string{}, lines[:pos]...)
+		next = append(next, replaced...)
+		lines = append(next, lines[fi:]...)
+		cursor = pos + len(replaced)
+		shift += len(replaced) - (fi - pos)
+
+		if h.newNoEOL {
+			trailingNewline = false
+		} else if h.oldNoEOL {
+			trailingNewline = true
+		}
+	}
+	out := strings.Join(lines, "\n")
+	if trailingNewline && len(lines) > 0 {
+		out += "\n"
+	}
+	return out, fuzzy, nil
+}
+
+func findPatchAnchor(lines []string, anchor string, from int) int {
+	for _, eq := range patchLineComparators {
+		for i := from; i < len(lines); i++ {
+			if eq(lines[i], anchor) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// patchLineComparators are tried in order: exact, then ignoring trailing
+// whitespace, then ignoring surrounding whitespace.
+var patchLineComparators = []func(a, b string) bool{
+	func(a, b string) bool { return a == b },
+	func(a, b string) bool { return strings.TrimRight(a, " \t") == strings.TrimRight(b, " \t") },
+	func(a, b string) bool { return strings.TrimSpace(a) == strings.TrimSpace(b) },
+}
+
+// locateHunk finds where h applies at or after start, preferring the match
+// nearest hint. Without an exact match it compares whitespace-insensitively
+// and then drops up to patchMaxFuzz context lines from each end. It returns
+// the position, the hunk lines that matched there and whether fuzz was used.
+func locateHunk(lines []string, h patchHunk, start, hint int) (int, []patchLine, bool) {
+	lead, trail := 0, 0
+	for lead < len(h.lines) && h.lines[lead].kind == ' ' {
+		lead++
+	}
+	for trail < len(h.lines)-lead && h.lines[len(h.lines)-1-trail].kind == ' ' {
+		trail++
+	}
+	for fuzz := 0; fuzz <= patchMaxFuzz; fuzz++ {
+		dropLead, dropTrail := min(fuzz, lead), min(fuzz, trail)
+		if fuzz > 0 && dropLead < fuzz && dropTrail < fuzz {
+			break
+		}
+		body := h.lines[dropLead : len(h.lines)-dropTrail]
+		var old []string
+		for _, l := range body {
+			if l.kind != '+' {
+				old = append(old, l.text)
+			}
+		}
+		if len(old) == 0 {
+			// Pure insertion: use the stated position, else the end of file.
+			pos := len(lines)
+			if hint >= 0 && !h.endOfFile {
+				pos = min(max(hint, start), len(lines))
+			} else if h.anchor != "" && !h.endOfFile {
+				pos = start
+			}
+			return pos, body, false
+		}
+		for c, eq := range patchLineComparators {
+			best := -1
+			for pos := start; pos+len(old) <= len(lines); pos++ {
+				if h.endOfFile && dropTrail == 0 && pos+len(old) != len(lines) {
+					continue
+				}
+				if !patchLinesMatch(lines[pos:pos+len(old)], old, eq) {
+					continue
+				}
+				if best < 0 || lineDistance(pos, hint+dropLead) < lineDistance(best, hint+dropLead) {
+					best = pos
+				}
+				if hint < 0 {
+					break
+				}
+			}
+			if best >= 0 {
+				return best, body, fuzz > 0 || c > 0
+			}
+		}
+	}
+	return -1, nil, false
+}
+
+func patchLinesMatch(got, want []string, eq func(a, b string) bool) bool {
+	for i := range want {
+		if !eq(got[i], want[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func lineDistance(a, b int) int {
+	if a < b {
+		return b - a
+	}
+	return a - b
+}
+
+// writeStagedPatch writes the staged files, restoring the ones already
+// written if a later write fails.
+func writeStagedPatch(files []*patchFile) error {
+	var done []*patchFile
+	for _, f := range files {
+		if f.exists == f.existed && bytes.Equal(f.content, f.original) {
+			continue
+		}
+		var err error
+		if f.exists {
+			if err = os.MkdirAll(filepath.Dir(f.abs), 0o755); err == nil {
+				err = os.WriteFile(f.abs, f.content, 0o644)
+			}
+		} else {
+			err = os.Remove(f.abs)
+		}
+		if err != nil {
+			for i := len(done) - 1; i >= 0; i-- {
+				if d := done[i]; d.existed {
+					_ = os.WriteFile(d.abs, d.original, 0o644)
+				} else {
+					_ = os.Remove(d.abs)
+				}
+			}
+			return fmt.Errorf("apply_patch_failed: %w", err)
+		}
+		done = append(done, f)
+	}
+	return nil
+}
+
+func patchToolResult(results []patchResult) core.ToolResult {
+	var summary, diffs strings.Builder
+	fmt.Fprintf(&summary, "applied patch to %d file", len(results))
+	if len(results) != 1 {
+		summary.WriteString("s")
+	}
+	summary.WriteString(":\n")
+
+	files := make([]map[string]any, 0, len(results))
+	names := make([]string, 0, len(results))
+	additions, deletions := 0, 0
+	for _, r := range results {
+		name := r.path
+		verb := map[string]string{patchAdd: "added", patchUpdate: "updated", patchDelete: "deleted"}[r.op]
+		if r.dest != "" {
+			verb, name = "moved", r.path+" -> "+r.dest
+		}
+		fmt.Fprintf(&summary, "%s %s (+%d -%d)", verb, name, r.diff.Additions, r.diff.Deletions)
+		if len(r.fuzzy) > 0 {
+			hunks := make([]string, 0, len(r.fuzzy))
+			for _, n := range r.fuzzy {
+				hunks = append(hunks, strconv.Itoa(n))
+			}
+			fmt.Fprintf(&summary, "; fuzz in hunk %s", strings.Join(hunks, ", "))
+		}
+		summary.WriteString("\n")
+		if r.diff.FirstChangedLine > 0 {
+			diffs.WriteString(r.diff.Text)
+		}
+
+		d := r.diff.details()
+		d["action"] = r.op
+		if r.dest != "" {
+			d["action"] = "move"
+			d["from"] = r.path
+		}
+		files = append(files, d)
+		names = append(names, r.diff.Path)
+		additions += r.diff.Additions
+		deletions += r.diff.Deletions
+	}
+
+	details := map[string]any{
+		"path":      strings.Join(names, ", "),
+		"diff":      diffs.String(),
+		"additions": additions,
+		"deletions": deletions,
+		"files":     files,
+	}
+	if len(results) > 0 {
+		details["first_changed_line"] = results[0].diff.FirstChangedLine
+	}
+	text := summary.String()
+	if diffs.Len() > 0 {
+		text += "\n" + diffs.String()
+	}
+	return core.ToolResult{Text: strings.TrimSuffix(text, "\n"), Details: details}
+}
@@ -0,0 +1,184 @@
+package builtins
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nous/internal/core"
+)
+
+func writePatchFixtures(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func readPatchFixture(t *testing.T, dir, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return string(b)
+}
+
+func runApplyPatch(dir, patch string) (core.ToolResult, error) {
+	tool := newApplyPatchTool(newPathResolver(Config{Workdir: dir})).(core.DetailedTool)
+	return tool.ExecuteWithDetails(context.Background(), map[string]any{"patch": patch})
+}
+
+func TestApplyPatchCodexFormat(t *testing.T) {
+	dir := writePatchFixtures(t, map[string]string{
+		"main.go":  "package main\n\nfunc a() {\n\treturn\n}\n\nfunc b() {\n\treturn\n}\n",
+		"old.txt":  "keep\n",
+		"gone.txt": "bye\n",
+	})
+	res, err := runApplyPatch(dir, `*** Begin Patch
+*** Add File: docs/new.md
++# New
++text
+*** Update File: main.go
+@@ func b() {
+-	return
++	println("b")
+*** Update File: old.txt
+*** Move to: moved/new.txt
+@@
+-keep
++kept
+*** Delete File: gone.txt
+*** End Patch`)
+	if err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	for _, want := range []string{
+		"applied patch to 4 files:",
+		"added docs/new.md (+2 -0)",
+		"updated main.go (+1 -1)",
+		"moved old.txt -> moved/new.txt (+1 -1)",
+		"deleted gone.txt (+0 -1)",
+		"+++ /dev/null",
+	} {
+		if !strings.Contains(res.Text, want) {
+			t.Fatalf("result missing %q:\n%s", want, res.Text)
+		}
+	}
+	if got := readPatchFixture(t, dir, "main.go"); got != "package main\n\nfunc a() {\n\treturn\n}\n\nfunc b() {\n\tprintln(\"b\")\n}\n" {
+		t.Fatalf("anchor must select the second return, got %q", got)
+	}
+	if got := readPatchFixture(t, dir, "docs/new.md"); got != "# New\ntext\n" {
+		t.Fatalf("unexpected added file: %q", got)
+	}
+	if got := readPatchFixture(t, dir, "moved/new.txt"); got != "kept\n" {
+		t.Fatalf("unexpected moved file: %q", got)
+	}
+	for _, name := range []string{"old.txt", "gone.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Fatalf("expected %s removed, got %v", name, err)
+		}
+	}
+	if files, _ := res.Details["files"].([]map[string]any); len(files) != 4 || files[2]["action"] != "move" {
+		t.Fatalf("unexpected per-file details: %+v", res.Details["files"])
+	}
+}
+
+func TestApplyPatchUnifiedDiffWithFuzz(t *testing.T) {
+	dir := writePatchFixtures(t, map[string]string{
+		"a.txt": "inserted\none\ntwo  \nthree\nfour\nfive\n",
+		"b.txt": "x\ny",
+	})
+	res, err := runApplyPatch(dir, `diff --git a/a.txt b/a.txt
+index 1111111..2222222 100644
+--- a/a.txt
++++ b/a.txt
+@@ -1,4 +1,4 @@
+ one
+ two
+-three
++THREE
+ four
+diff --git a/b.txt b/b.txt
+--- a/b.txt
++++ b/b.txt
+@@ -1,2 +1,2 @@
+ x
+-y
+\ No newline at end of file
++z
+`)
+	if err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if got := readPatchFixture(t, dir, "a.txt"); got != "inserted\none\ntwo  \nTHREE\nfour\nfive\n" {
+		t.Fatalf("unexpected a.txt: %q", got)
+	}
+	if !strings.Contains(res.Text, "updated a.txt (+1 -1); fuzz in hunk 1") {
+		t.Fatalf("expected fuzz to be reported:\n%s", res.Text)
+	}
+	if got := readPatchFixture(t, dir, "b.txt"); got != "x\nz\n" {
+		t.Fatalf("expected trailing newline added, got %q", got)
+	}
+}
+
+func TestApplyPatchDropsDriftedContext(t *testing.T) {
+	dir := writePatchFixtures(t, map[string]string{"a.txt": "header changed\nbody\nold\nfooter\n"})
+	_, err := runApplyPatch(dir, "--- a.txt\n+++ a.txt\n@@ -1,4 +1,4 @@\n header\n body\n-old\n+new\n footer\n")
+	if err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if got := readPatchFixture(t, dir, "a.txt"); got != "header changed\nbody\nnew\nfooter\n" {
+		t.Fatalf("unexpected content: %q", got)
+	}
+}
+
+func TestApplyPatchLeavesTreeUntouchedOnFailure(t *testing.T) {
+	dir := writePatchFixtures(t, map[string]string{"a.txt": "one\n", "b.txt": "two\n"})
+	_, err := runApplyPatch(dir, `*** Begin Patch
+*** Update File: a.txt
+-one
++ONE
+*** Add File: c.txt
++new
+*** Update File: b.txt
+-missing
++x
+*** End Patch`)
+	if err == nil || !strings.HasPrefix(err.Error(), "apply_patch_hunk_failed: b.txt hunk 1") {
+		t.Fatalf("expected hunk failure on b.txt, got %v", err)
+	}
+	if got := readPatchFixture(t, dir, "a.txt"); got != "one\n" {
+		t.Fatalf("a failed patch must not write earlier files, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "c.txt")); !os.IsNotExist(err) {
+		t.Fatalf("a failed patch must not add files, got %v", err)
+	}
+}
+
+func TestApplyPatchRejectsBadPatches(t *testing.T) {
+	dir := writePatchFixtures(t, map[string]string{"a.txt": "one\n"})
+	cases := map[string]string{
+		"":                                     "apply_patch_empty",
+		"just some text":                       "apply_patch_invalid",
+		"*** Begin Patch\n*** Add File: x\n+y": "apply_patch_invalid: missing *** End Patch",
+		"*** Begin Patch\n*** Add File: a.txt\n+y\n*** End Patch":   "apply_patch_file_exists: a.txt",
+		"*** Begin Patch\n*** Delete File: nope.txt\n*** End Patch": "apply_patch_file_not_found: nope.txt",
+		"--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n one\n":         "apply_patch_invalid: line 4: hunk ends early",
+	}
+	for patch, want := range cases {
+		if _, err := runApplyPatch(dir, patch); err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Fatalf("patch %q: expected %s, got %v", patch, want, err)
+		}
+	}
+}
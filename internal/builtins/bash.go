@@ -1,6 +1,7 @@
 package builtins
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
@@ -11,6 +12,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"nous/internal/core"
@@ -22,11 +24,52 @@ const (
 )
 
 func NewBashTool(cwd string) core.Tool {
-	base := resolveBaseDir(cwd)
+	return newBashTool(resolveBaseDir(cwd), defaultShell(), DefaultExecProfile(), false)
+}
+
+// NewPersistentBashTool returns a bash tool that runs every call in one
+// long-lived shell, so the working directory and environment carry over.
+func NewPersistentBashTool(cwd string) core.Tool {
+	return newBashTool(resolveBaseDir(cwd), defaultShell(), DefaultExecProfile(), true)
+}
+
+// bashTool wraps the bash ProgressiveToolFunc so it can report its shell and
+// reset its persistent session.
+type bashTool struct {
+	core.ProgressiveToolFunc
+	shell   Shell
+	profile ExecProfile
+	session *bashSession
+}
+
+func (t bashTool) ResetSession() {
+	if t.session != nil {
+		t.session.reset()
+	}
+}
 
-	return core.ToolFunc{
-		ToolName: "bash",
-		Run: func(ctx context.Context, args map[string]any) (string, error) {
+func (t bashTool) ToolState() map[string]any {
+	return map[string]any{
+		"shell":        t.shell.Path,
+		"login":        t.shell.Login,
+		"persistent":   t.session != nil,
+		"exec_profile": t.profile.state(t.shell.Path),
+	}
+}
+
+func newBashTool(base string, shell Shell, profile ExecProfile, persistent bool) core.Tool {
+	var session *bashSession
+	if persistent {
+		session = newBashSession(shell.Path, shell.sessionArgs(), base, profile)
+	}
+	fn := core.ProgressiveToolFunc{
+		ToolName:        "bash",
+		ToolDescription: "Execute a shell command in current working directory.",
+		ToolSchema: objectSchema(map[string]any{
+			"command": withAliases(requiredStringProperty("Shell command to execute."), "cmd"),
+			"timeout": withAliases(withMinimum(numberProperty("Optional timeout in seconds."), 0), "timeout_seconds", "timeoutSeconds"),
+		}, "command"),
+		Run: func(ctx context.Context, args map[string]any, progress core.ToolProgressFunc) (string, error) {
 			cmdText := resolveStringArgLocal(args, "command", "cmd")
 			if cmdText == "" {
 				return "", fmt.Errorf("bash_invalid_command")
@@ -44,10 +87,31 @@ func NewBashTool(cwd string) core.Tool {
 			}
 			defer cancel()
 
-			cmd := exec.CommandContext(runCtx, "/bin/zsh", "-lc", cmdText)
-			cmd.Dir = base
-			raw, execErr := cmd.CombinedOutput()
-			full := sanitizeBashOutput(string(raw))
+			stream := newBashStreamer(progress)
+			var raw string
+			var execErr error
+			exitCode := 0
+			if session != nil {
+				raw, exitCode, execErr = session.run(runCtx, cmdText, stream)
+				if execErr != nil && runCtx.Err() == nil && !errors.Is(execErr, errBashSessionExited) {
+					return "", execErr
+				}
+				if execErr == nil && exitCode != 0 {
+					execErr = fmt.Errorf("exit status %d", exitCode)
+				}
+			} else {
+				cmd := exec.CommandContext(runCtx, shell.Path, shell.commandArgs(profile.limitScript()+cmdText)...)
+				cmd.Dir = base
+				profile.apply(cmd)
+				// Kill the whole process group on timeout or abort so
+				// grandchildren do not outlive the call.
+				cmd.Cancel = func() error { return signalProcessGroup(cmd, syscall.SIGKILL) }
+				raw, execErr = runStreamingCommand(cmd, stream)
+				if execErr != nil {
+					exitCode = exitCodeOf(execErr)
+				}
+			}
+			full := sanitizeBashOutput(raw)
 			rendered, details := truncateBashOutput(full)
 			if strings.TrimSpace(rendered) == "" {
 				rendered = "(no output)"
@@ -60,11 +124,13 @@ func NewBashTool(cwd string) core.Tool {
 				return "", fmt.Errorf("%s\n\nCommand timed out after %s seconds", strings.TrimSpace(rendered), formatSeconds(timeoutSecs))
 			}
 			if execErr != nil {
-				exitCode := exitCodeOf(execErr)
 				msg := strings.TrimSpace(rendered)
 				if msg == "" {
 					msg = "(no output)"
 				}
+				if errors.Is(execErr, errBashSessionExited) {
+					return "", fmt.Errorf("%s\n\nShell session exited; a new session starts on the next call", msg)
+				}
 				if exitCode >= 0 {
 					return "", fmt.Errorf("%s\n\nCommand exited with code %d", msg, exitCode)
 				}
@@ -73,6 +139,57 @@ func NewBashTool(cwd string) core.Tool {
 			return strings.TrimSpace(rendered), nil
 		},
 	}
+	return bashTool{ProgressiveToolFunc: fn, shell: shell, profile: profile, session: session}
+}
+
+// bashWaitDelay bounds how long Wait keeps reading output after the shell
+// exits, in case a background child still holds the pipe open.
+const bashWaitDelay = 2 * time.Second
+
+// runStreamingCommand runs cmd with stdout and stderr combined, feeding
+// output chunks to stream on the calling goroutine.
+func runStreamingCommand(cmd *exec.Cmd, stream *bashStreamer) (string, error) {
+	chunks := make(chan []byte, 64)
+	w := chunkWriter(chunks)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	cmd.WaitDelay = bashWaitDelay
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+	waitErr := make(chan error, 1)
+	go func() {
+		waitErr <- cmd.Wait()
+		close(chunks)
+	}()
+
+	ticker := time.NewTicker(bashProgressInterval)
+	defer ticker.Stop()
+	var full bytes.Buffer
+	for done := false; !done; {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				done = true
+				break
+			}
+			full.Write(chunk)
+			stream.add(chunk)
+		case <-ticker.C:
+			stream.tick()
+		}
+	}
+	stream.finish()
+	return full.String(), <-waitErr
+}
+
+// chunkWriter forwards copies of written bytes to a channel. exec serializes
+// writes when Stdout and Stderr are the same comparable writer.
+type chunkWriter chan<- []byte
+
+func (w chunkWriter) Write(p []byte) (int, error) {
+	w <- append([]byte(nil), p...)
+	return len(p), nil
 }
 
 func resolveStringArgLocal(args map[string]any, keys ...string) string {
@@ -0,0 +1,245 @@
+package builtins
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const bashSessionStartTimeout = 15 * time.Second
+
+var errBashSessionExited = errors.New("bash_session_exited")
+
+// bashSession keeps one shell process alive across tool calls so cd, export
+// and shell variables carry over. Each command is followed by a sentinel
+// line carrying its exit status; the shell is restarted after it dies,
+// times out or is cancelled.
+type bashSession struct {
+	shell   string
+	args    []string
+	dir     string
+	profile ExecProfile
+
+	mu   sync.Mutex
+	proc *bashProcess
+}
+
+type bashProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	output *os.File
+	out    chan []byte
+	done   chan struct{}
+	once   sync.Once
+	marker string
+	buf    []byte
+}
+
+func newBashSession(shell string, args []string, dir string, profile ExecProfile) *bashSession {
+	return &bashSession{shell: shell, args: append([]string(nil), args...), dir: dir, profile: profile}
+}
+
+// run executes command in the session shell, feeding output to stream (may
+// be nil) as it arrives. exitCode is -1 when the command did not finish
+// (timeout, cancellation or the shell exiting).
+func (s *bashSession) run(ctx context.Context, command string, stream *bashStreamer) (output string, exitCode int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.proc == nil {
+		proc, err := s.start(ctx)
+		if err != nil {
+			return "", -1, err
+		}
+		s.proc = proc
+	}
+	proc := s.proc
+	if _, err := io.WriteString(proc.stdin, frameBashCommand(command, proc.marker)); err != nil {
+		s.stopLocked()
+		return "", -1, errBashSessionExited
+	}
+	out, code, err := proc.waitForMarker(ctx, stream)
+	if err != nil {
+		s.stopLocked()
+		return out, -1, err
+	}
+	return out, code, nil
+}
+
+// reset stops the shell; the next call starts a fresh one in the base dir.
+func (s *bashSession) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopLocked()
+}
+
+func (s *bashSession) start(ctx context.Context) (*bashProcess, error) {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("bash_session_start_failed: %w", err)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("bash_session_start_failed: %w", err)
+	}
+	cmd := exec.Command(s.shell, s.args...)
+	cmd.Dir = s.dir
+	s.profile.apply(cmd)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		_ = r.Close()
+		_ = w.Close()
+		return nil, fmt.Errorf("bash_session_start_failed: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		_ = r.Close()
+		_ = w.Close()
+		return nil, fmt.Errorf("bash_session_start_failed: %w", err)
+	}
+	_ = w.Close()
+
+	proc := &bashProcess{
+		cmd:    cmd,
+		stdin:  stdin,
+		output: r,
+		out:    make(chan []byte, 16),
+		done:   make(chan struct{}),
+		marker: "__nous_done_" + hex.EncodeToString(token),
+	}
+	go proc.readLoop()
+
+	// Drain anything printed by shell startup files before the first command.
+	startCtx, cancel := context.WithTimeout(ctx, bashSessionStartTimeout)
+	defer cancel()
+	if _, err := io.WriteString(stdin, s.profile.limitScript()+frameBashCommand(":", proc.marker)); err != nil {
+		proc.kill()
+		return nil, fmt.Errorf("bash_session_start_failed: %w", err)
+	}
+	if _, _, err := proc.waitForMarker(startCtx, nil); err != nil {
+		proc.kill()
+		return nil, fmt.Errorf("bash_session_start_failed: %w", err)
+	}
+	return proc, nil
+}
+
+func (s *bashSession) stopLocked() {
+	if s.proc != nil {
+		s.proc.kill()
+		s.proc = nil
+	}
+}
+
+// frameBashCommand evals command with stdin detached, so commands cannot read
+// the framing, then prints the sentinel line with the exit status.
+func frameBashCommand(command, marker string) string {
+	quoted := "'" + strings.ReplaceAll(command, "'", `'\''`) + "'"
+	return fmt.Sprintf("eval %s </dev/null\nprintf '\\n%s:%%s\\n' \"$?\"\n", quoted, marker)
+}
+
+func (p *bashProcess) readLoop() {
+	defer close(p.out)
+	chunk := make([]byte, 32*1024)
+	for {
+		n, err := p.output.Read(chunk)
+		if n > 0 {
+			select {
+			case p.out <- append([]byte(nil), chunk[:n]...):
+			case <-p.done:
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (p *bashProcess) waitForMarker(ctx context.Context, stream *bashStreamer) (string, int, error) {
+	needle := []byte("\n" + p.marker + ":")
+	// streamed counts bytes of p.buf already passed to stream; a trailing
+	// partial match of the sentinel is held back.
+	streamed := 0
+	var tick <-chan time.Time
+	if stream != nil {
+		ticker := time.NewTicker(stream.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		if idx := bytes.Index(p.buf, needle); idx >= 0 {
+			rest := p.buf[idx+len(needle):]
+			if end := bytes.IndexByte(rest, '\n'); end >= 0 {
+				code, err := strconv.Atoi(string(rest[:end]))
+				if err != nil {
+					code = -1
+				}
+				out := string(p.buf[:idx])
+				if streamed < idx {
+					stream.add(p.buf[streamed:idx])
+				}
+				stream.finish()
+				p.buf = append([]byte(nil), rest[end+1:]...)
+				return out, code, nil
+			}
+		} else if safe := len(p.buf) - partialSuffix(p.buf, needle); safe > streamed {
+			stream.add(p.buf[streamed:safe])
+			streamed = safe
+		}
+		select {
+		case chunk, ok := <-p.out:
+			if !ok {
+				out := string(p.buf)
+				stream.add(p.buf[streamed:])
+				stream.finish()
+				p.buf = nil
+				return out, -1, errBashSessionExited
+			}
+			p.buf = append(p.buf, chunk...)
+		case <-tick:
+			stream.tick()
+		case <-ctx.Done():
+			out := string(p.buf)
+			stream.add(p.buf[streamed:])
+			stream.finish()
+			p.buf = nil
+			return out, -1, ctx.Err()
+		}
+	}
+}
+
+// partialSuffix returns the length of the longest suffix of buf that is a
+// proper prefix of needle.
+func partialSuffix(buf, needle []byte) int {
+	for n := min(len(buf), len(needle)-1); n > 0; n-- {
+		if bytes.HasSuffix(buf, needle[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+func (p *bashProcess) kill() {
+	p.once.Do(func() {
+		close(p.done)
+		_ = p.stdin.Close()
+		if p.cmd.Process != nil {
+			_ = signalProcessGroup(p.cmd, syscall.SIGKILL)
+			_ = p.cmd.Process.Kill()
+		}
+		_ = p.output.Close()
+		go func() { _ = p.cmd.Wait() }()
+	})
+}
@@ -0,0 +1,117 @@
+package builtins
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestBashSession(t *testing.T, dir string) *bashSession {
+	t.Helper()
+	shell, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	s := newBashSession(shell, []string{"-s"}, dir, DefaultExecProfile())
+	t.Cleanup(s.reset)
+	return s
+}
+
+func TestBashSessionKeepsCwdAndEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestBashSession(t, dir)
+	ctx := context.Background()
+
+	if _, code, err := s.run(ctx, "mkdir sub && cd sub && export NOUS_TEST_VAR=kept", nil); err != nil || code != 0 {
+		t.Fatalf("first command failed: code=%d err=%v", code, err)
+	}
+	out, code, err := s.run(ctx, `pwd; printf '%s' "$NOUS_TEST_VAR"`, nil)
+	if err != nil || code != 0 {
+		t.Fatalf("second command failed: code=%d err=%v", code, err)
+	}
+	wantDir, _ := filepath.EvalSymlinks(filepath.Join(dir, "sub"))
+	if out != wantDir+"\nkept" {
+		t.Fatalf("expected cwd and env to persist, got: %q", out)
+	}
+}
+
+func TestBashSessionCapturesExitCodeAndQuotes(t *testing.T) {
+	s := newTestBashSession(t, t.TempDir())
+	ctx := context.Background()
+
+	out, code, err := s.run(ctx, "echo 'it'\"'\"'s' >&2; (exit 7)", nil)
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if code != 7 || strings.TrimSpace(out) != "it's" {
+		t.Fatalf("unexpected result: code=%d out=%q", code, out)
+	}
+	out, code, err = s.run(ctx, "cat; printf done", nil)
+	if err != nil || code != 0 || out != "done" {
+		t.Fatalf("commands must not read the session stdin: code=%d out=%q err=%v", code, out, err)
+	}
+}
+
+func TestBashSessionRestartsAfterExitAndTimeout(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestBashSession(t, dir)
+	ctx := context.Background()
+
+	if _, _, err := s.run(ctx, "cd / && exit 3", nil); !errors.Is(err, errBashSessionExited) {
+		t.Fatalf("expected session exit error, got: %v", err)
+	}
+	out, code, err := s.run(ctx, "pwd", nil)
+	wantDir, _ := filepath.EvalSymlinks(dir)
+	if err != nil || code != 0 || strings.TrimSpace(out) != wantDir {
+		t.Fatalf("expected fresh shell in base dir: code=%d out=%q err=%v", code, out, err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if _, _, err := s.run(timeoutCtx, "sleep 5", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got: %v", err)
+	}
+	if out, code, err := s.run(ctx, "printf ok", nil); err != nil || code != 0 || out != "ok" {
+		t.Fatalf("expected restart after timeout: code=%d out=%q err=%v", code, out, err)
+	}
+}
+
+func TestPersistentBashToolReportsExitCodeAndResets(t *testing.T) {
+	dir := t.TempDir()
+	shell, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	tool := newBashTool(dir, Shell{Path: shell}, DefaultExecProfile(), true)
+	t.Cleanup(tool.(bashTool).ResetSession)
+	ctx := context.Background()
+
+	if _, err := tool.Execute(ctx, map[string]any{"command": "export NOUS_X=1; false"}); err == nil || !strings.Contains(err.Error(), "Command exited with code 1") {
+		t.Fatalf("expected exit code error, got: %v", err)
+	}
+	out, err := tool.Execute(ctx, map[string]any{"command": `printf '%s' "$NOUS_X"`})
+	if err != nil || out != "1" {
+		t.Fatalf("expected env to persist, out=%q err=%v", out, err)
+	}
+	if _, err := tool.Execute(ctx, map[string]any{"command": "sleep 5", "timeout": 0.1}); err == nil || !strings.Contains(err.Error(), "Command timed out after 0.1 seconds") {
+		t.Fatalf("expected timeout error, got: %v", err)
+	}
+
+	out, err = tool.Execute(ctx, map[string]any{"command": "seq 1 2200"})
+	if err != nil || !strings.HasSuffix(strings.SplitN(out, "\n\n", 2)[0], "2200") || !strings.Contains(out, "Full output: ") {
+		t.Fatalf("expected truncated tail with full output path, got err=%v tail=%q", err, out[max(0, len(out)-200):])
+	}
+
+	if _, err := tool.Execute(ctx, map[string]any{"command": "export NOUS_X=2"}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	tool.(bashTool).ResetSession()
+	out, err = tool.Execute(ctx, map[string]any{"command": `printf '[%s]' "$NOUS_X"`})
+	if err != nil || out != "[]" {
+		t.Fatalf("expected clean shell after reset, out=%q err=%v", out, err)
+	}
+}
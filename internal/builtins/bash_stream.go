@@ -0,0 +1,91 @@
+package builtins
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"nous/internal/core"
+)
+
+const (
+	bashProgressInterval = 100 * time.Millisecond
+	bashProgressMaxDelta = 8 * 1024
+)
+
+// bashStreamer coalesces shell output into progress deltas. It sends at most
+// one delta per interval and only the tail of a burst larger than
+// bashProgressMaxDelta. It is not safe for concurrent use; callers feed it
+// from the goroutine running the tool so progress stays on that goroutine.
+type bashStreamer struct {
+	progress core.ToolProgressFunc
+	interval time.Duration
+	pending  []byte
+	skipped  int
+	last     time.Time
+}
+
+func newBashStreamer(progress core.ToolProgressFunc) *bashStreamer {
+	return &bashStreamer{progress: progress, interval: bashProgressInterval}
+}
+
+func (s *bashStreamer) add(chunk []byte) {
+	if s == nil || s.progress == nil || len(chunk) == 0 {
+		return
+	}
+	s.pending = append(s.pending, chunk...)
+	if over := len(s.pending) - bashProgressMaxDelta; over > 0 {
+		s.skipped += over
+		s.pending = append([]byte(nil), s.pending[over:]...)
+	}
+	if time.Since(s.last) >= s.interval {
+		s.flush(true)
+	}
+}
+
+// tick flushes pending output once the interval has passed.
+func (s *bashStreamer) tick() {
+	if s == nil || s.progress == nil || len(s.pending) == 0 {
+		return
+	}
+	if time.Since(s.last) >= s.interval {
+		s.flush(true)
+	}
+}
+
+// finish sends whatever output is still pending.
+func (s *bashStreamer) finish() {
+	s.flush(false)
+}
+
+func (s *bashStreamer) flush(holdPartialRune bool) {
+	if s == nil || s.progress == nil || len(s.pending) == 0 {
+		return
+	}
+	// Hold back a multi-byte rune split across chunks.
+	cut := len(s.pending)
+	for i := 1; holdPartialRune && i < utf8.UTFMax && i <= len(s.pending); i++ {
+		b := s.pending[len(s.pending)-i]
+		if b&0xc0 != 0x80 {
+			if !utf8.FullRune(s.pending[len(s.pending)-i:]) {
+				cut = len(s.pending) - i
+			}
+			break
+		}
+	}
+	start := 0
+	for start < cut && s.pending[start]&0xc0 == 0x80 {
+		start++
+	}
+	if start == cut && s.skipped == 0 {
+		return
+	}
+	delta := sanitizeBashOutput(string(s.pending[start:cut]))
+	if s.skipped > 0 {
+		delta = fmt.Sprintf("[... %s skipped ...]\n%s", formatSize(s.skipped), delta)
+		s.skipped = 0
+	}
+	s.pending = append([]byte(nil), s.pending[cut:]...)
+	s.last = time.Now()
+	s.progress(delta)
+}
@@ -0,0 +1,109 @@
+package builtins
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBashStreamerCoalescesWithinInterval(t *testing.T) {
+	deltas := []string{}
+	s := newBashStreamer(func(delta string) { deltas = append(deltas, delta) })
+	s.interval = time.Hour
+
+	s.add([]byte("a\n"))
+	s.add([]byte("b\n"))
+	s.add([]byte("c\r\n"))
+	s.tick()
+	if len(deltas) != 1 || deltas[0] != "a\n" {
+		t.Fatalf("expected only the first chunk before the interval, got %q", deltas)
+	}
+	s.finish()
+	if len(deltas) != 2 || deltas[1] != "b\nc\n" {
+		t.Fatalf("expected coalesced remainder on finish, got %q", deltas)
+	}
+}
+
+func TestBashStreamerKeepsTailOfLargeBurstAndSplitRunes(t *testing.T) {
+	deltas := []string{}
+	s := newBashStreamer(func(delta string) { deltas = append(deltas, delta) })
+	s.interval = time.Hour
+	s.last = time.Now()
+
+	s.add([]byte(strings.Repeat("x", bashProgressMaxDelta+100)))
+	s.finish()
+	if len(deltas) != 1 || !strings.HasPrefix(deltas[0], "[... 100B skipped ...]\n") || len(deltas[0]) != len("[... 100B skipped ...]\n")+bashProgressMaxDelta {
+		t.Fatalf("expected tail with skip note, got %d deltas (first %q)", len(deltas), deltas[0][:40])
+	}
+
+	deltas = deltas[:0]
+	s.interval = 0
+	euro := []byte("€")
+	s.add(append([]byte("price "), euro[:2]...))
+	s.add(euro[2:])
+	s.finish()
+	if strings.Join(deltas, "") != "price €" || deltas[0] != "price " {
+		t.Fatalf("expected split rune to be held back, got %q", deltas)
+	}
+}
+
+func TestBashToolStreamsProgressBeforeCompletion(t *testing.T) {
+	if !isExecutableFile("/bin/sh") {
+		t.Skip("/bin/sh not available")
+	}
+	tool := newBashTool(t.TempDir(), Shell{Path: "/bin/sh"}, DefaultExecProfile(), false).(bashTool)
+
+	var mu sync.Mutex
+	var deltas []string
+	var firstAt time.Time
+	start := time.Now()
+	out, err := tool.ExecuteWithProgress(context.Background(), map[string]any{
+		"command": "echo first; echo oops >&2; sleep 0.4; echo last",
+	}, func(delta string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(deltas) == 0 {
+			firstAt = time.Now()
+		}
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatalf("bash failed: %v", err)
+	}
+	if out != "first\noops\nlast" {
+		t.Fatalf("unexpected final output: %q", out)
+	}
+	if len(deltas) < 2 || !strings.Contains(deltas[0], "first") {
+		t.Fatalf("expected streamed deltas, got %q", deltas)
+	}
+	if firstAt.Sub(start) > 300*time.Millisecond {
+		t.Fatalf("first delta arrived only after %s", firstAt.Sub(start))
+	}
+	if got := strings.Join(deltas, ""); got != "first\noops\nlast\n" {
+		t.Fatalf("deltas must cover the whole output, got %q", got)
+	}
+}
+
+func TestPersistentBashToolStreamsWithoutSentinel(t *testing.T) {
+	shell, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	tool := newBashTool(t.TempDir(), Shell{Path: shell}, DefaultExecProfile(), true).(bashTool)
+	t.Cleanup(tool.ResetSession)
+
+	var deltas []string
+	out, err := tool.ExecuteWithProgress(context.Background(), map[string]any{
+		"command": "printf 'one\\n'; sleep 0.3; printf 'two'",
+	}, func(delta string) { deltas = append(deltas, delta) })
+	if err != nil || out != "one\ntwo" {
+		t.Fatalf("unexpected result: out=%q err=%v", out, err)
+	}
+	joined := strings.Join(deltas, "")
+	if len(deltas) < 2 || joined != "one\ntwo" || strings.Contains(joined, "__nous_done_") {
+		t.Fatalf("unexpected deltas: %q", deltas)
+	}
+}
@@ -0,0 +1,259 @@
+package builtins
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	diffContextLines = 3
+	diffMaxLines     = 400
+	// diffMaxCells bounds the LCS table; larger changed regions are shown as
+	// one delete-and-add block.
+	diffMaxCells = 4_000_000
+)
+
+// fileDiff is a unified diff between two versions of a file.
+type fileDiff struct {
+	Path string
+	// Text is the unified diff, cut after diffMaxLines lines.
+	Text      string
+	Truncated bool
+	// FirstChangedLine is the 1-based line in the new file where the first
+	// change starts; 0 when nothing changed.
+	FirstChangedLine int
+	Additions        int
+	Deletions        int
+}
+
+func (d fileDiff) details() map[string]any {
+	return map[string]any{
+		"path":               d.Path,
+		"diff":               d.Text,
+		"truncated":          d.Truncated,
+		"first_changed_line": d.FirstChangedLine,
+		"additions":          d.Additions,
+		"deletions":          d.Deletions,
+	}
+}
+
+// summary describes the diff for a tool result: the first changed line
+// followed by the diff itself.
+func (d fileDiff) summary() string {
+	if d.FirstChangedLine == 0 {
+		return "no changes"
+	}
+	return fmt.Sprintf("first changed line: %d (+%d -%d)\n\n%s", d.FirstChangedLine, d.Additions, d.Deletions, d.Text)
+}
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	text string
+	// oldLine and newLine are the 0-based positions before the op.
+	oldLine, newLine int
+}
+
+// unifiedDiff diffs oldText into newText. existed is false for a file that
+// is being created, which is diffed against /dev/null.
+func unifiedDiff(path, oldText, newText string, existed bool) fileDiff {
+	d := fileDiff{Path: path}
+	if oldText == newText && existed {
+		return d
+	}
+	if strings.ContainsRune(oldText, 0) || strings.ContainsRune(newText, 0) {
+		d.Text = fmt.Sprintf("Binary file %s changed\n", path)
+		d.FirstChangedLine = 1
+		return d
+	}
+	oldLines := splitDiffLines(oldText)
+	newLines := splitDiffLines(newText)
+	ops := diffLines(oldLines, newLines)
+
+	from := "a/" + path
+	if !existed {
+		from = "/dev/null"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ b/%s\n", from, path)
+	lines := 2
+	for _, h := range diffHunks(ops) {
+		if d.FirstChangedLine == 0 {
+			for _, op := range ops[h.start:h.end] {
+				if op.kind != ' ' {
+					d.FirstChangedLine = op.newLine + 1
+					break
+				}
+			}
+		}
+		hunk := formatHunk(ops[h.start:h.end])
+		for _, line := range hunk {
+			if lines >= diffMaxLines {
+				d.Truncated = true
+				break
+			}
+			b.WriteString(line)
+			b.WriteByte('\n')
+			lines++
+		}
+	}
+	for _, op := range ops {
+		switch op.kind {
+		case '+':
+			d.Additions++
+		case '-':
+			d.Deletions++
+		}
+	}
+	if d.Truncated {
+		fmt.Fprintf(&b, "[diff truncated after %d lines]\n", diffMaxLines)
+	}
+	if d.FirstChangedLine == 0 {
+		// A new empty file has no lines to show.
+		d.FirstChangedLine = 1
+	}
+	d.Text = b.String()
+	return d
+}
+
+// splitDiffLines splits text into lines, marking a missing final newline
+// the way diff(1) does.
+func splitDiffLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n\\ No newline at end of file"
+	return lines
+}
+
+// diffLines returns the edit script turning a into b. Common leading and
+// trailing lines are matched directly; the rest uses an LCS table when it
+// fits in diffMaxCells.
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{kind: ' ', text: a[i], oldLine: i, newLine: i})
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	ops = append(ops, diffMiddle(ma, mb, prefix)...)
+	for k := 0; k < suffix; k++ {
+		i, j := len(a)-suffix+k, len(b)-suffix+k
+		ops = append(ops, diffOp{kind: ' ', text: a[i], oldLine: i, newLine: j})
+	}
+	return ops
+}
+
+func diffMiddle(a, b []string, offset int) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	if len(a)*len(b) > diffMaxCells {
+		for i, line := range a {
+			ops = append(ops, diffOp{kind: '-', text: line, oldLine: offset + i, newLine: offset})
+		}
+		for j, line := range b {
+			ops = append(ops, diffOp{kind: '+', text: line, oldLine: offset + len(a), newLine: offset + j})
+		}
+		return ops
+	}
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', text: a[i], oldLine: offset + i, newLine: offset + j})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			ops = append(ops, diffOp{kind: '+', text: b[j], oldLine: offset + i, newLine: offset + j})
+			j++
+		default:
+			ops = append(ops, diffOp{kind: '-', text: a[i], oldLine: offset + i, newLine: offset + j})
+			i++
+		}
+	}
+	return ops
+}
+
+type hunkRange struct{ start, end int }
+
+// diffHunks groups changes with diffContextLines of context, merging hunks
+// whose context would overlap.
+func diffHunks(ops []diffOp) []hunkRange {
+	var hunks []hunkRange
+	for i := 0; i < len(ops); i++ {
+		if ops[i].kind == ' ' {
+			continue
+		}
+		start := max(0, i-diffContextLines)
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContextLines {
+				end = min(len(ops), end+diffContextLines)
+				break
+			}
+			end = run
+		}
+		hunks = append(hunks, hunkRange{start: start, end: end})
+		i = end - 1
+	}
+	return hunks
+}
+
+func formatHunk(ops []diffOp) []string {
+	oldCount, newCount := 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+	}
+	out := []string{fmt.Sprintf("@@ -%s +%s @@", hunkSpan(ops[0].oldLine, oldCount), hunkSpan(ops[0].newLine, newCount))}
+	for _, op := range ops {
+		out = append(out, string(op.kind)+op.text)
+	}
+	return out
+}
+
+// hunkSpan renders a hunk range; empty ranges name the line before them.
+func hunkSpan(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
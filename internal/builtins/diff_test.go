@@ -0,0 +1,68 @@
+package builtins
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiffHunks(t *testing.T) {
+	var old, updated []string
+	for i := 1; i <= 20; i++ {
+		old = append(old, fmt.Sprintf("line %d", i))
+	}
+	updated = append(updated, old...)
+	updated[2] = "changed 3"
+	updated = append(updated[:15], append([]string{"inserted"}, updated[15:]...)...)
+
+	d := unifiedDiff("a.txt", strings.Join(old, "\n")+"\n", strings.Join(updated, "\n")+"\n", true)
+	want := "--- a/a.txt\n+++ b/a.txt\n" +
+		"@@ -1,6 +1,6 @@\n line 1\n line 2\n-line 3\n+changed 3\n line 4\n line 5\n line 6\n" +
+		"@@ -13,6 +13,7 @@\n line 13\n line 14\n line 15\n+inserted\n line 16\n line 17\n line 18\n"
+	if d.Text != want {
+		t.Fatalf("unexpected diff:\n%s", d.Text)
+	}
+	if d.FirstChangedLine != 3 || d.Additions != 2 || d.Deletions != 1 || d.Truncated {
+		t.Fatalf("unexpected diff stats: %+v", d)
+	}
+	if !strings.HasPrefix(d.summary(), "first changed line: 3 (+2 -1)\n\n--- a/a.txt") {
+		t.Fatalf("unexpected summary: %q", d.summary())
+	}
+}
+
+func TestUnifiedDiffMergesNearbyChanges(t *testing.T) {
+	d := unifiedDiff("a.txt", "a\nb\nc\nd\ne\nf\ng\nh\n", "A\nb\nc\nd\ne\nf\ng\nH\n", true)
+	if strings.Count(d.Text, "@@ -") != 1 || !strings.Contains(d.Text, "@@ -1,8 +1,8 @@") {
+		t.Fatalf("expected one merged hunk, got:\n%s", d.Text)
+	}
+}
+
+func TestUnifiedDiffNewFileAndMissingNewline(t *testing.T) {
+	d := unifiedDiff("n.txt", "", "one\ntwo", false)
+	want := "--- /dev/null\n+++ b/n.txt\n@@ -0,0 +1,2 @@\n+one\n+two\n\\ No newline at end of file\n"
+	if d.Text != want || d.FirstChangedLine != 1 || d.Additions != 2 {
+		t.Fatalf("unexpected new file diff: %+v", d)
+	}
+
+	d = unifiedDiff("n.txt", "one\ntwo", "one\ntwo\n", true)
+	if !strings.Contains(d.Text, "-two\n\\ No newline at end of file\n+two\n") || d.FirstChangedLine != 2 {
+		t.Fatalf("expected newline change on line 2, got %+v", d)
+	}
+
+	if d := unifiedDiff("n.txt", "same", "same", true); d.FirstChangedLine != 0 || d.summary() != "no changes" {
+		t.Fatalf("expected no changes, got %+v", d)
+	}
+}
+
+func TestUnifiedDiffTruncatesLongDiffs(t *testing.T) {
+	d := unifiedDiff("big.txt", "", strings.Repeat("x\n", diffMaxLines*2), false)
+	if !d.Truncated || d.Additions != diffMaxLines*2 {
+		t.Fatalf("expected truncated diff with full counts, got truncated=%v additions=%d", d.Truncated, d.Additions)
+	}
+	if got := strings.Count(d.Text, "\n"); got != diffMaxLines+1 {
+		t.Fatalf("expected %d lines plus a note, got %d", diffMaxLines, got)
+	}
+	if !strings.HasSuffix(d.Text, fmt.Sprintf("[diff truncated after %d lines]\n", diffMaxLines)) {
+		t.Fatalf("missing truncation note: %q", d.Text[len(d.Text)-60:])
+	}
+}
@@ -1,60 +1,326 @@
 package builtins
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 
 	"nous/internal/core"
 )
 
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+type textEdit struct {
+	oldText    string
+	newText    string
+	replaceAll bool
+	// label names the edit in errors; empty for a single top-level edit so
+	// its errors keep their bare codes.
+	label string
+}
+
 func NewEditTool(cwd string) core.Tool {
-	base := resolveBaseDir(cwd)
+	return newEditTool(newPathResolver(Config{Workdir: cwd}))
+}
+
+func newEditTool(paths *pathResolver) core.Tool {
+	editItem := objectSchema(map[string]any{
+		"oldText":     withAliases(requiredStringProperty("Text to replace."), "old_text"),
+		"newText":     withAliases(stringProperty("Replacement text."), "new_text"),
+		"replace_all": withAliases(booleanProperty("Replace every occurrence instead of requiring a unique match."), "replaceAll"),
+	}, "oldText", "newText")
 
-	return core.ToolFunc{
-		ToolName: "edit",
-		Run: func(_ context.Context, args map[string]any) (string, error) {
+	return core.DetailedToolFunc{
+		ToolName:        "edit",
+		ToolDescription: "Edit a file by replacing oldText with newText. Pass edits to apply several replacements in order; either all apply or none. When oldText has no exact match, lines are matched ignoring indentation and trailing whitespace.",
+		ToolSchema: objectSchema(map[string]any{
+			"path":        withAliases(requiredStringProperty("Path to file to edit."), pathAliases...),
+			"oldText":     withAliases(stringProperty("Exact old text to replace."), "old_text"),
+			"newText":     withAliases(stringProperty("Replacement text."), "new_text"),
+			"replace_all": withAliases(booleanProperty("Replace every occurrence of oldText."), "replaceAll"),
+			"edits":       withMinItems(arrayProperty("Replacements applied in order, each seeing the result of the previous ones. Use instead of oldText/newText.", editItem), 1),
+		}, "path"),
+		Run: func(_ context.Context, args map[string]any) (core.ToolResult, error) {
+			fail := func(err error) (core.ToolResult, error) { return core.ToolResult{}, err }
 			path := resolveWritePathArg(args)
 			if path == "" {
-				return "", fmt.Errorf("edit_invalid_path")
-			}
-			oldText, ok := resolveRequiredStringFieldLocal(args, "oldText", "old_text")
-			if !ok {
-				return "", fmt.Errorf("edit_invalid_old_text")
+				return fail(fmt.Errorf("edit_invalid_path"))
 			}
-			newText, ok := resolveRequiredStringFieldLocal(args, "newText", "new_text")
-			if !ok {
-				return "", fmt.Errorf("edit_invalid_new_text")
+			edits, err := resolveTextEdits(args)
+			if err != nil {
+				return fail(err)
 			}
 
-			abs := resolveToolPath(base, path)
+			abs, err := paths.resolve(path)
+			if err != nil {
+				return fail(err)
+			}
 
 			b, err := os.ReadFile(abs)
 			if err != nil {
-				return "", fmt.Errorf("edit_failed: %w", err)
+				return fail(fmt.Errorf("edit_failed: %w", err))
+			}
+			content, bom, crlf := decodeText(b)
+
+			updated := content
+			replacements := 0
+			fuzzy := []string{}
+			for _, edit := range edits {
+				next, n, loose, err := applyTextEdit(updated, edit)
+				if err != nil {
+					return fail(err)
+				}
+				if loose {
+					fuzzy = append(fuzzy, editName(edit))
+				}
+				updated = next
+				replacements += n
 			}
-			content := string(b)
-			count := strings.Count(content, oldText)
-			if count == 0 {
-				return "", fmt.Errorf("edit_old_text_not_found")
+			if updated == content {
+				return fail(fmt.Errorf("edit_noop"))
 			}
-			if count > 1 {
-				return "", fmt.Errorf("edit_old_text_not_unique")
+
+			if err := os.WriteFile(abs, encodeText(updated, bom, crlf), 0o644); err != nil {
+				return fail(fmt.Errorf("edit_failed: %w", err))
 			}
 
-			updated := strings.Replace(content, oldText, newText, 1)
-			if updated == content {
-				return "", fmt.Errorf("edit_noop")
+			msg := fmt.Sprintf("edited %s", path)
+			if replacements > 1 {
+				msg += fmt.Sprintf(" (%d replacements)", replacements)
 			}
-			if err := os.WriteFile(abs, []byte(updated), 0o644); err != nil {
-				return "", fmt.Errorf("edit_failed: %w", err)
+			if len(fuzzy) > 0 {
+				msg += fmt.Sprintf("; matched %s ignoring whitespace", strings.Join(fuzzy, ", "))
 			}
-			return fmt.Sprintf("edited %s", path), nil
+			diff := unifiedDiff(path, content, updated, true)
+			return core.ToolResult{Text: msg + "\n" + diff.summary(), Details: diff.details()}, nil
 		},
 	}
 }
 
+// decodeText strips a UTF-8 BOM and converts CRLF to LF when the file uses it
+// throughout; mixed files are left as they are.
+func decodeText(b []byte) (text string, bom, crlf bool) {
+	bom = bytes.HasPrefix(b, utf8BOM)
+	text = string(bytes.TrimPrefix(b, utf8BOM))
+	crlf = strings.Contains(text, "\r\n") && strings.Count(text, "\r\n") == strings.Count(text, "\n")
+	if crlf {
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+	return text, bom, crlf
+}
+
+func encodeText(text string, bom, crlf bool) []byte {
+	if crlf {
+		text = strings.ReplaceAll(text, "\n", "\r\n")
+	}
+	out := []byte(text)
+	if bom {
+		out = append(append([]byte(nil), utf8BOM...), out...)
+	}
+	return out
+}
+
+// resolveTextEdits reads either the edits array or the top-level
+// oldText/newText pair.
+func resolveTextEdits(args map[string]any) ([]textEdit, error) {
+	raw, hasEdits := args["edits"]
+	if !hasEdits || raw == nil {
+		oldText, ok := resolveRequiredStringFieldLocal(args, "oldText", "old_text")
+		if !ok || oldText == "" {
+			return nil, fmt.Errorf("edit_invalid_old_text")
+		}
+		newText, ok := resolveRequiredStringFieldLocal(args, "newText", "new_text")
+		if !ok {
+			return nil, fmt.Errorf("edit_invalid_new_text")
+		}
+		replaceAll := boolArgLocal(args, "replace_all", "replaceAll")
+		return []textEdit{{oldText: normalizeEditText(oldText), newText: normalizeEditText(newText), replaceAll: replaceAll}}, nil
+	}
+	if _, ok := resolveRequiredStringFieldLocal(args, "oldText", "old_text"); ok {
+		return nil, fmt.Errorf("edit_invalid_edits: use either edits or oldText/newText")
+	}
+	items, ok := raw.([]any)
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("edit_invalid_edits: edits must be a non-empty array")
+	}
+	edits := make([]textEdit, 0, len(items))
+	for i, item := range items {
+		label := "edits[" + strconv.Itoa(i) + "]"
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("edit_invalid_edits: %s must be an object", label)
+		}
+		oldText, ok := resolveRequiredStringFieldLocal(m, "oldText", "old_text")
+		if !ok || oldText == "" {
+			return nil, fmt.Errorf("edit_invalid_old_text: %s", label)
+		}
+		newText, ok := resolveRequiredStringFieldLocal(m, "newText", "new_text")
+		if !ok {
+			return nil, fmt.Errorf("edit_invalid_new_text: %s", label)
+		}
+		replaceAll := boolArgLocal(m, "replace_all", "replaceAll")
+		edits = append(edits, textEdit{oldText: normalizeEditText(oldText), newText: normalizeEditText(newText), replaceAll: replaceAll, label: label})
+	}
+	return edits, nil
+}
+
+func boolArgLocal(args map[string]any, keys ...string) bool {
+	for _, k := range keys {
+		if v, ok := args[k].(bool); ok {
+			return v
+		}
+	}
+	return false
+}
+
+func normalizeEditText(s string) string {
+	return strings.ReplaceAll(s, "\r\n", "\n")
+}
+
+func editName(edit textEdit) string {
+	if edit.label != "" {
+		return edit.label
+	}
+	return "oldText"
+}
+
+func editError(code string, edit textEdit, detail string) error {
+	parts := []string{}
+	if edit.label != "" {
+		parts = append(parts, edit.label)
+	}
+	if detail != "" {
+		parts = append(parts, detail)
+	}
+	if len(parts) == 0 {
+		return fmt.Errorf("%s", code)
+	}
+	return fmt.Errorf("%s: %s", code, strings.Join(parts, " "))
+}
+
+// applyTextEdit applies one edit to LF content. It tries an exact match
+// first and falls back to a line match that ignores indentation and
+// trailing whitespace; loose reports that the fallback was used.
+func applyTextEdit(content string, edit textEdit) (updated string, replaced int, loose bool, err error) {
+	count := strings.Count(content, edit.oldText)
+	if count > 1 && !edit.replaceAll {
+		return "", 0, false, editError("edit_old_text_not_unique", edit, "")
+	}
+	if count > 0 {
+		if edit.replaceAll {
+			return strings.ReplaceAll(content, edit.oldText, edit.newText), count, false, nil
+		}
+		return strings.Replace(content, edit.oldText, edit.newText, 1), 1, false, nil
+	}
+
+	lines := strings.Split(content, "\n")
+	matches := looseLineMatches(lines, edit.oldText)
+	if len(matches) == 0 {
+		return "", 0, false, editError("edit_old_text_not_found", edit, "")
+	}
+	if len(matches) > 1 && !edit.replaceAll {
+		starts := make([]string, 0, len(matches))
+		for _, m := range matches {
+			starts = append(starts, strconv.Itoa(m+1))
+		}
+		return "", 0, false, editError("edit_old_text_ambiguous", edit,
+			fmt.Sprintf("matches %d places ignoring whitespace (lines %s); include more context or set replace_all", len(matches), strings.Join(starts, ", ")))
+	}
+
+	oldLines := splitEditLines(edit.oldText)
+	var newLines []string
+	if edit.newText != "" {
+		newLines = splitEditLines(edit.newText)
+	}
+	// Replace from the bottom so earlier line numbers stay valid.
+	for i := len(matches) - 1; i >= 0; i-- {
+		start := matches[i]
+		end := start + len(oldLines)
+		replacement := reindentLines(newLines, oldLines, lines[start:end])
+		next := append([]string{}, lines[:start]...)
+		next = append(next, replacement...)
+		lines = append(next, lines[end:]...)
+	}
+	return strings.Join(lines, "\n"), len(matches), true, nil
+}
+
+// splitEditLines splits text into lines, ignoring one trailing newline.
+func splitEditLines(text string) []string {
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// looseLineMatches returns the starting line of every non-overlapping run of
+// lines equal to oldText's lines once surrounding whitespace is trimmed.
+func looseLineMatches(lines []string, oldText string) []int {
+	want := splitEditLines(oldText)
+	for i := range want {
+		want[i] = strings.TrimSpace(want[i])
+	}
+	if strings.Join(want, "") == "" {
+		return nil
+	}
+	var matches []int
+	for start := 0; start+len(want) <= len(lines); start++ {
+		ok := true
+		for j, w := range want {
+			if strings.TrimSpace(lines[start+j]) != w {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matches = append(matches, start)
+			start += len(want) - 1
+		}
+	}
+	return matches
+}
+
+func leadingWhitespace(s string) string {
+	return s[:len(s)-len(strings.TrimLeft(s, " \t"))]
+}
+
+// reindentLines moves newLines from the indentation oldLines used to the
+// indentation of the matching file lines. Each old indentation maps to the
+// file indentation of the first line that used it; new lines take the
+// mapping of the longest indentation they start with.
+func reindentLines(newLines, oldLines, fileLines []string) []string {
+	mapping := map[string]string{}
+	for j, line := range oldLines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		from := leadingWhitespace(line)
+		if _, ok := mapping[from]; !ok {
+			mapping[from] = leadingWhitespace(fileLines[j])
+		}
+	}
+	keys := make([]string, 0, len(mapping))
+	for k := range mapping {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+
+	out := make([]string, len(newLines))
+	for i, line := range newLines {
+		out[i] = line
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		for _, k := range keys {
+			if strings.HasPrefix(line, k) {
+				out[i] = mapping[k] + line[len(k):]
+				break
+			}
+		}
+	}
+	return out
+}
+
 func resolveRequiredStringFieldLocal(args map[string]any, keys ...string) (string, bool) {
 	for _, k := range keys {
 		v, ok := args[k]
@@ -109,3 +109,122 @@ func TestEditToolWithEngineLoop(t *testing.T) {
 		t.Fatalf("unexpected final content: %q", string(b))
 	}
 }
+
+func writeEditFixture(t *testing.T, content string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write fixture failed: %v", err)
+	}
+	return dir, filepath.Join(dir, "a.txt")
+}
+
+func readEditFixture(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read edited file failed: %v", err)
+	}
+	return string(b)
+}
+
+func TestEditToolAppliesEditsInOrderAtomically(t *testing.T) {
+	dir, p := writeEditFixture(t, "a b c\n")
+	tool := NewEditTool(dir)
+
+	out, err := tool.Execute(context.Background(), map[string]any{
+		"path": "a.txt",
+		"edits": []any{
+			map[string]any{"oldText": "a", "newText": "x"},
+			map[string]any{"old_text": "x b", "new_text": "y"},
+		},
+	})
+	if err != nil || !strings.HasPrefix(out, "edited a.txt (2 replacements)\n") {
+		t.Fatalf("unexpected result: out=%q err=%v", out, err)
+	}
+	if got := readEditFixture(t, p); got != "y c\n" {
+		t.Fatalf("edits must see earlier results, got %q", got)
+	}
+
+	_, err = tool.Execute(context.Background(), map[string]any{
+		"path": "a.txt",
+		"edits": []any{
+			map[string]any{"oldText": "y", "newText": "z"},
+			map[string]any{"oldText": "missing", "newText": "k"},
+		},
+	})
+	if err == nil || err.Error() != "edit_old_text_not_found: edits[1]" {
+		t.Fatalf("expected failing edit to be named, got %v", err)
+	}
+	if got := readEditFixture(t, p); got != "y c\n" {
+		t.Fatalf("a failed edit must leave the file untouched, got %q", got)
+	}
+	if _, err := tool.Execute(context.Background(), map[string]any{"path": "a.txt", "oldText": "y", "edits": []any{}}); err == nil || !strings.HasPrefix(err.Error(), "edit_invalid_edits") {
+		t.Fatalf("expected edit_invalid_edits, got %v", err)
+	}
+}
+
+func TestEditToolReplaceAll(t *testing.T) {
+	dir, p := writeEditFixture(t, "x x x")
+	out, err := NewEditTool(dir).Execute(context.Background(), map[string]any{"path": "a.txt", "oldText": "x", "newText": "k", "replace_all": true})
+	if err != nil || !strings.Contains(out, "3 replacements") {
+		t.Fatalf("unexpected result: out=%q err=%v", out, err)
+	}
+	if got := readEditFixture(t, p); got != "k k k" {
+		t.Fatalf("unexpected content: %q", got)
+	}
+}
+
+func TestEditToolWhitespaceTolerantFallback(t *testing.T) {
+	dir, p := writeEditFixture(t, "func f() {\n\tif x {  \n\t\treturn 1\n\t}\n}\n")
+	tool := NewEditTool(dir)
+
+	out, err := tool.Execute(context.Background(), map[string]any{
+		"path":    "a.txt",
+		"oldText": "if x {\n    return 1\n}",
+		"newText": "if x {\n    log()\n    return 2\n}",
+	})
+	if err != nil || !strings.Contains(out, "matched oldText ignoring whitespace") {
+		t.Fatalf("unexpected result: out=%q err=%v", out, err)
+	}
+	if got := readEditFixture(t, p); got != "func f() {\n\tif x {\n\t\tlog()\n\t\treturn 2\n\t}\n}\n" {
+		t.Fatalf("expected replacement in file indentation, got %q", got)
+	}
+
+	dir, _ = writeEditFixture(t, "a:\n  v: 1\nb:\n    v: 1\n")
+	_, err = NewEditTool(dir).Execute(context.Background(), map[string]any{"path": "a.txt", "oldText": "v: 1 ", "newText": "v: 2"})
+	if err == nil || !strings.HasPrefix(err.Error(), "edit_old_text_ambiguous: matches 2 places ignoring whitespace (lines 2, 4)") {
+		t.Fatalf("expected ambiguity error with line numbers, got %v", err)
+	}
+}
+
+func TestEditToolPreservesCRLFAndBOM(t *testing.T) {
+	dir, p := writeEditFixture(t, "\xef\xbb\xbfone\r\ntwo\r\nthree\r\n")
+	_, err := NewEditTool(dir).Execute(context.Background(), map[string]any{
+		"path":    "a.txt",
+		"oldText": "one\ntwo\n",
+		"newText": "one\n2\nmore\n",
+	})
+	if err != nil {
+		t.Fatalf("edit failed: %v", err)
+	}
+	if got := readEditFixture(t, p); got != "\xef\xbb\xbfone\r\n2\r\nmore\r\nthree\r\n" {
+		t.Fatalf("expected BOM and CRLF preserved, got %q", got)
+	}
+}
+
+func TestEditToolReturnsDiff(t *testing.T) {
+	dir, _ := writeEditFixture(t, "a\r\nb\r\nc\r\n")
+	res, err := NewEditTool(dir).(core.DetailedTool).ExecuteWithDetails(context.Background(), map[string]any{
+		"path":    "a.txt",
+		"oldText": "b",
+		"newText": "B",
+	})
+	want := "edited a.txt\nfirst changed line: 2 (+1 -1)\n\n--- a/a.txt\n+++ b/a.txt\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"
+	if err != nil || res.Text != want {
+		t.Fatalf("unexpected result: %q err=%v", res.Text, err)
+	}
+	if res.Details["first_changed_line"] != 2 || res.Details["additions"] != 1 || res.Details["deletions"] != 1 {
+		t.Fatalf("unexpected details: %+v", res.Details)
+	}
+}
@@ -0,0 +1,190 @@
+package builtins
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"nous/internal/glob"
+)
+
+// ResourceLimits are per-process rlimits applied to shell commands. Zero
+// leaves a limit unchanged.
+type ResourceLimits struct {
+	CPUSeconds     int `json:"cpu_seconds,omitempty"`
+	AddressSpaceMB int `json:"address_space_mb,omitempty"`
+	OpenFiles      int `json:"open_files,omitempty"`
+	Processes      int `json:"processes,omitempty"`
+}
+
+// ExecProfile controls the environment and limits of commands run by the
+// bash and process tools. EnvAllow and EnvDeny hold variable name globs; an
+// empty EnvAllow keeps every variable not denied. IsolateNetwork runs
+// commands in new user and network namespaces where the kernel allows it.
+type ExecProfile struct {
+	Name           string         `json:"name,omitempty"`
+	EnvAllow       []string       `json:"env_allow,omitempty"`
+	EnvDeny        []string       `json:"env_deny,omitempty"`
+	Limits         ResourceLimits `json:"limits,omitempty"`
+	IsolateNetwork bool           `json:"isolate_network,omitempty"`
+}
+
+// secretEnvPatterns match the provider keys and other credentials the core
+// itself may hold.
+var secretEnvPatterns = []string{
+	"*_API_KEY", "*_APIKEY", "*_TOKEN", "*_SECRET", "*_SECRET_*", "*_SECRET_KEY", "*PASSWORD*", "*_CREDENTIALS",
+}
+
+var execProfiles = map[string]ExecProfile{
+	"unrestricted": {Name: "unrestricted"},
+	"default": {
+		Name:    "default",
+		EnvDeny: secretEnvPatterns,
+	},
+	"strict": {
+		Name:     "strict",
+		EnvAllow: []string{"PATH", "HOME", "USER", "LOGNAME", "SHELL", "TERM", "LANG", "LC_*", "TZ", "TMPDIR"},
+		Limits: ResourceLimits{
+			CPUSeconds:     300,
+			AddressSpaceMB: 4096,
+			OpenFiles:      1024,
+			Processes:      1024,
+		},
+		IsolateNetwork: true,
+	},
+}
+
+// DefaultExecProfile scrubs credentials from the environment and sets no
+// limits.
+func DefaultExecProfile() ExecProfile {
+	p, _ := ExecProfileByName("default")
+	return p
+}
+
+// ExecProfileByName returns a builtin profile: unrestricted, default or
+// strict.
+func ExecProfileByName(name string) (ExecProfile, error) {
+	p, ok := execProfiles[strings.TrimSpace(name)]
+	if !ok {
+		return ExecProfile{}, fmt.Errorf("exec_profile_unknown: %s", name)
+	}
+	return p, nil
+}
+
+func ExecProfileNames() []string {
+	names := make([]string, 0, len(execProfiles))
+	for name := range execProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func LoadExecProfileFile(path string) (ExecProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ExecProfile{}, fmt.Errorf("exec_profile_read_failed: %s: %w", path, err)
+	}
+	p, err := ParseExecProfile(data)
+	if err != nil {
+		return ExecProfile{}, fmt.Errorf("%w (%s)", err, path)
+	}
+	if p.Name == "" {
+		p.Name = path
+	}
+	return p, nil
+}
+
+func ParseExecProfile(data []byte) (ExecProfile, error) {
+	var p ExecProfile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return ExecProfile{}, fmt.Errorf("invalid_exec_profile: %v", err)
+	}
+	for _, pattern := range append(append([]string(nil), p.EnvAllow...), p.EnvDeny...) {
+		if strings.TrimSpace(pattern) == "" || strings.Contains(pattern, "/") || !glob.Valid(pattern) {
+			return ExecProfile{}, fmt.Errorf("invalid_exec_profile: bad env pattern %q", pattern)
+		}
+	}
+	l := p.Limits
+	if l.CPUSeconds < 0 || l.AddressSpaceMB < 0 || l.OpenFiles < 0 || l.Processes < 0 {
+		return ExecProfile{}, fmt.Errorf("invalid_exec_profile: limits must not be negative")
+	}
+	return p, nil
+}
+
+// environ filters env (KEY=value pairs) through the allow and deny lists.
+func (p ExecProfile) environ(env []string) []string {
+	out := make([]string, 0, len(env))
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		if len(p.EnvAllow) > 0 && !matchesAnyEnv(p.EnvAllow, name) {
+			continue
+		}
+		if matchesAnyEnv(p.EnvDeny, name) {
+			continue
+		}
+		out = append(out, kv)
+	}
+	return out
+}
+
+func matchesAnyEnv(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if glob.Match(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply sets the environment, process group and isolation of cmd.
+func (p ExecProfile) apply(cmd *exec.Cmd) {
+	cmd.Env = p.environ(os.Environ())
+	setProcessGroup(cmd)
+	if p.IsolateNetwork {
+		isolateNetwork(cmd)
+	}
+}
+
+// limitScript returns shell lines setting the profile's rlimits, failing the
+// shell with status 126 when they cannot be applied. It is empty when the
+// profile sets no limits.
+func (p ExecProfile) limitScript() string {
+	l := p.Limits
+	var parts []string
+	if l.CPUSeconds > 0 {
+		parts = append(parts, "ulimit -t "+strconv.Itoa(l.CPUSeconds))
+	}
+	if l.AddressSpaceMB > 0 {
+		parts = append(parts, "ulimit -v "+strconv.Itoa(l.AddressSpaceMB*1024))
+	}
+	if l.OpenFiles > 0 {
+		parts = append(parts, "ulimit -n "+strconv.Itoa(l.OpenFiles))
+	}
+	if l.Processes > 0 {
+		// bash, zsh and ksh use -u; dash and busybox ash use -p.
+		n := strconv.Itoa(l.Processes)
+		parts = append(parts, "{ ulimit -u "+n+" 2>/dev/null || ulimit -p "+n+"; }")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{ " + strings.Join(parts, " && ") + "; } || { echo 'exec_profile_limits_failed' >&2; exit 126; }\n"
+}
+
+// state describes the profile for get_state; isolated reports whether
+// commands run by shell actually get network isolation.
+func (p ExecProfile) state(shell string) map[string]any {
+	return map[string]any{
+		"name":     p.Name,
+		"limits":   p.Limits,
+		"isolated": p.IsolateNetwork && networkIsolationAvailable(shell),
+	}
+}
@@ -0,0 +1,46 @@
+//go:build linux
+
+package builtins
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+)
+
+var (
+	userNamespacesOnce sync.Once
+	userNamespacesOK   bool
+)
+
+// networkIsolationAvailable reports whether this process may create user
+// and network namespaces, probing once by running shell in them.
+func networkIsolationAvailable(shell string) bool {
+	userNamespacesOnce.Do(func() {
+		probe := exec.Command(shell, "-c", "exit 0")
+		setNamespaceAttrs(probe)
+		userNamespacesOK = probe.Run() == nil
+	})
+	return userNamespacesOK
+}
+
+// isolateNetwork runs cmd in new user and network namespaces, mapping the
+// current uid and gid, so it has no network access beyond an unconfigured
+// loopback. It does nothing when unprivileged namespaces are unavailable.
+func isolateNetwork(cmd *exec.Cmd) {
+	if networkIsolationAvailable(cmd.Path) {
+		setNamespaceAttrs(cmd)
+	}
+}
+
+func setNamespaceAttrs(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	attr := cmd.SysProcAttr
+	attr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET
+	attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+	attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+	attr.GidMappingsEnableSetgroups = false
+}
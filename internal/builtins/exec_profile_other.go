@@ -0,0 +1,9 @@
+//go:build !linux
+
+package builtins
+
+import "os/exec"
+
+func networkIsolationAvailable(string) bool { return false }
+
+func isolateNetwork(*exec.Cmd) {}
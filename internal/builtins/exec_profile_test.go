@@ -0,0 +1,145 @@
+package builtins
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecProfileFiltersEnvironment(t *testing.T) {
+	env := []string{"PATH=/bin", "HOME=/home/me", "OPENAI_API_KEY=sk", "GITHUB_TOKEN=gh", "DB_PASSWORD=pw", "LC_ALL=C", "EDITOR=vi"}
+
+	if got := DefaultExecProfile().environ(env); !slices.Equal(got, []string{"PATH=/bin", "HOME=/home/me", "LC_ALL=C", "EDITOR=vi"}) {
+		t.Fatalf("default profile must drop credentials, got %v", got)
+	}
+	strict, _ := ExecProfileByName("strict")
+	if got := strict.environ(env); !slices.Equal(got, []string{"PATH=/bin", "HOME=/home/me", "LC_ALL=C"}) {
+		t.Fatalf("strict profile must keep only allowed variables, got %v", got)
+	}
+	custom := ExecProfile{EnvAllow: []string{"*"}, EnvDeny: []string{"EDITOR"}}
+	if got := custom.environ(env); len(got) != len(env)-1 || slices.Contains(got, "EDITOR=vi") {
+		t.Fatalf("deny must win over allow, got %v", got)
+	}
+	if _, err := ExecProfileByName("lax"); err == nil || !strings.HasPrefix(err.Error(), "exec_profile_unknown") {
+		t.Fatalf("expected exec_profile_unknown, got %v", err)
+	}
+}
+
+func TestParseExecProfile(t *testing.T) {
+	p, err := ParseExecProfile([]byte(`{"env_deny":["AWS_*"],"limits":{"cpu_seconds":5,"open_files":64}}`))
+	if err != nil || p.Limits.CPUSeconds != 5 || p.Limits.OpenFiles != 64 {
+		t.Fatalf("unexpected profile: %+v err=%v", p, err)
+	}
+	bad := map[string]string{
+		"unknown field": `{"env":["A"]}`,
+		"bad glob":      `{"env_allow":["A["]}`,
+		"empty pattern": `{"env_deny":[" "]}`,
+		"negative":      `{"limits":{"processes":-1}}`,
+	}
+	for name, data := range bad {
+		if _, err := ParseExecProfile([]byte(data)); err == nil || !strings.HasPrefix(err.Error(), "invalid_exec_profile") {
+			t.Fatalf("%s: expected invalid_exec_profile, got %v", name, err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "profile.json")
+	if err := os.WriteFile(path, []byte(`{"limits":{"open_files":64}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := LoadExecProfileFile(path); err != nil || p.Name != path {
+		t.Fatalf("expected file profile named after its path, got %+v err=%v", p, err)
+	}
+	if _, err := LoadExecProfileFile(path + ".missing"); err == nil || !strings.HasPrefix(err.Error(), "exec_profile_read_failed") {
+		t.Fatalf("expected exec_profile_read_failed, got %v", err)
+	}
+}
+
+func TestBashToolAppliesExecProfile(t *testing.T) {
+	if !isExecutableFile("/bin/sh") {
+		t.Skip("/bin/sh not available")
+	}
+	t.Setenv("NOUS_TEST_API_KEY", "s3cret")
+	profile := DefaultExecProfile()
+	profile.Limits = ResourceLimits{CPUSeconds: 30, OpenFiles: 64}
+	ctx := context.Background()
+
+	for _, persistent := range []bool{false, true} {
+		tool := newBashTool(t.TempDir(), Shell{Path: "/bin/sh"}, profile, persistent).(bashTool)
+		out, err := tool.Execute(ctx, map[string]any{"command": `printf '%s ' "${NOUS_TEST_API_KEY:-unset}"; ulimit -n; ulimit -t`})
+		tool.ResetSession()
+		if err != nil || out != "unset 64\n30" {
+			t.Fatalf("persistent=%v: expected scrubbed env and limits, out=%q err=%v", persistent, out, err)
+		}
+	}
+
+	failing := ExecProfile{Name: "huge", Limits: ResourceLimits{OpenFiles: 1 << 30}}
+	tool := newBashTool(t.TempDir(), Shell{Path: "/bin/sh"}, failing, false)
+	if _, err := tool.Execute(ctx, map[string]any{"command": "echo ran"}); err == nil || strings.Contains(err.Error(), "ran") || !strings.Contains(err.Error(), "exec_profile_limits_failed") {
+		t.Fatalf("expected command to be refused when limits fail, got %v", err)
+	}
+}
+
+func TestBashToolTimeoutKillsProcessGroup(t *testing.T) {
+	if !isExecutableFile("/bin/sh") {
+		t.Skip("/bin/sh not available")
+	}
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("/proc not available")
+	}
+	dir := t.TempDir()
+	tool := newBashTool(dir, Shell{Path: "/bin/sh"}, DefaultExecProfile(), false)
+	_, err := tool.Execute(context.Background(), map[string]any{
+		"command": "sleep 30 & echo $! > child.pid; wait",
+		"timeout": 0.3,
+	})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, "child.pid"))
+	if err != nil {
+		t.Fatalf("read child pid: %v", err)
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(raw)))
+	deadline := time.Now().Add(2 * time.Second)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			t.Fatalf("background child %d survived the timeout", pid)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// processAlive treats zombies as dead, since nothing may reap orphans here.
+func processAlive(pid int) bool {
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+func TestExecProfileIsolatesNetworkWhereAvailable(t *testing.T) {
+	if !isExecutableFile("/bin/sh") || !networkIsolationAvailable("/bin/sh") {
+		t.Skip("unprivileged user namespaces not available")
+	}
+	hostNS, err := os.Readlink("/proc/self/ns/net")
+	if err != nil {
+		t.Skip("network namespaces not visible")
+	}
+	profile := ExecProfile{Name: "isolated", IsolateNetwork: true}
+	tool := newBashTool(t.TempDir(), Shell{Path: "/bin/sh"}, profile, false).(bashTool)
+	out, err := tool.Execute(context.Background(), map[string]any{"command": "readlink /proc/self/ns/net"})
+	if err != nil || out == "" || out == hostNS {
+		t.Fatalf("expected a separate network namespace, host=%q out=%q err=%v", hostNS, out, err)
+	}
+	state, _ := tool.ToolState()["exec_profile"].(map[string]any)
+	if state["isolated"] != true {
+		t.Fatalf("expected tool state to report isolation, got %+v", state)
+	}
+}
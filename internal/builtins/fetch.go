@@ -0,0 +1,251 @@
+package builtins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"nous/internal/core"
+)
+
+const (
+	fetchDefaultTimeout = 30 * time.Second
+	fetchMaxTimeout     = 120 * time.Second
+	fetchMaxBodyBytes   = 5 << 20
+	fetchMaxOutputBytes = 100 << 10
+	fetchMaxRedirects   = 5
+)
+
+// fetchContentTypes are the media types fetch returns; anything else is
+// rejected before the body is read. A trailing /* or a +suffix matches a
+// family of types.
+var fetchContentTypes = []string{
+	"text/*",
+	"application/json", "application/*+json",
+	"application/xml", "application/*+xml",
+	"application/javascript", "application/x-yaml", "application/yaml", "application/toml",
+}
+
+func NewFetchTool(allowedDomains []string) core.Tool {
+	return newFetchTool(http.DefaultTransport, allowedDomains)
+}
+
+func newFetchTool(transport http.RoundTripper, allowedDomains []string) core.Tool {
+	allowed := make([]string, 0, len(allowedDomains))
+	for _, d := range allowedDomains {
+		if d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "*."); d != "" {
+			allowed = append(allowed, d)
+		}
+	}
+	client := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= fetchMaxRedirects {
+				return fmt.Errorf("fetch_too_many_redirects: stopped after %d", fetchMaxRedirects)
+			}
+			if err := checkFetchURL(req.Context(), req.URL, allowed); err != nil {
+				return err
+			}
+			return checkFetchRedirectPolicy(req.Context(), via[0].URL, req.URL)
+		},
+	}
+
+	return core.ToolFunc{
+		ToolName:        "fetch",
+		ToolDescription: "Fetch an http(s) URL and return its content, with HTML converted to markdown.",
+		ToolSchema: objectSchema(map[string]any{
+			"url":       withAliases(requiredStringProperty("Absolute http or https URL."), "uri", "href"),
+			"raw":       booleanProperty("Return HTML source instead of converting it to markdown."),
+			"max_bytes": withAliases(withMinimum(integerProperty("Maximum bytes of content to return. Defaults to 100KB."), 1), "maxBytes"),
+			"timeout":   withAliases(withMinimum(numberProperty("Timeout in seconds. Defaults to 30, at most 120."), 0), "timeout_seconds", "timeoutSeconds"),
+		}, "url"),
+		Run: func(ctx context.Context, args map[string]any) (string, error) {
+			raw, _ := args["url"].(string)
+			u, err := url.Parse(strings.TrimSpace(raw))
+			if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+				return "", fmt.Errorf("fetch_invalid_url: %s", raw)
+			}
+			if err := checkFetchURL(ctx, u, allowed); err != nil {
+				return "", err
+			}
+			maxBytes, err := intArg(args, "max_bytes", fetchMaxOutputBytes)
+			if err != nil || maxBytes <= 0 {
+				return "", fmt.Errorf("fetch_invalid_max_bytes")
+			}
+			maxBytes = min(maxBytes, fetchMaxBodyBytes)
+			timeoutSecs, err := floatArg(args, "timeout", 0)
+			if err != nil || timeoutSecs < 0 {
+				return "", fmt.Errorf("fetch_invalid_timeout")
+			}
+			timeout := fetchDefaultTimeout
+			if timeoutSecs > 0 {
+				timeout = min(time.Duration(float64(time.Second)*timeoutSecs), fetchMaxTimeout)
+			}
+
+			runCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			req, err := http.NewRequestWithContext(runCtx, http.MethodGet, u.String(), nil)
+			if err != nil {
+				return "", fmt.Errorf("fetch_invalid_url: %v", err)
+			}
+			req.Header.Set("User-Agent", "nous-fetch/1")
+			req.Header.Set("Accept", "text/markdown, text/html;q=0.9, text/plain;q=0.8, application/json;q=0.8, */*;q=0.1")
+
+			resp, err := client.Do(req)
+			if err != nil {
+				return "", fetchError(ctx, err, timeout)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				return "", fmt.Errorf("fetch_http_error: %s", resp.Status)
+			}
+			contentType := resp.Header.Get("Content-Type")
+			if contentType != "" && !fetchContentTypeAllowed(contentType) {
+				return "", fmt.Errorf("fetch_unsupported_content_type: %s", contentType)
+			}
+
+			body, err := io.ReadAll(io.LimitReader(resp.Body, fetchMaxBodyBytes+1))
+			if err != nil {
+				return "", fetchError(ctx, err, timeout)
+			}
+			bodyCut := len(body) > fetchMaxBodyBytes
+			if bodyCut {
+				body = body[:fetchMaxBodyBytes]
+			}
+			if contentType == "" {
+				contentType = http.DetectContentType(body)
+				if !fetchContentTypeAllowed(contentType) {
+					return "", fmt.Errorf("fetch_unsupported_content_type: %s", contentType)
+				}
+			}
+
+			text := strings.ToValidUTF8(string(body), "�")
+			title := ""
+			if mediaType, _, _ := mime.ParseMediaType(contentType); !boolArgLocal(args, "raw") && (mediaType == "text/html" || mediaType == "application/xhtml+xml") {
+				title, text = htmlToMarkdown(text, resp.Request.URL)
+			}
+
+			var b strings.Builder
+			fmt.Fprintf(&b, "url: %s\nstatus: %d\ncontent-type: %s\n", resp.Request.URL, resp.StatusCode, contentType)
+			if title != "" {
+				fmt.Fprintf(&b, "title: %s\n", title)
+			}
+			b.WriteString("\n")
+			if len(text) > maxBytes {
+				cut := truncateUTF8(text, maxBytes)
+				fmt.Fprintf(&b, "%s\n\n[content cut at %d of %d bytes; raise max_bytes to see more]", cut, len(cut), len(text))
+			} else {
+				b.WriteString(text)
+				if bodyCut {
+					fmt.Fprintf(&b, "\n\n[download stopped at %d bytes]", fetchMaxBodyBytes)
+				}
+			}
+			return b.String(), nil
+		},
+	}
+}
+
+// checkFetchURL enforces the domain allowlist; an entry also allows its
+// subdomains. With an empty list any host is allowed except loopback,
+// link-local and unspecified addresses, which must be listed explicitly.
+func checkFetchURL(ctx context.Context, u *url.URL, allowed []string) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("fetch_invalid_url: unsupported scheme %q", u.Scheme)
+	}
+	host := strings.ToLower(u.Hostname())
+	if len(allowed) == 0 {
+		return checkFetchAddress(ctx, host)
+	}
+	for _, d := range allowed {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return nil
+		}
+	}
+	return fmt.Errorf("fetch_domain_not_allowed: %s", host)
+}
+
+// checkFetchAddress rejects hosts that are, or resolve to, local addresses
+// such as 127.0.0.1 or the 169.254.169.254 metadata service. Lookup failures
+// are left for the request itself to report.
+func checkFetchAddress(ctx context.Context, host string) error {
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else if addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host); err == nil {
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+	for _, ip := range ips {
+		if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+			if ip.String() != host {
+				host = fmt.Sprintf("%s (%s)", host, ip)
+			}
+			return fmt.Errorf("fetch_local_address: %s; add the host to --fetch-allowed-domains to fetch it", host)
+		}
+	}
+	return nil
+}
+
+// checkFetchRedirectPolicy applies the tool policy to a redirect target,
+// since the engine only checked the requested URL. A hop is followed when
+// the policy allows it, or asks for it under the rule the user already
+// approved for the original URL.
+func checkFetchRedirectPolicy(ctx context.Context, from, to *url.URL) error {
+	p := core.PolicyFromContext(ctx)
+	if p == nil {
+		return nil
+	}
+	next := p.Evaluate("fetch", map[string]any{"url": to.String()})
+	switch {
+	case next.Action == core.PolicyAllow:
+		return nil
+	case next.Action == core.PolicyAsk && next.RuleID == p.Evaluate("fetch", map[string]any{"url": from.String()}).RuleID:
+		return nil
+	case next.Action == core.PolicyAsk:
+		return fmt.Errorf("fetch_redirect_blocked: %s needs approval under policy rule %s; fetch it directly", to.Hostname(), next.RuleID)
+	default:
+		return fmt.Errorf("fetch_redirect_blocked: %s denied by policy rule %s", to.Hostname(), next.RuleID)
+	}
+}
+
+func fetchContentTypeAllowed(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	kind, sub, _ := strings.Cut(mediaType, "/")
+	for _, allowed := range fetchContentTypes {
+		aKind, aSub, _ := strings.Cut(allowed, "/")
+		if aKind != kind {
+			continue
+		}
+		switch {
+		case aSub == "*", aSub == sub:
+			return true
+		case strings.HasPrefix(aSub, "*+") && strings.HasSuffix(sub, aSub[1:]):
+			return true
+		}
+	}
+	return false
+}
+
+// fetchError unwraps the client's *url.Error so redirect checks keep their
+// error codes, and reports our own deadline as a timeout.
+func fetchError(ctx context.Context, err error, timeout time.Duration) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && strings.HasPrefix(urlErr.Err.Error(), "fetch_") {
+		return urlErr.Err
+	}
+	if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("fetch_timeout: no response within %s", timeout)
+	}
+	return fmt.Errorf("fetch_failed: %w", err)
+}
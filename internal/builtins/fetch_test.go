@@ -0,0 +1,197 @@
+package builtins
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"nous/internal/core"
+)
+
+// fetchTestHosts allows the loopback test server, which fetch otherwise
+// rejects.
+var fetchTestHosts = []string{"127.0.0.1"}
+
+func newFetchTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/doc", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<!doctype html><html><head><title>Guide &amp; Notes</title><style>p{}</style></head>
+<body><nav><a href="/">Home</a></nav><h1>Install</h1><p>Run <code>make</code> then see <a href="/next">next steps</a>.</p>
+<script>alert(1)</script></body></html>`))
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(strings.Repeat("abcdefghij", 100)))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("\x89PNG"))
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Query().Get("to"), http.StatusFound)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetchToolConvertsHTML(t *testing.T) {
+	srv := newFetchTestServer(t)
+	out, err := NewFetchTool(fetchTestHosts).Execute(context.Background(), map[string]any{"url": srv.URL + "/doc"})
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	for _, want := range []string{
+		"url: " + srv.URL + "/doc\n",
+		"status: 200\n",
+		"content-type: text/html; charset=utf-8\n",
+		"title: Guide & Notes\n",
+		"# Install\n\nRun `make` then see [next steps](" + srv.URL + "/next).",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"alert", "p{}", "Home"} {
+		if strings.Contains(out, unwanted) {
+			t.Fatalf("output must not contain %q:\n%s", unwanted, out)
+		}
+	}
+
+	out, err = NewFetchTool(fetchTestHosts).Execute(context.Background(), map[string]any{"url": srv.URL + "/doc", "raw": true})
+	if err != nil || !strings.Contains(out, "<h1>Install</h1>") {
+		t.Fatalf("raw fetch must return HTML source, got %v:\n%s", err, out)
+	}
+}
+
+func TestFetchToolLimits(t *testing.T) {
+	srv := newFetchTestServer(t)
+	tool := NewFetchTool(fetchTestHosts)
+
+	out, err := tool.Execute(context.Background(), map[string]any{"url": srv.URL + "/plain", "max_bytes": 25})
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if !strings.HasSuffix(out, "\n\nabcdefghijabcdefghijabcde\n\n[content cut at 25 of 1000 bytes; raise max_bytes to see more]") {
+		t.Fatalf("unexpected truncated output:\n%s", out)
+	}
+
+	start := time.Now()
+	_, err = tool.Execute(context.Background(), map[string]any{"url": srv.URL + "/slow", "timeout": 0.2})
+	if err == nil || !strings.HasPrefix(err.Error(), "fetch_timeout") {
+		t.Fatalf("expected fetch_timeout, got %v", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Fatalf("timeout not enforced, took %s", time.Since(start))
+	}
+}
+
+func TestFetchToolErrors(t *testing.T) {
+	srv := newFetchTestServer(t)
+	tool := NewFetchTool(fetchTestHosts)
+	cases := map[string]string{
+		"ftp://example.com/x":  "fetch_invalid_url",
+		"not a url":            "fetch_invalid_url",
+		srv.URL + "/image":     "fetch_unsupported_content_type: image/png",
+		srv.URL + "/missing":   "fetch_http_error: 404 Not Found",
+		"http://127.0.0.1:1/x": "fetch_failed",
+	}
+	for u, want := range cases {
+		if _, err := tool.Execute(context.Background(), map[string]any{"url": u}); err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Fatalf("%s: expected %s, got %v", u, want, err)
+		}
+	}
+}
+
+func TestFetchToolDomainAllowlist(t *testing.T) {
+	srv := newFetchTestServer(t)
+
+	if _, err := NewFetchTool([]string{"docs.example.com"}).Execute(context.Background(), map[string]any{"url": srv.URL + "/doc"}); err == nil || err.Error() != "fetch_domain_not_allowed: 127.0.0.1" {
+		t.Fatalf("expected fetch_domain_not_allowed, got %v", err)
+	}
+
+	tool := NewFetchTool([]string{"127.0.0.1"})
+	if _, err := tool.Execute(context.Background(), map[string]any{"url": srv.URL + "/doc"}); err != nil {
+		t.Fatalf("allowed host must be fetched: %v", err)
+	}
+	_, err := tool.Execute(context.Background(), map[string]any{"url": srv.URL + "/redirect?to=http://evil.example.com/"})
+	if err == nil || err.Error() != "fetch_domain_not_allowed: evil.example.com" {
+		t.Fatalf("redirects must be checked against the allowlist, got %v", err)
+	}
+
+	if err := checkFetchURL(context.Background(), mustParseURL(t, "https://api.docs.example.com/x"), []string{"example.com"}); err != nil {
+		t.Fatalf("subdomains must be allowed: %v", err)
+	}
+	if err := checkFetchURL(context.Background(), mustParseURL(t, "https://notexample.com/x"), []string{"example.com"}); err == nil {
+		t.Fatal("suffix match must respect label boundaries")
+	}
+}
+
+func TestFetchToolRejectsLocalAddressesByDefault(t *testing.T) {
+	srv := newFetchTestServer(t)
+	tool := NewFetchTool(nil)
+	for _, u := range []string{srv.URL + "/doc", "http://169.254.169.254/latest/meta-data/", "http://[::1]:8080/", "http://0.0.0.0/"} {
+		if _, err := tool.Execute(context.Background(), map[string]any{"url": u}); err == nil || !strings.HasPrefix(err.Error(), "fetch_local_address") {
+			t.Fatalf("%s: expected fetch_local_address, got %v", u, err)
+		}
+	}
+	if err := checkFetchAddress(context.Background(), "localhost"); err == nil || !strings.HasPrefix(err.Error(), "fetch_local_address: localhost (") {
+		t.Fatalf("names resolving to loopback must be rejected, got %v", err)
+	}
+}
+
+func TestFetchToolChecksRedirectsAgainstPolicy(t *testing.T) {
+	srv := newFetchTestServer(t)
+	tool := NewFetchTool([]string{"127.0.0.1", "localhost"})
+	local := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	p, err := core.ParsePolicy([]byte(`{"rules":[
+		{"id":"no-localhost","tool":"fetch","domains":["localhost"],"action":"deny"},
+		{"id":"ask-fetch","tool":"fetch","action":"ask"}
+	]}`))
+	if err != nil {
+		t.Fatalf("parse policy failed: %v", err)
+	}
+	ctx := core.WithPolicy(context.Background(), p)
+
+	_, err = tool.Execute(ctx, map[string]any{"url": srv.URL + "/redirect?to=" + local + "/doc"})
+	if err == nil || err.Error() != "fetch_redirect_blocked: localhost denied by policy rule no-localhost" {
+		t.Fatalf("redirect to a denied host must be blocked, got %v", err)
+	}
+	// The hop falls under the rule already approved for the original URL.
+	if _, err := tool.Execute(ctx, map[string]any{"url": srv.URL + "/redirect?to=" + srv.URL + "/doc"}); err != nil {
+		t.Fatalf("redirect under the approved rule must be followed: %v", err)
+	}
+	if _, err := tool.Execute(context.Background(), map[string]any{"url": srv.URL + "/redirect?to=" + local + "/doc"}); err != nil {
+		t.Fatalf("redirects must be followed without a policy: %v", err)
+	}
+}
+
+func TestFetchContentTypeAllowlist(t *testing.T) {
+	for contentType, want := range map[string]bool{
+		"text/html; charset=utf-8":       true,
+		"text/markdown":                  true,
+		"application/json":               true,
+		"application/problem+json":       true,
+		"application/atom+xml":           true,
+		"application/octet-stream":       false,
+		"image/svg+xml":                  false,
+		"application/pdf":                false,
+		"multipart/form-data; boundary=": false,
+	} {
+		if got := fetchContentTypeAllowed(contentType); got != want {
+			t.Fatalf("%s: allowed=%v, want %v", contentType, got, want)
+		}
+	}
+}
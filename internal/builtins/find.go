@@ -4,31 +4,67 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"nous/internal/core"
+	"nous/internal/glob"
 )
 
+type findEntry struct {
+	Path  string `json:"path"`
+	Type  string `json:"type"`
+	Size  int64  `json:"size"`
+	Mtime string `json:"mtime"`
+
+	modTime time.Time
+}
+
 func NewFindTool(cwd string) core.Tool {
-	base := resolveBaseDir(cwd)
+	return newFindTool(newPathResolver(Config{Workdir: cwd}))
+}
+
+func newFindTool(paths *pathResolver) core.Tool {
 	return core.ToolFunc{
-		ToolName: "find",
+		ToolName:        "find",
+		ToolDescription: "Find files under a path by glob or substring match. Respects .gitignore and .ignore files.",
+		ToolSchema: objectSchema(map[string]any{
+			"path":        withAliases(withDefault(stringProperty("Root directory to search. Defaults to current directory."), "."), dirAliases...),
+			"query":       withAliases(requiredStringProperty("Glob such as \"**/*.go\" or \"*_test.go\" (without a slash it matches names at any depth), or a substring of the relative path when it has no *, ? or [."), "pattern", "glob"),
+			"type":        withEnum(withDefault(stringProperty("Only return entries of this type."), "any"), "any", "file", "dir", "symlink"),
+			"sort":        withEnum(withDefault(stringProperty("Order by relative path, or by modification time, newest first."), "path"), "path", "mtime"),
+			"metadata":    withAliases(booleanProperty("Return objects with path, type, size and mtime instead of plain paths."), "long", "details"),
+			"no_ignore":   withAliases(booleanProperty("Also return entries excluded by .gitignore and .ignore."), "noIgnore"),
+			"max_results": withAliases(withMinimum(integerProperty("Maximum number of matches to return."), 1), "maxResults"),
+			"max_depth":   withAliases(withMinimum(integerProperty("Maximum depth to walk. -1 means unlimited."), -1), "maxDepth"),
+		}, "query"),
 		Run: func(_ context.Context, args map[string]any) (string, error) {
 			query, _ := args["query"].(string)
 			query = strings.TrimSpace(query)
 			if query == "" {
 				return "", fmt.Errorf("find_invalid_query")
 			}
+			isGlob := strings.ContainsAny(query, "*?[")
+			if isGlob {
+				query = strings.TrimPrefix(query, "./")
+				if !glob.Valid(query) {
+					return "", fmt.Errorf("find_invalid_query: %s", query)
+				}
+			}
 
 			root, _ := args["path"].(string)
 			root = strings.TrimSpace(root)
 			if root == "" {
 				root = "."
 			}
-			absRoot := resolveToolPath(base, root)
+			absRoot, err := paths.resolve(root)
+			if err != nil {
+				return "", err
+			}
 
 			info, err := os.Stat(absRoot)
 			if err != nil {
@@ -46,43 +82,75 @@ func NewFindTool(cwd string) core.Tool {
 			if err != nil || maxDepth < -1 {
 				return "", fmt.Errorf("find_invalid_max_depth")
 			}
+			typ, _ := args["type"].(string)
+			switch typ {
+			case "", "any", "file", "dir", "symlink":
+			default:
+				return "", fmt.Errorf("find_invalid_type: %s", typ)
+			}
+			sortBy, _ := args["sort"].(string)
+			switch sortBy {
+			case "", "path", "mtime":
+			default:
+				return "", fmt.Errorf("find_invalid_sort: %s", sortBy)
+			}
+			metadata := boolArgLocal(args, "metadata", "long", "details")
+			byMtime := sortBy == "mtime"
 
-			matches := make([]string, 0, 32)
-			walkErr := filepath.WalkDir(absRoot, func(path string, d os.DirEntry, inErr error) error {
-				if inErr != nil {
-					return inErr
-				}
-				rel, err := filepath.Rel(absRoot, path)
-				if err != nil {
-					return err
+			matches := make([]findEntry, 0, 32)
+			opts := walkOptions{noIgnore: boolArgLocal(args, "no_ignore", "noIgnore"), maxDepth: maxDepth}
+			walkErr := walkTree(paths, absRoot, opts, func(path, rel string, d fs.DirEntry) error {
+				entryType := findEntryType(d)
+				if typ != "" && typ != "any" && typ != entryType {
+					return nil
 				}
-				if rel == "." {
+				if (isGlob && !matchPathGlob(query, rel)) || (!isGlob && !strings.Contains(filepath.FromSlash(rel), query)) {
 					return nil
 				}
-
-				if maxDepth >= 0 {
-					depth := strings.Count(rel, string(os.PathSeparator)) + 1
-					if depth > maxDepth {
-						if d.IsDir() {
-							return filepath.SkipDir
-						}
-						return nil
+				entry := findEntry{Path: filepath.FromSlash(rel), Type: entryType}
+				if metadata || byMtime {
+					fi, err := d.Info()
+					if err != nil {
+						return err
 					}
+					entry.Size, entry.modTime = fi.Size(), fi.ModTime()
+					entry.Mtime = fi.ModTime().UTC().Format(time.RFC3339)
 				}
-
-				if strings.Contains(rel, query) {
-					matches = append(matches, rel)
-					if len(matches) >= maxResults {
-						return filepath.SkipAll
-					}
+				matches = append(matches, entry)
+				// Sorting by mtime needs every candidate; by path the walk
+				// can stop once enough are found.
+				if !byMtime && len(matches) >= maxResults {
+					return filepath.SkipAll
 				}
 				return nil
 			})
 			if walkErr != nil {
 				return "", fmt.Errorf("find_failed: %w", walkErr)
 			}
-			sort.Strings(matches)
-			b, err := json.Marshal(matches)
+			if byMtime {
+				sort.SliceStable(matches, func(i, j int) bool {
+					if !matches[i].modTime.Equal(matches[j].modTime) {
+						return matches[i].modTime.After(matches[j].modTime)
+					}
+					return matches[i].Path < matches[j].Path
+				})
+			} else {
+				sort.Slice(matches, func(i, j int) bool { return matches[i].Path < matches[j].Path })
+			}
+			if len(matches) > maxResults {
+				matches = matches[:maxResults]
+			}
+
+			var b []byte
+			if metadata {
+				b, err = json.Marshal(matches)
+			} else {
+				names := make([]string, len(matches))
+				for i, m := range matches {
+					names[i] = m.Path
+				}
+				b, err = json.Marshal(names)
+			}
 			if err != nil {
 				return "", fmt.Errorf("find_failed: %w", err)
 			}
@@ -90,3 +158,14 @@ func NewFindTool(cwd string) core.Tool {
 		},
 	}
 }
+
+func findEntryType(d fs.DirEntry) string {
+	switch {
+	case d.Type()&fs.ModeSymlink != 0:
+		return "symlink"
+	case d.IsDir():
+		return "dir"
+	default:
+		return "file"
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"nous/internal/core"
 	"nous/internal/provider"
@@ -77,6 +79,90 @@ func TestFindToolErrors(t *testing.T) {
 	}
 }
 
+func TestFindToolGlobTypeAndIgnore(t *testing.T) {
+	dir := t.TempDir()
+	mustWrite(t, filepath.Join(dir, ".gitignore"), "vendor/\n")
+	mustWrite(t, filepath.Join(dir, "main.go"), "")
+	mustWrite(t, filepath.Join(dir, "pkg", "a.go"), "")
+	mustWrite(t, filepath.Join(dir, "pkg", "a_test.go"), "")
+	mustWrite(t, filepath.Join(dir, "pkg", "go.sum"), "")
+	mustWrite(t, filepath.Join(dir, "vendor", "dep.go"), "")
+	mustMkdirAll(t, filepath.Join(dir, "pkg", "gofiles"))
+	if err := os.Symlink("main.go", filepath.Join(dir, "link.go")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	tool := NewFindTool(dir)
+	find := func(args map[string]any) []string {
+		t.Helper()
+		out, err := tool.Execute(context.Background(), args)
+		if err != nil {
+			t.Fatalf("find failed: %v", err)
+		}
+		var got []string
+		if err := json.Unmarshal([]byte(out), &got); err != nil {
+			t.Fatalf("decode failed: %v", err)
+		}
+		for i := range got {
+			got[i] = filepath.ToSlash(got[i])
+		}
+		return got
+	}
+
+	if got := find(map[string]any{"query": "**/*.go"}); !slices.Equal(got, []string{"link.go", "main.go", "pkg/a.go", "pkg/a_test.go"}) {
+		t.Fatalf("unexpected glob matches: %v", got)
+	}
+	if got := find(map[string]any{"query": "*.go", "type": "file"}); !slices.Equal(got, []string{"main.go", "pkg/a.go", "pkg/a_test.go"}) {
+		t.Fatalf("unexpected file matches: %v", got)
+	}
+	if got := find(map[string]any{"query": "*", "type": "symlink"}); !slices.Equal(got, []string{"link.go"}) {
+		t.Fatalf("unexpected symlink matches: %v", got)
+	}
+	if got := find(map[string]any{"query": "go", "type": "dir"}); !slices.Equal(got, []string{"pkg/gofiles"}) {
+		t.Fatalf("unexpected dir matches: %v", got)
+	}
+	if got := find(map[string]any{"query": "*.go", "no_ignore": true, "type": "file"}); !slices.Contains(got, "vendor/dep.go") {
+		t.Fatalf("no_ignore must include vendor: %v", got)
+	}
+	if _, err := tool.Execute(context.Background(), map[string]any{"query": "[a-"}); err == nil || !strings.HasPrefix(err.Error(), "find_invalid_query") {
+		t.Fatalf("expected find_invalid_query, got %v", err)
+	}
+	if _, err := tool.Execute(context.Background(), map[string]any{"query": "x", "type": "socket"}); err == nil || !strings.HasPrefix(err.Error(), "find_invalid_type") {
+		t.Fatalf("expected find_invalid_type, got %v", err)
+	}
+}
+
+func TestFindToolSortByMtimeWithMetadata(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i, name := range []string{"old.txt", "new.txt", "mid.txt"} {
+		p := filepath.Join(dir, name)
+		mustWrite(t, p, strings.Repeat("x", i+1))
+		mtime := now.Add(-time.Hour * time.Duration([]int{3, 1, 2}[i]))
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := NewFindTool(dir).Execute(context.Background(), map[string]any{"query": "*.txt", "sort": "mtime", "metadata": true, "max_results": 2})
+	if err != nil {
+		t.Fatalf("find failed: %v", err)
+	}
+	var got []findEntry
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if len(got) != 2 || got[0].Path != "new.txt" || got[1].Path != "mid.txt" {
+		t.Fatalf("expected newest two files, got %+v", got)
+	}
+	if got[0].Type != "file" || got[0].Size != 2 || got[0].Mtime == "" {
+		t.Fatalf("missing metadata: %+v", got[0])
+	}
+	if _, err := time.Parse(time.RFC3339, got[0].Mtime); err != nil {
+		t.Fatalf("mtime must be RFC3339: %v", err)
+	}
+}
+
 type findToolCallProvider struct{}
 
 func (findToolCallProvider) Stream(_ context.Context, req provider.Request) <-chan provider.Event {
@@ -117,6 +203,9 @@ func TestFindToolWithEngineLoop(t *testing.T) {
 
 func mustWrite(t *testing.T, path, content string) {
 	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir for %s failed: %v", path, err)
+	}
 	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
 		t.Fatalf("write %s failed: %v", path, err)
 	}
@@ -0,0 +1,659 @@
+package builtins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"nous/internal/core"
+)
+
+const (
+	gitTimeout         = 30 * time.Second
+	gitMaxPatchBytes   = 50 * 1024
+	gitDefaultLogLimit = 20
+	gitMaxLogLimit     = 200
+	gitMaxBlameLines   = 2000
+)
+
+// gitReadOnlyActions run without tool approval; everything else changes the
+// repository or working tree.
+var gitReadOnlyActions = map[string]bool{
+	"status": true, "diff": true, "log": true, "show": true, "blame": true, "branch": true,
+}
+
+type gitTool struct {
+	core.ToolFunc
+}
+
+// NeedsApproval limits tool approval to commit, checkout and stash calls
+// other than "stash list".
+func (gitTool) NeedsApproval(args map[string]any) bool {
+	action, _ := args["action"].(string)
+	action = strings.TrimSpace(action)
+	if action == "stash" {
+		op, _ := args["stash"].(string)
+		return strings.TrimSpace(op) != "list"
+	}
+	return !gitReadOnlyActions[action]
+}
+
+type gitFileChange struct {
+	Path      string `json:"path"`
+	OldPath   string `json:"old_path,omitempty"`
+	Status    string `json:"status"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Binary    bool   `json:"binary,omitempty"`
+}
+
+type gitCommit struct {
+	Hash    string   `json:"hash"`
+	Short   string   `json:"short"`
+	Author  string   `json:"author"`
+	Email   string   `json:"email"`
+	Date    string   `json:"date"`
+	Subject string   `json:"subject"`
+	Parents []string `json:"parents,omitempty"`
+	Message string   `json:"message,omitempty"`
+}
+
+type gitRunner struct {
+	dir     string
+	profile ExecProfile
+}
+
+func NewGitTool(cwd string) core.Tool {
+	return newGitTool(newPathResolver(Config{Workdir: cwd}), DefaultExecProfile())
+}
+
+func newGitTool(paths *pathResolver, profile ExecProfile) core.Tool {
+	g := gitRunner{dir: paths.base, profile: profile}
+	fn := core.ToolFunc{
+		ToolName:        "git",
+		ToolDescription: "Run git in the working directory and return structured JSON: status, diff, log, show, blame and branch are read-only; commit, checkout and stash change the repository and may need approval.",
+		ToolSchema: objectSchema(map[string]any{
+			"action":     withEnum(requiredStringProperty("Operation to perform."), "status", "diff", "log", "show", "blame", "branch", "commit", "checkout", "stash"),
+			"path":       withAliases(stringProperty("Limit to this file or directory (diff, log, commit); the file to annotate (blame)."), pathAliases...),
+			"ref":        withAliases(stringProperty("Commit, branch or tag: diff against it (diff), start from it (log), the commit to show (show, default HEAD), the branch to switch to (checkout)."), "revision", "branch"),
+			"staged":     withAliases(booleanProperty("Diff the index against HEAD instead of the working tree against the index (diff)."), "cached"),
+			"limit":      withAliases(withMinimum(integerProperty("Maximum commits to return (log). Defaults to 20, at most 200."), 1), "max_count", "maxCount"),
+			"start_line": withAliases(withMinimum(integerProperty("First line to annotate, 1-based (blame)."), 1), "startLine"),
+			"end_line":   withAliases(withMinimum(integerProperty("Last line to annotate (blame)."), 1), "endLine"),
+			"all":        booleanProperty("Include remote branches (branch); stage all tracked changes first (commit)."),
+			"message":    withAliases(stringProperty("Commit message (commit) or stash message (stash push)."), "msg"),
+			"create":     booleanProperty("Create the branch before switching (checkout)."),
+			"stash":      withEnum(withDefault(stringProperty("Stash operation (stash)."), "push"), "push", "pop", "apply", "drop", "list"),
+		}, "action"),
+		Run: func(ctx context.Context, args map[string]any) (string, error) {
+			ctx, cancel := context.WithTimeout(ctx, gitTimeout)
+			defer cancel()
+
+			action, _ := args["action"].(string)
+			var out any
+			var err error
+			switch strings.TrimSpace(action) {
+			case "status":
+				out, err = g.status(ctx)
+			case "diff":
+				out, err = g.diff(ctx, paths, args)
+			case "log":
+				out, err = g.log(ctx, paths, args)
+			case "show":
+				out, err = g.show(ctx, args)
+			case "blame":
+				out, err = g.blame(ctx, paths, args)
+			case "branch":
+				out, err = g.branches(ctx, boolArgLocal(args, "all"))
+			case "commit":
+				out, err = g.commit(ctx, paths, args)
+			case "checkout":
+				out, err = g.checkout(ctx, args)
+			case "stash":
+				out, err = g.stash(ctx, args)
+			default:
+				return "", fmt.Errorf("git_invalid_action: %s", action)
+			}
+			if err != nil {
+				return "", err
+			}
+			b, err := json.Marshal(out)
+			if err != nil {
+				return "", fmt.Errorf("git_failed: %w", err)
+			}
+			return string(b), nil
+		},
+	}
+	return gitTool{ToolFunc: fn}
+}
+
+func (g gitRunner) run(ctx context.Context, args ...string) (string, error) {
+	full := append([]string{"--no-pager", "-c", "color.ui=false", "-c", "core.quotepath=false"}, args...)
+	cmd := exec.CommandContext(ctx, "git", full...)
+	cmd.Dir = g.dir
+	// git and the hooks it runs get the exec profile's environment.
+	// GIT_OPTIONAL_LOCKS=0 stops status from refreshing the index (and
+	// taking its lock) as a side effect; commands that write still lock it.
+	cmd.Env = append(g.profile.environ(os.Environ()), "GIT_OPTIONAL_LOCKS=0", "GIT_TERMINAL_PROMPT=0", "LC_ALL=C")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", fmt.Errorf("git_not_found: %w", err)
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("git_timeout: %s after %s", args[0], gitTimeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "not a git repository") {
+			return "", fmt.Errorf("git_not_repository: %s", g.dir)
+		}
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git_failed: %s", msg)
+	}
+	return stdout.String(), nil
+}
+
+func (g gitRunner) status(ctx context.Context) (map[string]any, error) {
+	out, err := g.run(ctx, "status", "--porcelain=v1", "--branch", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]any{"branch": "", "ahead": 0, "behind": 0, "clean": true}
+	staged, unstaged := []gitFileChange{}, []gitFileChange{}
+	untracked, conflicted := []string{}, []string{}
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if strings.HasPrefix(entry, "## ") {
+			parseGitBranchHeader(entry[3:], result)
+			continue
+		}
+		if len(entry) < 4 {
+			continue
+		}
+		x, y, path := entry[0], entry[1], entry[3:]
+		oldPath := ""
+		if x == 'R' || x == 'C' {
+			i++
+			if i < len(entries) {
+				oldPath = entries[i]
+			}
+		}
+		switch {
+		case x == '?' && y == '?':
+			untracked = append(untracked, path)
+		case x == 'U' || y == 'U' || (x == 'A' && y == 'A') || (x == 'D' && y == 'D'):
+			conflicted = append(conflicted, path)
+		default:
+			if x != ' ' {
+				staged = append(staged, gitFileChange{Path: path, OldPath: oldPath, Status: gitStatusName(x)})
+			}
+			if y != ' ' {
+				unstaged = append(unstaged, gitFileChange{Path: path, Status: gitStatusName(y)})
+			}
+		}
+	}
+	result["staged"], result["unstaged"] = staged, unstaged
+	result["untracked"], result["conflicted"] = untracked, conflicted
+	result["clean"] = len(staged)+len(unstaged)+len(untracked)+len(conflicted) == 0
+	return result, nil
+}
+
+// parseGitBranchHeader reads "main...origin/main [ahead 1, behind 2]" or
+// "No commits yet on main".
+func parseGitBranchHeader(header string, result map[string]any) {
+	header, counts, _ := strings.Cut(header, " [")
+	if name, ok := strings.CutPrefix(header, "No commits yet on "); ok {
+		header = name
+	}
+	branch, upstream, _ := strings.Cut(header, "...")
+	if strings.HasPrefix(branch, "HEAD (no branch)") {
+		branch, result["detached"] = "", true
+	}
+	result["branch"] = branch
+	if upstream != "" {
+		result["upstream"] = upstream
+	}
+	for _, part := range strings.Split(strings.TrimSuffix(counts, "]"), ", ") {
+		if n, ok := strings.CutPrefix(part, "ahead "); ok {
+			result["ahead"], _ = strconv.Atoi(n)
+		} else if n, ok := strings.CutPrefix(part, "behind "); ok {
+			result["behind"], _ = strconv.Atoi(n)
+		}
+	}
+}
+
+func gitStatusName(code byte) string {
+	switch code {
+	case 'M':
+		return "modified"
+	case 'A':
+		return "added"
+	case 'D':
+		return "deleted"
+	case 'R':
+		return "renamed"
+	case 'C':
+		return "copied"
+	case 'T':
+		return "type_changed"
+	case 'U':
+		return "unmerged"
+	default:
+		return string(code)
+	}
+}
+
+func (g gitRunner) diff(ctx context.Context, paths *pathResolver, args map[string]any) (map[string]any, error) {
+	base := []string{"diff", "-M"}
+	if boolArgLocal(args, "staged", "cached") {
+		base = append(base, "--cached")
+	}
+	ref, err := gitRefArg(args, "")
+	if err != nil {
+		return nil, err
+	}
+	if ref != "" {
+		base = append(base, ref)
+	}
+	pathspec, err := gitPathspec(paths, args)
+	if err != nil {
+		return nil, err
+	}
+	return g.changes(ctx, base, pathspec)
+}
+
+// changes runs a diff-producing command three ways: for per-file status,
+// for line counts and for the (size-limited) patch itself.
+func (g gitRunner) changes(ctx context.Context, base, pathspec []string) (map[string]any, error) {
+	withArgs := func(extra ...string) []string {
+		out := append(append([]string{}, base...), extra...)
+		return append(append(out, "--"), pathspec...)
+	}
+	names, err := g.run(ctx, withArgs("--name-status", "-z")...)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := g.run(ctx, withArgs("--numstat", "-z")...)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := g.run(ctx, withArgs()...)
+	if err != nil {
+		return nil, err
+	}
+	files := parseGitNameStatus(names)
+	applyGitNumstat(files, stats)
+	patch, truncated := truncateGitPatch(patch)
+	return map[string]any{"files": files, "patch": patch, "truncated": truncated}, nil
+}
+
+func parseGitNameStatus(out string) []gitFileChange {
+	files := []gitFileChange{}
+	fields := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		code := fields[i]
+		change := gitFileChange{Path: fields[i+1], Status: gitStatusName(code[0])}
+		if (code[0] == 'R' || code[0] == 'C') && i+2 < len(fields) {
+			change.OldPath, change.Path = fields[i+1], fields[i+2]
+			i++
+		}
+		files = append(files, change)
+	}
+	return files
+}
+
+// applyGitNumstat fills in line counts from "add\tdel\tpath\0" records;
+// renames are "add\tdel\t\0old\0new\0" and binary files count as "-".
+func applyGitNumstat(files []gitFileChange, out string) {
+	byPath := make(map[string]*gitFileChange, len(files))
+	for i := range files {
+		byPath[files[i].Path] = &files[i]
+	}
+	fields := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	for i := 0; i < len(fields); i++ {
+		parts := strings.SplitN(fields[i], "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		path := parts[2]
+		if path == "" && i+2 < len(fields) {
+			path = fields[i+2]
+			i += 2
+		}
+		f, ok := byPath[path]
+		if !ok {
+			continue
+		}
+		if parts[0] == "-" {
+			f.Binary = true
+			continue
+		}
+		f.Additions, _ = strconv.Atoi(parts[0])
+		f.Deletions, _ = strconv.Atoi(parts[1])
+	}
+}
+
+func truncateGitPatch(patch string) (string, bool) {
+	if len(patch) <= gitMaxPatchBytes {
+		return patch, false
+	}
+	cut := patch[:gitMaxPatchBytes]
+	if i := strings.LastIndexByte(cut, '\n'); i > 0 {
+		cut = cut[:i+1]
+	}
+	return cut + fmt.Sprintf("[patch cut at %d of %d bytes; narrow it with path]\n", len(cut), len(patch)), true
+}
+
+const gitLogFormat = "%H%x1f%h%x1f%an%x1f%ae%x1f%aI%x1f%s%x1f%P%x1e"
+
+func (g gitRunner) log(ctx context.Context, paths *pathResolver, args map[string]any) (map[string]any, error) {
+	limit, err := intArg(args, "limit", gitDefaultLogLimit)
+	if err != nil || limit <= 0 {
+		return nil, fmt.Errorf("git_invalid_limit")
+	}
+	limit = min(limit, gitMaxLogLimit)
+	ref, err := gitRefArg(args, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	pathspec, err := gitPathspec(paths, args)
+	if err != nil {
+		return nil, err
+	}
+	cmd := append([]string{"log", "-n", strconv.Itoa(limit), "--format=" + gitLogFormat, ref, "--"}, pathspec...)
+	out, err := g.run(ctx, cmd...)
+	if err != nil {
+		return nil, err
+	}
+	commits := []gitCommit{}
+	for _, record := range strings.Split(out, "\x1e") {
+		if c, ok := parseGitCommit(strings.TrimLeft(record, "\n")); ok {
+			commits = append(commits, c)
+		}
+	}
+	return map[string]any{"ref": ref, "commits": commits}, nil
+}
+
+func parseGitCommit(record string) (gitCommit, bool) {
+	f := strings.Split(record, "\x1f")
+	if len(f) < 7 {
+		return gitCommit{}, false
+	}
+	c := gitCommit{Hash: f[0], Short: f[1], Author: f[2], Email: f[3], Date: f[4], Subject: f[5], Parents: strings.Fields(f[6])}
+	if len(f) > 7 {
+		c.Message = strings.TrimRight(f[7], "\n")
+	}
+	return c, true
+}
+
+func (g gitRunner) show(ctx context.Context, args map[string]any) (map[string]any, error) {
+	ref, err := gitRefArg(args, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	out, err := g.run(ctx, "show", "--no-patch", "--format="+strings.TrimSuffix(gitLogFormat, "%x1e")+"%x1f%B", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	commit, ok := parseGitCommit(out)
+	if !ok {
+		return nil, fmt.Errorf("git_failed: unexpected show output for %s", ref)
+	}
+	changes, err := g.changes(ctx, []string{"show", "-M", "--format=", ref}, nil)
+	if err != nil {
+		return nil, err
+	}
+	changes["commit"] = commit
+	return changes, nil
+}
+
+type gitBlameLine struct {
+	Line    int    `json:"line"`
+	Commit  string `json:"commit"`
+	Author  string `json:"author"`
+	Date    string `json:"date"`
+	Summary string `json:"summary"`
+	Text    string `json:"text"`
+}
+
+func (g gitRunner) blame(ctx context.Context, paths *pathResolver, args map[string]any) (map[string]any, error) {
+	pathspec, err := gitPathspec(paths, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(pathspec) == 0 {
+		return nil, fmt.Errorf("git_invalid_path: blame needs a file path")
+	}
+	start, err := intArg(args, "start_line", 1)
+	if err != nil || start < 1 {
+		return nil, fmt.Errorf("git_invalid_line_range")
+	}
+	end, err := intArg(args, "end_line", start+gitMaxBlameLines-1)
+	if err != nil || end < start {
+		return nil, fmt.Errorf("git_invalid_line_range")
+	}
+	end = min(end, start+gitMaxBlameLines-1)
+	rangeArg := fmt.Sprintf("%d,%d", start, end)
+	if _, explicit := args["end_line"]; !explicit {
+		// Without an end line blame fails on short files, so cap the
+		// range at the file's length.
+		rangeArg = fmt.Sprintf("%d,+%d", start, gitMaxBlameLines)
+	}
+	cmd := []string{"blame", "--porcelain", "-L", rangeArg}
+	if ref, err := gitRefArg(args, ""); err != nil {
+		return nil, err
+	} else if ref != "" {
+		cmd = append(cmd, ref)
+	}
+	out, err := g.run(ctx, append(append(cmd, "--"), pathspec...)...)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"path": pathspec[0], "lines": parseGitBlame(out)}, nil
+}
+
+// parseGitBlame reads --porcelain output: a "<hash> <orig> <final> [n]"
+// header per line, commit headers the first time a commit appears, and the
+// line text prefixed by a tab.
+func parseGitBlame(out string) []gitBlameLine {
+	type commitInfo struct{ author, date, summary string }
+	commits := map[string]*commitInfo{}
+	lines := []gitBlameLine{}
+	var cur *gitBlameLine
+	for _, raw := range strings.Split(out, "\n") {
+		if cur == nil {
+			f := strings.Fields(raw)
+			if len(f) < 3 || len(f[0]) < 40 {
+				continue
+			}
+			n, _ := strconv.Atoi(f[2])
+			cur = &gitBlameLine{Line: n, Commit: f[0]}
+			if commits[f[0]] == nil {
+				commits[f[0]] = &commitInfo{}
+			}
+			continue
+		}
+		info := commits[cur.Commit]
+		switch {
+		case strings.HasPrefix(raw, "\t"):
+			cur.Text = raw[1:]
+			cur.Author, cur.Date, cur.Summary = info.author, info.date, info.summary
+			cur.Commit = cur.Commit[:12]
+			lines = append(lines, *cur)
+			cur = nil
+		case strings.HasPrefix(raw, "author "):
+			info.author = strings.TrimPrefix(raw, "author ")
+		case strings.HasPrefix(raw, "author-time "):
+			if sec, err := strconv.ParseInt(strings.TrimPrefix(raw, "author-time "), 10, 64); err == nil {
+				info.date = time.Unix(sec, 0).UTC().Format(time.RFC3339)
+			}
+		case strings.HasPrefix(raw, "summary "):
+			info.summary = strings.TrimPrefix(raw, "summary ")
+		}
+	}
+	return lines
+}
+
+type gitBranch struct {
+	Name     string `json:"name"`
+	Commit   string `json:"commit"`
+	Upstream string `json:"upstream,omitempty"`
+	Current  bool   `json:"current,omitempty"`
+	Remote   bool   `json:"remote,omitempty"`
+	Subject  string `json:"subject"`
+}
+
+func (g gitRunner) branches(ctx context.Context, all bool) (map[string]any, error) {
+	cmd := []string{"for-each-ref", "--format=%(refname)%1f%(refname:short)%1f%(objectname:short)%1f%(upstream:short)%1f%(HEAD)%1f%(subject)", "refs/heads"}
+	if all {
+		cmd = append(cmd, "refs/remotes")
+	}
+	out, err := g.run(ctx, cmd...)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]any{"current": ""}
+	branches := []gitBranch{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		f := strings.Split(line, "\x1f")
+		if len(f) != 6 || strings.HasSuffix(f[0], "/HEAD") {
+			continue
+		}
+		b := gitBranch{Name: f[1], Commit: f[2], Upstream: f[3], Current: f[4] == "*", Remote: strings.HasPrefix(f[0], "refs/remotes/"), Subject: f[5]}
+		if b.Current {
+			result["current"] = b.Name
+		}
+		branches = append(branches, b)
+	}
+	result["branches"] = branches
+	return result, nil
+}
+
+func (g gitRunner) commit(ctx context.Context, paths *pathResolver, args map[string]any) (map[string]any, error) {
+	message, _ := args["message"].(string)
+	if strings.TrimSpace(message) == "" {
+		return nil, fmt.Errorf("git_invalid_message")
+	}
+	pathspec, err := gitPathspec(paths, args)
+	if err != nil {
+		return nil, err
+	}
+	cmd := []string{"commit", "-m", message}
+	if boolArgLocal(args, "all") {
+		cmd = append(cmd, "-a")
+	}
+	out, err := g.run(ctx, append(append(cmd, "--"), pathspec...)...)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := g.run(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"commit": strings.TrimSpace(hash), "output": strings.TrimSpace(out)}, nil
+}
+
+func (g gitRunner) checkout(ctx context.Context, args map[string]any) (map[string]any, error) {
+	ref, err := gitRefArg(args, "")
+	if err != nil {
+		return nil, err
+	}
+	if ref == "" {
+		return nil, fmt.Errorf("git_invalid_ref: checkout needs a ref")
+	}
+	cmd := []string{"checkout", ref, "--"}
+	if boolArgLocal(args, "create") {
+		cmd = []string{"checkout", "-b", ref}
+	}
+	if _, err := g.run(ctx, cmd...); err != nil {
+		return nil, err
+	}
+	head, err := g.run(ctx, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"branch": strings.TrimSpace(head)}, nil
+}
+
+func (g gitRunner) stash(ctx context.Context, args map[string]any) (any, error) {
+	op, _ := args["stash"].(string)
+	switch op = strings.TrimSpace(op); op {
+	case "list":
+		out, err := g.run(ctx, "stash", "list", "--format=%gd%x1f%s")
+		if err != nil {
+			return nil, err
+		}
+		entries := []map[string]string{}
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			if ref, msg, ok := strings.Cut(line, "\x1f"); ok {
+				entries = append(entries, map[string]string{"ref": ref, "message": msg})
+			}
+		}
+		return map[string]any{"stashes": entries}, nil
+	case "", "push":
+		cmd := []string{"stash", "push", "--include-untracked"}
+		if message, _ := args["message"].(string); strings.TrimSpace(message) != "" {
+			cmd = append(cmd, "-m", message)
+		}
+		out, err := g.run(ctx, cmd...)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"output": strings.TrimSpace(out)}, nil
+	case "pop", "apply", "drop":
+		cmd := []string{"stash", op}
+		ref, err := gitRefArg(args, "")
+		if err != nil {
+			return nil, err
+		}
+		if ref != "" {
+			cmd = append(cmd, ref)
+		}
+		out, err := g.run(ctx, cmd...)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"output": strings.TrimSpace(out)}, nil
+	default:
+		return nil, fmt.Errorf("git_invalid_stash: %s", op)
+	}
+}
+
+// gitRefArg returns the ref argument, rejecting values git would read as an
+// option.
+func gitRefArg(args map[string]any, def string) (string, error) {
+	ref, _ := args["ref"].(string)
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return def, nil
+	}
+	if strings.HasPrefix(ref, "-") || strings.ContainsAny(ref, " \t\n") {
+		return "", fmt.Errorf("git_invalid_ref: %s", ref)
+	}
+	return ref, nil
+}
+
+// gitPathspec resolves the path argument like the file tools do, so
+// --confine-workdir applies; git accepts absolute paths inside the work tree.
+func gitPathspec(paths *pathResolver, args map[string]any) ([]string, error) {
+	raw, _ := args["path"].(string)
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	abs, err := paths.resolve(raw)
+	if err != nil {
+		return nil, err
+	}
+	return []string{abs}, nil
+}
@@ -0,0 +1,273 @@
+package builtins
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.name", "Test User"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "commit.gpgsign", "false"},
+	} {
+		gitCmd(t, dir, args...)
+	}
+	return dir
+}
+
+func gitCmd(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+func runGitTool(t *testing.T, dir string, args map[string]any, out any) {
+	t.Helper()
+	raw, err := NewGitTool(dir).Execute(context.Background(), args)
+	if err != nil {
+		t.Fatalf("git %v failed: %v", args["action"], err)
+	}
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		t.Fatalf("invalid git output %q: %v", raw, err)
+	}
+}
+
+func TestGitToolStatusAndDiff(t *testing.T) {
+	dir := newTestGitRepo(t)
+	mustWrite(t, filepath.Join(dir, "a.txt"), "one\ntwo\n")
+	mustWrite(t, filepath.Join(dir, "b.txt"), "b\n")
+	gitCmd(t, dir, "add", ".")
+	gitCmd(t, dir, "commit", "-q", "-m", "initial")
+
+	mustWrite(t, filepath.Join(dir, "a.txt"), "one\n2\nthree\n")
+	mustWrite(t, filepath.Join(dir, "new dir", "c.txt"), "c\n")
+	gitCmd(t, dir, "mv", "b.txt", "renamed.txt")
+
+	var status struct {
+		Branch    string          `json:"branch"`
+		Clean     bool            `json:"clean"`
+		Staged    []gitFileChange `json:"staged"`
+		Unstaged  []gitFileChange `json:"unstaged"`
+		Untracked []string        `json:"untracked"`
+	}
+	runGitTool(t, dir, map[string]any{"action": "status"}, &status)
+	if status.Branch != "main" || status.Clean {
+		t.Fatalf("unexpected status header: %+v", status)
+	}
+	if len(status.Staged) != 1 || status.Staged[0] != (gitFileChange{Path: "renamed.txt", OldPath: "b.txt", Status: "renamed"}) {
+		t.Fatalf("unexpected staged changes: %+v", status.Staged)
+	}
+	if len(status.Unstaged) != 1 || status.Unstaged[0].Path != "a.txt" || status.Unstaged[0].Status != "modified" {
+		t.Fatalf("unexpected unstaged changes: %+v", status.Unstaged)
+	}
+	if len(status.Untracked) != 1 || status.Untracked[0] != "new dir/c.txt" {
+		t.Fatalf("unexpected untracked files: %+v", status.Untracked)
+	}
+
+	var diff struct {
+		Files     []gitFileChange `json:"files"`
+		Patch     string          `json:"patch"`
+		Truncated bool            `json:"truncated"`
+	}
+	runGitTool(t, dir, map[string]any{"action": "diff"}, &diff)
+	if len(diff.Files) != 1 || diff.Files[0] != (gitFileChange{Path: "a.txt", Status: "modified", Additions: 2, Deletions: 1}) {
+		t.Fatalf("unexpected diff files: %+v", diff.Files)
+	}
+	if !strings.Contains(diff.Patch, "-two\n+2\n+three\n") || diff.Truncated {
+		t.Fatalf("unexpected patch:\n%s", diff.Patch)
+	}
+
+	runGitTool(t, dir, map[string]any{"action": "diff", "staged": true}, &diff)
+	if len(diff.Files) != 1 || diff.Files[0].Status != "renamed" || diff.Files[0].OldPath != "b.txt" {
+		t.Fatalf("unexpected staged diff files: %+v", diff.Files)
+	}
+
+	runGitTool(t, dir, map[string]any{"action": "diff", "ref": "HEAD", "path": "renamed.txt"}, &diff)
+	if len(diff.Files) != 1 || diff.Files[0].Path != "renamed.txt" {
+		t.Fatalf("path must limit the diff: %+v", diff.Files)
+	}
+}
+
+func TestGitToolLogShowBlameAndBranch(t *testing.T) {
+	dir := newTestGitRepo(t)
+	mustWrite(t, filepath.Join(dir, "a.txt"), "one\n")
+	gitCmd(t, dir, "add", ".")
+	gitCmd(t, dir, "commit", "-q", "-m", "first")
+	mustWrite(t, filepath.Join(dir, "a.txt"), "one\ntwo\n")
+	gitCmd(t, dir, "commit", "-q", "-am", "second\n\nbody text")
+	gitCmd(t, dir, "branch", "feature")
+
+	var log struct {
+		Commits []gitCommit `json:"commits"`
+	}
+	runGitTool(t, dir, map[string]any{"action": "log"}, &log)
+	if len(log.Commits) != 2 || log.Commits[0].Subject != "second" || log.Commits[1].Subject != "first" {
+		t.Fatalf("unexpected log: %+v", log.Commits)
+	}
+	if c := log.Commits[0]; c.Author != "Test User" || len(c.Parents) != 1 || c.Parents[0] != log.Commits[1].Hash {
+		t.Fatalf("unexpected commit metadata: %+v", c)
+	}
+	runGitTool(t, dir, map[string]any{"action": "log", "limit": 1}, &log)
+	if len(log.Commits) != 1 {
+		t.Fatalf("limit not applied: %+v", log.Commits)
+	}
+
+	var show struct {
+		Commit gitCommit       `json:"commit"`
+		Files  []gitFileChange `json:"files"`
+		Patch  string          `json:"patch"`
+	}
+	runGitTool(t, dir, map[string]any{"action": "show", "ref": "HEAD~1"}, &show)
+	if show.Commit.Subject != "first" || len(show.Files) != 1 || show.Files[0].Status != "added" || !strings.Contains(show.Patch, "+one") {
+		t.Fatalf("unexpected show: %+v", show)
+	}
+	runGitTool(t, dir, map[string]any{"action": "show"}, &show)
+	if show.Commit.Message != "second\n\nbody text" {
+		t.Fatalf("unexpected message: %q", show.Commit.Message)
+	}
+
+	var blame struct {
+		Lines []gitBlameLine `json:"lines"`
+	}
+	runGitTool(t, dir, map[string]any{"action": "blame", "path": "a.txt"}, &blame)
+	if len(blame.Lines) != 2 || blame.Lines[0].Summary != "first" || blame.Lines[1].Summary != "second" || blame.Lines[1].Text != "two" || blame.Lines[1].Line != 2 {
+		t.Fatalf("unexpected blame: %+v", blame.Lines)
+	}
+	runGitTool(t, dir, map[string]any{"action": "blame", "path": "a.txt", "start_line": 2, "end_line": 2}, &blame)
+	if len(blame.Lines) != 1 || blame.Lines[0].Text != "two" {
+		t.Fatalf("unexpected blame range: %+v", blame.Lines)
+	}
+
+	var branches struct {
+		Current  string      `json:"current"`
+		Branches []gitBranch `json:"branches"`
+	}
+	runGitTool(t, dir, map[string]any{"action": "branch"}, &branches)
+	if branches.Current != "main" || len(branches.Branches) != 2 || branches.Branches[0].Name != "feature" || !branches.Branches[1].Current {
+		t.Fatalf("unexpected branches: %+v", branches)
+	}
+}
+
+func TestGitToolCommitCheckoutAndStash(t *testing.T) {
+	dir := newTestGitRepo(t)
+	mustWrite(t, filepath.Join(dir, "a.txt"), "one\n")
+	gitCmd(t, dir, "add", ".")
+
+	var committed struct {
+		Commit string `json:"commit"`
+	}
+	runGitTool(t, dir, map[string]any{"action": "commit", "message": "add a"}, &committed)
+	if head := strings.TrimSpace(gitCmd(t, dir, "rev-parse", "HEAD")); committed.Commit != head {
+		t.Fatalf("commit returned %q, HEAD is %q", committed.Commit, head)
+	}
+
+	var checkout struct {
+		Branch string `json:"branch"`
+	}
+	runGitTool(t, dir, map[string]any{"action": "checkout", "ref": "topic", "create": true}, &checkout)
+	if checkout.Branch != "topic" {
+		t.Fatalf("unexpected branch after checkout: %+v", checkout)
+	}
+
+	mustWrite(t, filepath.Join(dir, "a.txt"), "changed\n")
+	var out map[string]any
+	runGitTool(t, dir, map[string]any{"action": "stash", "message": "wip"}, &out)
+	var stashes struct {
+		Stashes []map[string]string `json:"stashes"`
+	}
+	runGitTool(t, dir, map[string]any{"action": "stash", "stash": "list"}, &stashes)
+	if len(stashes.Stashes) != 1 || stashes.Stashes[0]["ref"] != "stash@{0}" || !strings.Contains(stashes.Stashes[0]["message"], "wip") {
+		t.Fatalf("unexpected stash list: %+v", stashes)
+	}
+	runGitTool(t, dir, map[string]any{"action": "stash", "stash": "pop"}, &out)
+	if got := gitCmd(t, dir, "status", "--porcelain"); got != " M a.txt\n" {
+		t.Fatalf("stash pop must restore the change, status %q", got)
+	}
+}
+
+func TestGitToolScrubsEnvironmentForHooks(t *testing.T) {
+	dir := newTestGitRepo(t)
+	t.Setenv("NOUS_GIT_TEST_API_KEY", "secret")
+	t.Setenv("NOUS_GIT_TEST_VISIBLE", "shown")
+	hook := filepath.Join(dir, ".git", "hooks", "pre-commit")
+	mustWrite(t, hook, "#!/bin/sh\nenv > hook-env.txt\n")
+	if err := os.Chmod(hook, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, filepath.Join(dir, "a.txt"), "one\n")
+	gitCmd(t, dir, "add", "a.txt")
+
+	var out map[string]any
+	runGitTool(t, dir, map[string]any{"action": "commit", "message": "add a"}, &out)
+	env, err := os.ReadFile(filepath.Join(dir, "hook-env.txt"))
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	if strings.Contains(string(env), "NOUS_GIT_TEST_API_KEY") || !strings.Contains(string(env), "NOUS_GIT_TEST_VISIBLE=shown") {
+		t.Fatalf("hooks must get the exec profile environment:\n%s", env)
+	}
+}
+
+func TestGitToolRejectsBadInput(t *testing.T) {
+	dir := newTestGitRepo(t)
+	tool := NewGitTool(dir)
+	cases := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"action": "push"}, "git_invalid_action: push"},
+		{map[string]any{"action": "log", "ref": "--output=/tmp/x"}, "git_invalid_ref: --output=/tmp/x"},
+		{map[string]any{"action": "commit", "message": " "}, "git_invalid_message"},
+		{map[string]any{"action": "checkout"}, "git_invalid_ref"},
+		{map[string]any{"action": "blame"}, "git_invalid_path"},
+		{map[string]any{"action": "show", "ref": "nope"}, "git_failed"},
+	}
+	for _, tc := range cases {
+		if _, err := tool.Execute(context.Background(), tc.args); err == nil || !strings.HasPrefix(err.Error(), tc.want) {
+			t.Fatalf("%v: expected %s, got %v", tc.args, tc.want, err)
+		}
+	}
+
+	if _, err := NewGitTool(t.TempDir()).Execute(context.Background(), map[string]any{"action": "status"}); err == nil || !strings.HasPrefix(err.Error(), "git_not_repository") {
+		t.Fatalf("expected git_not_repository, got %v", err)
+	}
+}
+
+func TestGitToolNeedsApproval(t *testing.T) {
+	tool := NewGitTool(t.TempDir()).(gitTool)
+	for _, tc := range []struct {
+		args map[string]any
+		want bool
+	}{
+		{map[string]any{"action": "status"}, false},
+		{map[string]any{"action": "diff", "staged": true}, false},
+		{map[string]any{"action": "blame", "path": "a"}, false},
+		{map[string]any{"action": "commit", "message": "x"}, true},
+		{map[string]any{"action": "checkout", "ref": "main"}, true},
+		{map[string]any{"action": "stash"}, true},
+		{map[string]any{"action": "stash", "stash": "pop"}, true},
+		{map[string]any{"action": "stash", "stash": "list"}, false},
+		{map[string]any{"action": "unknown"}, true},
+	} {
+		if got := tool.NeedsApproval(tc.args); got != tc.want {
+			t.Fatalf("%v: NeedsApproval=%v, want %v", tc.args, got, tc.want)
+		}
+	}
+}
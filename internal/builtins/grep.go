@@ -4,22 +4,86 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"strconv"
 	"strings"
-	"unicode/utf8"
+	"sync"
 
 	"nous/internal/core"
+	"nous/internal/glob"
 )
 
+const (
+	grepMaxLineBytes = 500
+	grepSniffBytes   = 8000
+	// grepBatchSize files are searched concurrently before their results are
+	// merged in walk order, so output is stable and a reached limit stops the
+	// walk early.
+	grepBatchSize  = 64
+	grepMaxWorkers = 8
+)
+
+const (
+	grepModeContent = "content"
+	grepModeFiles   = "files_with_matches"
+	grepModeCount   = "count"
+)
+
+type grepOptions struct {
+	re     *regexp.Regexp
+	mode   string
+	before int
+	after  int
+	limit  int
+}
+
+func (o grepOptions) hasContext() bool {
+	return o.before > 0 || o.after > 0
+}
+
+// grepLine is one output line of a file's search. kind is 'm' for a match,
+// 'b' or 'a' for context before or after a match, and 's' for a separator.
+type grepLine struct {
+	kind byte
+	text string
+}
+
+type grepFileResult struct {
+	lines   []grepLine
+	matches int
+	err     error
+}
+
 func NewGrepTool(cwd string) core.Tool {
-	base := resolveBaseDir(cwd)
+	return newGrepTool(newPathResolver(Config{Workdir: cwd}))
+}
+
+func newGrepTool(paths *pathResolver) core.Tool {
 
 	return core.ToolFunc{
-		ToolName: "grep",
-		Run: func(_ context.Context, args map[string]any) (string, error) {
+		ToolName:        "grep",
+		ToolDescription: "Search file contents with a pattern and return matching lines. Respects .gitignore and .ignore files.",
+		ToolSchema: objectSchema(map[string]any{
+			"pattern":     withAliases(requiredStringProperty("Pattern to search (regex)."), "query"),
+			"path":        withAliases(withDefault(stringProperty("File or directory path to search. Defaults to current directory."), "."), dirAliases...),
+			"ignore_case": withAliases(booleanProperty("Case-insensitive search."), "ignoreCase"),
+			"limit":       withAliases(withMinimum(integerProperty("Maximum number of matching lines to return (files in files_with_matches and count modes)."), 1), "max_results", "maxResults"),
+			"include":     withAliases(arrayProperty("Only search files matching these globs, e.g. \"*.go\" or \"internal/**/*.ts\".", stringProperty("Glob.")), "glob", "includes"),
+			"exclude":     withAliases(arrayProperty("Skip files and directories matching these globs.", stringProperty("Glob.")), "excludes"),
+			"context":     withMinimum(integerProperty("Lines of context to show before and after each match."), 0),
+			"before":      withAliases(withMinimum(integerProperty("Lines of context to show before each match."), 0), "before_context", "beforeContext"),
+			"after":       withAliases(withMinimum(integerProperty("Lines of context to show after each match."), 0), "after_context", "afterContext"),
+			"output_mode": withAliases(withEnum(withDefault(stringProperty("content returns matching lines, files_with_matches returns matching file paths, count returns per-file match counts."), grepModeContent), grepModeContent, grepModeFiles, grepModeCount), "outputMode"),
+			"no_ignore":   withAliases(booleanProperty("Also search files excluded by .gitignore and .ignore."), "noIgnore"),
+		}, "pattern"),
+		Run: func(ctx context.Context, args map[string]any) (string, error) {
 			pattern, _ := args["pattern"].(string)
 			pattern = strings.TrimSpace(pattern)
 			if pattern == "" {
@@ -31,7 +95,10 @@ func NewGrepTool(cwd string) core.Tool {
 			if root == "" {
 				root = "."
 			}
-			absRoot := resolveToolPath(base, root)
+			absRoot, err := paths.resolve(root)
+			if err != nil {
+				return "", err
+			}
 
 			info, err := os.Stat(absRoot)
 			if err != nil {
@@ -49,71 +116,286 @@ func NewGrepTool(cwd string) core.Tool {
 				return "", fmt.Errorf("grep_invalid_pattern")
 			}
 
-			matches := make([]string, 0, 32)
-			appendFromFile := func(path, rel string) error {
-				b, err := os.ReadFile(path)
-				if err != nil {
-					return err
-				}
-				if !utf8.Valid(b) {
-					return nil
-				}
-				sc := bufio.NewScanner(bytes.NewReader(b))
-				sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
-				lineNo := 0
-				for sc.Scan() {
-					lineNo++
-					line := sc.Text()
-					if re.MatchString(line) {
-						matches = append(matches, fmt.Sprintf("%s:%d: %s", rel, lineNo, line))
-						if len(matches) >= limit {
-							return errLimitReached
-						}
-					}
-				}
-				return sc.Err()
+			opts := grepOptions{re: re, limit: limit}
+			opts.mode, _ = args["output_mode"].(string)
+			switch opts.mode {
+			case "":
+				opts.mode = grepModeContent
+			case grepModeContent, grepModeFiles, grepModeCount:
+			default:
+				return "", fmt.Errorf("grep_invalid_output_mode: %s", opts.mode)
+			}
+			contextLines, err := intArg(args, "context", 0)
+			if err != nil || contextLines < 0 {
+				return "", fmt.Errorf("grep_invalid_context")
+			}
+			if opts.before, err = intArg(args, "before", contextLines); err != nil || opts.before < 0 {
+				return "", fmt.Errorf("grep_invalid_context")
+			}
+			if opts.after, err = intArg(args, "after", contextLines); err != nil || opts.after < 0 {
+				return "", fmt.Errorf("grep_invalid_context")
+			}
+
+			include, err := globListArg(args, "include")
+			if err != nil {
+				return "", err
+			}
+			exclude, err := globListArg(args, "exclude")
+			if err != nil {
+				return "", err
 			}
 
 			if !info.IsDir() {
-				rel := filepath.Base(absRoot)
-				if err := appendFromFile(absRoot, rel); err != nil && err != errLimitReached {
-					return "", fmt.Errorf("grep_failed: %w", err)
+				res := grepFile(absRoot, filepath.Base(absRoot), opts)
+				if res.err != nil {
+					return "", fmt.Errorf("grep_failed: %w", res.err)
 				}
-				return strings.Join(matches, "\n"), nil
+				lines, _ := mergeGrepResults([]grepFileResult{res}, []string{filepath.Base(absRoot)}, opts)
+				return strings.Join(lines, "\n"), nil
 			}
 
-			walkErr := filepath.WalkDir(absRoot, func(path string, d os.DirEntry, inErr error) error {
-				if inErr != nil {
-					return inErr
+			var (
+				out     []string
+				batch   []string
+				rels    []string
+				matched int
+			)
+			flush := func() error {
+				if err := ctx.Err(); err != nil {
+					return err
 				}
-				if d.IsDir() {
-					if d.Name() == ".git" {
-						return filepath.SkipDir
+				results := searchGrepBatch(batch, rels, opts)
+				for _, res := range results {
+					if res.err != nil {
+						return res.err
 					}
-					return nil
 				}
-				rel, err := filepath.Rel(absRoot, path)
-				if err != nil {
-					return err
+				batchOpts := opts
+				batchOpts.limit -= matched
+				lines, kept := mergeGrepResults(results, rels, batchOpts)
+				if len(out) > 0 && len(lines) > 0 && opts.hasContext() {
+					out = append(out, "--")
 				}
-				rel = filepath.ToSlash(rel)
-				if err := appendFromFile(path, rel); err != nil {
-					if err == errLimitReached {
-						return filepath.SkipAll
-					}
-					return err
+				out = append(out, lines...)
+				matched += kept
+				batch, rels = batch[:0], rels[:0]
+				if matched >= opts.limit {
+					return filepath.SkipAll
+				}
+				return nil
+			}
+			noIgnore := boolArgLocal(args, "no_ignore", "noIgnore")
+			walkErr := walkTree(paths, absRoot, walkOptions{noIgnore: noIgnore, maxDepth: -1}, func(path, rel string, d fs.DirEntry) error {
+				if matchesAnyGlob(exclude, rel) {
+					return filepath.SkipDir
+				}
+				if d.IsDir() || (len(include) > 0 && !matchesAnyGlob(include, rel)) {
+					return nil
+				}
+				batch = append(batch, path)
+				rels = append(rels, rel)
+				if len(batch) >= grepBatchSize {
+					return flush()
 				}
 				return nil
 			})
-			if walkErr != nil && walkErr != filepath.SkipAll {
+			if walkErr == nil && len(batch) > 0 {
+				walkErr = flush()
+			}
+			if walkErr != nil && !errors.Is(walkErr, filepath.SkipAll) {
 				return "", fmt.Errorf("grep_failed: %w", walkErr)
 			}
-			return strings.Join(matches, "\n"), nil
+			return strings.Join(out, "\n"), nil
 		},
 	}
 }
 
-var errLimitReached = fmt.Errorf("grep_limit_reached")
+// searchGrepBatch searches files with a bounded number of workers and returns
+// the results in input order.
+func searchGrepBatch(files, rels []string, opts grepOptions) []grepFileResult {
+	results := make([]grepFileResult, len(files))
+	workers := min(runtime.NumCPU(), grepMaxWorkers, len(files))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = grepFile(files[i], rels[i], opts)
+			}
+		}()
+	}
+	for i := range files {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
+
+// grepFile streams a file line by line. Binary files are skipped, and the
+// search stops once limit matches and their trailing context are collected.
+func grepFile(path, rel string, opts grepOptions) grepFileResult {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return grepFileResult{}
+		}
+		return grepFileResult{err: err}
+	}
+	defer f.Close()
+
+	br := bufio.NewReaderSize(f, 64*1024)
+	if head, _ := br.Peek(grepSniffBytes); bytes.IndexByte(head, 0) >= 0 {
+		return grepFileResult{}
+	}
+
+	var (
+		res       grepFileResult
+		pending   []grepLine
+		printed   int
+		afterLeft int
+	)
+	format := func(sep string, lineNo int, line string) string {
+		return rel + sep + strconv.Itoa(lineNo) + sep + " " + truncateGrepLine(line)
+	}
+	for lineNo := 1; ; lineNo++ {
+		line, readErr := br.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return grepFileResult{err: readErr}
+		}
+		if line == "" && readErr == io.EOF {
+			break
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		matched := opts.re.MatchString(line)
+		switch {
+		case matched && res.matches >= opts.limit && opts.mode != grepModeCount:
+			// A match past the limit ends the last kept match's trailing
+			// context rather than being shown as context itself.
+			return res
+		case matched:
+			res.matches++
+			if opts.mode != grepModeContent {
+				if opts.mode == grepModeFiles {
+					return res
+				}
+				break
+			}
+			if printed > 0 && lineNo-len(pending) > printed+1 {
+				res.lines = append(res.lines, grepLine{kind: 's', text: "--"})
+			}
+			res.lines = append(res.lines, pending...)
+			pending = pending[:0]
+			res.lines = append(res.lines, grepLine{kind: 'm', text: format(":", lineNo, line)})
+			printed, afterLeft = lineNo, opts.after
+		case afterLeft > 0 && opts.mode == grepModeContent:
+			res.lines = append(res.lines, grepLine{kind: 'a', text: format("-", lineNo, line)})
+			printed = lineNo
+			afterLeft--
+		case opts.before > 0 && opts.mode == grepModeContent:
+			if len(pending) == opts.before {
+				pending = append(pending[:0], pending[1:]...)
+			}
+			pending = append(pending, grepLine{kind: 'b', text: format("-", lineNo, line)})
+		}
+		if res.matches >= opts.limit && afterLeft == 0 && opts.mode != grepModeCount {
+			break
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+	return res
+}
+
+// mergeGrepResults renders file results in order, keeping at most limit
+// matches (or files, outside content mode) and the trailing context of the
+// last kept match. It returns the lines and how many matches or files it kept.
+func mergeGrepResults(results []grepFileResult, rels []string, opts grepOptions) ([]string, int) {
+	var out []string
+	kept := 0
+	for i, res := range results {
+		if kept >= opts.limit {
+			break
+		}
+		if res.matches == 0 {
+			continue
+		}
+		switch opts.mode {
+		case grepModeFiles:
+			out = append(out, rels[i])
+			kept++
+			continue
+		case grepModeCount:
+			out = append(out, rels[i]+":"+strconv.Itoa(res.matches))
+			kept++
+			continue
+		}
+		if len(out) > 0 && opts.hasContext() {
+			out = append(out, "--")
+		}
+		for _, line := range res.lines {
+			if kept >= opts.limit && line.kind != 'a' {
+				break
+			}
+			out = append(out, line.text)
+			if line.kind == 'm' {
+				kept++
+			}
+		}
+	}
+	return out, kept
+}
+
+// truncateGrepLine keeps minified or generated lines from flooding the
+// result.
+func truncateGrepLine(line string) string {
+	if len(line) <= grepMaxLineBytes {
+		return line
+	}
+	cut := truncateUTF8(line, grepMaxLineBytes)
+	return fmt.Sprintf("%s [... %d more bytes]", cut, len(line)-len(cut))
+}
+
+func globListArg(args map[string]any, key string) ([]string, error) {
+	var raw []any
+	switch v := args[key].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		raw = []any{v}
+	case []any:
+		raw = v
+	case []string:
+		for _, s := range v {
+			raw = append(raw, s)
+		}
+	default:
+		return nil, fmt.Errorf("grep_invalid_glob: %s must be an array of strings", key)
+	}
+	globs := make([]string, 0, len(raw))
+	for _, item := range raw {
+		s, ok := item.(string)
+		s = strings.TrimPrefix(strings.TrimSpace(s), "./")
+		if !ok || s == "" || !glob.Valid(s) {
+			return nil, fmt.Errorf("grep_invalid_glob: %v", item)
+		}
+		globs = append(globs, s)
+	}
+	return globs, nil
+}
+
+func matchesAnyGlob(globs []string, rel string) bool {
+	for _, g := range globs {
+		if matchPathGlob(g, rel) {
+			return true
+		}
+	}
+	return false
+}
 
 func compileGrepPattern(pattern string, ignoreCase bool) (*regexp.Regexp, error) {
 	if ignoreCase {
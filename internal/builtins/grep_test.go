@@ -2,8 +2,10 @@ package builtins
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 
@@ -67,6 +69,141 @@ func TestGrepToolErrors(t *testing.T) {
 	}
 }
 
+func TestGrepToolRespectsIgnoreFilesAndGlobs(t *testing.T) {
+	dir := t.TempDir()
+	mustWrite(t, filepath.Join(dir, ".gitignore"), "node_modules/\n*.log\n!keep.log\n")
+	mustWrite(t, filepath.Join(dir, "src", ".ignore"), "gen_*.go\n")
+	mustWrite(t, filepath.Join(dir, ".git", "HEAD"), "needle\n")
+	mustWrite(t, filepath.Join(dir, "node_modules", "dep", "x.js"), "needle\n")
+	mustWrite(t, filepath.Join(dir, "debug.log"), "needle\n")
+	mustWrite(t, filepath.Join(dir, "keep.log"), "needle\n")
+	mustWrite(t, filepath.Join(dir, "src", "main.go"), "needle\n")
+	mustWrite(t, filepath.Join(dir, "src", "gen_api.go"), "needle\n")
+	mustWrite(t, filepath.Join(dir, "src", "main_test.go"), "needle\n")
+	mustWrite(t, filepath.Join(dir, "src", "notes.md"), "needle\n")
+
+	tool := NewGrepTool(dir)
+	run := func(args map[string]any) string {
+		t.Helper()
+		args["pattern"] = "needle"
+		args["output_mode"] = "files_with_matches"
+		out, err := tool.Execute(context.Background(), args)
+		if err != nil {
+			t.Fatalf("grep failed: %v", err)
+		}
+		return out
+	}
+
+	if got := run(map[string]any{}); got != "keep.log\nsrc/main.go\nsrc/main_test.go\nsrc/notes.md" {
+		t.Fatalf("unexpected files: %q", got)
+	}
+	if got := run(map[string]any{"include": []any{"*.go"}, "exclude": []any{"*_test.go"}}); got != "src/main.go" {
+		t.Fatalf("unexpected filtered files: %q", got)
+	}
+	if got := run(map[string]any{"exclude": []any{"src"}}); got != "keep.log" {
+		t.Fatalf("excluded directory must be skipped: %q", got)
+	}
+	got := run(map[string]any{"no_ignore": true})
+	if !strings.Contains(got, "node_modules/dep/x.js") || !strings.Contains(got, "src/gen_api.go") || strings.Contains(got, ".git") {
+		t.Fatalf("no_ignore must search ignored files but not .git: %q", got)
+	}
+	if _, err := tool.Execute(context.Background(), map[string]any{"pattern": "x", "include": []any{"[a-"}}); err == nil || !strings.HasPrefix(err.Error(), "grep_invalid_glob") {
+		t.Fatalf("expected grep_invalid_glob, got %v", err)
+	}
+}
+
+func TestGrepToolContextLines(t *testing.T) {
+	dir := t.TempDir()
+	mustWrite(t, filepath.Join(dir, "a.txt"), "1\n2\nhit one\n4\n5\n6\n7\nhit two\nhit three\n10\n")
+	mustWrite(t, filepath.Join(dir, "b.txt"), "x\nhit four\n")
+
+	out, err := NewGrepTool(dir).Execute(context.Background(), map[string]any{"pattern": "hit", "context": 1})
+	if err != nil {
+		t.Fatalf("grep failed: %v", err)
+	}
+	want := strings.Join([]string{
+		"a.txt-2- 2", "a.txt:3: hit one", "a.txt-4- 4",
+		"--",
+		"a.txt-7- 7", "a.txt:8: hit two", "a.txt:9: hit three", "a.txt-10- 10",
+		"--",
+		"b.txt-1- x", "b.txt:2: hit four",
+	}, "\n")
+	if out != want {
+		t.Fatalf("unexpected context output:\n%s", out)
+	}
+
+	out, err = NewGrepTool(dir).Execute(context.Background(), map[string]any{"pattern": "hit", "after": 1, "limit": 1})
+	if err != nil {
+		t.Fatalf("grep failed: %v", err)
+	}
+	if out != "a.txt:3: hit one\na.txt-4- 4" {
+		t.Fatalf("limit must keep trailing context of the last match: %q", out)
+	}
+
+	adjacent := t.TempDir()
+	mustWrite(t, filepath.Join(adjacent, "c.txt"), "hit a\nhit b\nc\n")
+	out, err = NewGrepTool(adjacent).Execute(context.Background(), map[string]any{"pattern": "hit", "after": 2, "limit": 1})
+	if err != nil {
+		t.Fatalf("grep failed: %v", err)
+	}
+	if out != "c.txt:1: hit a" {
+		t.Fatalf("a match past the limit must not be shown as context: %q", out)
+	}
+}
+
+func TestGrepToolCountModeAndLongLines(t *testing.T) {
+	dir := t.TempDir()
+	mustWrite(t, filepath.Join(dir, "a.txt"), "x\nx\nx\n")
+	mustWrite(t, filepath.Join(dir, "b.min.js"), "x"+strings.Repeat("y", 2*grepMaxLineBytes)+"\n")
+	mustWrite(t, filepath.Join(dir, "c.bin"), "x\x00\x01")
+
+	tool := NewGrepTool(dir)
+	out, err := tool.Execute(context.Background(), map[string]any{"pattern": "x", "output_mode": "count"})
+	if err != nil {
+		t.Fatalf("grep failed: %v", err)
+	}
+	if out != "a.txt:3\nb.min.js:1" {
+		t.Fatalf("unexpected counts: %q", out)
+	}
+
+	out, err = tool.Execute(context.Background(), map[string]any{"pattern": "x", "include": "*.js"})
+	if err != nil {
+		t.Fatalf("grep failed: %v", err)
+	}
+	if len(out) > grepMaxLineBytes+64 || !strings.HasSuffix(out, fmt.Sprintf("[... %d more bytes]", grepMaxLineBytes+1)) {
+		t.Fatalf("expected truncated line, got %d bytes: %q", len(out), out[len(out)-40:])
+	}
+}
+
+func TestGrepToolOrderIsDeterministicAcrossBatches(t *testing.T) {
+	dir := t.TempDir()
+	var want []string
+	for i := 0; i < grepBatchSize*3; i++ {
+		name := fmt.Sprintf("d%d/f%03d.txt", i%4, i)
+		mustWrite(t, filepath.Join(dir, filepath.FromSlash(name)), "skip\nmatch\n")
+		want = append(want, name+":2: match")
+	}
+	sort.Strings(want)
+
+	tool := NewGrepTool(dir)
+	for run := 0; run < 3; run++ {
+		out, err := tool.Execute(context.Background(), map[string]any{"pattern": "match", "limit": 1000})
+		if err != nil {
+			t.Fatalf("grep failed: %v", err)
+		}
+		if out != strings.Join(want, "\n") {
+			t.Fatalf("run %d: unexpected order:\n%s", run, out)
+		}
+	}
+	out, err := tool.Execute(context.Background(), map[string]any{"pattern": "match", "limit": grepBatchSize + 5})
+	if err != nil {
+		t.Fatalf("grep failed: %v", err)
+	}
+	if out != strings.Join(want[:grepBatchSize+5], "\n") {
+		t.Fatalf("limit must keep the first matches in walk order:\n%s", out)
+	}
+}
+
 type grepToolCallProvider struct{}
 
 func (grepToolCallProvider) Stream(_ context.Context, req provider.Request) <-chan provider.Event {
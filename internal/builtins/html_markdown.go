@@ -0,0 +1,477 @@
+package builtins
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// htmlSkipElements are dropped with everything inside them.
+var htmlSkipElements = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true,
+	"svg": true, "iframe": true, "nav": true, "button": true, "select": true, "form": true,
+}
+
+// htmlRawTextElements hold text that is not parsed for tags.
+var htmlRawTextElements = map[string]bool{"script": true, "style": true, "textarea": true, "title": true}
+
+var htmlVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+var htmlBlockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "body": true, "dd": true, "details": true,
+	"dialog": true, "div": true, "dl": true, "dt": true, "fieldset": true, "figcaption": true,
+	"figure": true, "footer": true, "header": true, "html": true, "main": true, "p": true,
+	"section": true, "summary": true, "table": true,
+}
+
+type htmlList struct {
+	ordered bool
+	next    int
+}
+
+// markdownWriter renders a stream of HTML tokens as markdown. Whitespace in
+// text is collapsed outside <pre>, and block boundaries are emitted lazily so
+// nested blocks do not pile up blank lines.
+type markdownWriter struct {
+	base *url.URL
+
+	out         strings.Builder
+	pendingLine int
+	// pendingQuote is the quote depth when the pending break was requested;
+	// blank lines use the shallower of it and the current depth.
+	pendingQuote int
+	space        bool
+	lineStart    bool
+
+	// skip is the element being dropped; skipDepth counts its nesting.
+	skip        string
+	skipDepth   int
+	pre         int
+	quote       int
+	lists       []htmlList
+	links       []string
+	afterMarker bool
+
+	rowCells   int
+	rowHeader  bool
+	tableRows  int
+	headerDone bool
+}
+
+var markdownBlankLines = regexp.MustCompile(`\n{3,}`)
+
+// htmlToMarkdown converts an HTML document to markdown and returns its title.
+// Relative links and images are resolved against base when it is set.
+func htmlToMarkdown(src string, base *url.URL) (title, markdown string) {
+	w := &markdownWriter{base: base, lineStart: true}
+	for i := 0; i < len(src); {
+		if src[i] != '<' {
+			end := strings.IndexByte(src[i:], '<')
+			if end < 0 {
+				end = len(src) - i
+			}
+			w.text(html.UnescapeString(src[i : i+end]))
+			i += end
+			continue
+		}
+		switch {
+		case strings.HasPrefix(src[i:], "<!--"):
+			end := strings.Index(src[i+4:], "-->")
+			if end < 0 {
+				return title, w.String()
+			}
+			i += 4 + end + 3
+			continue
+		case strings.HasPrefix(src[i:], "<!"), strings.HasPrefix(src[i:], "<?"):
+			end := strings.IndexByte(src[i:], '>')
+			if end < 0 {
+				return title, w.String()
+			}
+			i += end + 1
+			continue
+		}
+		name, attrs, closing, selfClosing, n := parseHTMLTag(src[i:])
+		if n == 0 {
+			w.text("<")
+			i++
+			continue
+		}
+		i += n
+		if closing {
+			w.end(name)
+			continue
+		}
+		if htmlRawTextElements[name] && !selfClosing {
+			end := indexFold(src[i:], "</"+name)
+			if end < 0 {
+				end = len(src) - i
+			}
+			raw := src[i : i+end]
+			i += end
+			if name == "title" && title == "" {
+				title = strings.Join(strings.Fields(html.UnescapeString(raw)), " ")
+			} else if name == "textarea" && w.skipDepth == 0 {
+				w.text(html.UnescapeString(raw))
+			}
+			continue
+		}
+		w.start(name, attrs)
+		if selfClosing && !htmlVoidElements[name] {
+			w.end(name)
+		}
+	}
+	return title, w.String()
+}
+
+// parseHTMLTag parses the tag at the start of s and returns its lower-case
+// name, attributes and length. n is 0 when s does not start with a tag.
+func parseHTMLTag(s string) (name string, attrs map[string]string, closing, selfClosing bool, n int) {
+	i := 1
+	if i < len(s) && s[i] == '/' {
+		closing = true
+		i++
+	}
+	start := i
+	for i < len(s) && (isASCIILetter(s[i]) || (i > start && (s[i] >= '0' && s[i] <= '9' || s[i] == '-' || s[i] == ':'))) {
+		i++
+	}
+	if i == start {
+		return "", nil, false, false, 0
+	}
+	name = strings.ToLower(s[start:i])
+	attrs = map[string]string{}
+	for i < len(s) {
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			break
+		}
+		if s[i] == '>' {
+			return name, attrs, closing, selfClosing, i + 1
+		}
+		if s[i] == '/' {
+			selfClosing = true
+			i++
+			continue
+		}
+		keyStart := i
+		for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		key := strings.ToLower(s[keyStart:i])
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+		value := ""
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isHTMLSpace(s[i]) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				quote := s[i]
+				end := strings.IndexByte(s[i+1:], quote)
+				if end < 0 {
+					return "", nil, false, false, 0
+				}
+				value = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				valueStart := i
+				for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				value = s[valueStart:i]
+			}
+		}
+		if key != "" {
+			attrs[key] = html.UnescapeString(value)
+		} else {
+			i++
+		}
+	}
+	return "", nil, false, false, 0
+}
+
+func (w *markdownWriter) start(name string, attrs map[string]string) {
+	if w.skipDepth > 0 {
+		if name == w.skip {
+			w.skipDepth++
+		}
+		return
+	}
+	if htmlSkipElements[name] {
+		w.skip, w.skipDepth = name, 1
+		w.space = true
+		return
+	}
+	switch name {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		w.block(2)
+		w.write(strings.Repeat("#", int(name[1]-'0')) + " ")
+	case "br":
+		w.block(1)
+	case "hr":
+		w.block(2)
+		w.write("---")
+		w.block(2)
+	case "pre":
+		w.block(2)
+		w.write("```")
+		w.block(1)
+		w.pre++
+	case "code", "kbd", "samp", "tt":
+		if w.pre == 0 {
+			w.inline("`")
+		}
+	case "strong", "b":
+		w.inline("**")
+	case "em", "i":
+		w.inline("_")
+	case "blockquote":
+		w.block(2)
+		w.quote++
+	case "ul", "ol":
+		w.afterMarker = false
+		w.block(1)
+		start := 1
+		if v, err := strconv.Atoi(attrs["start"]); err == nil {
+			start = v
+		}
+		w.lists = append(w.lists, htmlList{ordered: name == "ol", next: start})
+	case "li":
+		w.afterMarker = false
+		w.block(1)
+		marker := "- "
+		if n := len(w.lists); n > 0 {
+			w.write(strings.Repeat("  ", n-1))
+			if l := &w.lists[n-1]; l.ordered {
+				marker = strconv.Itoa(l.next) + ". "
+				l.next++
+			}
+		}
+		w.write(marker)
+		w.afterMarker = true
+	case "a":
+		href := strings.TrimSpace(attrs["href"])
+		if strings.HasPrefix(href, "javascript:") || strings.HasPrefix(href, "#") {
+			href = ""
+		}
+		href = w.resolve(href)
+		w.links = append(w.links, href)
+		if href != "" {
+			w.inline("[")
+		}
+	case "img":
+		if alt := strings.TrimSpace(attrs["alt"]); alt != "" {
+			w.inline("![" + alt + "](" + w.resolve(attrs["src"]) + ")")
+		}
+	case "tr":
+		w.block(1)
+		w.rowCells, w.rowHeader = 0, false
+	case "td", "th":
+		if w.rowCells == 0 {
+			w.write("|")
+		}
+		w.rowCells++
+		w.rowHeader = w.rowHeader || name == "th"
+		w.space = true
+	case "table":
+		w.block(2)
+		w.tableRows, w.headerDone = 0, false
+	default:
+		if htmlBlockElements[name] {
+			w.block(2)
+		}
+	}
+}
+
+func (w *markdownWriter) end(name string) {
+	if w.skipDepth > 0 {
+		if name == w.skip {
+			w.skipDepth--
+			w.space = true
+		}
+		return
+	}
+	switch name {
+	case "h1", "h2", "h3", "h4", "h5", "h6", "p":
+		w.block(2)
+	case "pre":
+		if w.pre > 0 {
+			w.pre--
+			w.block(1)
+			w.write("```")
+			w.block(2)
+		}
+	case "code", "kbd", "samp", "tt":
+		if w.pre == 0 {
+			w.closeInline("`")
+		}
+	case "strong", "b":
+		w.closeInline("**")
+	case "em", "i":
+		w.closeInline("_")
+	case "blockquote":
+		if w.quote > 0 {
+			w.block(2)
+			w.quote--
+		}
+	case "ul", "ol":
+		if n := len(w.lists); n > 0 {
+			w.lists = w.lists[:n-1]
+		}
+		w.block(2)
+	case "a":
+		if n := len(w.links); n > 0 {
+			if href := w.links[n-1]; href != "" {
+				w.closeInline("](" + href + ")")
+			}
+			w.links = w.links[:n-1]
+		}
+	case "td", "th":
+		w.write(" |")
+	case "tr":
+		w.tableRows++
+		if w.tableRows == 1 && w.rowHeader && !w.headerDone && w.rowCells > 0 {
+			w.block(1)
+			w.write("|" + strings.Repeat(" --- |", w.rowCells))
+			w.headerDone = true
+		}
+		w.block(1)
+	default:
+		if htmlBlockElements[name] {
+			w.block(2)
+		}
+	}
+}
+
+func (w *markdownWriter) text(s string) {
+	if w.skipDepth > 0 || s == "" {
+		return
+	}
+	if w.pre > 0 {
+		for i, line := range strings.Split(s, "\n") {
+			if i > 0 {
+				w.block(1)
+			}
+			w.write(line)
+		}
+		return
+	}
+	if r, _ := utf8.DecodeRuneInString(s); unicode.IsSpace(r) {
+		w.space = true
+	}
+	for _, word := range strings.Fields(s) {
+		if w.space && !w.lineStart {
+			w.write(" ")
+		}
+		w.write(word)
+		w.space = true
+	}
+	r, _ := utf8.DecodeLastRuneInString(s)
+	w.space = unicode.IsSpace(r)
+}
+
+// inline writes an opening marker, keeping the space that preceded it.
+func (w *markdownWriter) inline(s string) {
+	if w.space && !w.lineStart {
+		w.write(" ")
+	}
+	w.write(s)
+}
+
+// closeInline writes a closing marker, keeping the space that followed the
+// text before it.
+func (w *markdownWriter) closeInline(s string) {
+	space := w.space
+	w.write(s)
+	w.space = space
+}
+
+func (w *markdownWriter) block(lines int) {
+	// A block that opens a list item stays on the marker's line.
+	if w.afterMarker {
+		return
+	}
+	// Lists stay compact: paragraphs inside items are not separated by
+	// blank lines.
+	if len(w.lists) > 0 {
+		lines = 1
+	}
+	if w.out.Len() > 0 && lines > w.pendingLine {
+		if w.pendingLine == 0 {
+			w.pendingQuote = w.quote
+		}
+		w.pendingLine = lines
+	}
+	w.space = false
+}
+
+func (w *markdownWriter) write(s string) {
+	if w.pendingLine > 0 {
+		prefix := strings.Repeat("> ", w.quote)
+		w.out.WriteString("\n")
+		for i := 1; i < w.pendingLine; i++ {
+			w.out.WriteString(strings.Repeat(">", min(w.quote, w.pendingQuote)) + "\n")
+		}
+		w.out.WriteString(prefix)
+		w.pendingLine = 0
+		w.lineStart = true
+	} else if w.out.Len() == 0 && w.quote > 0 {
+		w.out.WriteString(strings.Repeat("> ", w.quote))
+	}
+	if s == " " && w.lineStart {
+		return
+	}
+	w.out.WriteString(s)
+	w.lineStart = false
+	w.space = false
+	w.afterMarker = false
+}
+
+func (w *markdownWriter) resolve(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if w.base == nil || ref == "" {
+		return ref
+	}
+	u, err := w.base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+func (w *markdownWriter) String() string {
+	lines := strings.Split(w.out.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(markdownBlankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// indexFold is an ASCII case-insensitive strings.Index.
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,63 @@
+package builtins
+
+import (
+	"net/url"
+	"testing"
+)
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("parse %s: %v", raw, err)
+	}
+	return u
+}
+
+func TestHTMLToMarkdown(t *testing.T) {
+	src := `<html><head><title> API
+  Reference </title><script>var x = "</div>";</script></head>
+<body>
+<header><nav><ul><li><a href="/">Home</a></li></ul></nav></header>
+<main>
+<h2 id="x">Usage <small>v2</small></h2>
+<p>Call   <strong>Open</strong>&nbsp;first, then <em>read</em>.<br>Next line.</p>
+<ul>
+  <li>One</li>
+  <li><p>Two</p>
+    <ol start="3"><li>Nested</li></ol>
+  </li>
+</ul>
+<pre><code>func main() {
+	fmt.Println("&lt;hi&gt;")
+}</code></pre>
+<blockquote><p>Quoted</p><p>Twice</p></blockquote>
+<table><tr><th>Name</th><th>Type</th></tr><tr><td>id</td><td>int</td></tr></table>
+<p><img src="img/logo.png" alt="Logo"> <a href="guide.html#top">Guide</a> <a href="#local">local</a></p>
+<!-- <p>hidden</p> -->
+</main>
+</body></html>`
+
+	title, md := htmlToMarkdown(src, mustParseURL(t, "https://example.com/docs/index.html"))
+	if title != "API Reference" {
+		t.Fatalf("unexpected title: %q", title)
+	}
+	want := "## Usage v2\n\n" +
+		"Call **Open** first, then _read_.\nNext line.\n\n" +
+		"- One\n- Two\n  3. Nested\n\n" +
+		"```\nfunc main() {\n\tfmt.Println(\"<hi>\")\n}\n```\n\n" +
+		"> Quoted\n>\n> Twice\n\n" +
+		"| Name | Type |\n| --- | --- |\n| id | int |\n\n" +
+		"![Logo](https://example.com/docs/img/logo.png) [Guide](https://example.com/docs/guide.html#top) local"
+	if md != want {
+		t.Fatalf("unexpected markdown:\n%q\n--- want ---\n%q", md, want)
+	}
+}
+
+func TestHTMLToMarkdownToleratesBrokenMarkup(t *testing.T) {
+	_, md := htmlToMarkdown(`<p>a < b and <unclosed <p>c<nav><p>skip</nav>d <a href="x`, nil)
+	// The unterminated attribute keeps the final tag as text.
+	if md != `a < b and c d <a href="x` {
+		t.Fatalf("unexpected markdown: %q", md)
+	}
+}
@@ -18,9 +18,16 @@ type lsEntry struct {
 }
 
 func NewLSTool(cwd string) core.Tool {
-	base := resolveBaseDir(cwd)
+	return newLSTool(newPathResolver(Config{Workdir: cwd}))
+}
+
+func newLSTool(paths *pathResolver) core.Tool {
 	return core.ToolFunc{
-		ToolName: "ls",
+		ToolName:        "ls",
+		ToolDescription: "List directory contents.",
+		ToolSchema: objectSchema(map[string]any{
+			"path": withAliases(withDefault(stringProperty("Directory path to list. Defaults to current directory."), "."), dirAliases...),
+		}),
 		Run: func(_ context.Context, args map[string]any) (string, error) {
 			rawPath, _ := args["path"].(string)
 			rawPath = strings.TrimSpace(rawPath)
@@ -28,7 +35,10 @@ func NewLSTool(cwd string) core.Tool {
 				rawPath = "."
 			}
 
-			abs := resolveToolPath(base, rawPath)
+			abs, err := paths.resolve(rawPath)
+			if err != nil {
+				return "", err
+			}
 
 			info, err := os.Stat(abs)
 			if err != nil {
@@ -1,11 +1,87 @@
 package builtins
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"nous/internal/fspath"
 )
 
+// Config controls how builtin tools resolve paths.
+type Config struct {
+	Workdir string
+	// ConfineWorkdir rejects paths that resolve, after following symlinks,
+	// outside Workdir and AllowedRoots.
+	ConfineWorkdir bool
+	AllowedRoots   []string
+	// PersistentBash runs bash calls in one long-lived shell per session.
+	PersistentBash bool
+	// Shell runs bash tool commands; the zero value auto-detects a login
+	// shell (see ResolveShell).
+	Shell Shell
+	// ExecProfile sets the environment and limits of bash and process
+	// commands; the zero value uses DefaultExecProfile.
+	ExecProfile ExecProfile
+	// FetchAllowedDomains limits the fetch tool to these hosts and their
+	// subdomains; empty allows any host.
+	FetchAllowedDomains []string
+}
+
+type pathResolver struct {
+	base    string
+	confine bool
+	roots   []string
+}
+
+func newPathResolver(cfg Config) *pathResolver {
+	r := &pathResolver{base: resolveBaseDir(cfg.Workdir), confine: cfg.ConfineWorkdir}
+	if !r.confine {
+		return r
+	}
+	r.roots = append(r.roots, fspath.Canonical(r.base))
+	for _, root := range cfg.AllowedRoots {
+		if abs := fspath.Resolve(r.base, root); abs != "" {
+			r.roots = append(r.roots, fspath.Canonical(abs))
+		}
+	}
+	return r
+}
+
+// resolve turns a tool path argument into an absolute path. When confined,
+// the returned path is canonical and errors carry path_outside_workdir.
+func (r *pathResolver) resolve(rawPath string) (string, error) {
+	abs := fspath.Resolve(r.base, rawPath)
+	if !r.confine || abs == "" {
+		return abs, nil
+	}
+	canonical := fspath.Canonical(abs)
+	if !r.within(canonical) {
+		return "", fmt.Errorf("path_outside_workdir: %s", rawPath)
+	}
+	return canonical, nil
+}
+
+// allowed reports whether path, after following symlinks, stays inside the
+// allowed roots. It is always true when confinement is off.
+func (r *pathResolver) allowed(path string) bool {
+	return !r.confine || r.within(fspath.Canonical(path))
+}
+
+func (r *pathResolver) within(canonical string) bool {
+	for _, root := range r.roots {
+		rel, err := filepath.Rel(root, canonical)
+		if err != nil {
+			continue
+		}
+		if rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))) {
+			return true
+		}
+	}
+	return false
+}
+
 func resolveBaseDir(cwd string) string {
 	base := strings.TrimSpace(cwd)
 	if base == "" {
@@ -13,7 +89,7 @@ func resolveBaseDir(cwd string) string {
 			base = wd
 		}
 	}
-	base = expandPath(base)
+	base = fspath.Expand(base)
 	if base == "" {
 		base = "."
 	}
@@ -24,43 +100,3 @@ func resolveBaseDir(cwd string) string {
 	}
 	return filepath.Clean(base)
 }
-
-func resolveToolPath(base, rawPath string) string {
-	path := expandPath(rawPath)
-	if path == "" {
-		return ""
-	}
-	if !filepath.IsAbs(path) {
-		path = filepath.Join(base, path)
-	}
-	return filepath.Clean(path)
-}
-
-func expandPath(raw string) string {
-	path := strings.TrimSpace(raw)
-	if path == "" {
-		return ""
-	}
-	path = os.Expand(path, func(name string) string {
-		if value, ok := os.LookupEnv(name); ok {
-			return value
-		}
-		return "$" + name
-	})
-	path = expandHome(path)
-	return strings.TrimSpace(path)
-}
-
-func expandHome(path string) string {
-	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, "~\\") {
-		return path
-	}
-	home, err := os.UserHomeDir()
-	if err != nil || strings.TrimSpace(home) == "" {
-		return path
-	}
-	if path == "~" {
-		return home
-	}
-	return filepath.Join(home, path[2:])
-}
@@ -1,36 +1,119 @@
 package builtins
 
 import (
+	"context"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"nous/internal/core"
 )
 
-func TestResolveToolPathExpandsEnvAndJoinsRelative(t *testing.T) {
+func TestPathResolverConfinesToWorkdir(t *testing.T) {
 	base := t.TempDir()
-	root := t.TempDir()
-	t.Setenv("NOUS_BUILTINS_ROOT", root)
+	outside := t.TempDir()
+	extra := t.TempDir()
+	t.Setenv("HOME", outside)
+	t.Setenv("NOUS_OUTSIDE", outside)
+	if err := os.Symlink(outside, filepath.Join(base, "escape")); err != nil {
+		t.Fatalf("symlink failed: %v", err)
+	}
+	// Dangling links are checked against their target, not their own path.
+	if err := os.Symlink(filepath.Join(outside, "missing", "pwned.txt"), filepath.Join(base, "dangling")); err != nil {
+		t.Fatalf("symlink failed: %v", err)
+	}
+	if err := os.Symlink("sub/../later.txt", filepath.Join(base, "pending")); err != nil {
+		t.Fatalf("symlink failed: %v", err)
+	}
+	r := newPathResolver(Config{Workdir: base, ConfineWorkdir: true, AllowedRoots: []string{extra}})
 
-	got := resolveToolPath(base, "$NOUS_BUILTINS_ROOT/data.txt")
-	want := filepath.Join(root, "data.txt")
-	if got != want {
-		t.Fatalf("unexpected env-expanded path: got=%q want=%q", got, want)
+	for _, raw := range []string{"notes.md", "new/dir/file.txt", ".", filepath.Join(extra, "shared.txt"), "pending"} {
+		if _, err := r.resolve(raw); err != nil {
+			t.Fatalf("expected %q to be allowed, got %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"../x", filepath.Join(outside, "x"), "~/x", "$NOUS_OUTSIDE/x", "escape/x", "escape", "dangling"} {
+		_, err := r.resolve(raw)
+		if err == nil || !strings.HasPrefix(err.Error(), "path_outside_workdir") {
+			t.Fatalf("expected path_outside_workdir for %q, got %v", raw, err)
+		}
 	}
 
-	got = resolveToolPath(base, "rel/data.txt")
-	want = filepath.Join(base, "rel", "data.txt")
-	if got != want {
-		t.Fatalf("unexpected relative path: got=%q want=%q", got, want)
+	open := newPathResolver(Config{Workdir: base})
+	if _, err := open.resolve("../x"); err != nil {
+		t.Fatalf("unconfined resolver must not reject paths: %v", err)
 	}
 }
 
-func TestResolveToolPathExpandsHome(t *testing.T) {
+func TestConfinedToolsRejectOutsidePaths(t *testing.T) {
 	base := t.TempDir()
-	home := t.TempDir()
-	t.Setenv("HOME", home)
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("token=abc\n"), 0o644); err != nil {
+		t.Fatalf("write outside file failed: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(base, "link.txt")); err != nil {
+		t.Fatalf("symlink failed: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "pwned.txt"), filepath.Join(base, "dangling.txt")); err != nil {
+		t.Fatalf("symlink failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(base, "inside.txt"), []byte("token=inside\n"), 0o644); err != nil {
+		t.Fatalf("write inside file failed: %v", err)
+	}
+	// A ".." in a link target applies after the links before it: hop.txt
+	// names outside/secret.txt, not base/secret.txt.
+	if err := os.Mkdir(filepath.Join(outside, "dir"), 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "dir"), filepath.Join(base, "l")); err != nil {
+		t.Fatalf("symlink failed: %v", err)
+	}
+	if err := os.Symlink("l/../secret.txt", filepath.Join(base, "hop.txt")); err != nil {
+		t.Fatalf("symlink failed: %v", err)
+	}
+	tools := map[string]core.Tool{}
+	for _, tool := range DefaultToolsWithConfig(Config{Workdir: base, ConfineWorkdir: true}) {
+		tools[tool.Name()] = tool
+	}
+
+	cases := []struct {
+		tool string
+		args map[string]any
+	}{
+		{tool: "read", args: map[string]any{"path": filepath.Join(outside, "secret.txt")}},
+		{tool: "read", args: map[string]any{"path": "link.txt"}},
+		{tool: "write", args: map[string]any{"path": "../out.txt", "content": "x"}},
+		{tool: "write", args: map[string]any{"path": "dangling.txt", "content": "x"}},
+		{tool: "read", args: map[string]any{"path": "hop.txt"}},
+		{tool: "write", args: map[string]any{"path": "hop.txt", "content": "x"}},
+		{tool: "apply_patch", args: map[string]any{"patch": "*** Begin Patch\n*** Add File: dangling.txt\n+x\n*** End Patch\n"}},
+		{tool: "edit", args: map[string]any{"path": "link.txt", "oldText": "abc", "newText": "def"}},
+		{tool: "ls", args: map[string]any{"path": outside}},
+		{tool: "find", args: map[string]any{"path": outside, "query": "secret"}},
+		{tool: "grep", args: map[string]any{"path": outside, "pattern": "token"}},
+	}
+	for _, tc := range cases {
+		_, err := tools[tc.tool].Execute(context.Background(), tc.args)
+		if err == nil || !strings.HasPrefix(err.Error(), "path_outside_workdir") {
+			t.Fatalf("%s %v: expected path_outside_workdir, got %v", tc.tool, tc.args, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(base), "out.txt")); err == nil {
+		t.Fatalf("write must not create files outside the workdir")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "pwned.txt")); err == nil {
+		t.Fatalf("writes must not follow dangling symlinks out of the workdir")
+	}
+	if b, _ := os.ReadFile(filepath.Join(outside, "secret.txt")); string(b) != "token=abc\n" {
+		t.Fatalf("writes must not follow links out of the workdir, secret is now %q", b)
+	}
 
-	got := resolveToolPath(base, "~/notes.md")
-	want := filepath.Join(home, "notes.md")
-	if got != want {
-		t.Fatalf("unexpected home-expanded path: got=%q want=%q", got, want)
+	out, err := tools["grep"].Execute(context.Background(), map[string]any{"path": ".", "pattern": "token"})
+	if err != nil {
+		t.Fatalf("grep inside workdir failed: %v", err)
+	}
+	if !strings.Contains(out, "inside.txt") || strings.Contains(out, "abc") {
+		t.Fatalf("grep must skip symlinks leaving the workdir, got: %q", out)
 	}
 }
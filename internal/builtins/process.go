@@ -0,0 +1,497 @@
+package builtins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"nous/internal/core"
+)
+
+const (
+	processLogLimit    = 256 * 1024
+	processReadLimit   = 32 * 1024
+	processMaxRunning  = 16
+	processMaxRetained = 32
+	processKillWait    = 2 * time.Second
+)
+
+// processLog is a ring buffer over a process's combined output. Offsets
+// count every byte ever written, so readers can resume where they left off
+// even after old output has been dropped.
+type processLog struct {
+	mu    sync.Mutex
+	buf   []byte // processLogLimit bytes once anything is written
+	head  int    // index in buf of the oldest kept byte
+	n     int    // bytes kept
+	total int64
+}
+
+func (l *processLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.total += int64(len(p))
+	if l.buf == nil {
+		l.buf = make([]byte, processLogLimit)
+	}
+	data := p
+	if len(data) >= processLogLimit {
+		data = data[len(data)-processLogLimit:]
+		copy(l.buf, data)
+		l.head, l.n = 0, processLogLimit
+		return len(p), nil
+	}
+	tail := (l.head + l.n) % processLogLimit
+	copied := copy(l.buf[tail:], data)
+	copy(l.buf, data[copied:])
+	l.n += len(data)
+	if over := l.n - processLogLimit; over > 0 {
+		l.head = (l.head + over) % processLogLimit
+		l.n = processLogLimit
+	}
+	return len(p), nil
+}
+
+// readSince returns up to limit bytes starting at offset, the offset the
+// returned bytes actually start at and the offset to continue from.
+func (l *processLog) readSince(offset int64, limit int) (data []byte, start, next int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	dropped := l.total - int64(l.n)
+	if offset < dropped {
+		offset = dropped
+	}
+	if offset > l.total {
+		offset = l.total
+	}
+	from := int(offset - dropped)
+	count := min(l.n-from, limit)
+	data = make([]byte, count)
+	if count > 0 {
+		at := (l.head + from) % processLogLimit
+		copied := copy(data, l.buf[at:])
+		copy(data[copied:], l.buf)
+	}
+	return data, offset, offset + int64(count)
+}
+
+func (l *processLog) size() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.total
+}
+
+type managedProcess struct {
+	seq     int
+	id      string
+	command string
+	dir     string
+	started time.Time
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	log     *processLog
+	done    chan struct{}
+
+	// Set before done is closed.
+	exitCode int
+	ended    time.Time
+}
+
+type processStatus struct {
+	ID          string `json:"id"`
+	PID         int    `json:"pid"`
+	Command     string `json:"command"`
+	Cwd         string `json:"cwd"`
+	Status      string `json:"status"`
+	ExitCode    *int   `json:"exit_code,omitempty"`
+	StartedAt   string `json:"started_at"`
+	EndedAt     string `json:"ended_at,omitempty"`
+	OutputBytes int64  `json:"output_bytes"`
+}
+
+func (p *managedProcess) status() processStatus {
+	st := processStatus{
+		ID:          p.id,
+		PID:         p.cmd.Process.Pid,
+		Command:     p.command,
+		Cwd:         p.dir,
+		Status:      "running",
+		StartedAt:   p.started.UTC().Format(time.RFC3339),
+		OutputBytes: p.log.size(),
+	}
+	select {
+	case <-p.done:
+		code := p.exitCode
+		st.ExitCode = &code
+		st.EndedAt = p.ended.UTC().Format(time.RFC3339)
+		st.Status = "exited"
+		if code < 0 {
+			st.Status = "killed"
+		}
+	default:
+	}
+	return st
+}
+
+func (p *managedProcess) running() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// processManager owns the background processes started by the process tool.
+type processManager struct {
+	paths   *pathResolver
+	shell   Shell
+	profile ExecProfile
+
+	mu     sync.Mutex
+	nextID int
+	procs  map[string]*managedProcess
+}
+
+// processTool wraps the process ToolFunc so it can report status to
+// get_state and stop its processes on abort and shutdown.
+type processTool struct {
+	core.ToolFunc
+	manager *processManager
+}
+
+func (t processTool) StopBackground() { t.manager.stopAll() }
+
+// processReadOnlyActions run without tool approval; every other action
+// starts, feeds or stops a process.
+var processReadOnlyActions = map[string]bool{"list": true, "read": true}
+
+// NeedsApproval exempts list and read calls from tool approval, so that
+// start, stdin, signal and kill always wait for it.
+func (processTool) NeedsApproval(args map[string]any) bool {
+	action, _ := args["action"].(string)
+	return !processReadOnlyActions[strings.TrimSpace(action)]
+}
+
+func (t processTool) ToolState() map[string]any {
+	return map[string]any{"processes": t.manager.list()}
+}
+
+func NewProcessTool(cwd string) core.Tool {
+	return newProcessTool(newPathResolver(Config{Workdir: cwd}), defaultShell(), DefaultExecProfile())
+}
+
+func newProcessTool(paths *pathResolver, shell Shell, profile ExecProfile) core.Tool {
+	m := &processManager{paths: paths, shell: shell, profile: profile, procs: map[string]*managedProcess{}}
+	fn := core.ToolFunc{
+		ToolName:        "process",
+		ToolDescription: "Manage background processes such as dev servers and watchers: start, list, read output since an offset, send stdin, signal and kill. Processes are killed when the run is aborted or the core stops.",
+		ToolSchema: objectSchema(map[string]any{
+			"action":      withEnum(requiredStringProperty("Operation to perform."), "start", "list", "read", "stdin", "signal", "kill"),
+			"command":     withAliases(stringProperty("Shell command to start (start)."), "cmd"),
+			"cwd":         withAliases(stringProperty("Working directory for the command (start). Defaults to current directory."), dirAliases...),
+			"id":          stringProperty("Process id returned by start (read, stdin, signal, kill)."),
+			"offset":      withMinimum(integerProperty("Output byte offset to read from (read). Use next_offset from the previous read."), 0),
+			"max_bytes":   withMinimum(integerProperty("Maximum bytes of output to return (read)."), 1),
+			"input":       stringProperty("Text to write to the process stdin (stdin)."),
+			"close_stdin": booleanProperty("Close stdin after writing input (stdin)."),
+			"signal":      stringProperty("Signal to send (signal): " + strings.Join(processSignalNames(), ", ") + ". Defaults to TERM."),
+		}, "action"),
+		Run: func(_ context.Context, args map[string]any) (string, error) {
+			action, _ := args["action"].(string)
+			var out any
+			var err error
+			switch strings.TrimSpace(action) {
+			case "start":
+				out, err = m.start(args)
+			case "list":
+				out = m.list()
+			case "read":
+				out, err = m.read(args)
+			case "stdin":
+				out, err = m.writeStdin(args)
+			case "signal":
+				out, err = m.signal(args)
+			case "kill":
+				out, err = m.kill(args)
+			default:
+				return "", fmt.Errorf("process_invalid_action: %s", action)
+			}
+			if err != nil {
+				return "", err
+			}
+			b, err := json.Marshal(out)
+			if err != nil {
+				return "", fmt.Errorf("process_failed: %w", err)
+			}
+			return string(b), nil
+		},
+	}
+	return processTool{ToolFunc: fn, manager: m}
+}
+
+func (m *processManager) start(args map[string]any) (processStatus, error) {
+	command := resolveStringArgLocal(args, "command", "cmd")
+	if command == "" {
+		return processStatus{}, fmt.Errorf("process_invalid_command")
+	}
+	rawDir := resolveStringArgLocal(args, append([]string{"cwd"}, dirAliases...)...)
+	if rawDir == "" {
+		rawDir = "."
+	}
+	dir, err := m.paths.resolve(rawDir)
+	if err != nil {
+		return processStatus{}, err
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return processStatus{}, fmt.Errorf("process_invalid_cwd: %s", rawDir)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	running := 0
+	for _, p := range m.procs {
+		if p.running() {
+			running++
+		}
+	}
+	if running >= processMaxRunning {
+		return processStatus{}, fmt.Errorf("process_limit_reached: %d processes running", running)
+	}
+
+	cmd := exec.Command(m.shell.Path, m.shell.commandArgs(m.profile.limitScript()+command)...)
+	cmd.Dir = dir
+	m.profile.apply(cmd)
+	output := &processLog{}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	// Stop waiting on the output pipe once the shell has exited, in case a
+	// child that escaped the process group still holds it open.
+	cmd.WaitDelay = bashWaitDelay
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return processStatus{}, fmt.Errorf("process_start_failed: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return processStatus{}, fmt.Errorf("process_start_failed: %w", err)
+	}
+
+	m.nextID++
+	p := &managedProcess{
+		seq:     m.nextID,
+		id:      "p" + strconv.Itoa(m.nextID),
+		command: command,
+		dir:     dir,
+		started: time.Now(),
+		cmd:     cmd,
+		stdin:   stdin,
+		log:     output,
+		done:    make(chan struct{}),
+	}
+	go func() {
+		_ = cmd.Wait()
+		p.exitCode = cmd.ProcessState.ExitCode()
+		p.ended = time.Now()
+		close(p.done)
+	}()
+	m.procs[p.id] = p
+	m.pruneLocked()
+	return p.status(), nil
+}
+
+// pruneLocked forgets the oldest exited processes once more than
+// processMaxRetained are tracked.
+func (m *processManager) pruneLocked() {
+	if len(m.procs) <= processMaxRetained {
+		return
+	}
+	exited := make([]*managedProcess, 0, len(m.procs))
+	for _, p := range m.procs {
+		if !p.running() {
+			exited = append(exited, p)
+		}
+	}
+	sort.Slice(exited, func(i, j int) bool { return exited[i].seq < exited[j].seq })
+	for _, p := range exited {
+		if len(m.procs) <= processMaxRetained {
+			return
+		}
+		delete(m.procs, p.id)
+	}
+}
+
+func (m *processManager) list() []processStatus {
+	m.mu.Lock()
+	procs := make([]*managedProcess, 0, len(m.procs))
+	for _, p := range m.procs {
+		procs = append(procs, p)
+	}
+	m.mu.Unlock()
+	sort.Slice(procs, func(i, j int) bool { return procs[i].seq < procs[j].seq })
+	out := make([]processStatus, 0, len(procs))
+	for _, p := range procs {
+		out = append(out, p.status())
+	}
+	return out
+}
+
+func (m *processManager) lookup(args map[string]any) (*managedProcess, error) {
+	id := resolveStringArgLocal(args, "id")
+	if id == "" {
+		return nil, fmt.Errorf("process_invalid_id")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.procs[id]
+	if !ok {
+		return nil, fmt.Errorf("process_not_found: %s", id)
+	}
+	return p, nil
+}
+
+type processOutput struct {
+	processStatus
+	Offset     int64  `json:"offset"`
+	NextOffset int64  `json:"next_offset"`
+	Skipped    int64  `json:"skipped,omitempty"`
+	Output     string `json:"output"`
+}
+
+func (m *processManager) read(args map[string]any) (processOutput, error) {
+	p, err := m.lookup(args)
+	if err != nil {
+		return processOutput{}, err
+	}
+	offset, err := intArg(args, "offset", 0)
+	if err != nil || offset < 0 {
+		return processOutput{}, fmt.Errorf("process_invalid_offset")
+	}
+	limit, err := intArg(args, "max_bytes", processReadLimit)
+	if err != nil || limit <= 0 {
+		return processOutput{}, fmt.Errorf("process_invalid_max_bytes")
+	}
+	limit = min(limit, processReadLimit)
+	// Take the status first so an exited status means the output is complete.
+	st := p.status()
+	data, start, next := p.log.readSince(int64(offset), limit)
+	return processOutput{
+		processStatus: st,
+		Offset:        start,
+		NextOffset:    next,
+		Skipped:       start - int64(offset),
+		Output:        sanitizeBashOutput(string(data)),
+	}, nil
+}
+
+func (m *processManager) writeStdin(args map[string]any) (processStatus, error) {
+	p, err := m.lookup(args)
+	if err != nil {
+		return processStatus{}, err
+	}
+	if !p.running() {
+		return processStatus{}, fmt.Errorf("process_not_running: %s", p.id)
+	}
+	input, _ := args["input"].(string)
+	closeStdin, _ := args["close_stdin"].(bool)
+	if input == "" && !closeStdin {
+		return processStatus{}, fmt.Errorf("process_invalid_input")
+	}
+	if input != "" {
+		if _, err := io.WriteString(p.stdin, input); err != nil {
+			return processStatus{}, fmt.Errorf("process_stdin_failed: %w", err)
+		}
+	}
+	if closeStdin {
+		if err := p.stdin.Close(); err != nil {
+			return processStatus{}, fmt.Errorf("process_stdin_failed: %w", err)
+		}
+	}
+	return p.status(), nil
+}
+
+func (m *processManager) signal(args map[string]any) (processStatus, error) {
+	p, err := m.lookup(args)
+	if err != nil {
+		return processStatus{}, err
+	}
+	name := strings.TrimPrefix(strings.ToUpper(resolveStringArgLocal(args, "signal")), "SIG")
+	if name == "" {
+		name = "TERM"
+	}
+	sig, ok := processSignals[name]
+	if !ok {
+		return processStatus{}, fmt.Errorf("process_invalid_signal: %s", name)
+	}
+	if !p.running() {
+		return processStatus{}, fmt.Errorf("process_not_running: %s", p.id)
+	}
+	if err := signalProcessGroup(p.cmd, sig); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return processStatus{}, fmt.Errorf("process_signal_failed: %w", err)
+	}
+	return p.status(), nil
+}
+
+func (m *processManager) kill(args map[string]any) (processStatus, error) {
+	p, err := m.lookup(args)
+	if err != nil {
+		return processStatus{}, err
+	}
+	if err := p.kill(); err != nil {
+		return processStatus{}, fmt.Errorf("process_kill_failed: %w", err)
+	}
+	select {
+	case <-p.done:
+	case <-time.After(processKillWait):
+	}
+	return p.status(), nil
+}
+
+func (p *managedProcess) kill() error {
+	if !p.running() {
+		return nil
+	}
+	if err := signalProcessGroup(p.cmd, syscall.SIGKILL); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	return nil
+}
+
+// stopAll kills every running process and waits briefly for them to exit.
+func (m *processManager) stopAll() {
+	m.mu.Lock()
+	procs := make([]*managedProcess, 0, len(m.procs))
+	for _, p := range m.procs {
+		procs = append(procs, p)
+	}
+	m.mu.Unlock()
+	deadline := time.After(processKillWait)
+	for _, p := range procs {
+		_ = p.kill()
+	}
+	for _, p := range procs {
+		select {
+		case <-p.done:
+		case <-deadline:
+			return
+		}
+	}
+}
+
+func processSignalNames() []string {
+	names := make([]string, 0, len(processSignals))
+	for name := range processSignals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
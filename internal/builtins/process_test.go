@@ -0,0 +1,284 @@
+package builtins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nous/internal/core"
+	"nous/internal/provider"
+)
+
+func newTestProcessTool(t *testing.T, dir string) processTool {
+	t.Helper()
+	if !isExecutableFile("/bin/sh") {
+		t.Skip("/bin/sh not available")
+	}
+	tool := newProcessTool(newPathResolver(Config{Workdir: dir}), Shell{Path: "/bin/sh"}, DefaultExecProfile()).(processTool)
+	t.Cleanup(tool.StopBackground)
+	return tool
+}
+
+func runProcessTool(t *testing.T, tool processTool, args map[string]any, out any) {
+	t.Helper()
+	raw, err := tool.Execute(context.Background(), args)
+	if err != nil {
+		t.Fatalf("process %v failed: %v", args["action"], err)
+	}
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		t.Fatalf("invalid process output %q: %v", raw, err)
+	}
+}
+
+func waitProcessOutput(t *testing.T, tool processTool, id string, offset int64, want string) processOutput {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		var out processOutput
+		runProcessTool(t, tool, map[string]any{"action": "read", "id": id, "offset": offset}, &out)
+		if strings.Contains(out.Output, want) || time.Now().After(deadline) {
+			return out
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestProcessToolStartReadStdinAndExit(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	tool := newTestProcessTool(t, dir)
+
+	var started processStatus
+	runProcessTool(t, tool, map[string]any{
+		"action":  "start",
+		"command": `pwd; while read line; do echo "got $line"; done; exit 3`,
+		"cwd":     "sub",
+	}, &started)
+	if started.ID != "p1" || started.Status != "running" || started.PID == 0 {
+		t.Fatalf("unexpected start status: %+v", started)
+	}
+
+	first := waitProcessOutput(t, tool, started.ID, 0, "sub")
+	if !strings.HasSuffix(strings.TrimSpace(first.Output), "sub") || first.Offset != 0 {
+		t.Fatalf("expected cwd output, got %+v", first)
+	}
+
+	var st processStatus
+	runProcessTool(t, tool, map[string]any{"action": "stdin", "id": started.ID, "input": "hello\n", "close_stdin": true}, &st)
+	next := waitProcessOutput(t, tool, started.ID, first.NextOffset, "got hello")
+	if next.Output != "got hello\n" || next.Offset != first.NextOffset {
+		t.Fatalf("expected only new output since offset, got %+v", next)
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for st.Status == "running" && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		runProcessTool(t, tool, map[string]any{"action": "read", "id": started.ID, "offset": next.NextOffset}, &next)
+		st = next.processStatus
+	}
+	if st.Status != "exited" || st.ExitCode == nil || *st.ExitCode != 3 {
+		t.Fatalf("expected exit code 3, got %+v", st)
+	}
+	if _, err := tool.Execute(context.Background(), map[string]any{"action": "stdin", "id": started.ID, "input": "x"}); err == nil || !strings.Contains(err.Error(), "process_not_running") {
+		t.Fatalf("expected process_not_running, got %v", err)
+	}
+}
+
+func TestProcessToolSignalKillAndStopBackground(t *testing.T) {
+	tool := newTestProcessTool(t, t.TempDir())
+
+	var trapped processStatus
+	runProcessTool(t, tool, map[string]any{"action": "start", "command": `trap 'echo bye; exit 0' TERM; echo ready; while :; do sleep 0.05; done`}, &trapped)
+	waitProcessOutput(t, tool, trapped.ID, 0, "ready")
+	runProcessTool(t, tool, map[string]any{"action": "signal", "id": trapped.ID, "signal": "sigterm"}, &trapped)
+	out := waitProcessOutput(t, tool, trapped.ID, 0, "bye")
+	if !strings.Contains(out.Output, "bye") {
+		t.Fatalf("expected TERM trap to run, got %+v", out)
+	}
+
+	var sleeper processStatus
+	runProcessTool(t, tool, map[string]any{"action": "start", "command": "sleep 30 & wait"}, &sleeper)
+	runProcessTool(t, tool, map[string]any{"action": "kill", "id": sleeper.ID}, &sleeper)
+	if sleeper.Status != "killed" {
+		t.Fatalf("expected killed process, got %+v", sleeper)
+	}
+
+	var bg processStatus
+	runProcessTool(t, tool, map[string]any{"action": "start", "command": "sleep 30"}, &bg)
+	tool.StopBackground()
+	procs, _ := tool.ToolState()["processes"].([]processStatus)
+	if len(procs) != 3 {
+		t.Fatalf("expected three tracked processes, got %+v", procs)
+	}
+	for _, p := range procs {
+		if p.Status == "running" {
+			t.Fatalf("expected every process stopped, got %+v", procs)
+		}
+	}
+}
+
+func TestProcessToolKillReturnsWhenChildHoldsOutput(t *testing.T) {
+	if _, err := exec.LookPath("setsid"); err != nil {
+		t.Skip("setsid not available")
+	}
+	tool := newTestProcessTool(t, t.TempDir())
+
+	// The setsid child leaves the process group, so the kill misses it and
+	// it keeps the output pipe open after the shell is gone.
+	var p processStatus
+	runProcessTool(t, tool, map[string]any{"action": "start", "command": "setsid sleep 10 & echo ready; wait"}, &p)
+	waitProcessOutput(t, tool, p.ID, 0, "ready")
+	runProcessTool(t, tool, map[string]any{"action": "kill", "id": p.ID}, &p)
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Status == "running" && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+		var out processOutput
+		runProcessTool(t, tool, map[string]any{"action": "read", "id": p.ID}, &out)
+		p = out.processStatus
+	}
+	if p.Status != "killed" {
+		t.Fatalf("expected the shell to be reaped despite the open pipe, got %+v", p)
+	}
+}
+
+func TestProcessToolNeedsApproval(t *testing.T) {
+	tool := newProcessTool(newPathResolver(Config{Workdir: t.TempDir()}), Shell{Path: "/bin/sh"}, DefaultExecProfile()).(processTool)
+	for _, tc := range []struct {
+		action string
+		want   bool
+	}{
+		{"list", false},
+		{"read", false},
+		{"start", true},
+		{"stdin", true},
+		{"signal", true},
+		{"kill", true},
+		{"unknown", true},
+	} {
+		if got := tool.NeedsApproval(map[string]any{"action": tc.action}); got != tc.want {
+			t.Fatalf("%s: NeedsApproval=%v, want %v", tc.action, got, tc.want)
+		}
+	}
+}
+
+type processStartProvider struct{}
+
+func (processStartProvider) Stream(_ context.Context, req provider.Request) <-chan provider.Event {
+	out := make(chan provider.Event)
+	go func() {
+		defer close(out)
+		if hasToolResult(req.Messages) {
+			out <- provider.Event{Type: provider.EventDone}
+			return
+		}
+		out <- provider.Event{Type: provider.EventToolCall, ToolCall: provider.ToolCall{
+			ID:        "t-start",
+			Name:      "process",
+			Arguments: map[string]any{"action": "start", "command": "touch started.txt"},
+		}}
+		out <- provider.Event{Type: provider.EventDone}
+	}()
+	return out
+}
+
+func TestProcessToolStartWaitsForApproval(t *testing.T) {
+	dir := t.TempDir()
+	tool := newTestProcessTool(t, dir)
+	e := core.NewEngine(core.NewRuntime(), processStartProvider{})
+	e.SetTools([]core.Tool{tool})
+	if err := e.SetToolApproval(core.ApprovalConfig{Enabled: true, Tools: []string{"process"}}); err != nil {
+		t.Fatalf("set approval failed: %v", err)
+	}
+	requested := make(chan bool, 1)
+	unsub := e.Subscribe(func(ev core.Event) {
+		if ev.Type != core.EventToolApprovalRequest {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+		_, err := os.Stat(filepath.Join(dir, "started.txt"))
+		requested <- os.IsNotExist(err)
+		go func() { _ = e.RejectToolCall(ev.ToolCallID, "no") }()
+	})
+	defer unsub()
+
+	out, err := e.Prompt(context.Background(), "run-process-approval", "start it")
+	if err != nil {
+		t.Fatalf("prompt failed: %v", err)
+	}
+	select {
+	case blocked := <-requested:
+		if !blocked {
+			t.Fatalf("process start ran before it was approved")
+		}
+	default:
+		t.Fatalf("expected process start to request approval, got %q", out)
+	}
+	if !strings.Contains(out, "tool call rejected") || len(tool.manager.list()) != 0 {
+		t.Fatalf("rejected start must not launch a process, got %q", out)
+	}
+}
+
+func TestProcessToolRejectsBadInput(t *testing.T) {
+	dir := t.TempDir()
+	if !isExecutableFile("/bin/sh") {
+		t.Skip("/bin/sh not available")
+	}
+	tool := newProcessTool(newPathResolver(Config{Workdir: dir, ConfineWorkdir: true}), Shell{Path: "/bin/sh"}, DefaultExecProfile()).(processTool)
+	ctx := context.Background()
+
+	cases := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"action": "start"}, "process_invalid_command"},
+		{map[string]any{"action": "start", "command": "true", "cwd": "missing"}, "process_invalid_cwd"},
+		{map[string]any{"action": "start", "command": "true", "cwd": filepath.Dir(dir)}, "path_outside_workdir"},
+		{map[string]any{"action": "read", "id": "p9"}, "process_not_found"},
+		{map[string]any{"action": "signal", "id": "p9"}, "process_not_found"},
+		{map[string]any{"action": "bogus"}, "process_invalid_action"},
+	}
+	for _, tc := range cases {
+		if _, err := tool.Execute(ctx, tc.args); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("args %v: expected %s, got %v", tc.args, tc.want, err)
+		}
+	}
+}
+
+func TestProcessLogKeepsTailAndAbsoluteOffsets(t *testing.T) {
+	l := &processLog{}
+	_, _ = l.Write([]byte(strings.Repeat("a", processLogLimit)))
+	_, _ = l.Write([]byte("tail"))
+
+	data, start, next := l.readSince(0, 10)
+	if start != 4 || next != 14 || string(data) != strings.Repeat("a", 10) {
+		t.Fatalf("expected read to resume at oldest kept byte, got start=%d next=%d data=%q", start, next, data)
+	}
+	data, start, next = l.readSince(int64(processLogLimit), 100)
+	if string(data) != "tail" || start != int64(processLogLimit) || next != int64(processLogLimit)+4 {
+		t.Fatalf("unexpected tail read: start=%d next=%d data=%q", start, next, data)
+	}
+	if data, _, next = l.readSince(next+50, 10); len(data) != 0 || next != l.size() {
+		t.Fatalf("reading past the end must return nothing, got next=%d data=%q", next, data)
+	}
+
+	// Small writes wrap around the ring without losing order.
+	l = &processLog{}
+	var all strings.Builder
+	for i := 0; i < processLogLimit/10+100; i++ {
+		line := fmt.Sprintf("%09d\n", i)
+		all.WriteString(line)
+		_, _ = l.Write([]byte(line))
+	}
+	data, start, _ = l.readSince(0, processLogLimit)
+	if want := all.String()[all.Len()-processLogLimit:]; start != int64(all.Len()-processLogLimit) || string(data) != want {
+		t.Fatalf("unexpected wrapped read: start=%d len=%d tail=%q", start, len(data), data[len(data)-20:])
+	}
+}
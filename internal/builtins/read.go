@@ -5,7 +5,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 
@@ -13,56 +17,268 @@ import (
 )
 
 func NewReadTool(cwd string) core.Tool {
-	base := resolveBaseDir(cwd)
+	return newReadTool(newPathResolver(Config{Workdir: cwd}))
+}
 
-	return core.ToolFunc{
-		ToolName: "read",
-		Run: func(_ context.Context, args map[string]any) (string, error) {
+func newReadTool(paths *pathResolver) core.Tool {
+	return core.DetailedToolFunc{
+		ToolName:        "read",
+		ToolDescription: fmt.Sprintf("Read file contents by path. Supports optional offset and limit. Output stops after %d lines or %dKB; continue with the offset given at the end. Images (png, jpg, gif, webp) are returned as images; other binary files are only described.", readDefaultLineLimit, readMaxBytes/1024),
+		ToolSchema: objectSchema(map[string]any{
+			"path":         withAliases(requiredStringProperty("Path to the file to read (relative or absolute)"), pathAliases...),
+			"offset":       withAliases(withMinimum(integerProperty("Optional line offset (0-based)"), 0), "start_line", "startLine"),
+			"limit":        withAliases(withMinimum(integerProperty("Optional max number of lines to read. -1 reads to the end."), -1), "max_lines", "maxLines"),
+			"line_numbers": withAliases(booleanProperty("Prefix each line with its 1-based line number and a tab."), "lineNumbers"),
+		}, "path"),
+		Run: func(_ context.Context, args map[string]any) (core.ToolResult, error) {
+			fail := func(err error) (core.ToolResult, error) { return core.ToolResult{}, err }
 			rawPath := resolveReadPathArg(args)
 			if rawPath == "" {
-				return "", fmt.Errorf("read_invalid_path")
+				return fail(fmt.Errorf("read_invalid_path"))
 			}
 
 			offset, err := intArg(args, "offset", 0)
 			if err != nil || offset < 0 {
-				return "", fmt.Errorf("read_invalid_offset")
+				return fail(fmt.Errorf("read_invalid_offset"))
 			}
-			limit, err := intArg(args, "limit", -1)
-			if err != nil || limit == 0 || limit < -1 {
-				return "", fmt.Errorf("read_invalid_limit")
+			limit, err := intArg(args, "limit", 0)
+			if _, ok := args["limit"]; ok && (err != nil || limit == 0 || limit < -1) {
+				return fail(fmt.Errorf("read_invalid_limit"))
 			}
 
-			abs := resolveToolPath(base, rawPath)
+			abs, err := paths.resolve(rawPath)
+			if err != nil {
+				return fail(err)
+			}
 
 			info, err := os.Stat(abs)
 			if err != nil {
-				return "", fmt.Errorf("read_failed: %w", err)
+				return fail(fmt.Errorf("read_failed: %w", err))
 			}
 			if info.IsDir() {
-				return "", fmt.Errorf("read_is_directory")
+				return fail(fmt.Errorf("read_is_directory"))
 			}
 
-			b, err := os.ReadFile(abs)
+			f, err := os.Open(abs)
 			if err != nil {
-				return "", fmt.Errorf("read_failed: %w", err)
+				return fail(fmt.Errorf("read_failed: %w", err))
 			}
-			if !utf8.Valid(b) {
-				return "", fmt.Errorf("read_non_utf8")
+			defer f.Close()
+			head := make([]byte, readSniffBytes)
+			n, err := io.ReadFull(f, head)
+			if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+				return fail(fmt.Errorf("read_failed: %w", err))
+			}
+			head = head[:n]
+			if res, ok, err := readNonText(rawPath, abs, head, info.Size()); err != nil {
+				return fail(fmt.Errorf("read_failed: %w", err))
+			} else if ok {
+				return res, nil
 			}
 
-			lines := readLines(b)
-			if offset >= len(lines) {
-				return "", nil
+			lines := &lineReader{r: bufio.NewReader(io.MultiReader(bytes.NewReader(head), f)), keep: readMaxBytes + utf8.UTFMax}
+			out, invalid, err := readWindow(lines, offset, limit, boolArgLocal(args, "line_numbers", "lineNumbers"))
+			if err != nil {
+				return fail(fmt.Errorf("read_failed: %w", err))
 			}
-			end := len(lines)
-			if limit > 0 && offset+limit < end {
-				end = offset + limit
+			if invalid && out != "" {
+				out += "\n\n[invalid UTF-8 sequences shown as \uFFFD]"
 			}
-			return strings.Join(lines[offset:end], "\n"), nil
+			return core.ToolResult{Text: out}, nil
 		},
 	}
 }
 
+const (
+	readDefaultLineLimit = 2000
+	readMaxBytes         = 50 * 1024
+	// readSniffBytes is how much of a file is checked for NUL bytes, as git
+	// does, to tell binary files from text.
+	readSniffBytes    = 8000
+	readMaxImageBytes = 5 << 20
+)
+
+var readImageTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// readNonText handles images and binary files from the first bytes of the
+// file; ok is false for text. Only images are read in full.
+func readNonText(path, abs string, head []byte, size int64) (core.ToolResult, bool, error) {
+	sniffed, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	if mediaType, ok := readImageTypes[strings.ToLower(filepath.Ext(path))]; ok && sniffed == mediaType {
+		desc := fmt.Sprintf("image %s (%s, %d bytes)", path, mediaType, size)
+		if size > readMaxImageBytes {
+			return core.ToolResult{Text: fmt.Sprintf("%s is too large to attach (limit %dMB)", desc, readMaxImageBytes>>20)}, true, nil
+		}
+		b, err := os.ReadFile(abs)
+		if err != nil {
+			return core.ToolResult{}, false, err
+		}
+		return core.ToolResult{Text: desc, Images: []core.ToolImage{{MediaType: mediaType, Data: b}}}, true, nil
+	}
+	if bytes.IndexByte(head, 0) < 0 {
+		return core.ToolResult{}, false, nil
+	}
+	return core.ToolResult{Text: fmt.Sprintf("binary file %s (%s, %d bytes); contents not shown", path, sniffed, size)}, true, nil
+}
+
+// lineReader yields a file's lines without their line endings, keeping at
+// most keep bytes of each so one huge line never has to fit in memory.
+type lineReader struct {
+	r    *bufio.Reader
+	keep int
+}
+
+// next returns the next line; ok is false at the end of the file.
+func (l *lineReader) next() (line string, ok bool, err error) {
+	var buf []byte
+	cut := false
+	for {
+		chunk, err := l.r.ReadSlice('\n')
+		if len(chunk) > 0 {
+			ok = true
+		}
+		if err == nil {
+			chunk = chunk[:len(chunk)-1]
+		}
+		room := max(l.keep-len(buf), 0)
+		if len(chunk) > room {
+			cut = true
+			chunk = chunk[:room]
+		}
+		buf = append(buf, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return "", false, err
+		}
+		break
+	}
+	if cut {
+		// Drop a rune split by the cut so it is not taken for invalid UTF-8.
+		for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(buf[i]) {
+				if !utf8.FullRune(buf[i:]) {
+					buf = buf[:i]
+				}
+				break
+			}
+		}
+	}
+	return strings.TrimSuffix(string(buf), "\r"), ok, nil
+}
+
+// count consumes and counts the remaining lines.
+func (l *lineReader) count() (int, error) {
+	l.keep = 0
+	n := 0
+	for {
+		_, ok, err := l.next()
+		if err != nil || !ok {
+			return n, err
+		}
+		n++
+	}
+}
+
+// readWindow renders the lines from offset within the limit and the default
+// line and byte budgets. When a budget cuts the output short it ends with
+// the offset to continue from; an explicit limit is taken as asked. Lines
+// past the window are read only to count them for that footer. invalid
+// reports whether shown lines had invalid UTF-8, replaced with U+FFFD.
+func readWindow(lines *lineReader, offset, limit int, numbered bool) (out string, invalid bool, err error) {
+	for i := 0; i < offset; i++ {
+		if _, ok, err := lines.next(); err != nil || !ok {
+			return "", false, err
+		}
+	}
+	end := -1
+	budget := false
+	switch {
+	case limit > 0:
+		end = offset + limit
+	case limit == 0:
+		end = offset + readDefaultLineLimit
+		budget = true
+	}
+	footer := func(b *strings.Builder, i, rest int) {
+		fmt.Fprintf(b, "\n\n[showing lines %d-%d of %d; use offset=%d to continue]", offset+1, i, i+rest, i)
+	}
+
+	var b strings.Builder
+	size := 0
+	i := offset
+	for ; end < 0 || i < end; i++ {
+		line, ok, err := lines.next()
+		if err != nil {
+			return "", false, err
+		}
+		if !ok {
+			return b.String(), invalid, nil
+		}
+		if !utf8.ValidString(line) {
+			line = strings.ToValidUTF8(line, "\uFFFD")
+			invalid = true
+		}
+		if numbered {
+			line = strconv.Itoa(i+1) + "\t" + line
+		}
+		if size+len(line) <= readMaxBytes {
+			if i > offset {
+				b.WriteByte('\n')
+			}
+			b.WriteString(line)
+			size += len(line) + 1
+			continue
+		}
+		if i > offset {
+			rest, err := lines.count()
+			if err != nil {
+				return "", false, err
+			}
+			footer(&b, i, rest+1)
+			return b.String(), invalid, nil
+		}
+		// A single line over the budget is cut rather than skipped.
+		b.WriteString(truncateUTF8(line, readMaxBytes))
+		if _, more, err := lines.next(); err != nil {
+			return "", false, err
+		} else if more {
+			fmt.Fprintf(&b, "\n\n[line %d cut at %dKB; use offset=%d to continue]", offset+1, readMaxBytes/1024, i+1)
+		} else {
+			fmt.Fprintf(&b, "\n\n[line %d cut at %dKB]", offset+1, readMaxBytes/1024)
+		}
+		return b.String(), invalid, nil
+	}
+	if budget {
+		rest, err := lines.count()
+		if err != nil {
+			return "", false, err
+		}
+		if rest > 0 {
+			footer(&b, i, rest)
+		}
+	}
+	return b.String(), invalid, nil
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a rune.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
 func resolveReadPathArg(args map[string]any) string {
 	keys := []string{"path", "file_path", "filePath", "filepath", "file", "target_path", "targetPath"}
 	for _, key := range keys {
@@ -94,14 +310,32 @@ func normalizeReadPathValue(v any) string {
 }
 
 func DefaultTools(cwd string) []core.Tool {
+	return DefaultToolsWithConfig(Config{Workdir: cwd})
+}
+
+func DefaultToolsWithConfig(cfg Config) []core.Tool {
+	paths := newPathResolver(cfg)
+	shell := cfg.Shell
+	if shell.Path == "" {
+		shell = defaultShell()
+	}
+	profile := cfg.ExecProfile
+	if profile.Name == "" {
+		profile = DefaultExecProfile()
+	}
 	return []core.Tool{
-		NewReadTool(cwd),
-		NewBashTool(cwd),
-		NewEditTool(cwd),
-		NewWriteTool(cwd),
-		NewGrepTool(cwd),
-		NewLSTool(cwd),
-		NewFindTool(cwd),
+		newReadTool(paths),
+		newBashTool(paths.base, shell, profile, cfg.PersistentBash),
+		newEditTool(paths),
+		newWriteTool(paths),
+		newApplyPatchTool(paths),
+		newGrepTool(paths),
+		newLSTool(paths),
+		newFindTool(paths),
+		newProcessTool(paths, shell, profile),
+		newFetchTool(http.DefaultTransport, cfg.FetchAllowedDomains),
+		newGitTool(paths, profile),
+		newSymbolsTool(paths),
 	}
 }
 
@@ -123,17 +357,3 @@ func intArg(args map[string]any, key string, def int) (int, error) {
 		return 0, fmt.Errorf("invalid_int_arg")
 	}
 }
-
-func readLines(b []byte) []string {
-	scanner := bufio.NewScanner(bytes.NewReader(b))
-	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
-
-	lines := make([]string, 0, 64)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
-	}
-	if len(b) == 0 {
-		return []string{}
-	}
-	return lines
-}
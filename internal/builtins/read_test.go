@@ -2,10 +2,12 @@ package builtins
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"nous/internal/core"
 	"nous/internal/provider"
@@ -78,6 +80,121 @@ func TestReadToolOffsetLimit(t *testing.T) {
 	}
 }
 
+func TestReadToolLineNumbersAndBudgets(t *testing.T) {
+	dir := t.TempDir()
+	var lines []string
+	for i := 1; i <= 2500; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	if err := os.WriteFile(filepath.Join(dir, "long.txt"), []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tool := NewReadTool(dir)
+
+	got, err := tool.Execute(context.Background(), map[string]any{"path": "long.txt", "line_numbers": true, "offset": 1, "limit": 2})
+	if err != nil || got != "2\tline 2\n3\tline 3" {
+		t.Fatalf("unexpected numbered output: %q err=%v", got, err)
+	}
+	got, err = tool.Execute(context.Background(), map[string]any{"path": "long.txt"})
+	if err != nil || !strings.HasSuffix(got, "line 2000\n\n[showing lines 1-2000 of 2500; use offset=2000 to continue]") {
+		t.Fatalf("expected default line budget footer, got tail %q err=%v", got[len(got)-80:], err)
+	}
+	got, _ = tool.Execute(context.Background(), map[string]any{"path": "long.txt", "offset": 2000})
+	if !strings.HasPrefix(got, "line 2001\n") || strings.Contains(got, "[showing") {
+		t.Fatalf("expected the rest of the file without footer, got tail %q", got[len(got)-40:])
+	}
+
+	wide := strings.Repeat(strings.Repeat("x", 1023)+"\n", 100)
+	if err := os.WriteFile(filepath.Join(dir, "wide.txt"), []byte(wide), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = tool.Execute(context.Background(), map[string]any{"path": "wide.txt", "limit": -1})
+	if len(got) > readMaxBytes+100 || !strings.HasSuffix(got, "[showing lines 1-50 of 100; use offset=50 to continue]") {
+		t.Fatalf("expected byte budget footer, got %d bytes ending %q", len(got), got[len(got)-60:])
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "one.txt"), []byte(strings.Repeat("é", readMaxBytes)+"\nnext\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = tool.Execute(context.Background(), map[string]any{"path": "one.txt"})
+	if !utf8.ValidString(got) || !strings.HasSuffix(got, "[line 1 cut at 50KB; use offset=1 to continue]") {
+		t.Fatalf("expected an oversized line to be cut, got tail %q", got[len(got)-60:])
+	}
+}
+
+func TestReadToolStreamsLargeFiles(t *testing.T) {
+	dir := t.TempDir()
+	huge := "a" + strings.Repeat("é", 4*readMaxBytes)
+	if err := os.WriteFile(filepath.Join(dir, "huge.txt"), []byte(huge+"\r\nsecond\r\nthird"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tool := NewReadTool(dir)
+
+	got, err := tool.Execute(context.Background(), map[string]any{"path": "huge.txt"})
+	if err != nil || !utf8.ValidString(got) || strings.Contains(got, "\uFFFD") || !strings.HasSuffix(got, "[line 1 cut at 50KB; use offset=1 to continue]") {
+		t.Fatalf("expected a cleanly cut first line, got tail %q err=%v", got[max(len(got)-60, 0):], err)
+	}
+	got, err = tool.Execute(context.Background(), map[string]any{"path": "huge.txt", "offset": 1})
+	if err != nil || got != "second\nthird" {
+		t.Fatalf("expected the lines after the huge one, got %q err=%v", got, err)
+	}
+
+	var b strings.Builder
+	for i := 1; i <= 3000; i++ {
+		fmt.Fprintf(&b, "%s %d\n", strings.Repeat("y", 100), i)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "many.txt"), []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = tool.Execute(context.Background(), map[string]any{"path": "many.txt", "offset": 10, "limit": 5})
+	if !strings.HasPrefix(got, strings.Repeat("y", 100)+" 11\n") || strings.Contains(got, "[showing") {
+		t.Fatalf("an explicit limit must not need a footer, got %q", got)
+	}
+	got, _ = tool.Execute(context.Background(), map[string]any{"path": "many.txt"})
+	if !strings.HasSuffix(got, "[showing lines 1-488 of 3000; use offset=488 to continue]") {
+		t.Fatalf("expected byte budget footer counting every line, got tail %q", got[len(got)-80:])
+	}
+}
+
+func TestReadToolDescribesBinaryAndReturnsImages(t *testing.T) {
+	dir := t.TempDir()
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...)
+	files := map[string][]byte{
+		"a.png":      png,
+		"a.bin":      {0x7f, 'E', 'L', 'F', 0, 1, 2},
+		"fake.png":   []byte("not an image"),
+		"latin1.txt": []byte("caf\xe9\n"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tool := NewReadTool(dir).(core.DetailedTool)
+	read := func(path string) core.ToolResult {
+		t.Helper()
+		res, err := tool.ExecuteWithDetails(context.Background(), map[string]any{"path": path})
+		if err != nil {
+			t.Fatalf("read %s failed: %v", path, err)
+		}
+		return res
+	}
+
+	res := read("a.png")
+	if res.Text != "image a.png (image/png, 40 bytes)" || len(res.Images) != 1 || res.Images[0].MediaType != "image/png" {
+		t.Fatalf("expected image result, got %+v", res)
+	}
+	if res := read("a.bin"); res.Text != "binary file a.bin (application/octet-stream, 7 bytes); contents not shown" || len(res.Images) != 0 {
+		t.Fatalf("expected binary description, got %+v", res)
+	}
+	if res := read("fake.png"); res.Text != "not an image" {
+		t.Fatalf("a text file named .png must read as text, got %+v", res)
+	}
+	if res := read("latin1.txt"); res.Text != "caf\uFFFD\n\n[invalid UTF-8 sequences shown as \uFFFD]" {
+		t.Fatalf("expected replaced invalid UTF-8, got %q", res.Text)
+	}
+}
+
 func TestReadToolNotFound(t *testing.T) {
 	dir := t.TempDir()
 	_, err := NewReadTool(dir).Execute(context.Background(), map[string]any{"path": "missing.txt"})
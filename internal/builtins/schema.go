@@ -0,0 +1,68 @@
+package builtins
+
+var pathAliases = []string{"file_path", "filePath", "filepath", "file", "target_path", "targetPath"}
+
+var dirAliases = []string{"dir", "directory", "target_path", "targetPath"}
+
+func objectSchema(properties map[string]any, required ...string) map[string]any {
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": true,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func stringProperty(description string) map[string]any {
+	return map[string]any{"type": "string", "description": description}
+}
+
+// requiredStringProperty rejects blank values, which the validator then
+// reports as a missing argument.
+func requiredStringProperty(description string) map[string]any {
+	return map[string]any{"type": "string", "description": description, "minLength": 1}
+}
+
+func numberProperty(description string) map[string]any {
+	return map[string]any{"type": "number", "description": description}
+}
+
+func integerProperty(description string) map[string]any {
+	return map[string]any{"type": "integer", "description": description}
+}
+
+func booleanProperty(description string) map[string]any {
+	return map[string]any{"type": "boolean", "description": description}
+}
+
+func arrayProperty(description string, items map[string]any) map[string]any {
+	return map[string]any{"type": "array", "description": description, "items": items}
+}
+
+func withAliases(property map[string]any, aliases ...string) map[string]any {
+	property["x-aliases"] = aliases
+	return property
+}
+
+func withDefault(property map[string]any, value any) map[string]any {
+	property["default"] = value
+	return property
+}
+
+func withEnum(property map[string]any, values ...string) map[string]any {
+	property["enum"] = values
+	return property
+}
+
+func withMinItems(property map[string]any, n int) map[string]any {
+	property["minItems"] = n
+	return property
+}
+
+func withMinimum(property map[string]any, minimum int) map[string]any {
+	property["minimum"] = minimum
+	return property
+}
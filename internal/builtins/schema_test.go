@@ -0,0 +1,56 @@
+package builtins
+
+import (
+	"slices"
+	"testing"
+
+	"nous/internal/core"
+)
+
+func TestDefaultToolsDeclareSchemas(t *testing.T) {
+	wantRequired := map[string][]string{
+		"read":        {"path"},
+		"bash":        {"command"},
+		"edit":        {"path"},
+		"write":       {"path", "content"},
+		"apply_patch": {"patch"},
+		"grep":        {"pattern"},
+		"ls":          nil,
+		"find":        {"query"},
+		"process":     {"action"},
+		"fetch":       {"url"},
+		"git":         {"action"},
+		"symbols":     {"action"},
+	}
+	tools := DefaultTools(t.TempDir())
+	if len(tools) != len(wantRequired) {
+		t.Fatalf("unexpected default tool count: %d", len(tools))
+	}
+	for _, tool := range tools {
+		described, ok := tool.(core.DescribedTool)
+		if !ok {
+			t.Fatalf("tool %s must declare a schema", tool.Name())
+		}
+		if described.Description() == "" {
+			t.Fatalf("tool %s must declare a description", tool.Name())
+		}
+		schema := described.Schema()
+		if schema["type"] != "object" {
+			t.Fatalf("tool %s schema must be an object: %#v", tool.Name(), schema)
+		}
+		want, ok := wantRequired[tool.Name()]
+		if !ok {
+			t.Fatalf("unexpected default tool: %s", tool.Name())
+		}
+		got, _ := schema["required"].([]string)
+		if !slices.Equal(got, want) {
+			t.Fatalf("tool %s required=%v, want %v", tool.Name(), got, want)
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for _, field := range want {
+			if _, ok := props[field]; !ok {
+				t.Fatalf("tool %s required field %s has no property", tool.Name(), field)
+			}
+		}
+	}
+}
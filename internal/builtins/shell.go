@@ -0,0 +1,77 @@
+package builtins
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Shell is the program the bash tool runs commands with. Login shells read
+// profile files on start; non-login shells skip them.
+type Shell struct {
+	Path  string
+	Login bool
+}
+
+var shellFallbacks = []string{"/bin/bash", "/bin/sh"}
+
+// posixShells are the $SHELL values the bash tool can drive; others (fish,
+// csh, ...) are skipped during auto-detection.
+var posixShells = map[string]struct{}{
+	"sh": {}, "bash": {}, "zsh": {}, "dash": {}, "ksh": {}, "mksh": {}, "ash": {},
+}
+
+// ResolveShell picks the shell for the bash tool. An explicit path (or name
+// on $PATH) must exist; otherwise $SHELL is used when it is a POSIX shell,
+// then /bin/bash, then /bin/sh.
+func ResolveShell(path string, login bool) (Shell, error) {
+	if path = strings.TrimSpace(path); path != "" {
+		resolved, err := exec.LookPath(path)
+		if err != nil {
+			return Shell{}, fmt.Errorf("bash_shell_not_found: %s", path)
+		}
+		return Shell{Path: resolved, Login: login}, nil
+	}
+	candidates := make([]string, 0, len(shellFallbacks)+1)
+	if env := strings.TrimSpace(os.Getenv("SHELL")); env != "" {
+		if _, ok := posixShells[filepath.Base(env)]; ok {
+			candidates = append(candidates, env)
+		}
+	}
+	candidates = append(candidates, shellFallbacks...)
+	for _, candidate := range candidates {
+		if isExecutableFile(candidate) {
+			return Shell{Path: candidate, Login: login}, nil
+		}
+	}
+	return Shell{}, fmt.Errorf("bash_shell_not_found")
+}
+
+func defaultShell() Shell {
+	shell, err := ResolveShell("", true)
+	if err != nil {
+		return Shell{Path: "/bin/sh", Login: true}
+	}
+	return shell
+}
+
+func (s Shell) commandArgs(command string) []string {
+	if s.Login {
+		return []string{"-lc", command}
+	}
+	return []string{"-c", command}
+}
+
+func (s Shell) sessionArgs() []string {
+	if s.Login {
+		return []string{"-l", "-s"}
+	}
+	return []string{"-s"}
+}
+
+func isExecutableFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir() && info.Mode()&0o111 != 0
+}
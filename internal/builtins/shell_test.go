@@ -0,0 +1,82 @@
+package builtins
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFakeShell(t *testing.T, dir, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatalf("write fake shell failed: %v", err)
+	}
+	return path
+}
+
+func TestResolveShellPrefersExplicitThenPosixShellEnv(t *testing.T) {
+	dir := t.TempDir()
+	zsh := writeFakeShell(t, dir, "zsh")
+	fish := writeFakeShell(t, dir, "fish")
+
+	got, err := ResolveShell(zsh, false)
+	if err != nil || got.Path != zsh || got.Login {
+		t.Fatalf("expected explicit shell, got %+v err=%v", got, err)
+	}
+	if _, err := ResolveShell(filepath.Join(dir, "missing"), true); err == nil || !strings.HasPrefix(err.Error(), "bash_shell_not_found") {
+		t.Fatalf("expected bash_shell_not_found, got %v", err)
+	}
+
+	t.Setenv("SHELL", zsh)
+	if got, err := ResolveShell("", true); err != nil || got.Path != zsh || !got.Login {
+		t.Fatalf("expected $SHELL, got %+v err=%v", got, err)
+	}
+
+	t.Setenv("SHELL", fish)
+	got, err = ResolveShell("", true)
+	if err != nil {
+		t.Skipf("no fallback shell available: %v", err)
+	}
+	if got.Path == fish {
+		t.Fatalf("non-POSIX $SHELL must be skipped")
+	}
+	if got.Path != "/bin/bash" && got.Path != "/bin/sh" {
+		t.Fatalf("expected /bin/bash or /bin/sh fallback, got %q", got.Path)
+	}
+}
+
+func TestShellArgsFollowLoginMode(t *testing.T) {
+	login := Shell{Path: "/bin/sh", Login: true}
+	plain := Shell{Path: "/bin/sh"}
+	if strings.Join(login.commandArgs("ls"), " ") != "-lc ls" || strings.Join(plain.commandArgs("ls"), " ") != "-c ls" {
+		t.Fatalf("unexpected command args: %v %v", login.commandArgs("ls"), plain.commandArgs("ls"))
+	}
+	if strings.Join(login.sessionArgs(), " ") != "-l -s" || strings.Join(plain.sessionArgs(), " ") != "-s" {
+		t.Fatalf("unexpected session args: %v %v", login.sessionArgs(), plain.sessionArgs())
+	}
+}
+
+func TestBashToolRunsWithPosixShAndReportsState(t *testing.T) {
+	if !isExecutableFile("/bin/sh") {
+		t.Skip("/bin/sh not available")
+	}
+	dir := t.TempDir()
+	tools := DefaultToolsWithConfig(Config{Workdir: dir, Shell: Shell{Path: "/bin/sh"}})
+	var bash bashTool
+	for _, tool := range tools {
+		if b, ok := tool.(bashTool); ok {
+			bash = b
+		}
+	}
+	out, err := bash.Execute(context.Background(), map[string]any{"command": "printf 'sh-ok'"})
+	if err != nil || out != "sh-ok" {
+		t.Fatalf("expected /bin/sh to run the command, out=%q err=%v", out, err)
+	}
+	state := bash.ToolState()
+	if state["shell"] != "/bin/sh" || state["login"] != false || state["persistent"] != false {
+		t.Fatalf("unexpected tool state: %+v", state)
+	}
+}
@@ -0,0 +1,376 @@
+package builtins
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"nous/internal/core"
+)
+
+const symbolsMaxValueBytes = 60
+
+// goSymbol is a declaration found in a Go file. Members (struct fields and
+// interface methods) are only used for definition lookups.
+type goSymbol struct {
+	name      string
+	recv      string
+	start     int
+	end       int
+	signature string
+	member    bool
+}
+
+func NewSymbolsTool(cwd string) core.Tool {
+	return newSymbolsTool(newPathResolver(Config{Workdir: cwd}))
+}
+
+func newSymbolsTool(paths *pathResolver) core.Tool {
+	return core.ToolFunc{
+		ToolName:        "symbols",
+		ToolDescription: "Navigate Go code: outline the declarations of a file or package, find where a symbol is defined, or list references to an identifier.",
+		ToolSchema: objectSchema(map[string]any{
+			"action": withEnum(requiredStringProperty("outline lists declarations in a file or package directory; definition finds declarations named name; references lists lines using the identifier name."), "outline", "definition", "references"),
+			"path":   withAliases(withDefault(stringProperty("File or directory. outline reads one directory without recursing; definition and references search the tree below it."), "."), dirAliases...),
+			"name":   withAliases(stringProperty("Identifier to look up, such as \"Engine\" or \"Engine.Run\" for a method or field."), "symbol", "query"),
+			"tests":  withDefault(booleanProperty("Include _test.go files."), true),
+			"limit":  withAliases(withMinimum(integerProperty("Maximum number of results. Defaults to 200."), 1), "max_results", "maxResults"),
+		}, "action"),
+		Run: func(ctx context.Context, args map[string]any) (string, error) {
+			action, _ := args["action"].(string)
+			action = strings.TrimSpace(action)
+			if action != "outline" && action != "definition" && action != "references" {
+				return "", fmt.Errorf("symbols_invalid_action: %s", action)
+			}
+			limit, err := intArg(args, "limit", 200)
+			if err != nil || limit <= 0 {
+				return "", fmt.Errorf("symbols_invalid_limit")
+			}
+			tests := true
+			if v, ok := args["tests"].(bool); ok {
+				tests = v
+			}
+
+			root, _ := args["path"].(string)
+			root = strings.TrimSpace(root)
+			if root == "" {
+				root = "."
+			}
+			absRoot, err := paths.resolve(root)
+			if err != nil {
+				return "", err
+			}
+			info, err := os.Stat(absRoot)
+			if err != nil {
+				return "", fmt.Errorf("symbols_failed: %w", err)
+			}
+
+			if action == "outline" {
+				return outlineGo(absRoot, info.IsDir(), tests, limit)
+			}
+
+			name, _ := args["name"].(string)
+			name = strings.TrimSpace(name)
+			recv, member, qualified := strings.Cut(name, ".")
+			if !qualified {
+				recv, member = "", name
+			}
+			if !token.IsIdentifier(member) || (qualified && !token.IsIdentifier(recv)) {
+				return "", fmt.Errorf("symbols_invalid_name: %q", name)
+			}
+
+			var out []string
+			total := 0
+			visit := func(path, rel string) error {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				src, err := os.ReadFile(path)
+				if err != nil || !bytes.Contains(src, []byte(member)) {
+					return nil
+				}
+				fset := token.NewFileSet()
+				// Files that do not parse are skipped; partial trees give
+				// misleading line ranges.
+				file, err := parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+				if err != nil {
+					return nil
+				}
+				var lines []string
+				if action == "definition" {
+					for _, sym := range goFileSymbols(fset, file) {
+						if sym.name == member && (!qualified || sym.recv == recv) {
+							lines = append(lines, formatGoSymbol(rel, sym))
+						}
+					}
+				} else {
+					lines = goReferences(fset, file, src, rel, member)
+				}
+				total += len(lines)
+				for _, line := range lines {
+					if len(out) < limit {
+						out = append(out, line)
+					}
+				}
+				return nil
+			}
+
+			if !info.IsDir() {
+				if !strings.HasSuffix(absRoot, ".go") {
+					return "", fmt.Errorf("symbols_not_go_file: %s", root)
+				}
+				if err := visit(absRoot, filepath.Base(absRoot)); err != nil {
+					return "", fmt.Errorf("symbols_failed: %w", err)
+				}
+				return joinSymbolResults(out, total), nil
+			}
+			walkErr := walkTree(paths, absRoot, walkOptions{maxDepth: -1}, func(path, rel string, d fs.DirEntry) error {
+				if d.IsDir() {
+					// Skipped by the go tool as well.
+					if n := d.Name(); n == "testdata" || strings.HasPrefix(n, "_") || strings.HasPrefix(n, ".") {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if !isGoSource(d.Name(), tests) {
+					return nil
+				}
+				return visit(path, rel)
+			})
+			if walkErr != nil && !errors.Is(walkErr, filepath.SkipAll) {
+				return "", fmt.Errorf("symbols_failed: %w", walkErr)
+			}
+			return joinSymbolResults(out, total), nil
+		},
+	}
+}
+
+func isGoSource(name string, tests bool) bool {
+	return strings.HasSuffix(name, ".go") && (tests || !strings.HasSuffix(name, "_test.go"))
+}
+
+// outlineGo lists the top-level declarations of one file, or of every Go
+// file directly inside a directory.
+func outlineGo(absRoot string, isDir, tests bool, limit int) (string, error) {
+	files := []string{absRoot}
+	if isDir {
+		entries, err := os.ReadDir(absRoot)
+		if err != nil {
+			return "", fmt.Errorf("symbols_failed: %w", err)
+		}
+		files = files[:0]
+		for _, e := range entries {
+			if !e.IsDir() && isGoSource(e.Name(), tests) {
+				files = append(files, filepath.Join(absRoot, e.Name()))
+			}
+		}
+		if len(files) == 0 {
+			return "", fmt.Errorf("symbols_no_go_files: %s", absRoot)
+		}
+	} else if !strings.HasSuffix(absRoot, ".go") {
+		return "", fmt.Errorf("symbols_not_go_file: %s", absRoot)
+	}
+
+	var out []string
+	total := 0
+	for _, path := range files {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return "", fmt.Errorf("symbols_parse_failed: %v", err)
+		}
+		rel := filepath.Base(path)
+		for _, sym := range goFileSymbols(fset, file) {
+			if sym.member {
+				continue
+			}
+			total++
+			if len(out) < limit {
+				out = append(out, formatGoSymbol(rel, sym))
+			}
+		}
+	}
+	return joinSymbolResults(out, total), nil
+}
+
+func joinSymbolResults(out []string, total int) string {
+	if total > len(out) {
+		out = append(out, fmt.Sprintf("[showing %d of %d results; raise limit to see more]", len(out), total))
+	}
+	return strings.Join(out, "\n")
+}
+
+func formatGoSymbol(rel string, sym goSymbol) string {
+	return fmt.Sprintf("%s:%d-%d: %s", rel, sym.start, sym.end, sym.signature)
+}
+
+// goFileSymbols returns a file's declarations in source order.
+func goFileSymbols(fset *token.FileSet, file *ast.File) []goSymbol {
+	var syms []goSymbol
+	lines := func(n ast.Node) (int, int) {
+		return fset.Position(n.Pos()).Line, fset.Position(n.End()).Line
+	}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			sym := goSymbol{name: d.Name.Name}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				sym.recv = goReceiverName(d.Recv.List[0].Type)
+			}
+			sym.start, sym.end = lines(d)
+			sig := *d
+			sig.Doc, sig.Body = nil, nil
+			sym.signature = goNodeString(fset, &sig)
+			syms = append(syms, sym)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				// A lone spec spans its keyword; grouped specs only themselves.
+				var span ast.Node = spec
+				if !d.Lparen.IsValid() {
+					span = d
+				}
+				start, end := lines(span)
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					syms = append(syms, goSymbol{name: s.Name.Name, start: start, end: end, signature: "type " + goTypeSignature(fset, s)})
+					syms = append(syms, goMemberSymbols(fset, s)...)
+				case *ast.ValueSpec:
+					for i, n := range s.Names {
+						if n.Name == "_" {
+							continue
+						}
+						sig := d.Tok.String() + " " + n.Name
+						if s.Type != nil {
+							sig += " " + goNodeString(fset, s.Type)
+						}
+						if i < len(s.Values) {
+							if v := goNodeString(fset, s.Values[i]); len(v) <= symbolsMaxValueBytes {
+								sig += " = " + v
+							}
+						}
+						syms = append(syms, goSymbol{name: n.Name, start: start, end: end, signature: sig})
+					}
+				}
+			}
+		}
+	}
+	return syms
+}
+
+// goTypeSignature prints a type spec with struct and interface bodies
+// reduced to the keyword.
+func goTypeSignature(fset *token.FileSet, s *ast.TypeSpec) string {
+	sig := *s
+	sig.Doc, sig.Comment = nil, nil
+	switch s.Type.(type) {
+	case *ast.StructType:
+		sig.Type = ast.NewIdent("struct")
+	case *ast.InterfaceType:
+		sig.Type = ast.NewIdent("interface")
+	}
+	return goNodeString(fset, &sig)
+}
+
+func goMemberSymbols(fset *token.FileSet, s *ast.TypeSpec) []goSymbol {
+	var fields *ast.FieldList
+	kind := "field"
+	switch t := s.Type.(type) {
+	case *ast.StructType:
+		fields = t.Fields
+	case *ast.InterfaceType:
+		fields, kind = t.Methods, "method"
+	default:
+		return nil
+	}
+	var syms []goSymbol
+	for _, f := range fields.List {
+		typ := goNodeString(fset, f.Type)
+		if ft, ok := f.Type.(*ast.FuncType); ok && kind == "method" {
+			typ = strings.TrimPrefix(goNodeString(fset, ft), "func")
+		} else {
+			typ = " " + typ
+		}
+		for _, n := range f.Names {
+			syms = append(syms, goSymbol{
+				name:      n.Name,
+				recv:      s.Name.Name,
+				start:     fset.Position(f.Pos()).Line,
+				end:       fset.Position(f.End()).Line,
+				signature: fmt.Sprintf("%s %s.%s%s", kind, s.Name.Name, n.Name, typ),
+				member:    true,
+			})
+		}
+	}
+	return syms
+}
+
+func goReceiverName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// goReferences lists each line where name appears as an identifier, so
+// matches inside strings and comments are left out.
+func goReferences(fset *token.FileSet, file *ast.File, src []byte, rel, name string) []string {
+	var lineNums []int
+	seen := map[int]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == name {
+			if line := fset.Position(id.Pos()).Line; !seen[line] {
+				seen[line] = true
+				lineNums = append(lineNums, line)
+			}
+		}
+		return true
+	})
+	sort.Ints(lineNums)
+	srcLines := strings.Split(string(src), "\n")
+	out := make([]string, 0, len(lineNums))
+	for _, n := range lineNums {
+		text := ""
+		if n-1 < len(srcLines) {
+			text = truncateGrepLine(strings.TrimSpace(strings.TrimSuffix(srcLines[n-1], "\r")))
+		}
+		out = append(out, fmt.Sprintf("%s:%d: %s", rel, n, text))
+	}
+	return out
+}
+
+// goNodeString prints a node on one line, folding signatures that the
+// source splits across lines.
+func goNodeString(fset *token.FileSet, node any) string {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, node); err != nil {
+		return ""
+	}
+	lines := strings.Split(buf.String(), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	s := strings.ReplaceAll(strings.Join(lines, " "), "( ", "(")
+	return strings.ReplaceAll(s, ", )", ")")
+}
@@ -0,0 +1,144 @@
+package builtins
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const symbolsTestSource = `package shapes
+
+import "fmt"
+
+// Shape has an area.
+type Shape interface {
+	Area() float64
+}
+
+type Rect struct {
+	W, H float64
+}
+
+type Pair[K comparable, V any] struct{ Key K }
+
+type ID = string
+
+const (
+	Unit  = 1
+	other = "x"
+)
+
+var registry = map[string]Shape{}
+
+func (r *Rect) Area() float64 {
+	return r.W * r.H
+}
+
+func NewRect(w,
+	h float64) *Rect {
+	fmt.Println("Rect")
+	return &Rect{W: w, H: h}
+}
+`
+
+func TestSymbolsToolOutline(t *testing.T) {
+	dir := t.TempDir()
+	mustWrite(t, filepath.Join(dir, "shapes.go"), symbolsTestSource)
+	mustWrite(t, filepath.Join(dir, "shapes_test.go"), "package shapes\n\nfunc helper() {}\n")
+	tool := NewSymbolsTool(dir)
+
+	out, err := tool.Execute(context.Background(), map[string]any{"action": "outline", "path": "shapes.go"})
+	if err != nil {
+		t.Fatalf("outline failed: %v", err)
+	}
+	want := strings.Join([]string{
+		"shapes.go:6-8: type Shape interface",
+		"shapes.go:10-12: type Rect struct",
+		"shapes.go:14-14: type Pair[K comparable, V any] struct",
+		"shapes.go:16-16: type ID = string",
+		"shapes.go:19-19: const Unit = 1",
+		`shapes.go:20-20: const other = "x"`,
+		"shapes.go:23-23: var registry = map[string]Shape{}",
+		"shapes.go:25-27: func (r *Rect) Area() float64",
+		"shapes.go:29-33: func NewRect(w, h float64) *Rect",
+	}, "\n")
+	if out != want {
+		t.Fatalf("unexpected outline:\n%s\n--- want ---\n%s", out, want)
+	}
+
+	out, err = tool.Execute(context.Background(), map[string]any{"action": "outline", "limit": 2})
+	if err != nil {
+		t.Fatalf("package outline failed: %v", err)
+	}
+	if !strings.HasSuffix(out, "[showing 2 of 10 results; raise limit to see more]") {
+		t.Fatalf("package outline must include test files and report the limit:\n%s", out)
+	}
+	out, _ = tool.Execute(context.Background(), map[string]any{"action": "outline", "tests": false})
+	if strings.Contains(out, "helper") {
+		t.Fatalf("tests=false must skip _test.go files:\n%s", out)
+	}
+}
+
+func TestSymbolsToolDefinitionAndReferences(t *testing.T) {
+	dir := t.TempDir()
+	mustWrite(t, filepath.Join(dir, "shapes", "shapes.go"), symbolsTestSource)
+	mustWrite(t, filepath.Join(dir, "main.go"), "package main\n\n// Rect in a comment.\nvar r = shapes.NewRect(1, 2)\nvar a = r.Area()\n")
+	mustWrite(t, filepath.Join(dir, "testdata", "x.go"), "package x\n\ntype Rect int\n")
+	mustWrite(t, filepath.Join(dir, "broken.go"), "package main\n\nfunc Rect( {\n")
+	tool := NewSymbolsTool(dir)
+
+	cases := map[string]string{
+		"Rect":       filepath.Join("shapes", "shapes.go") + ":10-12: type Rect struct",
+		"Rect.Area":  filepath.Join("shapes", "shapes.go") + ":25-27: func (r *Rect) Area() float64",
+		"Shape.Area": filepath.Join("shapes", "shapes.go") + ":7-7: method Shape.Area() float64",
+		"Rect.H":     filepath.Join("shapes", "shapes.go") + ":11-11: field Rect.H float64",
+		"Missing":    "",
+	}
+	for name, want := range cases {
+		out, err := tool.Execute(context.Background(), map[string]any{"action": "definition", "name": name})
+		if err != nil {
+			t.Fatalf("definition %s failed: %v", name, err)
+		}
+		if out != want {
+			t.Fatalf("definition %s: got %q, want %q", name, out, want)
+		}
+	}
+
+	out, err := tool.Execute(context.Background(), map[string]any{"action": "references", "name": "Rect"})
+	if err != nil {
+		t.Fatalf("references failed: %v", err)
+	}
+	shapes := filepath.Join("shapes", "shapes.go")
+	want := strings.Join([]string{
+		shapes + ":10: type Rect struct {",
+		shapes + ":25: func (r *Rect) Area() float64 {",
+		shapes + ":30: h float64) *Rect {",
+		shapes + ":32: return &Rect{W: w, H: h}",
+	}, "\n")
+	if out != want {
+		t.Fatalf("unexpected references:\n%s\n--- want ---\n%s", out, want)
+	}
+}
+
+func TestSymbolsToolRejectsBadInput(t *testing.T) {
+	dir := t.TempDir()
+	mustWrite(t, filepath.Join(dir, "notes.txt"), "x")
+	mustWrite(t, filepath.Join(dir, "bad.go"), "package x\nfunc {\n")
+	tool := NewSymbolsTool(dir)
+	cases := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"action": "rename"}, "symbols_invalid_action: rename"},
+		{map[string]any{"action": "definition", "name": "a b"}, `symbols_invalid_name: "a b"`},
+		{map[string]any{"action": "references"}, `symbols_invalid_name: ""`},
+		{map[string]any{"action": "outline", "path": "notes.txt"}, "symbols_not_go_file"},
+		{map[string]any{"action": "outline", "path": "bad.go"}, "symbols_parse_failed"},
+	}
+	for _, tc := range cases {
+		if _, err := tool.Execute(context.Background(), tc.args); err == nil || !strings.HasPrefix(err.Error(), tc.want) {
+			t.Fatalf("%v: expected %s, got %v", tc.args, tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package builtins
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"nous/internal/glob"
+)
+
+// ignoreFileNames are read in every directory walked. Rules from deeper
+// directories, and later rules within a file, take precedence, as in git.
+var ignoreFileNames = []string{".gitignore", ".ignore"}
+
+type ignoreRule struct {
+	// base is the absolute directory the rule's file lives in.
+	base    string
+	pattern string
+	negate  bool
+	dirOnly bool
+	// anchored patterns contain a slash and match from base; others match
+	// a name at any depth.
+	anchored bool
+}
+
+type ignoreRules []ignoreRule
+
+func parseIgnoreRules(base string, data []byte) ignoreRules {
+	var rules ignoreRules
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimRight(strings.TrimSuffix(sc.Text(), "\r"), " \t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		rule.anchored = strings.Contains(line, "/")
+		rule.pattern = strings.TrimPrefix(line, "/")
+		if rule.pattern == "" || !glob.Valid(rule.pattern) {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// withDir returns the rules extended by dir's ignore files. The receiver is
+// never modified, so sibling directories can share it.
+func (r ignoreRules) withDir(dir string) ignoreRules {
+	out := r
+	for _, name := range ignoreFileNames {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		if added := parseIgnoreRules(dir, data); len(added) > 0 {
+			out = append(out[:len(out):len(out)], added...)
+		}
+	}
+	return out
+}
+
+func (r ignoreRules) ignored(abs string, isDir bool) bool {
+	for i := len(r) - 1; i >= 0; i-- {
+		rule := r[i]
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		rel, err := filepath.Rel(rule.base, abs)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		target := rel
+		if !rule.anchored {
+			target = path.Base(rel)
+		}
+		if glob.Match(rule.pattern, target) {
+			return !rule.negate
+		}
+	}
+	return false
+}
+
+// ancestorIgnoreRules loads the ignore rules that apply to root from its
+// parent directories, up to the enclosing git repository's top level. Outside
+// a repository only root's own files apply.
+func ancestorIgnoreRules(root string) ignoreRules {
+	var dirs []string
+	top := ""
+	for dir := filepath.Dir(root); ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			top = dir
+			break
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, ".git")); err == nil {
+		top, dirs = root, nil
+	}
+	if top == "" {
+		return nil
+	}
+	var rules ignoreRules
+	if data, err := os.ReadFile(filepath.Join(top, ".git", "info", "exclude")); err == nil {
+		rules = parseIgnoreRules(top, data)
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		rules = rules.withDir(dirs[i])
+	}
+	return rules
+}
+
+type walkOptions struct {
+	// noIgnore disables .gitignore and .ignore handling; .git is still
+	// skipped.
+	noIgnore bool
+	// maxDepth limits how deep entries are visited; entries directly under
+	// the root are at depth 1. -1 means unlimited.
+	maxDepth int
+}
+
+// walkTree visits the entries under root in lexical order, root itself
+// excluded. It never enters .git, skips symlinks outside the allowed roots
+// and, unless noIgnore is set, skips ignored paths. visit may return
+// filepath.SkipDir to not descend into a directory, or filepath.SkipAll.
+func walkTree(paths *pathResolver, root string, opts walkOptions, visit func(path, rel string, d fs.DirEntry) error) error {
+	if opts.maxDepth == 0 {
+		return nil
+	}
+	var rules ignoreRules
+	if !opts.noIgnore {
+		rules = ancestorIgnoreRules(root)
+	}
+	var walk func(dir, rel string, depth int, rules ignoreRules) error
+	walk = func(dir, rel string, depth int, rules ignoreRules) error {
+		if !opts.noIgnore {
+			rules = rules.withDir(dir)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, d := range entries {
+			p := filepath.Join(dir, d.Name())
+			r := d.Name()
+			if rel != "" {
+				r = rel + "/" + r
+			}
+			if d.IsDir() && d.Name() == ".git" {
+				continue
+			}
+			if !opts.noIgnore && rules.ignored(p, d.IsDir()) {
+				continue
+			}
+			if d.Type()&fs.ModeSymlink != 0 && !paths.allowed(p) {
+				continue
+			}
+			if err := visit(p, r, d); err != nil {
+				if errors.Is(err, filepath.SkipDir) {
+					continue
+				}
+				return err
+			}
+			if d.IsDir() && (opts.maxDepth < 0 || depth+1 < opts.maxDepth) {
+				if err := walk(p, r, depth+1, rules); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(root, "", 0, rules); err != nil && !errors.Is(err, filepath.SkipAll) {
+		return err
+	}
+	return nil
+}
+
+// matchPathGlob matches a glob against a slash-separated relative path.
+// Patterns without a slash match the base name, so "*.go" finds Go files at
+// any depth.
+func matchPathGlob(pattern, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		return glob.Match(pattern, path.Base(rel))
+	}
+	return glob.Match(pattern, rel)
+}
@@ -0,0 +1,59 @@
+package builtins
+
+import (
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestIgnoreRulesMatching(t *testing.T) {
+	base := filepath.FromSlash("/repo")
+	rules := parseIgnoreRules(base, []byte("# comment\n\n*.o\n/build\ndocs/*.tmp\nlogs/\n!important.o\n\\#hash\n**/cache/**\n"))
+
+	cases := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"a.o", false, true},
+		{"src/deep/b.o", false, true},
+		{"important.o", false, false},
+		{"build", true, true},
+		{"src/build", true, false},
+		{"docs/x.tmp", false, true},
+		{"docs/sub/x.tmp", false, false},
+		{"logs", true, true},
+		{"logs", false, false},
+		{"#hash", false, true},
+		{"a/cache/b.txt", false, true},
+		{"main.go", false, false},
+	}
+	for _, tc := range cases {
+		if got := rules.ignored(filepath.Join(base, filepath.FromSlash(tc.rel)), tc.isDir); got != tc.want {
+			t.Fatalf("%s (dir=%v): ignored=%v, want %v", tc.rel, tc.isDir, got, tc.want)
+		}
+	}
+}
+
+func TestWalkTreeAppliesAncestorIgnoreFiles(t *testing.T) {
+	dir := t.TempDir()
+	mustWrite(t, filepath.Join(dir, ".git", "info", "exclude"), "secret.txt\n")
+	mustWrite(t, filepath.Join(dir, ".gitignore"), "*.gen\n")
+	mustWrite(t, filepath.Join(dir, "pkg", "a.go"), "")
+	mustWrite(t, filepath.Join(dir, "pkg", "a.gen"), "")
+	mustWrite(t, filepath.Join(dir, "pkg", "secret.txt"), "")
+	mustWrite(t, filepath.Join(dir, "pkg", "sub", "b.go"), "")
+
+	var got []string
+	err := walkTree(newPathResolver(Config{Workdir: dir}), filepath.Join(dir, "pkg"), walkOptions{maxDepth: -1}, func(_, rel string, _ fs.DirEntry) error {
+		got = append(got, rel)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	if want := []string{"a.go", "sub", "sub/b.go"}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -2,7 +2,9 @@ package builtins
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 
@@ -10,28 +12,46 @@ import (
 )
 
 func NewWriteTool(cwd string) core.Tool {
-	base := resolveBaseDir(cwd)
+	return newWriteTool(newPathResolver(Config{Workdir: cwd}))
+}
 
-	return core.ToolFunc{
-		ToolName: "write",
-		Run: func(_ context.Context, args map[string]any) (string, error) {
+func newWriteTool(paths *pathResolver) core.Tool {
+	return core.DetailedToolFunc{
+		ToolName:        "write",
+		ToolDescription: "Write text content into a file path.",
+		ToolSchema: objectSchema(map[string]any{
+			"path":    withAliases(requiredStringProperty("Path to the file to write (relative or absolute)."), pathAliases...),
+			"content": withAliases(stringProperty("Text content to write."), "text", "body"),
+		}, "path", "content"),
+		Run: func(_ context.Context, args map[string]any) (core.ToolResult, error) {
+			fail := func(err error) (core.ToolResult, error) { return core.ToolResult{}, err }
 			path := resolveWritePathArg(args)
 			if path == "" {
-				return "", fmt.Errorf("write_invalid_path")
+				return fail(fmt.Errorf("write_invalid_path"))
 			}
 			content, ok := args["content"].(string)
 			if !ok {
-				return "", fmt.Errorf("write_invalid_content")
+				return fail(fmt.Errorf("write_invalid_content"))
 			}
 
-			abs := resolveToolPath(base, path)
+			abs, err := paths.resolve(path)
+			if err != nil {
+				return fail(err)
+			}
+			previous, err := os.ReadFile(abs)
+			existed := err == nil
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fail(fmt.Errorf("write_failed: %w", err))
+			}
 			if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
-				return "", fmt.Errorf("write_failed: %w", err)
+				return fail(fmt.Errorf("write_failed: %w", err))
 			}
 			if err := os.WriteFile(abs, []byte(content), 0o644); err != nil {
-				return "", fmt.Errorf("write_failed: %w", err)
+				return fail(fmt.Errorf("write_failed: %w", err))
 			}
-			return fmt.Sprintf("wrote %d bytes to %s", len(content), path), nil
+			diff := unifiedDiff(path, string(previous), content, existed)
+			msg := fmt.Sprintf("wrote %d bytes to %s", len(content), path)
+			return core.ToolResult{Text: msg + "\n" + diff.summary(), Details: diff.details()}, nil
 		},
 	}
 }
@@ -46,6 +46,24 @@ func TestWriteToolErrors(t *testing.T) {
 	}
 }
 
+func TestWriteToolReturnsDiff(t *testing.T) {
+	dir := t.TempDir()
+	tool := NewWriteTool(dir).(core.DetailedTool)
+
+	res, err := tool.ExecuteWithDetails(context.Background(), map[string]any{"path": "a.txt", "content": "one\ntwo\n"})
+	if err != nil || !strings.Contains(res.Text, "--- /dev/null\n+++ b/a.txt\n@@ -0,0 +1,2 @@\n+one\n+two\n") {
+		t.Fatalf("expected creation diff, got %q err=%v", res.Text, err)
+	}
+
+	res, err = tool.ExecuteWithDetails(context.Background(), map[string]any{"path": "a.txt", "content": "one\n2\n"})
+	if err != nil || !strings.HasPrefix(res.Text, "wrote 6 bytes to a.txt\nfirst changed line: 2 (+1 -1)") {
+		t.Fatalf("unexpected overwrite result: %q err=%v", res.Text, err)
+	}
+	if res.Details["path"] != "a.txt" || res.Details["first_changed_line"] != 2 || !strings.Contains(res.Details["diff"].(string), "-two\n+2\n") {
+		t.Fatalf("unexpected details: %+v", res.Details)
+	}
+}
+
 func TestWriteToolExpandsHomePath(t *testing.T) {
 	home := t.TempDir()
 	t.Setenv("HOME", home)
@@ -0,0 +1,246 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nous/internal/provider"
+)
+
+type ApprovalDecision string
+
+const (
+	ApprovalAllowOnce    ApprovalDecision = "allow_once"
+	ApprovalAllowSession ApprovalDecision = "allow_session"
+	ApprovalDeny         ApprovalDecision = "deny"
+)
+
+const DefaultApprovalTimeout = 2 * time.Minute
+
+// ApprovalConfig selects which tool calls pause for an approve/reject
+// answer. Tools lists tool names; "*" matches every tool.
+type ApprovalConfig struct {
+	Enabled bool
+	Tools   []string
+	Timeout time.Duration
+}
+
+type ApprovalResponse struct {
+	Decision ApprovalDecision
+	Reason   string
+}
+
+// PendingApproval is a tool call waiting for an answer.
+type PendingApproval struct {
+	ToolCallID  string         `json:"tool_call_id"`
+	ToolName    string         `json:"tool_name"`
+	Arguments   map[string]any `json:"arguments,omitempty"`
+	RequestedAt string         `json:"requested_at"`
+}
+
+type pendingApproval struct {
+	info  PendingApproval
+	reply chan ApprovalResponse
+}
+
+type approvalBroker struct {
+	mu       sync.Mutex
+	cfg      ApprovalConfig
+	tools    map[string]struct{}
+	pending  map[string]*pendingApproval
+	sessions map[string]struct{}
+}
+
+func newApprovalBroker() *approvalBroker {
+	return &approvalBroker{
+		cfg:      ApprovalConfig{Timeout: DefaultApprovalTimeout},
+		tools:    map[string]struct{}{},
+		pending:  map[string]*pendingApproval{},
+		sessions: map[string]struct{}{},
+	}
+}
+
+func (b *approvalBroker) configure(cfg ApprovalConfig) error {
+	if cfg.Timeout < 0 {
+		return fmt.Errorf("invalid_approval_timeout")
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultApprovalTimeout
+	}
+	tools := make(map[string]struct{}, len(cfg.Tools))
+	for _, name := range cfg.Tools {
+		if name = strings.TrimSpace(name); name != "" {
+			tools[name] = struct{}{}
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cfg = cfg
+	b.tools = tools
+	return nil
+}
+
+func (b *approvalBroker) config() ApprovalConfig {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cfg := b.cfg
+	cfg.Tools = append([]string(nil), b.cfg.Tools...)
+	return cfg
+}
+
+// requires reports whether a call to toolName must wait for approval. force
+// (a policy "ask") always asks, even when the tool is approved for the
+// session or approval is not configured for it.
+func (b *approvalBroker) requires(toolName string, force bool) bool {
+	if force {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.sessions[toolName]; ok {
+		return false
+	}
+	if !b.cfg.Enabled {
+		return false
+	}
+	if _, ok := b.tools["*"]; ok {
+		return true
+	}
+	_, ok := b.tools[toolName]
+	return ok
+}
+
+func (b *approvalBroker) register(info PendingApproval) (chan ApprovalResponse, time.Duration, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, exists := b.pending[info.ToolCallID]; exists {
+		return nil, 0, fmt.Errorf("approval_already_pending: %s", info.ToolCallID)
+	}
+	reply := make(chan ApprovalResponse, 1)
+	b.pending[info.ToolCallID] = &pendingApproval{info: info, reply: reply}
+	return reply, b.cfg.Timeout, nil
+}
+
+func (b *approvalBroker) unregister(toolCallID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.pending, toolCallID)
+}
+
+func (b *approvalBroker) resolve(toolCallID string, resp ApprovalResponse) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p, ok := b.pending[toolCallID]
+	if !ok {
+		return fmt.Errorf("approval_not_found: %s", toolCallID)
+	}
+	delete(b.pending, toolCallID)
+	if resp.Decision == ApprovalAllowSession {
+		b.sessions[p.info.ToolName] = struct{}{}
+	}
+	p.reply <- resp
+	return nil
+}
+
+func (b *approvalBroker) list() []PendingApproval {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]PendingApproval, 0, len(b.pending))
+	for _, p := range b.pending {
+		out = append(out, p.info)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].RequestedAt != out[j].RequestedAt {
+			return out[i].RequestedAt < out[j].RequestedAt
+		}
+		return out[i].ToolCallID < out[j].ToolCallID
+	})
+	return out
+}
+
+func (b *approvalBroker) clearSession() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sessions = map[string]struct{}{}
+}
+
+// SetToolApproval configures which tool calls wait for approval.
+func (e *Engine) SetToolApproval(cfg ApprovalConfig) error {
+	return e.approvals.configure(cfg)
+}
+
+func (e *Engine) ToolApproval() ApprovalConfig {
+	return e.approvals.config()
+}
+
+// ApproveToolCall answers a pending approval. ApprovalAllowSession also
+// approves later calls of the same tool until ClearSessionApprovals.
+func (e *Engine) ApproveToolCall(toolCallID string, decision ApprovalDecision) error {
+	if decision != ApprovalAllowOnce && decision != ApprovalAllowSession {
+		return fmt.Errorf("invalid_approval_decision: %s", decision)
+	}
+	return e.approvals.resolve(strings.TrimSpace(toolCallID), ApprovalResponse{Decision: decision})
+}
+
+func (e *Engine) RejectToolCall(toolCallID, reason string) error {
+	return e.approvals.resolve(strings.TrimSpace(toolCallID), ApprovalResponse{
+		Decision: ApprovalDeny,
+		Reason:   strings.TrimSpace(reason),
+	})
+}
+
+func (e *Engine) PendingApprovals() []PendingApproval {
+	return e.approvals.list()
+}
+
+// ClearSessionApprovals forgets allow-for-session answers.
+func (e *Engine) ClearSessionApprovals() {
+	e.approvals.clearSession()
+}
+
+// awaitToolApproval blocks until call is approved, rejected or times out.
+// When the call may not run it returns the tool result to report instead.
+// force is set when a policy rule asks for approval.
+func (e *Engine) awaitToolApproval(ctx context.Context, call provider.ToolCall, force bool) (string, bool, error) {
+	if !e.approvals.requires(call.Name, force) {
+		return "", true, nil
+	}
+	reply, timeout, err := e.approvals.register(PendingApproval{
+		ToolCallID:  call.ID,
+		ToolName:    call.Name,
+		Arguments:   cloneMap(call.Arguments),
+		RequestedAt: nowTS(),
+	})
+	if err != nil {
+		return "", false, err
+	}
+	defer e.approvals.unregister(call.ID)
+	if err := e.runtime.ToolApprovalRequested(call.ID, call.Name, call.Arguments); err != nil {
+		return "", false, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case resp := <-reply:
+		if resp.Decision == ApprovalDeny {
+			reason := resp.Reason
+			if reason == "" {
+				reason = "rejected by user"
+			}
+			e.runtime.Warning("tool_approval_denied", fmt.Sprintf("%s: %s", call.Name, reason))
+			return fmt.Sprintf("tool_error: tool call rejected: %s", reason), false, nil
+		}
+		e.runtime.Status(fmt.Sprintf("tool_approval: %s %s", call.ID, resp.Decision))
+		return "", true, nil
+	case <-timer.C:
+		e.runtime.Warning("tool_approval_timeout", fmt.Sprintf("%s: no answer within %s", call.Name, timeout))
+		return fmt.Sprintf("tool_error: tool call not approved within %s", timeout), false, nil
+	case <-ctx.Done():
+		return "", false, ctx.Err()
+	}
+}
@@ -0,0 +1,199 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newApprovalTestEngine(t *testing.T, executed *[]string) *Engine {
+	t.Helper()
+	e := NewEngine(NewRuntime(), scriptedProvider{})
+	var mu sync.Mutex
+	record := func(name string) func(context.Context, map[string]any) (string, error) {
+		return func(context.Context, map[string]any) (string, error) {
+			mu.Lock()
+			*executed = append(*executed, name)
+			mu.Unlock()
+			return name + "-ok", nil
+		}
+	}
+	e.SetTools([]Tool{
+		ToolFunc{ToolName: "first", Run: record("first")},
+		ToolFunc{ToolName: "second", Run: record("second")},
+	})
+	return e
+}
+
+func answerApprovals(e *Engine, answer func(ev Event)) (requests *[]string, unsub func()) {
+	var mu sync.Mutex
+	seen := []string{}
+	unsub = e.Subscribe(func(ev Event) {
+		if ev.Type != EventToolApprovalRequest {
+			return
+		}
+		mu.Lock()
+		seen = append(seen, ev.ToolName)
+		mu.Unlock()
+		go answer(ev)
+	})
+	return &seen, unsub
+}
+
+func TestToolApprovalAllowOnceRunsTool(t *testing.T) {
+	executed := []string{}
+	e := newApprovalTestEngine(t, &executed)
+	if err := e.SetToolApproval(ApprovalConfig{Enabled: true, Tools: []string{"first"}}); err != nil {
+		t.Fatalf("set tool approval failed: %v", err)
+	}
+	requests, unsub := answerApprovals(e, func(ev Event) {
+		if ev.ToolCallID != "t1" {
+			t.Errorf("unexpected approval request: %+v", ev)
+		}
+		_ = e.ApproveToolCall(ev.ToolCallID, ApprovalAllowOnce)
+	})
+	defer unsub()
+
+	out, err := e.Prompt(context.Background(), "run-approve", "go")
+	if err != nil {
+		t.Fatalf("prompt failed: %v", err)
+	}
+	if out != "first-oksecond-ok" {
+		t.Fatalf("unexpected output: %q", out)
+	}
+	if len(*requests) != 1 || (*requests)[0] != "first" {
+		t.Fatalf("expected one approval request for first, got: %v", *requests)
+	}
+	if len(executed) != 2 {
+		t.Fatalf("expected both tools to run, got: %v", executed)
+	}
+	if pending := e.PendingApprovals(); len(pending) != 0 {
+		t.Fatalf("expected no pending approvals, got: %+v", pending)
+	}
+}
+
+func TestToolApprovalRejectReturnsReasonToModel(t *testing.T) {
+	executed := []string{}
+	e := newApprovalTestEngine(t, &executed)
+	_ = e.SetToolApproval(ApprovalConfig{Enabled: true, Tools: []string{"*"}})
+	_, unsub := answerApprovals(e, func(ev Event) {
+		_ = e.RejectToolCall(ev.ToolCallID, "not in prod")
+	})
+	defer unsub()
+
+	out, err := e.Prompt(context.Background(), "run-reject", "go")
+	if err != nil {
+		t.Fatalf("prompt failed: %v", err)
+	}
+	if len(executed) != 0 {
+		t.Fatalf("rejected tools must not run, got: %v", executed)
+	}
+	if !strings.Contains(out, "tool_error: tool call rejected: not in prod") {
+		t.Fatalf("expected rejection reason in tool result, got: %q", out)
+	}
+}
+
+func TestToolApprovalTimeoutDenies(t *testing.T) {
+	executed := []string{}
+	e := newApprovalTestEngine(t, &executed)
+	_ = e.SetToolApproval(ApprovalConfig{Enabled: true, Tools: []string{"first"}, Timeout: 20 * time.Millisecond})
+	warnings := []string{}
+	unsub := e.Subscribe(func(ev Event) {
+		if ev.Type == EventWarning {
+			warnings = append(warnings, ev.Code)
+		}
+	})
+	defer unsub()
+
+	out, err := e.Prompt(context.Background(), "run-timeout", "go")
+	if err != nil {
+		t.Fatalf("prompt failed: %v", err)
+	}
+	if len(executed) != 1 || executed[0] != "second" {
+		t.Fatalf("expected only unguarded tool to run, got: %v", executed)
+	}
+	if !strings.Contains(out, "tool call not approved within 20ms") {
+		t.Fatalf("expected timeout result, got: %q", out)
+	}
+	if len(warnings) == 0 || warnings[0] != "tool_approval_timeout" {
+		t.Fatalf("expected tool_approval_timeout warning, got: %v", warnings)
+	}
+}
+
+func TestToolApprovalAllowSessionSkipsLaterRequests(t *testing.T) {
+	executed := []string{}
+	e := newApprovalTestEngine(t, &executed)
+	_ = e.SetToolApproval(ApprovalConfig{Enabled: true, Tools: []string{"first"}})
+	requests, unsub := answerApprovals(e, func(ev Event) {
+		_ = e.ApproveToolCall(ev.ToolCallID, ApprovalAllowSession)
+	})
+	defer unsub()
+
+	for _, runID := range []string{"run-session-1", "run-session-2"} {
+		if _, err := e.Prompt(context.Background(), runID, "go"); err != nil {
+			t.Fatalf("prompt %s failed: %v", runID, err)
+		}
+	}
+	if len(*requests) != 1 {
+		t.Fatalf("expected a single approval request for the session, got: %v", *requests)
+	}
+
+	e.ClearSessionApprovals()
+	if _, err := e.Prompt(context.Background(), "run-session-3", "go"); err != nil {
+		t.Fatalf("prompt after clearing approvals failed: %v", err)
+	}
+	if len(*requests) != 2 {
+		t.Fatalf("expected approval to be requested again after clearing, got: %v", *requests)
+	}
+}
+
+func TestToolApprovalRejectsUnknownCallAndDecision(t *testing.T) {
+	e := NewEngine(NewRuntime(), scriptedProvider{})
+	if err := e.ApproveToolCall("missing", ApprovalAllowOnce); err == nil || !strings.Contains(err.Error(), "approval_not_found") {
+		t.Fatalf("expected approval_not_found, got: %v", err)
+	}
+	if err := e.ApproveToolCall("missing", ApprovalDeny); err == nil || !strings.Contains(err.Error(), "invalid_approval_decision") {
+		t.Fatalf("expected invalid_approval_decision, got: %v", err)
+	}
+	if err := e.SetToolApproval(ApprovalConfig{Enabled: true, Timeout: -time.Second}); err == nil {
+		t.Fatalf("expected negative timeout to be rejected")
+	}
+}
+
+type scopedApprovalTool struct {
+	ToolFunc
+	needsApproval bool
+}
+
+func (s scopedApprovalTool) NeedsApproval(map[string]any) bool {
+	return s.needsApproval
+}
+
+func TestToolApprovalScopedToolAsksOnlyForSomeCalls(t *testing.T) {
+	e := NewEngine(NewRuntime(), scriptedProvider{})
+	ran := []string{}
+	record := func(name string) func(context.Context, map[string]any) (string, error) {
+		return func(context.Context, map[string]any) (string, error) {
+			ran = append(ran, name)
+			return name + "-ok", nil
+		}
+	}
+	e.SetTools([]Tool{
+		scopedApprovalTool{ToolFunc: ToolFunc{ToolName: "first", Run: record("first")}, needsApproval: true},
+		scopedApprovalTool{ToolFunc: ToolFunc{ToolName: "second", Run: record("second")}},
+	})
+	_ = e.SetToolApproval(ApprovalConfig{Enabled: true, Tools: []string{"*"}, Timeout: 20 * time.Millisecond})
+
+	out, err := e.Prompt(context.Background(), "run-scoped", "go")
+	if err != nil {
+		t.Fatalf("prompt failed: %v", err)
+	}
+	if len(ran) != 1 || ran[0] != "second" {
+		t.Fatalf("only the call that needs no approval may run unasked, got: %v", ran)
+	}
+	if !strings.Contains(out, "tool call not approved within 20ms") {
+		t.Fatalf("expected the scoped call to wait for approval, got: %q", out)
+	}
+}
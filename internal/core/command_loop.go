@@ -11,6 +11,16 @@ type TurnExecutor interface {
 	Prompt(ctx context.Context, runID, prompt string) (string, error)
 }
 
+// HistoryTurnExecutor runs a turn on top of prior conversation messages and
+// reports the messages the turn added.
+type HistoryTurnExecutor interface {
+	RunTurn(ctx context.Context, runID string, history []Message, prompt string) (TurnOutput, error)
+}
+
+// HistoryProvider returns the conversation messages a queued turn of runID
+// should build on.
+type HistoryProvider func(runID string) ([]Message, error)
+
 type RunCoordinator interface {
 	BeginRun(runID string) error
 	EndRun(runID string) error
@@ -35,12 +45,17 @@ type TurnResult struct {
 	Input  string
 	Output string
 	Err    error
+
+	// Messages holds the tool calls and tool results of the turn when the
+	// executor reports them.
+	Messages []Message
 }
 
 type queuedTurn struct {
 	kind      TurnKind
 	inputText string
 	execText  string
+	history   []Message
 }
 
 type CommandLoop struct {
@@ -59,6 +74,7 @@ type CommandLoop struct {
 
 	currentCancel context.CancelFunc
 	onTurnEnd     func(TurnResult)
+	history       HistoryProvider
 }
 
 func NewCommandLoop(executor TurnExecutor) *CommandLoop {
@@ -76,6 +92,14 @@ func (l *CommandLoop) SetOnTurnEnd(fn func(TurnResult)) {
 	l.onTurnEnd = fn
 }
 
+// SetHistoryProvider sets where steer and follow-up turns load their
+// conversation history from. Without one they run without history.
+func (l *CommandLoop) SetHistoryProvider(fn HistoryProvider) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.history = fn
+}
+
 func (l *CommandLoop) State() RunState {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -100,10 +124,23 @@ func (l *CommandLoop) PromptWithExecutionText(inputText, executionText string) (
 		return "", fmt.Errorf("empty_prompt")
 	}
 
-	return l.enqueuePrompt(inputText, executionText)
+	return l.enqueuePrompt(queuedTurn{kind: TurnPrompt, inputText: inputText, execText: executionText})
+}
+
+// PromptWithHistory starts a run whose first turn builds on history.
+func (l *CommandLoop) PromptWithHistory(text string, history []Message) (string, error) {
+	if text == "" {
+		return "", fmt.Errorf("empty_prompt")
+	}
+	return l.enqueuePrompt(queuedTurn{
+		kind:      TurnPrompt,
+		inputText: text,
+		execText:  text,
+		history:   cloneMessages(history),
+	})
 }
 
-func (l *CommandLoop) enqueuePrompt(inputText, executionText string) (string, error) {
+func (l *CommandLoop) enqueuePrompt(initial queuedTurn) (string, error) {
 	l.mu.Lock()
 	if l.state != StateIdle {
 		l.mu.Unlock()
@@ -114,7 +151,6 @@ func (l *CommandLoop) enqueuePrompt(inputText, executionText string) (string, er
 	l.runID = fmt.Sprintf("run-%d", l.runCounter)
 	runID := l.runID
 	l.state = StateRunning
-	initial := queuedTurn{kind: TurnPrompt, inputText: inputText, execText: executionText}
 	l.mu.Unlock()
 
 	go l.process(initial)
@@ -207,6 +243,7 @@ func (l *CommandLoop) process(next queuedTurn) {
 	l.mu.Lock()
 	runID := l.runID
 	onTurnEnd := l.onTurnEnd
+	historyProvider := l.history
 	l.mu.Unlock()
 
 	if coordinator, ok := l.executor.(RunCoordinator); ok {
@@ -243,15 +280,16 @@ func (l *CommandLoop) process(next queuedTurn) {
 		l.currentCancel = cancel
 		l.mu.Unlock()
 
-		out, err := l.executor.Prompt(ctx, runID, next.execText)
+		out, err := l.executeTurn(ctx, runID, next, historyProvider)
 		cancel()
 
 		result := TurnResult{
-			RunID:  runID,
-			Kind:   next.kind,
-			Input:  next.inputText,
-			Output: out,
-			Err:    err,
+			RunID:    runID,
+			Kind:     next.kind,
+			Input:    next.inputText,
+			Output:   out.Text,
+			Err:      err,
+			Messages: out.Messages,
 		}
 		if onTurnEnd != nil {
 			onTurnEnd(result)
@@ -280,6 +318,23 @@ func (l *CommandLoop) process(next queuedTurn) {
 	}
 }
 
+func (l *CommandLoop) executeTurn(ctx context.Context, runID string, next queuedTurn, historyProvider HistoryProvider) (TurnOutput, error) {
+	executor, ok := l.executor.(HistoryTurnExecutor)
+	if !ok {
+		out, err := l.executor.Prompt(ctx, runID, next.execText)
+		return TurnOutput{Text: out}, err
+	}
+	history := next.history
+	if history == nil && next.kind != TurnPrompt && historyProvider != nil {
+		loaded, err := historyProvider(runID)
+		if err != nil {
+			return TurnOutput{}, err
+		}
+		history = loaded
+	}
+	return executor.RunTurn(ctx, runID, history, next.execText)
+}
+
 func (l *CommandLoop) finishLocked() {
 	l.state = StateIdle
 	l.runID = ""
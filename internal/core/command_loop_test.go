@@ -412,3 +412,76 @@ func TestCommandLoopAbortPropagatesContextCancellation(t *testing.T) {
 	}
 	waitLoopIdle(t, loop)
 }
+
+type historyExecutor struct {
+	*gatedExecutor
+
+	mu        sync.Mutex
+	histories [][]Message
+}
+
+func (h *historyExecutor) RunTurn(ctx context.Context, runID string, history []Message, prompt string) (TurnOutput, error) {
+	h.mu.Lock()
+	h.histories = append(h.histories, cloneMessages(history))
+	h.mu.Unlock()
+	out, err := h.gatedExecutor.Prompt(ctx, runID, prompt)
+	return TurnOutput{Text: out}, err
+}
+
+func TestCommandLoopPassesHistoryToTurns(t *testing.T) {
+	exec := &historyExecutor{gatedExecutor: newGatedExecutor()}
+	loop := NewCommandLoop(exec)
+	loop.SetHistoryProvider(func(runID string) ([]Message, error) {
+		return []Message{NewTextMessage(RoleUser, "p0"), NewTextMessage(RoleAssistant, "ok:p0")}, nil
+	})
+
+	if _, err := loop.PromptWithHistory("p0", []Message{NewTextMessage(RoleUser, "earlier")}); err != nil {
+		t.Fatalf("prompt with history failed: %v", err)
+	}
+	<-exec.started
+	if err := loop.FollowUp("f1"); err != nil {
+		t.Fatalf("follow_up failed: %v", err)
+	}
+	exec.release <- struct{}{}
+	<-exec.started
+	exec.release <- struct{}{}
+	waitLoopIdle(t, loop)
+
+	exec.mu.Lock()
+	defer exec.mu.Unlock()
+	if len(exec.histories) != 2 {
+		t.Fatalf("expected two history turns, got %d", len(exec.histories))
+	}
+	if len(exec.histories[0]) != 1 || exec.histories[0][0].Text != "earlier" {
+		t.Fatalf("unexpected prompt history: %+v", exec.histories[0])
+	}
+	if len(exec.histories[1]) != 2 || exec.histories[1][1].Role != RoleAssistant {
+		t.Fatalf("unexpected follow-up history: %+v", exec.histories[1])
+	}
+}
+
+func TestCommandLoopHistoryProviderErrorFailsTurn(t *testing.T) {
+	exec := &historyExecutor{gatedExecutor: newGatedExecutor()}
+	loop := NewCommandLoop(exec)
+	loop.SetHistoryProvider(func(string) ([]Message, error) {
+		return nil, errors.New("history unavailable")
+	})
+	results := make(chan TurnResult, 2)
+	loop.SetOnTurnEnd(func(r TurnResult) { results <- r })
+
+	if _, err := loop.Prompt("p0"); err != nil {
+		t.Fatalf("prompt failed: %v", err)
+	}
+	<-exec.started
+	if err := loop.Steer("s1"); err != nil {
+		t.Fatalf("steer failed: %v", err)
+	}
+	exec.release <- struct{}{}
+	waitLoopIdle(t, loop)
+
+	<-results
+	r := <-results
+	if r.Kind != TurnSteer || r.Err == nil || r.Err.Error() != "history unavailable" {
+		t.Fatalf("expected steer turn to fail with history error, got %+v", r)
+	}
+}
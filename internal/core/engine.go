@@ -17,8 +17,17 @@ type Engine struct {
 	provider provider.Adapter
 	tools    map[string]Tool
 	active   map[string]struct{}
+	// narrowed is set once SetActiveTools picks the active tools; until
+	// then every extension tool is active too.
+	narrowed bool
 	ext      *extension.Manager
 
+	approvals *approvalBroker
+	policy    *Policy
+
+	systemPrompt string
+	contextFiles []ContextFile
+
 	transformContext TransformContextFn
 	convertToLLM     ConvertToLLMFn
 }
@@ -28,10 +37,11 @@ type ConvertToLLMFn func(messages []Message) ([]provider.Message, error)
 
 func NewEngine(runtime *Runtime, p provider.Adapter) *Engine {
 	return &Engine{
-		runtime:  runtime,
-		provider: p,
-		tools:    map[string]Tool{},
-		active:   map[string]struct{}{},
+		runtime:   runtime,
+		provider:  p,
+		tools:     map[string]Tool{},
+		active:    map[string]struct{}{},
+		approvals: newApprovalBroker(),
 	}
 }
 
@@ -43,6 +53,26 @@ func (e *Engine) ExtensionManager() *extension.Manager {
 	return e.ext
 }
 
+// SetSystemPrompt sets the base system prompt sent with every provider request.
+func (e *Engine) SetSystemPrompt(prompt string) {
+	e.systemPrompt = prompt
+}
+
+// SetContextFiles sets the project instruction files appended to the system
+// prompt.
+func (e *Engine) SetContextFiles(files []ContextFile) {
+	e.contextFiles = append([]ContextFile(nil), files...)
+}
+
+func (e *Engine) ContextFiles() []ContextFile {
+	return append([]ContextFile(nil), e.contextFiles...)
+}
+
+// SystemPrompt returns the system prompt as sent to the provider.
+func (e *Engine) SystemPrompt() string {
+	return BuildSystemPrompt(e.systemPrompt, e.contextFiles)
+}
+
 func (e *Engine) SetTransformContext(fn TransformContextFn) {
 	e.transformContext = fn
 }
@@ -72,33 +102,133 @@ func (e *Engine) Subscribe(fn EventListener) func() {
 func (e *Engine) SetTools(tools []Tool) {
 	e.tools = map[string]Tool{}
 	e.active = map[string]struct{}{}
+	e.narrowed = false
 	for _, t := range tools {
 		e.tools[t.Name()] = t
 		e.active[t.Name()] = struct{}{}
 	}
 }
 
+// ToolStates collects ToolState from every tool that reports one, keyed by
+// tool name.
+func (e *Engine) ToolStates() map[string]map[string]any {
+	out := map[string]map[string]any{}
+	for name, tool := range e.tools {
+		if r, ok := tool.(ToolStateReporter); ok {
+			out[name] = r.ToolState()
+		}
+	}
+	return out
+}
+
+// ResetToolSessions drops per-session tool state when the active session
+// changes.
+func (e *Engine) ResetToolSessions() {
+	for _, tool := range e.tools {
+		if st, ok := tool.(SessionTool); ok {
+			st.ResetSession()
+		}
+	}
+}
+
+// StopBackgroundTools stops work that tools left running, on abort and
+// shutdown.
+func (e *Engine) StopBackgroundTools() {
+	for _, tool := range e.tools {
+		if bt, ok := tool.(BackgroundTool); ok {
+			bt.StopBackground()
+		}
+	}
+}
+
 func (e *Engine) SetActiveTools(names []string) error {
 	next := map[string]struct{}{}
 	for _, name := range names {
-		if _, ok := e.tools[name]; !ok {
+		if _, ok := e.tools[name]; !ok && !e.isExtensionTool(name) {
 			return fmt.Errorf("tool_not_registered: %s", name)
 		}
 		next[name] = struct{}{}
 	}
 	e.active = next
+	e.narrowed = true
 	return nil
 }
 
+func (e *Engine) isExtensionTool(name string) bool {
+	if e.ext == nil {
+		return false
+	}
+	_, ok := e.ext.ToolSpec(name)
+	return ok
+}
+
+// toolActive reports whether name may be offered to and called by the
+// model. Builtin tools follow the active set; extension tools do too once
+// SetActiveTools has been called.
+func (e *Engine) toolActive(name string) bool {
+	if _, ok := e.active[name]; ok {
+		return true
+	}
+	_, builtin := e.tools[name]
+	return !builtin && !e.narrowed
+}
+
+// activeToolNames lists the active builtin tools; active extension tools
+// are described after them by toolSpecs.
 func (e *Engine) activeToolNames() []string {
 	out := make([]string, 0, len(e.active))
 	for name := range e.active {
-		out = append(out, name)
+		if _, builtin := e.tools[name]; builtin {
+			out = append(out, name)
+		}
 	}
 	slices.Sort(out)
 	return out
 }
 
+// toolSpecs describes the active builtin tools, followed by the active
+// extension tools that are not shadowed by a builtin of the same name.
+func (e *Engine) toolSpecs() []provider.ToolSpec {
+	names := e.activeToolNames()
+	out := make([]provider.ToolSpec, 0, len(names))
+	for _, name := range names {
+		spec := provider.ToolSpec{Name: name}
+		if described, ok := e.tools[name].(DescribedTool); ok {
+			spec.Description = described.Description()
+			spec.Parameters = publicToolSchema(described.Schema())
+		}
+		out = append(out, spec)
+	}
+	if e.ext != nil {
+		for _, ext := range e.ext.ToolSpecs() {
+			if _, ok := e.tools[ext.Name]; ok || !e.toolActive(ext.Name) {
+				continue
+			}
+			out = append(out, provider.ToolSpec{
+				Name:        ext.Name,
+				Description: ext.Description,
+				Parameters:  publicToolSchema(ext.Schema),
+			})
+		}
+	}
+	return out
+}
+
+func (e *Engine) toolSchema(name string) map[string]any {
+	if tool, ok := e.tools[name]; ok {
+		if described, ok := tool.(DescribedTool); ok {
+			return described.Schema()
+		}
+		return nil
+	}
+	if e.ext != nil {
+		if spec, ok := e.ext.ToolSpec(name); ok {
+			return spec.Schema
+		}
+	}
+	return nil
+}
+
 func (e *Engine) BeginRun(runID string) error {
 	if err := e.runtime.StartRun(runID); err != nil {
 		return err
@@ -130,18 +260,37 @@ func (e *Engine) EndRun(runID string) error {
 }
 
 func (e *Engine) Prompt(ctx context.Context, runID, prompt string) (string, error) {
+	return e.PromptWithHistory(ctx, runID, nil, prompt)
+}
+
+// PromptWithHistory runs one turn for prompt on top of prior conversation
+// messages, which are sent to the provider ahead of the new user message.
+func (e *Engine) PromptWithHistory(ctx context.Context, runID string, history []Message, prompt string) (string, error) {
+	out, err := e.RunTurn(ctx, runID, history, prompt)
+	return out.Text, err
+}
+
+// TurnOutput is the outcome of one turn: the final text plus the messages the
+// turn added after the user prompt (assistant tool calls and tool results).
+type TurnOutput struct {
+	Text     string
+	Messages []Message
+}
+
+// RunTurn is PromptWithHistory that also returns the turn transcript.
+func (e *Engine) RunTurn(ctx context.Context, runID string, history []Message, prompt string) (TurnOutput, error) {
 	if e.ext != nil {
 		out, err := e.ext.RunInputHooks(prompt)
 		if err != nil {
 			if errors.Is(err, extension.ErrTimeout) {
 				e.runtime.Warning("extension_timeout", fmt.Sprintf("input_hook: %v", err))
 			} else {
-				return "", err
+				return TurnOutput{}, err
 			}
 		} else {
 			prompt = out.Text
 			if out.Handled {
-				return prompt, nil
+				return TurnOutput{Text: prompt}, nil
 			}
 		}
 	}
@@ -150,15 +299,15 @@ func (e *Engine) Prompt(ctx context.Context, runID, prompt string) (string, erro
 	switch e.runtime.State() {
 	case StateIdle:
 		if err := e.BeginRun(runID); err != nil {
-			return "", err
+			return TurnOutput{}, err
 		}
 		managedRun = true
 	case StateRunning, StateAborting:
 		if active := e.runtime.RunID(); active != runID {
-			return "", fmt.Errorf("run_id_mismatch: active=%s requested=%s", active, runID)
+			return TurnOutput{}, fmt.Errorf("run_id_mismatch: active=%s requested=%s", active, runID)
 		}
 	default:
-		return "", fmt.Errorf("invalid_runtime_state: %s", e.runtime.State())
+		return TurnOutput{}, fmt.Errorf("invalid_runtime_state: %s", e.runtime.State())
 	}
 	defer func() {
 		if managedRun {
@@ -167,7 +316,7 @@ func (e *Engine) Prompt(ctx context.Context, runID, prompt string) (string, erro
 	}()
 
 	if err := e.runtime.StartTurn(); err != nil {
-		return "", err
+		return TurnOutput{}, err
 	}
 	if e.ext != nil {
 		if err := e.ext.RunTurnStartHooks(runID, e.runtime.TurnNumber()); err != nil {
@@ -185,31 +334,33 @@ func (e *Engine) Prompt(ctx context.Context, runID, prompt string) (string, erro
 
 	userID := fmt.Sprintf("user-%d", time.Now().UnixNano())
 	if err := e.runtime.MessageStart(userID, "user"); err != nil {
-		return "", err
+		return TurnOutput{}, err
 	}
 	if err := e.runtime.MessageEnd(userID); err != nil {
-		return "", err
+		return TurnOutput{}, err
 	}
 
 	assistantID := fmt.Sprintf("assistant-%d", time.Now().UnixNano())
 	if err := e.runtime.MessageStart(assistantID, "assistant"); err != nil {
-		return "", err
+		return TurnOutput{}, err
 	}
 
 	var final string
-	messages := []Message{{Role: RoleUser, Text: prompt}}
+	messages := append(cloneMessages(history), Message{Role: RoleUser, Text: prompt})
 	llmMessages, err := e.buildProviderMessages(ctx, messages)
 	if err != nil {
-		return "", err
+		return TurnOutput{}, err
 	}
 	req := provider.Request{
-		Messages:    llmMessages,
-		ActiveTools: e.activeToolNames(),
+		System:   e.SystemPrompt(),
+		Messages: llmMessages,
+		Tools:    e.toolSpecs(),
 	}
 	type toolResult struct {
 		CallID string
 		Name   string
 		Result string
+		Images []ToolImage
 	}
 	for step := 0; step < 8; step++ {
 		awaitNext := false
@@ -225,34 +376,37 @@ func (e *Engine) Prompt(ctx context.Context, runID, prompt string) (string, erro
 				final += ev.Delta
 				stepAssistant += ev.Delta
 				if err := e.runtime.MessageUpdate(assistantID, ev.Delta); err != nil {
-					return "", err
+					return TurnOutput{}, err
 				}
 			case provider.EventToolCall:
 				stepToolCalls = append(stepToolCalls, provider.ToolCall{
-					ID:        ev.ToolCall.ID,
-					Name:      ev.ToolCall.Name,
-					Arguments: cloneMap(ev.ToolCall.Arguments),
+					ID:               ev.ToolCall.ID,
+					Name:             ev.ToolCall.Name,
+					Arguments:        cloneMap(ev.ToolCall.Arguments),
+					ThoughtSignature: ev.ToolCall.ThoughtSignature,
 				})
 				var (
-					res string
-					err error
+					res    string
+					images []ToolImage
+					err    error
 				)
 				if interruptTools {
 					res, err = e.skipToolCall(ev.ToolCall, "Skipped due to queued user message.")
 				} else {
-					res, err = e.executeToolCall(ctx, ev.ToolCall)
+					res, images, err = e.executeToolCall(ctx, ev.ToolCall)
 				}
 				if err != nil {
-					return "", err
+					return TurnOutput{}, err
 				}
 				final += res
 				stepToolResults = append(stepToolResults, toolResult{
 					CallID: ev.ToolCall.ID,
 					Name:   ev.ToolCall.Name,
 					Result: fmt.Sprintf("%s => %s", ev.ToolCall.Name, res),
+					Images: images,
 				})
 				if err := e.runtime.MessageUpdate(assistantID, res); err != nil {
-					return "", err
+					return TurnOutput{}, err
 				}
 				if !interruptTools && steeringQueued != nil && steeringQueued() {
 					interruptTools = true
@@ -295,17 +449,17 @@ func (e *Engine) Prompt(ctx context.Context, runID, prompt string) (string, erro
 							reason = "provider request aborted"
 						}
 						e.runtime.Warning("provider_aborted", reason)
-						return "", ev.Err
+						return TurnOutput{}, ev.Err
 					}
 					if provider.IsRetryExhaustedError(ev.Err) {
 						e.runtime.Warning("provider_retry_exhausted", ev.Err.Error())
 					}
 					e.runtime.Error("provider_error", "provider stream returned error", ev.Err)
-					return "", ev.Err
+					return TurnOutput{}, ev.Err
 				}
 				err := fmt.Errorf("provider_error")
 				e.runtime.Error("provider_error", "provider stream returned error", err)
-				return "", err
+				return TurnOutput{}, err
 			}
 		}
 		if strings.TrimSpace(stepAssistant) != "" || len(stepToolCalls) > 0 {
@@ -318,10 +472,11 @@ func (e *Engine) Prompt(ctx context.Context, runID, prompt string) (string, erro
 			}
 			for _, call := range stepToolCalls {
 				assistantMsg.Blocks = append(assistantMsg.Blocks, MessageBlock{
-					Type:       BlockTypeToolCall,
-					ToolCallID: call.ID,
-					ToolName:   call.Name,
-					Arguments:  cloneMap(call.Arguments),
+					Type:             BlockTypeToolCall,
+					ToolCallID:       call.ID,
+					ToolName:         call.Name,
+					Arguments:        cloneMap(call.Arguments),
+					ThoughtSignature: call.ThoughtSignature,
 				})
 			}
 			messages = append(messages, assistantMsg)
@@ -334,14 +489,14 @@ func (e *Engine) Prompt(ctx context.Context, runID, prompt string) (string, erro
 			if step == 7 {
 				err := fmt.Errorf("tool_loop_limit_exceeded")
 				e.runtime.Error("tool_loop_limit_exceeded", "tool await-next loop exceeded max rounds", err)
-				return "", err
+				return TurnOutput{}, err
 			}
 			for _, item := range stepToolResults {
-				messages = appendToolResultMessage(messages, item.CallID, item.Name, item.Result)
+				messages = appendToolResultMessage(messages, item.CallID, item.Name, item.Result, item.Images...)
 			}
 			next, err := e.buildProviderMessages(ctx, messages)
 			if err != nil {
-				return "", err
+				return TurnOutput{}, err
 			}
 			req.Messages = next
 			if awaitNext {
@@ -353,24 +508,34 @@ func (e *Engine) Prompt(ctx context.Context, runID, prompt string) (string, erro
 	}
 
 	if err := e.runtime.MessageEnd(assistantID); err != nil {
-		return "", err
+		return TurnOutput{}, err
 	}
-	return final, nil
+	return TurnOutput{Text: final, Messages: cloneMessages(messages[len(history)+1:])}, nil
 }
 
-func (e *Engine) executeToolCall(ctx context.Context, call provider.ToolCall) (string, error) {
+// executeToolCall runs call and returns its result text along with any
+// images the tool attached.
+func (e *Engine) executeToolCall(ctx context.Context, call provider.ToolCall) (string, []ToolImage, error) {
 	if err := e.runtime.ToolExecutionStart(call.ID, call.Name); err != nil {
-		return "", err
+		return "", nil, err
 	}
 	defer func() { _ = e.runtime.ToolExecutionEnd(call.ID, call.Name) }()
 
-	normalizedArgs, err := normalizeToolArguments(call.Name, call.Arguments)
+	normalizedArgs, err := validateToolArguments(call.Name, e.toolSchema(call.Name), call.Arguments)
 	if err != nil {
 		e.runtime.Warning("tool_validation_error", err.Error())
-		return fmt.Sprintf("tool_error: %s", err.Error()), nil
+		return fmt.Sprintf("tool_error: %s", err.Error()), nil, nil
 	}
 	call.Arguments = normalizedArgs
 
+	decision := e.policy.Evaluate(call.Name, call.Arguments)
+	if decision.Action == PolicyDeny {
+		err := fmt.Errorf("tool_blocked: %s denied by policy rule %s", call.Name, decision.RuleID)
+		e.runtime.Warning("tool_blocked", err.Error())
+		return fmt.Sprintf("tool_error: %s", err.Error()), nil, nil
+	}
+	askApproval := decision.Action == PolicyAsk
+
 	if e.ext != nil {
 		hookOut, err := e.ext.RunToolCallHooks(call.Name, call.Arguments)
 		if err != nil {
@@ -378,26 +543,40 @@ func (e *Engine) executeToolCall(ctx context.Context, call provider.ToolCall) (s
 				e.runtime.Warning("extension_timeout", fmt.Sprintf("tool_call_hook(%s): %v", call.Name, err))
 			} else {
 				e.runtime.Error("extension_error", "tool_call hook failed", err)
-				return "", err
+				return "", nil, err
 			}
 		}
 		if hookOut.Blocked {
 			err := fmt.Errorf("tool_blocked: %s", hookOut.Reason)
 			e.runtime.Warning("tool_blocked", err.Error())
-			return "", err
+			return "", nil, err
 		}
 	}
 	tool, ok := e.tools[call.Name]
 	if !ok {
 		if e.ext != nil {
+			if _, registered := e.ext.ToolSpec(call.Name); registered {
+				if !e.toolActive(call.Name) {
+					err := fmt.Errorf("tool_not_active: %s", call.Name)
+					e.runtime.Warning("tool_not_active", err.Error())
+					return fmt.Sprintf("tool_error: %s", err.Error()), nil, nil
+				}
+				denied, allowed, err := e.awaitToolApproval(ctx, call, askApproval)
+				if err != nil {
+					return "", nil, err
+				}
+				if !allowed {
+					return denied, nil, nil
+				}
+			}
 			extResult, handled, err := e.ext.ExecuteTool(call.Name, call.Arguments)
 			if err != nil {
 				if errors.Is(err, extension.ErrTimeout) {
 					e.runtime.Warning("extension_timeout", fmt.Sprintf("extension_tool(%s): %v", call.Name, err))
-					return fmt.Sprintf("tool_error: %v", err), nil
+					return fmt.Sprintf("tool_error: %v", err), nil, nil
 				}
 				e.runtime.Error("extension_error", "extension tool execution failed", err)
-				return "", err
+				return "", nil, err
 			}
 			if handled {
 				result := extResult
@@ -408,29 +587,43 @@ func (e *Engine) executeToolCall(ctx context.Context, call provider.ToolCall) (s
 							e.runtime.Warning("extension_timeout", fmt.Sprintf("tool_result_hook(%s): %v", call.Name, err))
 						} else {
 							e.runtime.Error("extension_error", "tool_result hook failed", err)
-							return "", err
+							return "", nil, err
 						}
 					} else {
 						result = mutated.Result
 					}
 				}
 				if err := e.runtime.ToolExecutionUpdate(call.ID, call.Name, result); err != nil {
-					return "", err
+					return "", nil, err
 				}
-				return result, nil
+				return result, nil, nil
 			}
 		}
 		err := fmt.Errorf("tool_not_found: %s", call.Name)
 		e.runtime.Warning("tool_not_found", err.Error())
-		return "", err
+		return "", nil, err
 	}
-	if _, active := e.active[call.Name]; !active {
+	if !e.toolActive(call.Name) {
 		err := fmt.Errorf("tool_not_active: %s", call.Name)
 		e.runtime.Warning("tool_not_active", err.Error())
-		return fmt.Sprintf("tool_error: %s", err.Error()), nil
+		return fmt.Sprintf("tool_error: %s", err.Error()), nil, nil
 	}
+	if scoped, ok := tool.(ApprovalScopedTool); !ok || askApproval || scoped.NeedsApproval(call.Arguments) {
+		if denied, allowed, err := e.awaitToolApproval(ctx, call, askApproval); err != nil {
+			return "", nil, err
+		} else if !allowed {
+			return denied, nil, nil
+		}
+	}
+	ctx = WithPolicy(ctx, e.policy)
 	var result string
-	if progressive, ok := tool.(ProgressiveTool); ok {
+	var details map[string]any
+	var images []ToolImage
+	if detailed, ok := tool.(DetailedTool); ok {
+		var res ToolResult
+		res, err = detailed.ExecuteWithDetails(ctx, call.Arguments)
+		result, details, images = res.Text, res.Details, res.Images
+	} else if progressive, ok := tool.(ProgressiveTool); ok {
 		result, err = progressive.ExecuteWithProgress(ctx, call.Arguments, func(delta string) {
 			delta = strings.TrimSpace(delta)
 			if delta == "" {
@@ -443,7 +636,7 @@ func (e *Engine) executeToolCall(ctx context.Context, call provider.ToolCall) (s
 	}
 	if err != nil {
 		e.runtime.Warning("tool_execution_error", err.Error())
-		return fmt.Sprintf("tool_error: %v", err), nil
+		return fmt.Sprintf("tool_error: %v", err), nil, nil
 	}
 	if e.ext != nil {
 		mutated, err := e.ext.RunToolResultHooks(call.Name, result)
@@ -452,16 +645,16 @@ func (e *Engine) executeToolCall(ctx context.Context, call provider.ToolCall) (s
 				e.runtime.Warning("extension_timeout", fmt.Sprintf("tool_result_hook(%s): %v", call.Name, err))
 			} else {
 				e.runtime.Error("extension_error", "tool_result hook failed", err)
-				return "", err
+				return "", nil, err
 			}
 		} else {
 			result = mutated.Result
 		}
 	}
-	if err := e.runtime.ToolExecutionUpdate(call.ID, call.Name, result); err != nil {
-		return "", err
+	if err := e.runtime.ToolExecutionUpdateWithDetails(call.ID, call.Name, result, details); err != nil {
+		return "", nil, err
 	}
-	return result, nil
+	return result, images, nil
 }
 
 func (e *Engine) skipToolCall(call provider.ToolCall, reason string) (string, error) {
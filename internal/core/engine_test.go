@@ -174,3 +174,23 @@ func TestPromptStreamsMultipleDeltasIntoMessageUpdates(t *testing.T) {
 		}
 	}
 }
+
+func TestPromptWithHistorySendsPriorMessages(t *testing.T) {
+	p := &captureProvider{}
+	e := NewEngine(NewRuntime(), p)
+	history := []Message{
+		NewTextMessage(RoleUser, "first"),
+		NewTextMessage(RoleAssistant, "answer"),
+	}
+	if _, err := e.PromptWithHistory(context.Background(), "run-history", history, "second"); err != nil {
+		t.Fatalf("prompt with history failed: %v", err)
+	}
+	got := make([]string, 0, len(p.last.Messages))
+	for _, msg := range p.last.Messages {
+		got = append(got, msg.Role+":"+msg.Content)
+	}
+	want := []string{"user:first", "assistant:answer", "user:second"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected provider messages\nwant=%v\ngot=%v", want, got)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"nous/internal/extension"
 	"nous/internal/provider"
 )
 
@@ -120,8 +121,8 @@ func TestSetActiveToolsFiltersProviderPayload(t *testing.T) {
 	if _, err := e.Prompt(context.Background(), "run-active", "hello"); err != nil {
 		t.Fatalf("prompt failed: %v", err)
 	}
-	if len(p.last.ActiveTools) != 1 || p.last.ActiveTools[0] != "second" {
-		t.Fatalf("unexpected active tools payload: %+v", p.last.ActiveTools)
+	if len(p.last.Tools) != 1 || p.last.Tools[0].Name != "second" {
+		t.Fatalf("unexpected active tools payload: %+v", p.last.Tools)
 	}
 }
 
@@ -160,6 +161,54 @@ func TestInactiveToolCallReturnsToolErrorMessage(t *testing.T) {
 	}
 }
 
+func TestSetActiveToolsAppliesToExtensionTools(t *testing.T) {
+	p := &captureProvider{}
+	e := NewEngine(NewRuntime(), p)
+	e.SetTools([]Tool{
+		ToolFunc{ToolName: "first", Run: func(_ context.Context, _ map[string]any) (string, error) { return "", nil }},
+	})
+	ran := false
+	m := extension.NewManager()
+	for _, name := range []string{"second", "third"} {
+		if err := m.RegisterTool(name, func(map[string]any) (string, error) { ran = true; return "ext-ok", nil }); err != nil {
+			t.Fatalf("register extension tool failed: %v", err)
+		}
+	}
+	e.SetExtensionManager(m)
+	names := func() []string {
+		out := []string{}
+		for _, spec := range e.toolSpecs() {
+			out = append(out, spec.Name)
+		}
+		return out
+	}
+	if got := strings.Join(names(), ","); got != "first,second,third" {
+		t.Fatalf("expected every extension tool before narrowing, got %s", got)
+	}
+
+	if err := e.SetActiveTools([]string{"first", "third"}); err != nil {
+		t.Fatalf("set active tools failed: %v", err)
+	}
+	if _, err := e.Prompt(context.Background(), "run-active-ext", "hello"); err != nil {
+		t.Fatalf("prompt failed: %v", err)
+	}
+	if len(p.last.Tools) != 2 || p.last.Tools[0].Name != "first" || p.last.Tools[1].Name != "third" {
+		t.Fatalf("expected inactive extension tools hidden, got %+v", p.last.Tools)
+	}
+
+	e.provider = inactiveToolCallProvider{}
+	out, err := e.Prompt(context.Background(), "run-inactive-ext", "x")
+	if err != nil {
+		t.Fatalf("expected no fatal error, got: %v", err)
+	}
+	if ran || !strings.Contains(out, "tool_error: tool_not_active: second") {
+		t.Fatalf("inactive extension tool must not run, ran=%v output=%q", ran, out)
+	}
+	if err := e.SetActiveTools([]string{"missing"}); err == nil || err.Error() != "tool_not_registered: missing" {
+		t.Fatalf("expected unknown tool rejected, got %v", err)
+	}
+}
+
 type awaitNextProvider struct {
 	calls []provider.Request
 }
@@ -223,6 +272,26 @@ func (p infiniteAwaitProvider) Stream(_ context.Context, _ provider.Request) <-c
 	return out
 }
 
+func TestToolResultImagesReachProvider(t *testing.T) {
+	r := NewRuntime()
+	p := &awaitNextProvider{}
+	e := NewEngine(r, p)
+	e.SetTools([]Tool{
+		DetailedToolFunc{ToolName: "first", Run: func(_ context.Context, _ map[string]any) (ToolResult, error) {
+			return ToolResult{Text: "image a.png", Images: []ToolImage{{MediaType: "image/png", Data: []byte("hi")}}}, nil
+		}},
+	})
+
+	if _, err := e.Prompt(context.Background(), "run-tool-image", "hello"); err != nil {
+		t.Fatalf("prompt failed: %v", err)
+	}
+	last := p.calls[1].Messages[len(p.calls[1].Messages)-1]
+	image := last.Blocks[len(last.Blocks)-1]
+	if last.Role != "tool_result" || image.Type != BlockTypeImage || image.MediaType != "image/png" || image.Data != "aGk=" {
+		t.Fatalf("expected image block on tool result, got: %+v", last)
+	}
+}
+
 func TestAwaitNextTurnLimitExceeded(t *testing.T) {
 	r := NewRuntime()
 	e := NewEngine(r, infiniteAwaitProvider{})
@@ -378,3 +447,157 @@ func TestNonProgressToolStillEmitsSingleExecutionUpdate(t *testing.T) {
 		t.Fatalf("expected single final update for non-progress tool, got: %v", updates)
 	}
 }
+
+func TestDetailedToolAttachesDetailsToFinalUpdate(t *testing.T) {
+	r := NewRuntime()
+	p := &progressToolProvider{}
+	e := NewEngine(r, p)
+	e.SetTools([]Tool{
+		DetailedToolFunc{
+			ToolName: "progressive",
+			Run: func(_ context.Context, _ map[string]any) (ToolResult, error) {
+				return ToolResult{Text: "complete", Details: map[string]any{"diff": "+x\n"}}, nil
+			},
+		},
+	})
+
+	updates := make([]Event, 0, 2)
+	unsub := e.Subscribe(func(ev Event) {
+		if ev.Type == EventToolExecutionUpdate && ev.ToolCallID == "t-progress" {
+			updates = append(updates, ev)
+		}
+	})
+	defer unsub()
+
+	out, err := e.Prompt(context.Background(), "run-tool-details", "go")
+	if err != nil {
+		t.Fatalf("prompt failed: %v", err)
+	}
+	if !strings.Contains(out, "complete") {
+		t.Fatalf("expected tool text in output, got: %q", out)
+	}
+	if len(updates) != 1 || updates[0].Delta != "complete" || updates[0].Details["diff"] != "+x\n" {
+		t.Fatalf("expected final update with details, got: %+v", updates)
+	}
+}
+
+func TestProviderRequestCarriesDeclaredToolSchemas(t *testing.T) {
+	r := NewRuntime()
+	p := &captureProvider{}
+	e := NewEngine(r, p)
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"path": map[string]any{"type": "string"}},
+		"required":   []string{"path"},
+	}
+	e.SetTools([]Tool{
+		ToolFunc{ToolName: "plain", Run: func(_ context.Context, _ map[string]any) (string, error) { return "", nil }},
+		ToolFunc{ToolName: "read", ToolDescription: "Read a file.", ToolSchema: schema, Run: func(_ context.Context, _ map[string]any) (string, error) { return "", nil }},
+	})
+	m := extension.NewManager()
+	if err := m.RegisterToolWithSpec(extension.ToolSpec{Name: "demo.echo", Description: "Echo text."}, func(args map[string]any) (string, error) {
+		return "", nil
+	}); err != nil {
+		t.Fatalf("register extension tool failed: %v", err)
+	}
+	if err := m.RegisterTool("read", func(args map[string]any) (string, error) { return "", nil }); err != nil {
+		t.Fatalf("register shadowed extension tool failed: %v", err)
+	}
+	e.SetExtensionManager(m)
+
+	if _, err := e.Prompt(context.Background(), "run-specs", "hello"); err != nil {
+		t.Fatalf("prompt failed: %v", err)
+	}
+	tools := p.last.Tools
+	if len(tools) != 3 {
+		t.Fatalf("unexpected tool specs: %+v", tools)
+	}
+	if tools[0].Name != "plain" || tools[0].Description != "" || tools[0].Parameters != nil {
+		t.Fatalf("undeclared tool should carry an empty spec: %+v", tools[0])
+	}
+	if tools[1].Name != "read" || tools[1].Description != "Read a file." || tools[1].Parameters["type"] != "object" {
+		t.Fatalf("declared tool spec not threaded through: %+v", tools[1])
+	}
+	if tools[2].Name != "demo.echo" || tools[2].Description != "Echo text." {
+		t.Fatalf("extension tool spec missing: %+v", tools[2])
+	}
+}
+
+func TestRunTurnReturnsToolTranscript(t *testing.T) {
+	e := NewEngine(NewRuntime(), scriptedProvider{})
+	e.SetTools([]Tool{
+		ToolFunc{ToolName: "first", Run: func(_ context.Context, _ map[string]any) (string, error) { return "first-ok", nil }},
+		ToolFunc{ToolName: "second", Run: func(_ context.Context, _ map[string]any) (string, error) { return "second-ok", nil }},
+	})
+
+	history := []Message{NewTextMessage(RoleUser, "earlier")}
+	out, err := e.RunTurn(context.Background(), "run-transcript", history, "go")
+	if err != nil {
+		t.Fatalf("run turn failed: %v", err)
+	}
+	if out.Text != "first-oksecond-ok" {
+		t.Fatalf("unexpected final text: %q", out.Text)
+	}
+	if len(out.Messages) != 3 {
+		t.Fatalf("expected assistant call message and two results, got: %+v", out.Messages)
+	}
+	calls := extractToolCalls(out.Messages[0].Blocks)
+	if out.Messages[0].Role != RoleAssistant || len(calls) != 2 || calls[0].ID != "t1" || calls[1].Name != "second" {
+		t.Fatalf("unexpected assistant tool call message: %+v", out.Messages[0])
+	}
+	first := out.Messages[1]
+	if first.Role != RoleToolResult || first.ToolCallID != "t1" || first.Text != "first => first-ok" {
+		t.Fatalf("unexpected first tool result: %+v", first)
+	}
+	if out.Messages[2].ToolCallID != "t2" {
+		t.Fatalf("unexpected second tool result: %+v", out.Messages[2])
+	}
+}
+
+type resettableTool struct {
+	ToolFunc
+	resets *int
+}
+
+func (t resettableTool) ResetSession() { *t.resets++ }
+
+func (t resettableTool) ToolState() map[string]any {
+	return map[string]any{"resets": *t.resets}
+}
+
+func TestResetToolSessionsResetsSessionTools(t *testing.T) {
+	e := NewEngine(NewRuntime(), scriptedProvider{})
+	resets := 0
+	e.SetTools([]Tool{
+		resettableTool{ToolFunc: ToolFunc{ToolName: "shell"}, resets: &resets},
+		ToolFunc{ToolName: "plain"},
+	})
+	e.ResetToolSessions()
+	if resets != 1 {
+		t.Fatalf("expected one session reset, got %d", resets)
+	}
+	states := e.ToolStates()
+	if len(states) != 1 || states["shell"]["resets"] != 1 {
+		t.Fatalf("unexpected tool states: %+v", states)
+	}
+}
+
+type backgroundTool struct {
+	ToolFunc
+	stops *int
+}
+
+func (t backgroundTool) StopBackground() { *t.stops++ }
+
+func TestStopBackgroundToolsStopsOnlyBackgroundTools(t *testing.T) {
+	e := NewEngine(NewRuntime(), scriptedProvider{})
+	stops := 0
+	e.SetTools([]Tool{
+		backgroundTool{ToolFunc: ToolFunc{ToolName: "process"}, stops: &stops},
+		resettableTool{ToolFunc: ToolFunc{ToolName: "shell"}, resets: new(int)},
+	})
+	e.StopBackgroundTools()
+	if stops != 1 {
+		t.Fatalf("expected one background stop, got %d", stops)
+	}
+}
@@ -15,24 +15,27 @@ const (
 	EventToolExecutionStart  EventType = "tool_execution_start"
 	EventToolExecutionUpdate EventType = "tool_execution_update"
 	EventToolExecutionEnd    EventType = "tool_execution_end"
+	EventToolApprovalRequest EventType = "tool_approval_requested"
 	EventStatus              EventType = "status"
 	EventWarning             EventType = "warning"
 	EventError               EventType = "error"
 )
 
 type Event struct {
-	Type       EventType `json:"type"`
-	RunID      string    `json:"run_id,omitempty"`
-	Turn       int       `json:"turn,omitempty"`
-	MessageID  string    `json:"message_id,omitempty"`
-	Role       string    `json:"role,omitempty"`
-	Delta      string    `json:"delta,omitempty"`
-	ToolCallID string    `json:"tool_call_id,omitempty"`
-	ToolName   string    `json:"tool_name,omitempty"`
-	Message    string    `json:"message,omitempty"`
-	Code       string    `json:"code,omitempty"`
-	Cause      string    `json:"cause,omitempty"`
-	Timestamp  string    `json:"ts"`
+	Type       EventType      `json:"type"`
+	RunID      string         `json:"run_id,omitempty"`
+	Turn       int            `json:"turn,omitempty"`
+	MessageID  string         `json:"message_id,omitempty"`
+	Role       string         `json:"role,omitempty"`
+	Delta      string         `json:"delta,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+	ToolName   string         `json:"tool_name,omitempty"`
+	Arguments  map[string]any `json:"arguments,omitempty"`
+	Details    map[string]any `json:"details,omitempty"`
+	Message    string         `json:"message,omitempty"`
+	Code       string         `json:"code,omitempty"`
+	Cause      string         `json:"cause,omitempty"`
+	Timestamp  string         `json:"ts"`
 }
 
 type EventListener func(Event)
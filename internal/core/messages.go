@@ -1,6 +1,7 @@
 package core
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -22,6 +23,7 @@ const (
 	BlockTypeToolCall   = "tool_call"
 	BlockTypeToolResult = "tool_result"
 	BlockTypeThinking   = "thinking"
+	BlockTypeImage      = "image"
 )
 
 type MessageBlock struct {
@@ -30,6 +32,11 @@ type MessageBlock struct {
 	ToolCallID string
 	ToolName   string
 	Arguments  map[string]any
+	// ThoughtSignature is replayed with Gemini tool calls.
+	ThoughtSignature string
+	// MediaType and Data (base64) are set on image blocks.
+	MediaType string
+	Data      string
 }
 
 type Message struct {
@@ -39,26 +46,47 @@ type Message struct {
 	ToolCallID string
 }
 
+// NewTextMessage returns a message holding a single text block.
+func NewTextMessage(role MessageRole, text string) Message {
+	text = strings.TrimSpace(text)
+	return Message{
+		Role:   role,
+		Text:   text,
+		Blocks: []MessageBlock{{Type: BlockTypeText, Text: text}},
+	}
+}
+
 func appendMessage(messages []Message, role MessageRole, text string) []Message {
 	text = strings.TrimSpace(text)
 	if text == "" {
 		return messages
 	}
-	return append(messages, Message{
-		Role: role,
-		Text: text,
-		Blocks: []MessageBlock{
-			{Type: BlockTypeText, Text: text},
-		},
-	})
+	return append(messages, NewTextMessage(role, text))
 }
 
-func appendToolResultMessage(messages []Message, toolCallID, toolName, result string) []Message {
+func appendToolResultMessage(messages []Message, toolCallID, toolName, result string, images ...ToolImage) []Message {
 	result = strings.TrimSpace(result)
 	if result == "" {
-		return messages
+		if strings.TrimSpace(toolCallID) == "" {
+			return messages
+		}
+		result = provider.EmptyToolResult
+	}
+	msg := NewToolResultMessage(toolCallID, toolName, result)
+	for _, img := range images {
+		msg.Blocks = append(msg.Blocks, MessageBlock{
+			Type:      BlockTypeImage,
+			MediaType: img.MediaType,
+			Data:      base64.StdEncoding.EncodeToString(img.Data),
+		})
 	}
-	return append(messages, Message{
+	return append(messages, msg)
+}
+
+// NewToolResultMessage returns the tool_result message answering toolCallID.
+func NewToolResultMessage(toolCallID, toolName, result string) Message {
+	result = strings.TrimSpace(result)
+	return Message{
 		Role:       RoleToolResult,
 		Text:       result,
 		ToolCallID: strings.TrimSpace(toolCallID),
@@ -70,7 +98,7 @@ func appendToolResultMessage(messages []Message, toolCallID, toolName, result st
 				ToolName:   strings.TrimSpace(toolName),
 			},
 		},
-	})
+	}
 }
 
 func providerMessagesFromCore(messages []Message) []provider.Message {
@@ -137,6 +165,8 @@ func providerBlocksFromCore(blocks []MessageBlock) []provider.ContentBlock {
 			ToolCallID: strings.TrimSpace(block.ToolCallID),
 			ToolName:   strings.TrimSpace(block.ToolName),
 			Arguments:  cloneMap(block.Arguments),
+			MediaType:  block.MediaType,
+			Data:       block.Data,
 		})
 	}
 	return out
@@ -154,9 +184,10 @@ func extractToolCalls(blocks []MessageBlock) []provider.ToolCall {
 			continue
 		}
 		out = append(out, provider.ToolCall{
-			ID:        id,
-			Name:      name,
-			Arguments: cloneMap(block.Arguments),
+			ID:               id,
+			Name:             name,
+			Arguments:        cloneMap(block.Arguments),
+			ThoughtSignature: block.ThoughtSignature,
 		})
 	}
 	return out
@@ -122,3 +122,13 @@ func TestDefaultConvertRendersUnknownBlocksToTextFallback(t *testing.T) {
 		t.Fatalf("unexpected fallback content: %+v", got[0])
 	}
 }
+
+func TestAppendToolResultMessageKeepsEmptyResults(t *testing.T) {
+	got := appendToolResultMessage(nil, "c1", "grep", "  ")
+	if len(got) != 1 || got[0].ToolCallID != "c1" || got[0].Text != provider.EmptyToolResult {
+		t.Fatalf("expected placeholder result answering c1, got: %+v", got)
+	}
+	if got = appendToolResultMessage(nil, "", "grep", ""); len(got) != 0 {
+		t.Fatalf("expected empty result without call id to be dropped, got: %+v", got)
+	}
+}
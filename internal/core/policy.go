@@ -0,0 +1,387 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"nous/internal/fspath"
+	"nous/internal/glob"
+)
+
+type PolicyAction string
+
+const (
+	PolicyAllow PolicyAction = "allow"
+	PolicyDeny  PolicyAction = "deny"
+	PolicyAsk   PolicyAction = "ask"
+)
+
+// DefaultPolicyRuleID names the decision taken when no rule matches.
+const DefaultPolicyRuleID = "default"
+
+// pathPolicyTools are the builtins whose "path" argument is checked against
+// rule path globs.
+var pathPolicyTools = map[string]struct{}{
+	"read":    {},
+	"write":   {},
+	"edit":    {},
+	"ls":      {},
+	"grep":    {},
+	"find":    {},
+	"git":     {},
+	"symbols": {},
+}
+
+// commandPolicyTools are the tools whose "command" argument Commands rules
+// match.
+var commandPolicyTools = map[string]struct{}{
+	"bash":    {},
+	"process": {},
+}
+
+// domainPolicyTools are the tools whose "url" host Domains rules match.
+var domainPolicyTools = map[string]struct{}{
+	"fetch": {},
+}
+
+// PolicyRule matches a tool call by tool name and, optionally, by path glob,
+// shell command pattern or URL host. Within Paths, Commands and Domains any
+// entry may match; a rule with several needs a match in each.
+type PolicyRule struct {
+	ID       string       `json:"id"`
+	Tool     string       `json:"tool"`
+	Paths    []string     `json:"paths,omitempty"`
+	Commands []string     `json:"commands,omitempty"`
+	Domains  []string     `json:"domains,omitempty"`
+	Action   PolicyAction `json:"action"`
+
+	commands []*regexp.Regexp
+	domains  []*regexp.Regexp
+}
+
+// Policy is a static tool permission policy. Rules are checked in order and
+// the first match wins; Default applies when nothing matches.
+type Policy struct {
+	Source  string       `json:"source,omitempty"`
+	Workdir string       `json:"workdir,omitempty"`
+	Default PolicyAction `json:"default"`
+	Rules   []PolicyRule `json:"rules"`
+}
+
+type PolicyDecision struct {
+	Action PolicyAction
+	RuleID string
+}
+
+// LoadPolicyFile reads a JSON policy file. Relative path globs are matched
+// against paths relative to workdir.
+func LoadPolicyFile(path, workdir string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("policy_read_failed: %s: %w", path, err)
+	}
+	p, err := ParsePolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w (%s)", err, path)
+	}
+	p.Source = path
+	p.Workdir = workdir
+	return p, nil
+}
+
+func ParsePolicy(data []byte) (*Policy, error) {
+	var p Policy
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("invalid_policy: %v", err)
+	}
+	if err := p.compile(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (p *Policy) compile() error {
+	if p.Default == "" {
+		p.Default = PolicyAllow
+	}
+	if !validPolicyAction(p.Default) {
+		return fmt.Errorf("invalid_policy: unknown default action %q", p.Default)
+	}
+	seen := map[string]struct{}{}
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		rule.ID = strings.TrimSpace(rule.ID)
+		rule.Tool = strings.TrimSpace(rule.Tool)
+		if rule.ID == "" {
+			return fmt.Errorf("invalid_policy: rule %d has no id", i)
+		}
+		if rule.ID == DefaultPolicyRuleID {
+			return fmt.Errorf("invalid_policy: rule id %q is reserved", rule.ID)
+		}
+		if _, dup := seen[rule.ID]; dup {
+			return fmt.Errorf("invalid_policy: duplicate rule id %q", rule.ID)
+		}
+		seen[rule.ID] = struct{}{}
+		if rule.Tool == "" {
+			return fmt.Errorf("invalid_policy: rule %s has no tool", rule.ID)
+		}
+		if !validPolicyAction(rule.Action) {
+			return fmt.Errorf("invalid_policy: rule %s has unknown action %q", rule.ID, rule.Action)
+		}
+		for j, pattern := range rule.Paths {
+			pattern = filepath.ToSlash(expandPolicyHome(strings.TrimSpace(pattern)))
+			if pattern == "" || !glob.Valid(pattern) {
+				return fmt.Errorf("invalid_policy: rule %s has invalid path glob %q", rule.ID, rule.Paths[j])
+			}
+			rule.Paths[j] = pattern
+		}
+		rule.commands = make([]*regexp.Regexp, 0, len(rule.Commands))
+		for _, pattern := range rule.Commands {
+			if strings.TrimSpace(pattern) == "" {
+				return fmt.Errorf("invalid_policy: rule %s has empty command pattern", rule.ID)
+			}
+			rule.commands = append(rule.commands, compileCommandPattern(pattern))
+		}
+		rule.domains = make([]*regexp.Regexp, 0, len(rule.Domains))
+		for _, pattern := range rule.Domains {
+			if strings.TrimSpace(pattern) == "" {
+				return fmt.Errorf("invalid_policy: rule %s has empty domain pattern", rule.ID)
+			}
+			rule.domains = append(rule.domains, compileCommandPattern(strings.ToLower(pattern)))
+		}
+	}
+	return nil
+}
+
+func validPolicyAction(a PolicyAction) bool {
+	return a == PolicyAllow || a == PolicyDeny || a == PolicyAsk
+}
+
+// compileCommandPattern turns a wildcard pattern into a regexp: "*" matches
+// any text and surrounding whitespace is ignored.
+func compileCommandPattern(pattern string) *regexp.Regexp {
+	quoted := regexp.QuoteMeta(strings.TrimSpace(pattern))
+	return regexp.MustCompile(`^` + strings.ReplaceAll(quoted, `\*`, `.*`) + `$`)
+}
+
+func expandPolicyHome(pattern string) string {
+	if pattern != "~" && !strings.HasPrefix(pattern, "~/") {
+		return pattern
+	}
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return pattern
+	}
+	return filepath.Join(home, strings.TrimPrefix(pattern, "~"))
+}
+
+// Evaluate returns the action for a call to toolName with args.
+func (p *Policy) Evaluate(toolName string, args map[string]any) PolicyDecision {
+	if p == nil {
+		return PolicyDecision{Action: PolicyAllow, RuleID: DefaultPolicyRuleID}
+	}
+	for _, rule := range p.Rules {
+		if p.ruleMatches(rule, toolName, args) {
+			return PolicyDecision{Action: rule.Action, RuleID: rule.ID}
+		}
+	}
+	return PolicyDecision{Action: p.Default, RuleID: DefaultPolicyRuleID}
+}
+
+func (p *Policy) ruleMatches(rule PolicyRule, toolName string, args map[string]any) bool {
+	if rule.Tool != "*" && rule.Tool != toolName {
+		return false
+	}
+	if len(rule.Paths) > 0 && !p.pathMatches(rule.Paths, toolName, args) {
+		return false
+	}
+	if len(rule.commands) > 0 {
+		if _, ok := commandPolicyTools[toolName]; !ok {
+			return false
+		}
+		command, _ := args["command"].(string)
+		command = strings.TrimSpace(command)
+		matched := false
+		for _, re := range rule.commands {
+			if re.MatchString(command) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(rule.domains) > 0 && !domainMatches(rule.domains, toolName, args) {
+		return false
+	}
+	return true
+}
+
+// domainMatches checks the host of a fetch call's url; "*" in a pattern
+// matches any text, so "*.example.com" covers subdomains.
+func domainMatches(patterns []*regexp.Regexp, toolName string, args map[string]any) bool {
+	if _, ok := domainPolicyTools[toolName]; !ok {
+		return false
+	}
+	raw, _ := args["url"].(string)
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, re := range patterns {
+		if re.MatchString(host) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) pathMatches(patterns []string, toolName string, args map[string]any) bool {
+	if toolName == "apply_patch" {
+		patch, _ := args["patch"].(string)
+		for _, path := range patchPolicyPaths(patch) {
+			if p.pathMatchesOne(patterns, path) {
+				return true
+			}
+		}
+		return false
+	}
+	if _, ok := pathPolicyTools[toolName]; !ok {
+		return false
+	}
+	raw, _ := args["path"].(string)
+	return p.pathMatchesOne(patterns, raw)
+}
+
+func (p *Policy) pathMatchesOne(patterns []string, raw string) bool {
+	for _, path := range p.policyPaths(raw) {
+		for _, pattern := range patterns {
+			if strings.HasPrefix(pattern, "/") {
+				if path.abs != "" && glob.Match(pattern, filepath.ToSlash(path.abs)) {
+					return true
+				}
+				continue
+			}
+			if path.rel != "" && glob.Match(pattern, filepath.ToSlash(path.rel)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// policyPath is one spelling of a path argument: absolute, and relative to
+// the workdir when inside it.
+type policyPath struct {
+	abs string
+	rel string
+}
+
+// policyPaths expands a path argument the way the builtins do ($VAR, ~ and
+// the workdir) and adds its symlink-resolved form, so a rule matches however
+// the path is spelled.
+func (p *Policy) policyPaths(raw string) []policyPath {
+	path := fspath.Expand(raw)
+	if path == "" {
+		path = "."
+	}
+	if !filepath.IsAbs(path) {
+		if p.Workdir == "" {
+			return []policyPath{{rel: filepath.Clean(path)}}
+		}
+		path = filepath.Join(p.Workdir, path)
+	}
+	path = filepath.Clean(path)
+	paths := []policyPath{{abs: path, rel: relWithin(p.Workdir, path)}}
+	if canonical := fspath.Canonical(path); canonical != path {
+		workdir := p.Workdir
+		if workdir != "" {
+			workdir = fspath.Canonical(workdir)
+		}
+		paths = append(paths, policyPath{abs: canonical, rel: relWithin(workdir, canonical)})
+	}
+	return paths
+}
+
+func relWithin(dir, path string) string {
+	if dir == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return rel
+}
+
+// patchPolicyPaths lists every file an apply_patch patch names, in either
+// the unified or the "*** Begin Patch" format.
+func patchPolicyPaths(patch string) []string {
+	var paths []string
+	for _, line := range strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n") {
+		var path string
+		switch {
+		case strings.HasPrefix(line, "*** Add File: "), strings.HasPrefix(line, "*** Update File: "),
+			strings.HasPrefix(line, "*** Delete File: "), strings.HasPrefix(line, "*** Move to: "):
+			path = line[strings.Index(line, ": ")+2:]
+		case strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "+++ "):
+			path, _, _ = strings.Cut(line[4:], "\t")
+			path = strings.TrimSpace(path)
+			if path == "/dev/null" {
+				continue
+			}
+			// Check both the git-prefixed and the bare reading.
+			if len(path) > 2 && (path[:2] == "a/" || path[:2] == "b/") {
+				paths = append(paths, path[2:])
+			}
+		case strings.HasPrefix(line, "rename from "), strings.HasPrefix(line, "rename to "):
+			_, path, _ = strings.Cut(strings.TrimPrefix(line, "rename "), " ")
+		default:
+			continue
+		}
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+type policyContextKey struct{}
+
+// WithPolicy attaches p to ctx, so tools can check follow-up requests (such
+// as fetch redirects) that the engine never sees.
+func WithPolicy(ctx context.Context, p *Policy) context.Context {
+	if p == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, policyContextKey{}, p)
+}
+
+// PolicyFromContext returns the policy attached by WithPolicy, or nil.
+func PolicyFromContext(ctx context.Context) *Policy {
+	if ctx == nil {
+		return nil
+	}
+	p, _ := ctx.Value(policyContextKey{}).(*Policy)
+	return p
+}
+
+// SetPolicy installs a static tool policy; nil removes it.
+func (e *Engine) SetPolicy(p *Policy) {
+	e.policy = p
+}
+
+func (e *Engine) Policy() *Policy {
+	return e.policy
+}
@@ -0,0 +1,243 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPolicyJSON = `{
+  "default": "allow",
+  "rules": [
+    {"id": "no-env", "tool": "*", "paths": ["**/.env"], "action": "deny"},
+    {"id": "no-etc", "tool": "read", "paths": ["/etc/**"], "action": "deny"},
+    {"id": "ask-rm", "tool": "bash", "commands": ["rm *", "* | sh"], "action": "ask"},
+    {"id": "no-pkill", "tool": "*", "commands": ["pkill *"], "action": "deny"},
+    {"id": "ask-intranet", "tool": "*", "domains": ["*.corp.example", "localhost"], "action": "ask"},
+    {"id": "deny-bash", "tool": "bash", "action": "deny"}
+  ]
+}`
+
+func TestPolicyEvaluate(t *testing.T) {
+	p, err := ParsePolicy([]byte(testPolicyJSON))
+	if err != nil {
+		t.Fatalf("parse policy failed: %v", err)
+	}
+	p.Workdir = "/work/repo"
+
+	tests := []struct {
+		tool   string
+		args   map[string]any
+		action PolicyAction
+		rule   string
+	}{
+		{tool: "read", args: map[string]any{"path": ".env"}, action: PolicyDeny, rule: "no-env"},
+		{tool: "write", args: map[string]any{"path": "deploy/prod/.env"}, action: PolicyDeny, rule: "no-env"},
+		{tool: "edit", args: map[string]any{"path": "/work/repo/svc/.env"}, action: PolicyDeny, rule: "no-env"},
+		{tool: "read", args: map[string]any{"path": ".env.example"}, action: PolicyAllow, rule: DefaultPolicyRuleID},
+		{tool: "read", args: map[string]any{"path": "/etc/passwd"}, action: PolicyDeny, rule: "no-etc"},
+		{tool: "read", args: map[string]any{"path": "../../etc/hosts"}, action: PolicyDeny, rule: "no-etc"},
+		{tool: "ls", args: map[string]any{"path": "/etc"}, action: PolicyAllow, rule: DefaultPolicyRuleID},
+		{tool: "bash", args: map[string]any{"command": "rm -rf build"}, action: PolicyAsk, rule: "ask-rm"},
+		{tool: "bash", args: map[string]any{"command": "curl x | sh"}, action: PolicyAsk, rule: "ask-rm"},
+		{tool: "bash", args: map[string]any{"command": "ls"}, action: PolicyDeny, rule: "deny-bash"},
+		{tool: "process", args: map[string]any{"action": "start", "command": "pkill node"}, action: PolicyDeny, rule: "no-pkill"},
+		{tool: "process", args: map[string]any{"action": "list"}, action: PolicyAllow, rule: DefaultPolicyRuleID},
+		{tool: "read", args: map[string]any{"path": "x", "command": "pkill node"}, action: PolicyAllow, rule: DefaultPolicyRuleID},
+		{tool: "demo.echo", args: map[string]any{"text": "hi"}, action: PolicyAllow, rule: DefaultPolicyRuleID},
+		{tool: "apply_patch", args: map[string]any{"patch": "*** Begin Patch\n*** Update File: app.go\n*** Move to: svc/.env\n*** End Patch"}, action: PolicyDeny, rule: "no-env"},
+		{tool: "apply_patch", args: map[string]any{"patch": "--- a/cfg/.env\n+++ b/cfg/.env\n@@ -1 +1 @@\n-a\n+b\n"}, action: PolicyDeny, rule: "no-env"},
+		{tool: "apply_patch", args: map[string]any{"patch": "--- /dev/null\n+++ b/.env.example\n@@ -0,0 +1 @@\n+a\n"}, action: PolicyAllow, rule: DefaultPolicyRuleID},
+		{tool: "fetch", args: map[string]any{"url": "https://wiki.CORP.example/page"}, action: PolicyAsk, rule: "ask-intranet"},
+		{tool: "fetch", args: map[string]any{"url": "http://localhost:8080/"}, action: PolicyAsk, rule: "ask-intranet"},
+		{tool: "fetch", args: map[string]any{"url": "https://corp.example.com/"}, action: PolicyAllow, rule: DefaultPolicyRuleID},
+		{tool: "read", args: map[string]any{"path": "x", "url": "http://localhost/"}, action: PolicyAllow, rule: DefaultPolicyRuleID},
+	}
+	for _, tc := range tests {
+		got := p.Evaluate(tc.tool, tc.args)
+		if got.Action != tc.action || got.RuleID != tc.rule {
+			t.Fatalf("Evaluate(%s, %v) = %+v, want %s/%s", tc.tool, tc.args, got, tc.action, tc.rule)
+		}
+	}
+
+	var none *Policy
+	if got := none.Evaluate("bash", nil); got.Action != PolicyAllow {
+		t.Fatalf("nil policy must allow, got %+v", got)
+	}
+}
+
+func TestPolicyEvaluateNormalizesPaths(t *testing.T) {
+	p, err := ParsePolicy([]byte(testPolicyJSON))
+	if err != nil {
+		t.Fatalf("parse policy failed: %v", err)
+	}
+	p.Workdir = t.TempDir()
+	t.Setenv("HOME", "/usr")
+	t.Setenv("NOUS_POLICY_ROOT", "/")
+	if err := os.Symlink("/etc", filepath.Join(p.Workdir, "sys")); err != nil {
+		t.Fatalf("symlink failed: %v", err)
+	}
+	if err := os.Symlink(".env", filepath.Join(p.Workdir, "notes.txt")); err != nil {
+		t.Fatalf("symlink failed: %v", err)
+	}
+
+	for raw, want := range map[string]string{
+		"$HOME/../etc/passwd":            "no-etc",
+		"${NOUS_POLICY_ROOT}etc/passwd":  "no-etc",
+		"~/../etc/passwd":                "no-etc",
+		"sys/passwd":                     "no-etc",
+		"notes.txt":                      "no-env",
+		"$NOUS_POLICY_UNSET/etc/passwd":  DefaultPolicyRuleID,
+		"$HOME/../etc-backup/passwd.txt": DefaultPolicyRuleID,
+	} {
+		if got := p.Evaluate("read", map[string]any{"path": raw}); got.RuleID != want {
+			t.Fatalf("Evaluate(read, %s) = %+v, want rule %s", raw, got, want)
+		}
+	}
+}
+
+func TestParsePolicyRejectsInvalidRules(t *testing.T) {
+	cases := map[string]string{
+		"missing id":      `{"rules":[{"tool":"bash","action":"deny"}]}`,
+		"duplicate id":    `{"rules":[{"id":"a","tool":"bash","action":"deny"},{"id":"a","tool":"read","action":"deny"}]}`,
+		"reserved id":     `{"rules":[{"id":"default","tool":"bash","action":"deny"}]}`,
+		"missing tool":    `{"rules":[{"id":"a","action":"deny"}]}`,
+		"bad action":      `{"rules":[{"id":"a","tool":"bash","action":"maybe"}]}`,
+		"bad default":     `{"default":"maybe","rules":[]}`,
+		"bad glob":        `{"rules":[{"id":"a","tool":"read","paths":["src/[x"],"action":"deny"}]}`,
+		"unknown field":   `{"rules":[{"id":"a","tool":"read","action":"deny","path":"x"}]}`,
+		"empty command":   `{"rules":[{"id":"a","tool":"bash","commands":[" "],"action":"deny"}]}`,
+		"empty domain":    `{"rules":[{"id":"a","tool":"fetch","domains":[""],"action":"deny"}]}`,
+		"not json object": `[]`,
+	}
+	for name, raw := range cases {
+		if _, err := ParsePolicy([]byte(raw)); err == nil || !strings.HasPrefix(err.Error(), "invalid_policy") {
+			t.Fatalf("%s: expected invalid_policy error, got %v", name, err)
+		}
+	}
+}
+
+func TestLoadPolicyFileRecordsSource(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.json")
+	if err := os.WriteFile(path, []byte(testPolicyJSON), 0o644); err != nil {
+		t.Fatalf("write policy failed: %v", err)
+	}
+	p, err := LoadPolicyFile(path, dir)
+	if err != nil {
+		t.Fatalf("load policy failed: %v", err)
+	}
+	if p.Source != path || p.Workdir != dir || len(p.Rules) != 6 {
+		t.Fatalf("unexpected policy: %+v", p)
+	}
+	if _, err := LoadPolicyFile(filepath.Join(dir, "missing.json"), dir); err == nil || !strings.HasPrefix(err.Error(), "policy_read_failed") {
+		t.Fatalf("expected policy_read_failed, got %v", err)
+	}
+}
+
+func TestPolicyDenyBlocksToolWithRuleID(t *testing.T) {
+	executed := []string{}
+	e := newApprovalTestEngine(t, &executed)
+	p, err := ParsePolicy([]byte(`{"rules":[{"id":"no-first","tool":"first","action":"deny"}]}`))
+	if err != nil {
+		t.Fatalf("parse policy failed: %v", err)
+	}
+	e.SetPolicy(p)
+	warnings := []Event{}
+	unsub := e.Subscribe(func(ev Event) {
+		if ev.Type == EventWarning {
+			warnings = append(warnings, ev)
+		}
+	})
+	defer unsub()
+
+	out, err := e.Prompt(context.Background(), "run-policy-deny", "go")
+	if err != nil {
+		t.Fatalf("prompt failed: %v", err)
+	}
+	if len(executed) != 1 || executed[0] != "second" {
+		t.Fatalf("expected only the allowed tool to run, got: %v", executed)
+	}
+	if !strings.Contains(out, "tool_error: tool_blocked: first denied by policy rule no-first") {
+		t.Fatalf("expected blocked result, got: %q", out)
+	}
+	if len(warnings) != 1 || warnings[0].Code != "tool_blocked" || !strings.Contains(warnings[0].Message, "no-first") {
+		t.Fatalf("expected tool_blocked warning naming the rule, got: %+v", warnings)
+	}
+}
+
+func TestPolicyAskRequestsApprovalWithoutApprovalConfig(t *testing.T) {
+	executed := []string{}
+	e := newApprovalTestEngine(t, &executed)
+	p, err := ParsePolicy([]byte(`{"rules":[{"id":"ask-second","tool":"second","action":"ask"}]}`))
+	if err != nil {
+		t.Fatalf("parse policy failed: %v", err)
+	}
+	e.SetPolicy(p)
+	requests, unsub := answerApprovals(e, func(ev Event) {
+		_ = e.RejectToolCall(ev.ToolCallID, "")
+	})
+	defer unsub()
+
+	out, err := e.Prompt(context.Background(), "run-policy-ask", "go")
+	if err != nil {
+		t.Fatalf("prompt failed: %v", err)
+	}
+	if len(*requests) != 1 || (*requests)[0] != "second" {
+		t.Fatalf("expected approval request for second, got: %v", *requests)
+	}
+	if len(executed) != 1 || executed[0] != "first" {
+		t.Fatalf("expected rejected tool to be skipped, got: %v", executed)
+	}
+	if !strings.Contains(out, "tool call rejected: rejected by user") {
+		t.Fatalf("expected rejection result, got: %q", out)
+	}
+}
+
+func TestPolicyAskIgnoresSessionApproval(t *testing.T) {
+	executed := []string{}
+	e := newApprovalTestEngine(t, &executed)
+	_ = e.SetToolApproval(ApprovalConfig{Enabled: true, Tools: []string{"second"}})
+	requests, unsub := answerApprovals(e, func(ev Event) {
+		_ = e.ApproveToolCall(ev.ToolCallID, ApprovalAllowSession)
+	})
+	defer unsub()
+	if _, err := e.Prompt(context.Background(), "run-session-grant", "go"); err != nil {
+		t.Fatalf("prompt failed: %v", err)
+	}
+
+	p, err := ParsePolicy([]byte(`{"rules":[{"id":"ask-second","tool":"second","action":"ask"}]}`))
+	if err != nil {
+		t.Fatalf("parse policy failed: %v", err)
+	}
+	e.SetPolicy(p)
+	if _, err := e.Prompt(context.Background(), "run-policy-ask-after-grant", "go"); err != nil {
+		t.Fatalf("prompt failed: %v", err)
+	}
+	if len(*requests) != 2 {
+		t.Fatalf("policy ask must prompt despite the session grant, got: %v", *requests)
+	}
+}
+
+func TestEngineAttachesPolicyToToolContext(t *testing.T) {
+	e := NewEngine(NewRuntime(), scriptedProvider{})
+	var seen []*Policy
+	record := func(ctx context.Context, _ map[string]any) (string, error) {
+		seen = append(seen, PolicyFromContext(ctx))
+		return "ok", nil
+	}
+	e.SetTools([]Tool{ToolFunc{ToolName: "first", Run: record}, ToolFunc{ToolName: "second", Run: record}})
+	p, err := ParsePolicy([]byte(`{"rules":[]}`))
+	if err != nil {
+		t.Fatalf("parse policy failed: %v", err)
+	}
+	e.SetPolicy(p)
+	if _, err := e.Prompt(context.Background(), "run-policy-context", "go"); err != nil {
+		t.Fatalf("prompt failed: %v", err)
+	}
+	if len(seen) != 2 || seen[0] != p || seen[1] != p {
+		t.Fatalf("tools must see the engine policy, got: %v", seen)
+	}
+}
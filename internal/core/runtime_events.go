@@ -100,13 +100,19 @@ func (r *Runtime) ToolExecutionStart(toolCallID, toolName string) error {
 }
 
 func (r *Runtime) ToolExecutionUpdate(toolCallID, toolName, delta string) error {
+	return r.ToolExecutionUpdateWithDetails(toolCallID, toolName, delta, nil)
+}
+
+// ToolExecutionUpdateWithDetails is ToolExecutionUpdate with structured
+// details, such as a diff, for clients to render.
+func (r *Runtime) ToolExecutionUpdateWithDetails(toolCallID, toolName, delta string, details map[string]any) error {
 	if r.state != StateRunning && r.state != StateAborting {
 		return fmt.Errorf("invalid_transition: %s -> tool_execution_update", r.state)
 	}
 	if toolCallID == "" || toolName == "" {
 		return fmt.Errorf("invalid_tool_call")
 	}
-	r.emit(Event{Type: EventToolExecutionUpdate, RunID: r.runID, Turn: r.turnNumber, ToolCallID: toolCallID, ToolName: toolName, Delta: delta, Timestamp: nowTS()})
+	r.emit(Event{Type: EventToolExecutionUpdate, RunID: r.runID, Turn: r.turnNumber, ToolCallID: toolCallID, ToolName: toolName, Delta: delta, Details: details, Timestamp: nowTS()})
 	return nil
 }
 
@@ -147,3 +153,14 @@ func (r *Runtime) Error(code, message string, cause error) {
 	}
 	r.emit(ev)
 }
+
+func (r *Runtime) ToolApprovalRequested(toolCallID, toolName string, args map[string]any) error {
+	if r.state != StateRunning && r.state != StateAborting {
+		return fmt.Errorf("invalid_transition: %s -> tool_approval_requested", r.state)
+	}
+	if toolCallID == "" || toolName == "" {
+		return fmt.Errorf("invalid_tool_call")
+	}
+	r.emit(Event{Type: EventToolApprovalRequest, RunID: r.runID, Turn: r.turnNumber, ToolCallID: toolCallID, ToolName: toolName, Arguments: cloneMap(args), Timestamp: nowTS()})
+	return nil
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ContextFile is a project instruction file appended to the system prompt.
+type ContextFile struct {
+	Path    string
+	Content string
+}
+
+var contextFileNames = []string{
+	"AGENTS.md",
+	filepath.Join(".nous", "SYSTEM.md"),
+}
+
+// LoadContextFiles collects project instruction files from workdir up to the
+// enclosing repository root (the nearest directory containing .git). Outer
+// directories come first so files closer to workdir can refine them. When
+// workdir is not inside a repository only workdir itself is searched.
+func LoadContextFiles(workdir string) ([]ContextFile, error) {
+	dir, err := filepath.Abs(workdir)
+	if err != nil {
+		return nil, err
+	}
+	dirs := []string{dir}
+	if root, ok := findRepoRoot(dir); ok {
+		for cur := dir; cur != root; {
+			cur = filepath.Dir(cur)
+			dirs = append(dirs, cur)
+		}
+	}
+
+	files := make([]ContextFile, 0, 2)
+	for i := len(dirs) - 1; i >= 0; i-- {
+		for _, name := range contextFileNames {
+			path := filepath.Join(dirs[i], name)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				return nil, fmt.Errorf("context_file_read_failed: %s: %w", path, err)
+			}
+			content := strings.TrimSpace(string(data))
+			if content == "" {
+				continue
+			}
+			files = append(files, ContextFile{Path: path, Content: content})
+		}
+	}
+	return files, nil
+}
+
+func findRepoRoot(dir string) (string, bool) {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// BuildSystemPrompt joins the configured prompt with the project context
+// files, each under a heading naming its path.
+func BuildSystemPrompt(prompt string, files []ContextFile) string {
+	parts := make([]string, 0, len(files)+2)
+	if prompt = strings.TrimSpace(prompt); prompt != "" {
+		parts = append(parts, prompt)
+	}
+	if len(files) > 0 {
+		parts = append(parts, "# Project context")
+		for _, file := range files {
+			parts = append(parts, fmt.Sprintf("## %s\n\n%s", file.Path, strings.TrimSpace(file.Content)))
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
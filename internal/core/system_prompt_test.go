@@ -0,0 +1,91 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeContextFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+}
+
+func TestLoadContextFilesWalksUpToRepoRoot(t *testing.T) {
+	outside := t.TempDir()
+	writeContextFile(t, filepath.Join(outside, "AGENTS.md"), "outside repo")
+	root := filepath.Join(outside, "repo")
+	if err := os.MkdirAll(filepath.Join(root, ".git"), 0o755); err != nil {
+		t.Fatalf("mkdir .git failed: %v", err)
+	}
+	writeContextFile(t, filepath.Join(root, "AGENTS.md"), "root rules")
+	writeContextFile(t, filepath.Join(root, ".nous", "SYSTEM.md"), "root system")
+	workdir := filepath.Join(root, "pkg", "sub")
+	writeContextFile(t, filepath.Join(workdir, "AGENTS.md"), "sub rules")
+	writeContextFile(t, filepath.Join(root, "pkg", "AGENTS.md"), "  \n")
+
+	files, err := LoadContextFiles(workdir)
+	if err != nil {
+		t.Fatalf("load context files failed: %v", err)
+	}
+	got := make([]string, 0, len(files))
+	for _, file := range files {
+		got = append(got, file.Content)
+	}
+	want := []string{"root rules", "root system", "sub rules"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected context files: %+v", files)
+	}
+}
+
+func TestLoadContextFilesWithoutRepoOnlyReadsWorkdir(t *testing.T) {
+	parent := t.TempDir()
+	writeContextFile(t, filepath.Join(parent, "AGENTS.md"), "parent")
+	workdir := filepath.Join(parent, "work")
+	writeContextFile(t, filepath.Join(workdir, "AGENTS.md"), "work")
+
+	files, err := LoadContextFiles(workdir)
+	if err != nil {
+		t.Fatalf("load context files failed: %v", err)
+	}
+	if len(files) != 1 || files[0].Content != "work" {
+		t.Fatalf("unexpected context files: %+v", files)
+	}
+}
+
+func TestBuildSystemPrompt(t *testing.T) {
+	if got := BuildSystemPrompt("  ", nil); got != "" {
+		t.Fatalf("expected empty prompt, got %q", got)
+	}
+	got := BuildSystemPrompt("be brief", []ContextFile{{Path: "/repo/AGENTS.md", Content: "run tests\n"}})
+	want := "be brief\n\n# Project context\n\n## /repo/AGENTS.md\n\nrun tests"
+	if got != want {
+		t.Fatalf("unexpected system prompt\nwant=%q\ngot=%q", want, got)
+	}
+}
+
+func TestEngineSendsSystemPromptWithContextFiles(t *testing.T) {
+	p := &captureProvider{}
+	e := NewEngine(NewRuntime(), p)
+	e.SetSystemPrompt("you are nous")
+	e.SetContextFiles([]ContextFile{{Path: "AGENTS.md", Content: "use gofmt"}})
+
+	if _, err := e.Prompt(context.Background(), "run-system", "hello"); err != nil {
+		t.Fatalf("prompt failed: %v", err)
+	}
+	if !strings.HasPrefix(p.last.System, "you are nous") || !strings.Contains(p.last.System, "use gofmt") {
+		t.Fatalf("unexpected system prompt: %q", p.last.System)
+	}
+	for _, msg := range p.last.Messages {
+		if msg.Role == "system" {
+			t.Fatalf("system prompt must travel in Request.System, got message: %+v", msg)
+		}
+	}
+}
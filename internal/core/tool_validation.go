@@ -2,269 +2,427 @@ package core
 
 import (
 	"fmt"
+	"math"
+	"slices"
 	"strconv"
 	"strings"
 )
 
-func normalizeToolArguments(toolName string, args map[string]any) (map[string]any, error) {
-	switch toolName {
-	case "read":
-		return normalizeReadArgs(args)
-	case "ls":
-		return normalizeLSArgs(args)
-	case "find":
-		return normalizeFindArgs(args)
-	case "grep":
-		return normalizeGrepArgs(args)
-	case "write":
-		return normalizeWriteArgs(args)
-	case "edit":
-		return normalizeEditArgs(args)
-	case "bash":
-		return normalizeBashArgs(args)
-	default:
+// schemaAliasesKey lists alternative argument names a property accepts.
+// Aliases are rewritten to the canonical property name before validation.
+const schemaAliasesKey = "x-aliases"
+
+// validateToolArguments checks args against a tool's JSON schema and returns a
+// normalized copy: aliases resolved, scalars coerced to the declared type and
+// defaults applied. Tools without a schema get their arguments unchanged.
+func validateToolArguments(toolName string, schema map[string]any, args map[string]any) (map[string]any, error) {
+	if len(schema) == 0 {
 		return args, nil
 	}
+	if args == nil {
+		args = map[string]any{}
+	}
+	out, err := validateSchemaValue(toolName, schema, args)
+	if err != nil {
+		return nil, err
+	}
+	normalized, ok := out.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("validation_failed: %s arguments must be an object", toolName)
+	}
+	return normalized, nil
 }
 
-func normalizeReadArgs(args map[string]any) (map[string]any, error) {
-	path := resolveStringArg(args,
-		"path", "file_path", "filePath", "filepath", "file", "target_path", "targetPath")
-	if path == "" {
-		return nil, fmt.Errorf("validation_failed: read.path is required")
+// publicToolSchema returns a copy of schema without the engine-only "x-"
+// keywords, so providers only see standard JSON schema.
+func publicToolSchema(schema map[string]any) map[string]any {
+	if schema == nil {
+		return nil
 	}
-	out := map[string]any{"path": path}
-
-	if n, ok, err := resolveIntArg(args, []string{"offset", "start_line", "startLine"}); err != nil {
-		return nil, fmt.Errorf("validation_failed: read.offset must be a number")
-	} else if ok {
-		if n < 0 {
-			return nil, fmt.Errorf("validation_failed: read.offset must be >= 0")
+	out := make(map[string]any, len(schema))
+	for key, value := range schema {
+		if strings.HasPrefix(key, "x-") {
+			continue
 		}
-		out["offset"] = n
+		out[key] = publicSchemaValue(value)
 	}
-	if n, ok, err := resolveIntArg(args, []string{"limit", "max_lines", "maxLines"}); err != nil {
-		return nil, fmt.Errorf("validation_failed: read.limit must be a number")
-	} else if ok {
-		if n == 0 || n < -1 {
-			return nil, fmt.Errorf("validation_failed: read.limit must be > 0 or -1")
+	return out
+}
+
+func publicSchemaValue(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		return publicToolSchema(x)
+	case []any:
+		out := make([]any, 0, len(x))
+		for _, item := range x {
+			out = append(out, publicSchemaValue(item))
 		}
-		out["limit"] = n
+		return out
+	default:
+		return v
 	}
-	return out, nil
 }
 
-func normalizeLSArgs(args map[string]any) (map[string]any, error) {
-	path := resolveStringArg(args, "path", "dir", "directory", "target_path", "targetPath")
-	if path == "" {
-		path = "."
+func validateSchemaValue(path string, schema map[string]any, value any) (any, error) {
+	types := schemaTypes(schema)
+	var (
+		out any
+		err error
+	)
+	switch {
+	case len(types) == 0:
+		out = value
+	default:
+		out, err = coerceSchemaValue(path, types, schema, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := checkSchemaEnum(path, schema, out); err != nil {
+		return nil, err
+	}
+	if err := checkSchemaRange(path, schema, out); err != nil {
+		return nil, err
 	}
-	return map[string]any{"path": path}, nil
+	return out, nil
 }
 
-func normalizeFindArgs(args map[string]any) (map[string]any, error) {
-	query := resolveStringArg(args, "query", "pattern")
-	if query == "" {
-		return nil, fmt.Errorf("validation_failed: find.query is required")
+func coerceSchemaValue(path string, types []string, schema map[string]any, value any) (any, error) {
+	var firstErr error
+	for _, typ := range types {
+		out, err := coerceSchemaType(path, typ, schema, value)
+		if err == nil {
+			return out, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
 	}
-	out := map[string]any{"query": query}
-	path := resolveStringArg(args, "path", "dir", "directory", "target_path", "targetPath")
-	if path == "" {
-		path = "."
+	if len(types) > 1 {
+		return nil, fmt.Errorf("validation_failed: %s must be one of types [%s]", path, strings.Join(types, ", "))
 	}
-	out["path"] = path
+	return nil, firstErr
+}
 
-	if n, ok, err := resolveIntArg(args, []string{"max_results", "maxResults"}); err != nil {
-		return nil, fmt.Errorf("validation_failed: find.max_results must be a number")
-	} else if ok {
-		if n <= 0 {
-			return nil, fmt.Errorf("validation_failed: find.max_results must be > 0")
+func coerceSchemaType(path, typ string, schema map[string]any, value any) (any, error) {
+	switch typ {
+	case "string":
+		s, ok := toSchemaString(value)
+		if !ok {
+			return nil, fmt.Errorf("validation_failed: %s must be a string", path)
 		}
-		out["max_results"] = n
-	}
-	if n, ok, err := resolveIntArg(args, []string{"max_depth", "maxDepth"}); err != nil {
-		return nil, fmt.Errorf("validation_failed: find.max_depth must be a number")
-	} else if ok {
-		if n < -1 {
-			return nil, fmt.Errorf("validation_failed: find.max_depth must be >= -1")
+		return s, nil
+	case "integer":
+		n, err := toInt(value)
+		if err != nil {
+			return nil, fmt.Errorf("validation_failed: %s must be an integer", path)
+		}
+		return n, nil
+	case "number":
+		n, err := toFloat(value)
+		if err != nil {
+			return nil, fmt.Errorf("validation_failed: %s must be a number", path)
+		}
+		return n, nil
+	case "boolean":
+		b, err := toBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("validation_failed: %s must be a boolean", path)
+		}
+		return b, nil
+	case "null":
+		if value != nil {
+			return nil, fmt.Errorf("validation_failed: %s must be null", path)
 		}
-		out["max_depth"] = n
+		return nil, nil
+	case "array":
+		return validateSchemaArray(path, schema, value)
+	case "object":
+		return validateSchemaObject(path, schema, value)
+	default:
+		return value, nil
 	}
-	return out, nil
 }
 
-func normalizeGrepArgs(args map[string]any) (map[string]any, error) {
-	pattern := resolveStringArg(args, "pattern", "query")
-	if pattern == "" {
-		return nil, fmt.Errorf("validation_failed: grep.pattern is required")
+func validateSchemaArray(path string, schema map[string]any, value any) (any, error) {
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("validation_failed: %s must be an array", path)
 	}
-	out := map[string]any{"pattern": pattern}
-	path := resolveStringArg(args, "path", "dir", "directory", "target_path", "targetPath")
-	if path == "" {
-		path = "."
+	if n, ok := schemaNumber(schema, "minItems"); ok && float64(len(items)) < n {
+		return nil, fmt.Errorf("validation_failed: %s must have at least %d items", path, int(n))
 	}
-	out["path"] = path
-
-	if n, ok, err := resolveIntArg(args, []string{"limit", "max_results", "maxResults"}); err != nil {
-		return nil, fmt.Errorf("validation_failed: grep.limit must be a number")
-	} else if ok {
-		if n <= 0 {
-			return nil, fmt.Errorf("validation_failed: grep.limit must be > 0")
-		}
-		out["limit"] = n
+	if n, ok := schemaNumber(schema, "maxItems"); ok && float64(len(items)) > n {
+		return nil, fmt.Errorf("validation_failed: %s must have at most %d items", path, int(n))
 	}
-	if b, ok, err := resolveBoolArg(args, []string{"ignore_case", "ignoreCase"}); err != nil {
-		return nil, fmt.Errorf("validation_failed: grep.ignore_case must be a boolean")
-	} else if ok {
-		out["ignore_case"] = b
+	itemSchema, _ := schema["items"].(map[string]any)
+	out := make([]any, 0, len(items))
+	for i, item := range items {
+		if itemSchema == nil {
+			out = append(out, item)
+			continue
+		}
+		next, err := validateSchemaValue(fmt.Sprintf("%s[%d]", path, i), itemSchema, item)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, next)
 	}
 	return out, nil
 }
 
-func normalizeWriteArgs(args map[string]any) (map[string]any, error) {
-	path := resolveStringArg(args,
-		"path", "file_path", "filePath", "filepath", "file", "target_path", "targetPath")
-	if path == "" {
-		return nil, fmt.Errorf("validation_failed: write.path is required")
-	}
-	content, ok := resolveRequiredStringField(args, "content", "text", "body")
+func validateSchemaObject(path string, schema map[string]any, value any) (any, error) {
+	in, ok := value.(map[string]any)
 	if !ok {
-		return nil, fmt.Errorf("validation_failed: write.content is required")
+		return nil, fmt.Errorf("validation_failed: %s must be an object", path)
 	}
-	return map[string]any{
-		"path":    path,
-		"content": content,
-	}, nil
-}
-
-func normalizeEditArgs(args map[string]any) (map[string]any, error) {
-	path := resolveStringArg(args,
-		"path", "file_path", "filePath", "filepath", "file", "target_path", "targetPath")
-	if path == "" {
-		return nil, fmt.Errorf("validation_failed: edit.path is required")
+	properties, _ := schema["properties"].(map[string]any)
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
 	}
-	oldText, ok := resolveRequiredStringField(args, "oldText", "old_text")
-	if !ok {
-		return nil, fmt.Errorf("validation_failed: edit.oldText is required")
+	slices.Sort(names)
+
+	out := make(map[string]any, len(in))
+	consumed := map[string]bool{}
+	for _, name := range names {
+		propSchema, _ := properties[name].(map[string]any)
+		raw, key, found := lookupSchemaProperty(in, name, propSchema)
+		if !found {
+			if def, ok := propSchema["default"]; ok {
+				out[name] = def
+			}
+			continue
+		}
+		consumed[key] = true
+		next, err := validateSchemaValue(path+"."+name, propSchema, raw)
+		if err != nil {
+			return nil, err
+		}
+		out[name] = next
 	}
-	newText, ok := resolveRequiredStringField(args, "newText", "new_text")
-	if !ok {
-		return nil, fmt.Errorf("validation_failed: edit.newText is required")
+	for _, name := range schemaStrings(schema["required"]) {
+		if _, ok := out[name]; !ok {
+			return nil, fmt.Errorf("validation_failed: %s.%s is required", path, name)
+		}
 	}
-	return map[string]any{
-		"path":    path,
-		"oldText": oldText,
-		"newText": newText,
-	}, nil
-}
 
-func normalizeBashArgs(args map[string]any) (map[string]any, error) {
-	command := resolveStringArg(args, "command", "cmd")
-	if command == "" {
-		return nil, fmt.Errorf("validation_failed: bash.command is required")
+	extraKeys := make([]string, 0)
+	for key := range in {
+		if consumed[key] {
+			continue
+		}
+		if _, declared := properties[key]; declared {
+			continue
+		}
+		extraKeys = append(extraKeys, key)
 	}
-	out := map[string]any{"command": command}
-	if n, ok, err := resolveFloatArg(args, []string{"timeout", "timeout_seconds", "timeoutSeconds"}); err != nil {
-		return nil, fmt.Errorf("validation_failed: bash.timeout must be a number")
-	} else if ok {
-		if n < 0 {
-			return nil, fmt.Errorf("validation_failed: bash.timeout must be >= 0")
+	slices.Sort(extraKeys)
+	additional := schema["additionalProperties"]
+	for _, key := range extraKeys {
+		switch x := additional.(type) {
+		case bool:
+			if !x {
+				return nil, fmt.Errorf("validation_failed: %s.%s is not an allowed argument", path, key)
+			}
+			out[key] = in[key]
+		case map[string]any:
+			next, err := validateSchemaValue(path+"."+key, x, in[key])
+			if err != nil {
+				return nil, err
+			}
+			out[key] = next
+		default:
+			out[key] = in[key]
 		}
-		out["timeout"] = n
 	}
 	return out, nil
 }
 
-func resolveRequiredStringField(args map[string]any, keys ...string) (string, bool) {
-	for _, k := range keys {
-		v, ok := args[k]
-		if !ok {
+// lookupSchemaProperty finds a property by its canonical name first and then
+// by any declared alias. Empty and null values count as missing, so an alias
+// with a value can stand in for a blank canonical key.
+func lookupSchemaProperty(args map[string]any, name string, schema map[string]any) (any, string, bool) {
+	keys := append([]string{name}, schemaStrings(schema[schemaAliasesKey])...)
+	for _, key := range keys {
+		v, ok := args[key]
+		if !ok || v == nil {
 			continue
 		}
-		s, ok := v.(string)
-		if !ok {
-			return "", false
+		if s, ok := v.(string); ok && strings.TrimSpace(s) == "" && !schemaAllowsEmptyString(schema) {
+			continue
 		}
-		return s, true
+		return v, key, true
 	}
-	return "", false
+	return nil, "", false
 }
 
-func resolveStringArg(args map[string]any, keys ...string) string {
-	for _, k := range keys {
-		v, ok := args[k]
-		if !ok {
-			continue
-		}
-		if s := normalizeStringArgValue(v); s != "" {
-			return s
+// schemaAllowsEmptyString reports whether blank strings are meaningful for a
+// property. Only string properties without a minLength accept them, which
+// keeps e.g. empty file content valid while treating a blank path as missing.
+func schemaAllowsEmptyString(schema map[string]any) bool {
+	if !slices.Contains(schemaTypes(schema), "string") {
+		return false
+	}
+	n, ok := schemaNumber(schema, "minLength")
+	return !ok || n <= 0
+}
+
+func checkSchemaEnum(path string, schema map[string]any, value any) error {
+	enum, ok := schema["enum"]
+	if !ok {
+		return nil
+	}
+	options := schemaValues(enum)
+	if len(options) == 0 {
+		return nil
+	}
+	for _, option := range options {
+		if schemaValuesEqual(option, value) {
+			return nil
 		}
 	}
-	return ""
+	rendered := make([]string, 0, len(options))
+	for _, option := range options {
+		rendered = append(rendered, fmt.Sprint(option))
+	}
+	return fmt.Errorf("validation_failed: %s must be one of [%s] (got %v)", path, strings.Join(rendered, ", "), value)
 }
 
-func normalizeStringArgValue(v any) string {
-	switch x := v.(type) {
-	case string:
-		return strings.TrimSpace(x)
-	case map[string]any:
-		if p, ok := x["path"]; ok {
-			return normalizeStringArgValue(p)
+func checkSchemaRange(path string, schema map[string]any, value any) error {
+	if s, ok := value.(string); ok {
+		length := len([]rune(s))
+		if n, ok := schemaNumber(schema, "minLength"); ok && float64(length) < n {
+			return fmt.Errorf("validation_failed: %s must be at least %d characters", path, int(n))
 		}
-		if p, ok := x["value"]; ok {
-			return normalizeStringArgValue(p)
+		if n, ok := schemaNumber(schema, "maxLength"); ok && float64(length) > n {
+			return fmt.Errorf("validation_failed: %s must be at most %d characters", path, int(n))
 		}
+		return nil
+	}
+	var n float64
+	switch x := value.(type) {
+	case int:
+		n = float64(x)
+	case float64:
+		n = x
+	default:
+		return nil
+	}
+	if min, ok := schemaNumber(schema, "minimum"); ok && n < min {
+		return fmt.Errorf("validation_failed: %s must be >= %s (got %s)", path, formatSchemaNumber(min), formatSchemaNumber(n))
+	}
+	if max, ok := schemaNumber(schema, "maximum"); ok && n > max {
+		return fmt.Errorf("validation_failed: %s must be <= %s (got %s)", path, formatSchemaNumber(max), formatSchemaNumber(n))
+	}
+	if min, ok := schemaNumber(schema, "exclusiveMinimum"); ok && n <= min {
+		return fmt.Errorf("validation_failed: %s must be > %s (got %s)", path, formatSchemaNumber(min), formatSchemaNumber(n))
+	}
+	if max, ok := schemaNumber(schema, "exclusiveMaximum"); ok && n >= max {
+		return fmt.Errorf("validation_failed: %s must be < %s (got %s)", path, formatSchemaNumber(max), formatSchemaNumber(n))
+	}
+	return nil
+}
+
+func schemaTypes(schema map[string]any) []string {
+	switch x := schema["type"].(type) {
+	case string:
+		return []string{x}
+	default:
+		return schemaStrings(x)
+	}
+}
+
+func schemaStrings(v any) []string {
+	switch x := v.(type) {
+	case []string:
+		return x
 	case []any:
-		if len(x) > 0 {
-			return normalizeStringArgValue(x[0])
+		out := make([]string, 0, len(x))
+		for _, item := range x {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
 		}
+		return out
+	default:
+		return nil
 	}
-	return ""
 }
 
-func resolveIntArg(args map[string]any, keys []string) (int, bool, error) {
-	for _, k := range keys {
-		v, ok := args[k]
-		if !ok {
-			continue
+func schemaValues(v any) []any {
+	switch x := v.(type) {
+	case []any:
+		return x
+	case []string:
+		out := make([]any, 0, len(x))
+		for _, s := range x {
+			out = append(out, s)
 		}
-		n, err := toInt(v)
-		if err != nil {
-			return 0, true, err
+		return out
+	case []int:
+		out := make([]any, 0, len(x))
+		for _, n := range x {
+			out = append(out, n)
 		}
-		return n, true, nil
+		return out
+	default:
+		return nil
 	}
-	return 0, false, nil
 }
 
-func resolveBoolArg(args map[string]any, keys []string) (bool, bool, error) {
-	for _, k := range keys {
-		v, ok := args[k]
-		if !ok {
-			continue
-		}
-		b, err := toBool(v)
-		if err != nil {
-			return false, true, err
+func schemaValuesEqual(a, b any) bool {
+	if af, err := toFloat(a); err == nil {
+		if _, isString := a.(string); !isString {
+			if bf, err := toFloat(b); err == nil {
+				if _, isString := b.(string); !isString {
+					return af == bf
+				}
+			}
 		}
-		return b, true, nil
 	}
-	return false, false, nil
+	return a == b
 }
 
-func resolveFloatArg(args map[string]any, keys []string) (float64, bool, error) {
-	for _, k := range keys {
-		v, ok := args[k]
-		if !ok {
-			continue
+func schemaNumber(schema map[string]any, key string) (float64, bool) {
+	v, ok := schema[key]
+	if !ok {
+		return 0, false
+	}
+	if _, isString := v.(string); isString {
+		return 0, false
+	}
+	n, err := toFloat(v)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+func formatSchemaNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// toSchemaString accepts plain strings and unwraps the shapes models commonly
+// produce for single values: {"path": "..."}, {"value": "..."} and ["..."].
+func toSchemaString(v any) (string, bool) {
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case map[string]any:
+		if p, ok := x["path"]; ok {
+			return toSchemaString(p)
 		}
-		n, err := toFloat(v)
-		if err != nil {
-			return 0, true, err
+		if p, ok := x["value"]; ok {
+			return toSchemaString(p)
+		}
+	case []any:
+		if len(x) == 1 {
+			return toSchemaString(x[0])
 		}
-		return n, true, nil
 	}
-	return 0, false, nil
+	return "", false
 }
 
 func toInt(v any) (int, error) {
@@ -276,8 +434,14 @@ func toInt(v any) (int, error) {
 	case int64:
 		return int(n), nil
 	case float64:
+		if n != math.Trunc(n) {
+			return 0, fmt.Errorf("not_integer")
+		}
 		return int(n), nil
 	case float32:
+		if float64(n) != math.Trunc(float64(n)) {
+			return 0, fmt.Errorf("not_integer")
+		}
 		return int(n), nil
 	case string:
 		s := strings.TrimSpace(n)
@@ -291,7 +455,7 @@ func toInt(v any) (int, error) {
 		if err != nil {
 			return 0, err
 		}
-		return int(f), nil
+		return toInt(f)
 	default:
 		return 0, fmt.Errorf("not_number")
 	}
@@ -2,14 +2,74 @@ package core
 
 import "testing"
 
-func TestNormalizeReadArgsAcceptsAliasAndCoercesNumbers(t *testing.T) {
-	got, err := normalizeToolArguments("read", map[string]any{
+var pathAliasesForTest = []any{"file_path", "filePath", "filepath", "file", "target_path", "targetPath"}
+
+var testReadSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"path":   map[string]any{"type": "string", "minLength": 1, "x-aliases": pathAliasesForTest},
+		"offset": map[string]any{"type": "integer", "minimum": 0},
+		"limit":  map[string]any{"type": "integer", "minimum": -1},
+	},
+	"required": []any{"path"},
+}
+
+var testFindSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"path":  map[string]any{"type": "string", "default": "."},
+		"query": map[string]any{"type": "string", "minLength": 1, "x-aliases": []any{"pattern"}},
+	},
+	"required": []any{"query"},
+}
+
+var testGrepSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"pattern":     map[string]any{"type": "string", "minLength": 1, "x-aliases": []any{"query"}},
+		"path":        map[string]any{"type": "string", "default": ".", "x-aliases": []any{"dir", "directory"}},
+		"ignore_case": map[string]any{"type": "boolean", "x-aliases": []any{"ignoreCase"}},
+		"limit":       map[string]any{"type": "integer", "minimum": 1, "x-aliases": []any{"max_results", "maxResults"}},
+	},
+	"required": []any{"pattern"},
+}
+
+var testWriteSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"path":    map[string]any{"type": "string", "minLength": 1, "x-aliases": pathAliasesForTest},
+		"content": map[string]any{"type": "string", "x-aliases": []any{"text", "body"}},
+	},
+	"required": []any{"path", "content"},
+}
+
+var testEditSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"path":    map[string]any{"type": "string", "minLength": 1, "x-aliases": pathAliasesForTest},
+		"oldText": map[string]any{"type": "string", "x-aliases": []any{"old_text"}},
+		"newText": map[string]any{"type": "string", "x-aliases": []any{"new_text"}},
+	},
+	"required": []any{"path", "oldText", "newText"},
+}
+
+var testBashSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"command": map[string]any{"type": "string", "minLength": 1, "x-aliases": []any{"cmd"}},
+		"timeout": map[string]any{"type": "number", "minimum": 0, "x-aliases": []any{"timeout_seconds", "timeoutSeconds"}},
+	},
+	"required": []any{"command"},
+}
+
+func TestValidateReadArgsAcceptsAliasAndCoercesNumbers(t *testing.T) {
+	got, err := validateToolArguments("read", testReadSchema, map[string]any{
 		"filePath": "/tmp/txt",
 		"offset":   "2",
 		"limit":    "10",
 	})
 	if err != nil {
-		t.Fatalf("normalize read args failed: %v", err)
+		t.Fatalf("validate read args failed: %v", err)
 	}
 	if p, _ := got["path"].(string); p != "/tmp/txt" {
 		t.Fatalf("unexpected path: %#v", got["path"])
@@ -22,8 +82,8 @@ func TestNormalizeReadArgsAcceptsAliasAndCoercesNumbers(t *testing.T) {
 	}
 }
 
-func TestNormalizeReadArgsRequiresPath(t *testing.T) {
-	_, err := normalizeToolArguments("read", map[string]any{"offset": 0})
+func TestValidateReadArgsRequiresPath(t *testing.T) {
+	_, err := validateToolArguments("read", testReadSchema, map[string]any{"offset": 0})
 	if err == nil {
 		t.Fatalf("expected read path validation error")
 	}
@@ -32,8 +92,8 @@ func TestNormalizeReadArgsRequiresPath(t *testing.T) {
 	}
 }
 
-func TestNormalizeFindArgsRequiresQuery(t *testing.T) {
-	_, err := normalizeToolArguments("find", map[string]any{"path": "."})
+func TestValidateFindArgsRequiresQuery(t *testing.T) {
+	_, err := validateToolArguments("find", testFindSchema, map[string]any{"path": "."})
 	if err == nil {
 		t.Fatalf("expected find query validation error")
 	}
@@ -42,15 +102,15 @@ func TestNormalizeFindArgsRequiresQuery(t *testing.T) {
 	}
 }
 
-func TestNormalizeGrepArgsAcceptsAliases(t *testing.T) {
-	got, err := normalizeToolArguments("grep", map[string]any{
+func TestValidateGrepArgsAcceptsAliases(t *testing.T) {
+	got, err := validateToolArguments("grep", testGrepSchema, map[string]any{
 		"query":      "TODO",
 		"directory":  ".",
 		"maxResults": "3",
 		"ignoreCase": "true",
 	})
 	if err != nil {
-		t.Fatalf("normalize grep args failed: %v", err)
+		t.Fatalf("validate grep args failed: %v", err)
 	}
 	if p, _ := got["pattern"].(string); p != "TODO" {
 		t.Fatalf("unexpected pattern: %#v", got["pattern"])
@@ -66,8 +126,8 @@ func TestNormalizeGrepArgsAcceptsAliases(t *testing.T) {
 	}
 }
 
-func TestNormalizeGrepArgsRequiresPattern(t *testing.T) {
-	_, err := normalizeToolArguments("grep", map[string]any{"path": "."})
+func TestValidateGrepArgsRequiresPattern(t *testing.T) {
+	_, err := validateToolArguments("grep", testGrepSchema, map[string]any{"path": "."})
 	if err == nil {
 		t.Fatalf("expected grep pattern validation error")
 	}
@@ -76,13 +136,13 @@ func TestNormalizeGrepArgsRequiresPattern(t *testing.T) {
 	}
 }
 
-func TestNormalizeWriteArgsAcceptsAliases(t *testing.T) {
-	got, err := normalizeToolArguments("write", map[string]any{
+func TestValidateWriteArgsAcceptsAliases(t *testing.T) {
+	got, err := validateToolArguments("write", testWriteSchema, map[string]any{
 		"filePath": "a.txt",
 		"text":     "hello",
 	})
 	if err != nil {
-		t.Fatalf("normalize write args failed: %v", err)
+		t.Fatalf("validate write args failed: %v", err)
 	}
 	if p, _ := got["path"].(string); p != "a.txt" {
 		t.Fatalf("unexpected path: %#v", got["path"])
@@ -92,33 +152,33 @@ func TestNormalizeWriteArgsAcceptsAliases(t *testing.T) {
 	}
 }
 
-func TestNormalizeWriteArgsRequiresPathAndContent(t *testing.T) {
-	if _, err := normalizeToolArguments("write", map[string]any{"content": "x"}); err == nil || err.Error() != "validation_failed: write.path is required" {
+func TestValidateWriteArgsRequiresPathAndContent(t *testing.T) {
+	if _, err := validateToolArguments("write", testWriteSchema, map[string]any{"content": "x"}); err == nil || err.Error() != "validation_failed: write.path is required" {
 		t.Fatalf("unexpected error for missing path: %v", err)
 	}
-	if _, err := normalizeToolArguments("write", map[string]any{"path": "a.txt"}); err == nil || err.Error() != "validation_failed: write.content is required" {
+	if _, err := validateToolArguments("write", testWriteSchema, map[string]any{"path": "a.txt"}); err == nil || err.Error() != "validation_failed: write.content is required" {
 		t.Fatalf("unexpected error for missing content: %v", err)
 	}
 }
 
-func TestNormalizeWriteArgsAllowsEmptyContent(t *testing.T) {
-	got, err := normalizeToolArguments("write", map[string]any{"path": "a.txt", "content": ""})
+func TestValidateWriteArgsAllowsEmptyContent(t *testing.T) {
+	got, err := validateToolArguments("write", testWriteSchema, map[string]any{"path": "a.txt", "content": ""})
 	if err != nil {
-		t.Fatalf("normalize write with empty content failed: %v", err)
+		t.Fatalf("validate write with empty content failed: %v", err)
 	}
 	if c, _ := got["content"].(string); c != "" {
 		t.Fatalf("expected empty content, got: %#v", got["content"])
 	}
 }
 
-func TestNormalizeEditArgsAcceptsAliases(t *testing.T) {
-	got, err := normalizeToolArguments("edit", map[string]any{
+func TestValidateEditArgsAcceptsAliases(t *testing.T) {
+	got, err := validateToolArguments("edit", testEditSchema, map[string]any{
 		"filePath": "a.txt",
 		"old_text": "before",
 		"new_text": "after",
 	})
 	if err != nil {
-		t.Fatalf("normalize edit args failed: %v", err)
+		t.Fatalf("validate edit args failed: %v", err)
 	}
 	if p, _ := got["path"].(string); p != "a.txt" {
 		t.Fatalf("unexpected path: %#v", got["path"])
@@ -131,25 +191,25 @@ func TestNormalizeEditArgsAcceptsAliases(t *testing.T) {
 	}
 }
 
-func TestNormalizeEditArgsRequiresFields(t *testing.T) {
-	if _, err := normalizeToolArguments("edit", map[string]any{"oldText": "x", "newText": "y"}); err == nil || err.Error() != "validation_failed: edit.path is required" {
+func TestValidateEditArgsRequiresFields(t *testing.T) {
+	if _, err := validateToolArguments("edit", testEditSchema, map[string]any{"oldText": "x", "newText": "y"}); err == nil || err.Error() != "validation_failed: edit.path is required" {
 		t.Fatalf("unexpected error for missing path: %v", err)
 	}
-	if _, err := normalizeToolArguments("edit", map[string]any{"path": "a.txt", "newText": "y"}); err == nil || err.Error() != "validation_failed: edit.oldText is required" {
+	if _, err := validateToolArguments("edit", testEditSchema, map[string]any{"path": "a.txt", "newText": "y"}); err == nil || err.Error() != "validation_failed: edit.oldText is required" {
 		t.Fatalf("unexpected error for missing oldText: %v", err)
 	}
-	if _, err := normalizeToolArguments("edit", map[string]any{"path": "a.txt", "oldText": "x"}); err == nil || err.Error() != "validation_failed: edit.newText is required" {
+	if _, err := validateToolArguments("edit", testEditSchema, map[string]any{"path": "a.txt", "oldText": "x"}); err == nil || err.Error() != "validation_failed: edit.newText is required" {
 		t.Fatalf("unexpected error for missing newText: %v", err)
 	}
 }
 
-func TestNormalizeBashArgsAcceptsAliases(t *testing.T) {
-	got, err := normalizeToolArguments("bash", map[string]any{
+func TestValidateBashArgsAcceptsAliases(t *testing.T) {
+	got, err := validateToolArguments("bash", testBashSchema, map[string]any{
 		"cmd":            "printf OK",
 		"timeoutSeconds": "3",
 	})
 	if err != nil {
-		t.Fatalf("normalize bash args failed: %v", err)
+		t.Fatalf("validate bash args failed: %v", err)
 	}
 	if c, _ := got["command"].(string); c != "printf OK" {
 		t.Fatalf("unexpected command: %#v", got["command"])
@@ -159,8 +219,106 @@ func TestNormalizeBashArgsAcceptsAliases(t *testing.T) {
 	}
 }
 
-func TestNormalizeBashArgsRequiresCommand(t *testing.T) {
-	if _, err := normalizeToolArguments("bash", map[string]any{}); err == nil || err.Error() != "validation_failed: bash.command is required" {
+func TestValidateBashArgsRequiresCommand(t *testing.T) {
+	if _, err := validateToolArguments("bash", testBashSchema, map[string]any{}); err == nil || err.Error() != "validation_failed: bash.command is required" {
 		t.Fatalf("unexpected error for missing bash command: %v", err)
 	}
 }
+
+func TestValidateAppliesDefaults(t *testing.T) {
+	got, err := validateToolArguments("grep", testGrepSchema, map[string]any{"pattern": "TODO"})
+	if err != nil {
+		t.Fatalf("validate grep args failed: %v", err)
+	}
+	if path, _ := got["path"].(string); path != "." {
+		t.Fatalf("expected default path, got: %#v", got["path"])
+	}
+}
+
+func TestValidateReportsTypeAndRangeErrors(t *testing.T) {
+	cases := []struct {
+		tool   string
+		schema map[string]any
+		args   map[string]any
+		want   string
+	}{
+		{"read", testReadSchema, map[string]any{"path": "a", "offset": "abc"}, "validation_failed: read.offset must be an integer"},
+		{"read", testReadSchema, map[string]any{"path": "a", "offset": 1.5}, "validation_failed: read.offset must be an integer"},
+		{"read", testReadSchema, map[string]any{"path": "a", "offset": -1}, "validation_failed: read.offset must be >= 0 (got -1)"},
+		{"grep", testGrepSchema, map[string]any{"pattern": "x", "limit": 0}, "validation_failed: grep.limit must be >= 1 (got 0)"},
+		{"grep", testGrepSchema, map[string]any{"pattern": "x", "ignore_case": "maybe"}, "validation_failed: grep.ignore_case must be a boolean"},
+		{"bash", testBashSchema, map[string]any{"command": "ls", "timeout": -2}, "validation_failed: bash.timeout must be >= 0 (got -2)"},
+		{"write", testWriteSchema, map[string]any{"path": 12, "content": "x"}, "validation_failed: write.path must be a string"},
+	}
+	for _, tc := range cases {
+		_, err := validateToolArguments(tc.tool, tc.schema, tc.args)
+		if err == nil || err.Error() != tc.want {
+			t.Fatalf("args %#v: got error %v, want %q", tc.args, err, tc.want)
+		}
+	}
+}
+
+func TestValidateChecksEnumsAndNestedArrays(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"mode": map[string]any{"type": "string", "enum": []any{"fast", "safe"}},
+			"edits": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"oldText": map[string]any{"type": "string"},
+						"count":   map[string]any{"type": "integer", "default": 1},
+					},
+					"required": []any{"oldText"},
+				},
+			},
+		},
+		"additionalProperties": false,
+	}
+	if _, err := validateToolArguments("demo", schema, map[string]any{"mode": "slow"}); err == nil || err.Error() != "validation_failed: demo.mode must be one of [fast, safe] (got slow)" {
+		t.Fatalf("unexpected enum error: %v", err)
+	}
+	if _, err := validateToolArguments("demo", schema, map[string]any{"edits": []any{map[string]any{"oldText": "a"}, map[string]any{}}}); err == nil || err.Error() != "validation_failed: demo.edits[1].oldText is required" {
+		t.Fatalf("unexpected nested error: %v", err)
+	}
+	if _, err := validateToolArguments("demo", schema, map[string]any{"extra": true}); err == nil || err.Error() != "validation_failed: demo.extra is not an allowed argument" {
+		t.Fatalf("unexpected additionalProperties error: %v", err)
+	}
+	got, err := validateToolArguments("demo", schema, map[string]any{"mode": "safe", "edits": []any{map[string]any{"oldText": "a", "count": "3"}}})
+	if err != nil {
+		t.Fatalf("validate nested args failed: %v", err)
+	}
+	edits, _ := got["edits"].([]any)
+	first, _ := edits[0].(map[string]any)
+	if count, _ := first["count"].(int); count != 3 {
+		t.Fatalf("expected nested integer coercion, got: %#v", first)
+	}
+}
+
+func TestValidateWithoutSchemaPassesArgumentsThrough(t *testing.T) {
+	args := map[string]any{"anything": 1}
+	got, err := validateToolArguments("demo.echo", nil, args)
+	if err != nil {
+		t.Fatalf("validate without schema failed: %v", err)
+	}
+	if got["anything"] != 1 {
+		t.Fatalf("expected args to pass through, got: %#v", got)
+	}
+}
+
+func TestPublicToolSchemaDropsEngineKeywords(t *testing.T) {
+	public := publicToolSchema(testReadSchema)
+	props, _ := public["properties"].(map[string]any)
+	path, _ := props["path"].(map[string]any)
+	if _, ok := path["x-aliases"]; ok {
+		t.Fatalf("x-aliases must not reach providers: %#v", path)
+	}
+	if path["type"] != "string" {
+		t.Fatalf("standard keywords must be kept: %#v", path)
+	}
+	if _, ok := testReadSchema["properties"].(map[string]any)["path"].(map[string]any)["x-aliases"]; !ok {
+		t.Fatalf("publicToolSchema must not mutate the source schema")
+	}
+}
@@ -7,6 +7,67 @@ type Tool interface {
 	Execute(ctx context.Context, args map[string]any) (string, error)
 }
 
+// DescribedTool is implemented by tools that advertise a description and a
+// JSON schema for their arguments. Tools without one are sent to the model
+// with an open object schema.
+type DescribedTool interface {
+	Tool
+	Description() string
+	Schema() map[string]any
+}
+
+// SessionTool is implemented by tools that keep per-session state, such as
+// a persistent shell. ResetSession drops that state.
+type SessionTool interface {
+	Tool
+	ResetSession()
+}
+
+// ToolStateReporter is implemented by tools that expose runtime state, such
+// as the shell they run, to get_state.
+type ToolStateReporter interface {
+	Tool
+	ToolState() map[string]any
+}
+
+// BackgroundTool is implemented by tools that keep work running after a call
+// returns, such as background processes. StopBackground ends all of it.
+type BackgroundTool interface {
+	Tool
+	StopBackground()
+}
+
+// ApprovalScopedTool is implemented by tools where only some calls need
+// tool approval, such as the mutating actions of the git tool. When approval
+// is configured for the tool, calls for which NeedsApproval is false run
+// without asking. Policy "ask" rules still apply to every call.
+type ApprovalScopedTool interface {
+	Tool
+	NeedsApproval(args map[string]any) bool
+}
+
+// ToolResult is a tool's text result plus structured details for clients,
+// such as a diff. Text and Images are sent to the model; Details are not.
+type ToolResult struct {
+	Text    string
+	Details map[string]any
+	Images  []ToolImage
+}
+
+// ToolImage is an image a tool returns to the model, such as a screenshot
+// or an image file it read.
+type ToolImage struct {
+	MediaType string
+	Data      []byte
+}
+
+// DetailedTool is implemented by tools that report structured details. The
+// engine publishes them on the final tool_execution_update.
+type DetailedTool interface {
+	Tool
+	ExecuteWithDetails(ctx context.Context, args map[string]any) (ToolResult, error)
+}
+
 type ToolProgressFunc func(delta string)
 
 type ProgressiveTool interface {
@@ -15,23 +76,35 @@ type ProgressiveTool interface {
 }
 
 type ToolFunc struct {
-	ToolName string
-	Run      func(ctx context.Context, args map[string]any) (string, error)
+	ToolName        string
+	ToolDescription string
+	ToolSchema      map[string]any
+	Run             func(ctx context.Context, args map[string]any) (string, error)
 }
 
 func (t ToolFunc) Name() string { return t.ToolName }
 
+func (t ToolFunc) Description() string { return t.ToolDescription }
+
+func (t ToolFunc) Schema() map[string]any { return t.ToolSchema }
+
 func (t ToolFunc) Execute(ctx context.Context, args map[string]any) (string, error) {
 	return t.Run(ctx, args)
 }
 
 type ProgressiveToolFunc struct {
-	ToolName string
-	Run      func(ctx context.Context, args map[string]any, progress ToolProgressFunc) (string, error)
+	ToolName        string
+	ToolDescription string
+	ToolSchema      map[string]any
+	Run             func(ctx context.Context, args map[string]any, progress ToolProgressFunc) (string, error)
 }
 
 func (t ProgressiveToolFunc) Name() string { return t.ToolName }
 
+func (t ProgressiveToolFunc) Description() string { return t.ToolDescription }
+
+func (t ProgressiveToolFunc) Schema() map[string]any { return t.ToolSchema }
+
 func (t ProgressiveToolFunc) Execute(ctx context.Context, args map[string]any) (string, error) {
 	if t.Run == nil {
 		return "", nil
@@ -45,3 +118,29 @@ func (t ProgressiveToolFunc) ExecuteWithProgress(ctx context.Context, args map[s
 	}
 	return t.Run(ctx, args, progress)
 }
+
+// DetailedToolFunc is a ToolFunc whose Run also returns structured details.
+type DetailedToolFunc struct {
+	ToolName        string
+	ToolDescription string
+	ToolSchema      map[string]any
+	Run             func(ctx context.Context, args map[string]any) (ToolResult, error)
+}
+
+func (t DetailedToolFunc) Name() string { return t.ToolName }
+
+func (t DetailedToolFunc) Description() string { return t.ToolDescription }
+
+func (t DetailedToolFunc) Schema() map[string]any { return t.ToolSchema }
+
+func (t DetailedToolFunc) Execute(ctx context.Context, args map[string]any) (string, error) {
+	res, err := t.ExecuteWithDetails(ctx, args)
+	return res.Text, err
+}
+
+func (t DetailedToolFunc) ExecuteWithDetails(ctx context.Context, args map[string]any) (ToolResult, error) {
+	if t.Run == nil {
+		return ToolResult{}, nil
+	}
+	return t.Run(ctx, args)
+}
@@ -3,6 +3,7 @@ package extension
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 )
@@ -23,6 +24,14 @@ func (e TimeoutError) Is(target error) bool {
 }
 
 type ToolHandler func(args map[string]any) (string, error)
+
+// ToolSpec describes an extension tool to the model. Description and Schema
+// are optional; Schema is a JSON schema object for the tool arguments.
+type ToolSpec struct {
+	Name        string
+	Description string
+	Schema      map[string]any
+}
 type CommandHandler func(payload map[string]any) (map[string]any, error)
 
 type InputHookInput struct {
@@ -110,8 +119,9 @@ type SessionBeforeForkHook func(SessionBeforeForkHookInput) (SessionBeforeForkHo
 type Manager struct {
 	mu sync.RWMutex
 
-	tools    map[string]ToolHandler
-	commands map[string]CommandHandler
+	tools     map[string]ToolHandler
+	toolSpecs map[string]ToolSpec
+	commands  map[string]CommandHandler
 
 	inputHooks               []InputHook
 	toolCallHooks            []ToolCallHook
@@ -130,21 +140,45 @@ type Manager struct {
 
 func NewManager() *Manager {
 	return &Manager{
-		tools:    map[string]ToolHandler{},
-		commands: map[string]CommandHandler{},
+		tools:     map[string]ToolHandler{},
+		toolSpecs: map[string]ToolSpec{},
+		commands:  map[string]CommandHandler{},
 	}
 }
 
 func (m *Manager) RegisterTool(name string, handler ToolHandler) error {
-	if name == "" || handler == nil {
+	return m.RegisterToolWithSpec(ToolSpec{Name: name}, handler)
+}
+
+func (m *Manager) RegisterToolWithSpec(spec ToolSpec, handler ToolHandler) error {
+	if spec.Name == "" || handler == nil {
 		return fmt.Errorf("invalid_tool_registration")
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.tools[name] = handler
+	m.tools[spec.Name] = handler
+	m.toolSpecs[spec.Name] = spec
 	return nil
 }
 
+func (m *Manager) ToolSpec(name string) (ToolSpec, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	spec, ok := m.toolSpecs[name]
+	return spec, ok
+}
+
+func (m *Manager) ToolSpecs() []ToolSpec {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]ToolSpec, 0, len(m.toolSpecs))
+	for _, spec := range m.toolSpecs {
+		out = append(out, spec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
 func (m *Manager) SetHookTimeout(timeout time.Duration) error {
 	if timeout < 0 {
 		return fmt.Errorf("invalid_hook_timeout")
@@ -276,3 +276,36 @@ func TestToolTimeoutReturnsHandledTimeoutError(t *testing.T) {
 		t.Fatalf("expected ErrTimeout, got: %v", err)
 	}
 }
+
+func TestRegisterToolWithSpecListsSortedSpecs(t *testing.T) {
+	m := NewManager()
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"text": map[string]any{"type": "string"}},
+	}
+	if err := m.RegisterToolWithSpec(ToolSpec{Name: "z.echo", Description: "Echo text.", Schema: schema}, func(args map[string]any) (string, error) {
+		return "", nil
+	}); err != nil {
+		t.Fatalf("register tool with spec failed: %v", err)
+	}
+	if err := m.RegisterTool("a.plain", func(args map[string]any) (string, error) { return "", nil }); err != nil {
+		t.Fatalf("register tool failed: %v", err)
+	}
+	if err := m.RegisterToolWithSpec(ToolSpec{Description: "missing name"}, func(args map[string]any) (string, error) { return "", nil }); err == nil {
+		t.Fatalf("expected spec without name to be rejected")
+	}
+
+	specs := m.ToolSpecs()
+	if len(specs) != 2 || specs[0].Name != "a.plain" || specs[1].Name != "z.echo" {
+		t.Fatalf("unexpected tool specs: %+v", specs)
+	}
+	if specs[0].Description != "" || specs[0].Schema != nil {
+		t.Fatalf("plain registration should not carry a spec: %+v", specs[0])
+	}
+	if specs[1].Description != "Echo text." || specs[1].Schema["type"] != "object" {
+		t.Fatalf("unexpected declared spec: %+v", specs[1])
+	}
+	if _, handled, err := m.ExecuteTool("z.echo", nil); err != nil || !handled {
+		t.Fatalf("expected spec-registered tool to execute, handled=%v err=%v", handled, err)
+	}
+}
@@ -0,0 +1,119 @@
+// Package fspath resolves file path arguments the way the builtin tools do,
+// so that permission checks see the same path the tools open.
+package fspath
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Resolve expands rawPath and makes it absolute relative to base. It
+// returns "" for an empty path.
+func Resolve(base, rawPath string) string {
+	path := Expand(rawPath)
+	if path == "" {
+		return ""
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	return filepath.Clean(path)
+}
+
+// Expand trims raw and expands $VAR, ${VAR} and a leading ~. Unset
+// variables are kept as written.
+func Expand(raw string) string {
+	path := strings.TrimSpace(raw)
+	if path == "" {
+		return ""
+	}
+	path = os.Expand(path, func(name string) string {
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		return "$" + name
+	})
+	path = expandHome(path)
+	return strings.TrimSpace(path)
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, "~\\") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil || strings.TrimSpace(home) == "" {
+		return path
+	}
+	if path == "~" {
+		return home
+	}
+	return filepath.Join(home, path[2:])
+}
+
+// maxLinks bounds symlink hops, so link cycles end.
+const maxLinks = 255
+
+// Canonical resolves symlinks one component at a time and keeps the
+// components that do not exist yet, so write targets are checked against
+// where their parent really lives. A dangling symlink is followed to its
+// target, since writing through it would create the target.
+func Canonical(path string) string {
+	path = filepath.Clean(path)
+	if !filepath.IsAbs(path) {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return path
+		}
+		path = abs
+	}
+	vol := filepath.VolumeName(path)
+	root := vol + string(filepath.Separator)
+	resolved := root
+	todo := splitPath(path[len(vol):])
+	links := 0
+	for len(todo) > 0 {
+		part := todo[0]
+		todo = todo[1:]
+		if part == ".." {
+			// resolved holds no symlinks, so its parent is its real parent.
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+		next := filepath.Join(resolved, part)
+		info, err := os.Lstat(next)
+		if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		if links++; links > maxLinks {
+			return path
+		}
+		target, err := os.Readlink(next)
+		if err != nil {
+			return path
+		}
+		// The target's components are queued as written: a ".." in it must
+		// apply after the links before it are followed, as the kernel does.
+		if filepath.IsAbs(target) {
+			vol = filepath.VolumeName(target)
+			root = vol + string(filepath.Separator)
+			resolved = root
+			target = target[len(vol):]
+		}
+		todo = append(splitPath(target), todo...)
+	}
+	return resolved
+}
+
+func splitPath(path string) []string {
+	var parts []string
+	for _, part := range strings.Split(path, string(filepath.Separator)) {
+		if part != "" && part != "." {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
@@ -0,0 +1,68 @@
+package fspath
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveExpandsEnvAndJoinsRelative(t *testing.T) {
+	base := t.TempDir()
+	root := t.TempDir()
+	t.Setenv("NOUS_FSPATH_ROOT", root)
+
+	got := Resolve(base, "$NOUS_FSPATH_ROOT/data.txt")
+	want := filepath.Join(root, "data.txt")
+	if got != want {
+		t.Fatalf("unexpected env-expanded path: got=%q want=%q", got, want)
+	}
+
+	got = Resolve(base, "rel/data.txt")
+	want = filepath.Join(base, "rel", "data.txt")
+	if got != want {
+		t.Fatalf("unexpected relative path: got=%q want=%q", got, want)
+	}
+}
+
+func TestResolveExpandsHome(t *testing.T) {
+	base := t.TempDir()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	got := Resolve(base, "~/notes.md")
+	want := filepath.Join(home, "notes.md")
+	if got != want {
+		t.Fatalf("unexpected home-expanded path: got=%q want=%q", got, want)
+	}
+}
+
+func TestCanonicalResolvesSymlinks(t *testing.T) {
+	base := t.TempDir()
+	outside := t.TempDir()
+	real := Canonical(outside)
+	for name, target := range map[string]string{
+		"dir":      outside,
+		"dangling": filepath.Join(outside, "missing", "file.txt"),
+		"relative": "sub/../later.txt",
+		"loop":     "loop",
+		"hop":      "dir/../outside.txt",
+	} {
+		if err := os.Symlink(target, filepath.Join(base, name)); err != nil {
+			t.Fatalf("symlink failed: %v", err)
+		}
+	}
+	realBase := Canonical(base)
+	cases := map[string]string{
+		"dir/new/file.txt": filepath.Join(real, "new", "file.txt"),
+		"dangling":         filepath.Join(real, "missing", "file.txt"),
+		"relative":         filepath.Join(realBase, "later.txt"),
+		"loop/x":           filepath.Join(base, "loop", "x"),
+		"plain/../x":       filepath.Join(realBase, "x"),
+		"hop":              filepath.Join(filepath.Dir(real), "outside.txt"),
+	}
+	for rel, want := range cases {
+		if got := Canonical(filepath.Join(base, rel)); got != want {
+			t.Fatalf("%s: got %q, want %q", rel, got, want)
+		}
+	}
+}
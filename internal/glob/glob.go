@@ -0,0 +1,58 @@
+package glob
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether name matches the slash-separated pattern. Segments
+// use path.Match syntax; a "**" segment matches zero or more segments.
+// Malformed patterns never match.
+func Match(pattern, name string) bool {
+	pattern = strings.Trim(pattern, "/")
+	name = strings.Trim(name, "/")
+	return matchSegments(splitSegments(pattern), splitSegments(name))
+}
+
+// Valid reports whether every segment of pattern is well formed.
+func Valid(pattern string) bool {
+	for _, seg := range splitSegments(strings.Trim(pattern, "/")) {
+		if seg == "**" {
+			continue
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func splitSegments(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "/")
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		ok, err := path.Match(pattern[0], name[0])
+		if err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
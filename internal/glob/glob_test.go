@@ -0,0 +1,41 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "*.go", name: "main.go", want: true},
+		{pattern: "*.go", name: "cmd/main.go", want: false},
+		{pattern: "**/*.go", name: "main.go", want: true},
+		{pattern: "**/*.go", name: "cmd/core/main.go", want: true},
+		{pattern: "cmd/**", name: "cmd", want: true},
+		{pattern: "cmd/**", name: "cmd/core/main.go", want: true},
+		{pattern: "cmd/**/main.go", name: "cmd/main.go", want: true},
+		{pattern: "cmd/**/main.go", name: "cmd/core/tui/main.go", want: true},
+		{pattern: "cmd/**/main.go", name: "internal/main.go", want: false},
+		{pattern: "**/.env", name: "deploy/.env", want: true},
+		{pattern: "**/.env", name: "deploy/.env.example", want: false},
+		{pattern: "/etc/**", name: "/etc/passwd", want: true},
+		{pattern: "src/?.txt", name: "src/a.txt", want: true},
+		{pattern: "src/[ab].txt", name: "src/c.txt", want: false},
+		{pattern: "src/[", name: "src/[", want: false},
+	}
+	for _, tc := range tests {
+		if got := Match(tc.pattern, tc.name); got != tc.want {
+			t.Fatalf("Match(%q, %q) = %v, want %v", tc.pattern, tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	if !Valid("**/*.go") || !Valid("a/[bc]/d") {
+		t.Fatalf("expected valid patterns")
+	}
+	if Valid("a/[b") {
+		t.Fatalf("expected malformed pattern to be invalid")
+	}
+}
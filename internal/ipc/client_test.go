@@ -2057,8 +2057,11 @@ func TestAsyncPromptUsesSessionContextAndPersistsRawInput(t *testing.T) {
 	if lastAssistant.Role != "assistant" {
 		t.Fatalf("expected assistant entry at tail, got %+v", lastAssistant)
 	}
-	if !strings.Contains(lastAssistant.Text, "Conversation so far:") || !strings.Contains(lastAssistant.Text, "user: first") {
-		t.Fatalf("expected async execution to include prior session context, got: %q", lastAssistant.Text)
+	if !strings.Contains(lastAssistant.Text, "user: first\nassistant: ") || !strings.HasSuffix(lastAssistant.Text, "\nuser: second") {
+		t.Fatalf("expected async execution to include prior session messages, got: %q", lastAssistant.Text)
+	}
+	if strings.Contains(lastAssistant.Text, "Conversation so far:") {
+		t.Fatalf("expected structured history instead of flattened context, got: %q", lastAssistant.Text)
 	}
 
 	cancel()
@@ -2258,3 +2261,247 @@ func testWorkDir(t *testing.T) string {
 	t.Cleanup(func() { _ = os.RemoveAll(dir) })
 	return dir
 }
+
+func TestPromptPersistsToolCallAndResultEntries(t *testing.T) {
+	base := testWorkDir(t)
+	socket := filepath.Join(base, "core.sock")
+	srv := NewServer(socket)
+
+	mgr, err := session.NewManager(filepath.Join(base, "sessions"))
+	if err != nil {
+		t.Fatalf("new session manager failed: %v", err)
+	}
+	srv.SetSessionManager(mgr)
+
+	e := core.NewEngine(core.NewRuntime(), toolLogProvider{})
+	e.SetTools([]core.Tool{
+		core.ToolFunc{ToolName: "echo", Run: func(_ context.Context, args map[string]any) (string, error) {
+			text, _ := args["text"].(string)
+			return "echoed " + text, nil
+		}},
+	})
+	srv.SetEngine(e, core.NewCommandLoop(e))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ctx) }()
+	if err := waitForSocket(socket, 2*time.Second); err != nil {
+		t.Fatalf("server not ready: %v", err)
+	}
+
+	resp, err := SendCommand(socket, protocol.Envelope{
+		ID:      "tool-entries-prompt",
+		Type:    string(protocol.CmdPrompt),
+		Payload: map[string]any{"text": "use a tool", "wait": true},
+	})
+	if err != nil || !resp.OK {
+		t.Fatalf("prompt failed: resp=%+v err=%v", resp, err)
+	}
+	sessionID, _ := resp.Payload["session_id"].(string)
+
+	entries, err := mgr.BuildMessageContext(sessionID)
+	if err != nil {
+		t.Fatalf("build session context failed: %v", err)
+	}
+	gotTypes := make([]string, 0, len(entries))
+	for _, rec := range entries {
+		gotTypes = append(gotTypes, rec.Type)
+	}
+	// The provider says nothing after the tool, so the result closes the turn.
+	wantTypes := []string{session.EntryTypeMessage, session.EntryTypeToolCall, session.EntryTypeToolResult}
+	if strings.Join(gotTypes, ",") != strings.Join(wantTypes, ",") {
+		t.Fatalf("unexpected entry types: %v", gotTypes)
+	}
+	call, result := entries[1], entries[2]
+	if call.ToolCallID != "tc-1" || call.ToolName != "echo" || call.Arguments["text"] != "x" || call.ParentID != entries[0].ID {
+		t.Fatalf("unexpected tool_call entry: %+v", call)
+	}
+	if result.ToolCallID != "tc-1" || result.Text != "echo => echoed x" || result.ParentID != call.ID {
+		t.Fatalf("unexpected tool_result entry: %+v", result)
+	}
+
+	msgsResp, err := SendCommand(socket, protocol.Envelope{
+		ID:      "tool-entries-messages",
+		Type:    string(protocol.CmdGetMessages),
+		Payload: map[string]any{"session_id": sessionID},
+	})
+	if err != nil || !msgsResp.OK {
+		t.Fatalf("get_messages failed: resp=%+v err=%v", msgsResp, err)
+	}
+	rawMsgs, _ := msgsResp.Payload["messages"].([]any)
+	if len(rawMsgs) != 3 {
+		t.Fatalf("expected tool entries in get_messages, got: %+v", msgsResp.Payload["messages"])
+	}
+	if second, _ := rawMsgs[1].(map[string]any); second["type"] != session.EntryTypeToolCall || second["tool_name"] != "echo" {
+		t.Fatalf("unexpected get_messages tool_call: %+v", second)
+	}
+
+	treeResp, err := SendCommand(socket, protocol.Envelope{
+		ID:      "tool-entries-tree",
+		Type:    string(protocol.CmdGetTree),
+		Payload: map[string]any{"session_id": sessionID},
+	})
+	if err != nil || !treeResp.OK {
+		t.Fatalf("get_tree failed: resp=%+v err=%v", treeResp, err)
+	}
+	rawNodes, _ := treeResp.Payload["nodes"].([]any)
+	if len(rawNodes) != 3 {
+		t.Fatalf("expected tool nodes in get_tree, got: %+v", treeResp.Payload["nodes"])
+	}
+	if third, _ := rawNodes[2].(map[string]any); third["type"] != session.EntryTypeToolResult || third["tool_call_id"] != "tc-1" {
+		t.Fatalf("unexpected get_tree tool_result node: %+v", third)
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("server returned error: %v", err)
+	}
+}
+
+type narratedToolProvider struct{}
+
+func (narratedToolProvider) Stream(_ context.Context, req provider.Request) <-chan provider.Event {
+	out := make(chan provider.Event)
+	go func() {
+		defer close(out)
+		if hasToolResultMessage(req.Messages) {
+			out <- provider.Event{Type: provider.EventTextDelta, Delta: "done"}
+			out <- provider.Event{Type: provider.EventDone}
+			return
+		}
+		out <- provider.Event{Type: provider.EventTextDelta, Delta: "checking"}
+		out <- provider.Event{Type: provider.EventToolCall, ToolCall: provider.ToolCall{ID: "tc-1", Name: "echo", Arguments: map[string]any{"text": "x"}}}
+		out <- provider.Event{Type: provider.EventDone}
+	}()
+	return out
+}
+
+func TestResumedSessionReplaysToolOutputOnce(t *testing.T) {
+	base := testWorkDir(t)
+	srv := NewServer(filepath.Join(base, "core.sock"))
+	mgr, err := session.NewManager(filepath.Join(base, "sessions"))
+	if err != nil {
+		t.Fatalf("new session manager failed: %v", err)
+	}
+	srv.SetSessionManager(mgr)
+	e := core.NewEngine(core.NewRuntime(), narratedToolProvider{})
+	e.SetTools([]core.Tool{
+		core.ToolFunc{ToolName: "echo", Run: func(_ context.Context, args map[string]any) (string, error) {
+			text, _ := args["text"].(string)
+			return "echoed " + text, nil
+		}},
+	})
+	srv.SetEngine(e, core.NewCommandLoop(e))
+
+	resp := srv.dispatch(protocol.Envelope{ID: "narrated", Type: string(protocol.CmdPrompt), Payload: map[string]any{"text": "use a tool", "wait": true}})
+	if !resp.OK {
+		t.Fatalf("prompt failed: %+v", resp)
+	}
+	sessionID, _ := resp.Payload["session_id"].(string)
+	history, err := srv.sessionHistory(sessionID, "")
+	if err != nil {
+		t.Fatalf("rebuild history failed: %v", err)
+	}
+	if len(history) != 4 {
+		t.Fatalf("expected user, assistant with call, result, reply; got: %+v", history)
+	}
+	seen := 0
+	for _, msg := range history {
+		seen += strings.Count(msg.Text, "echoed x")
+	}
+	if seen != 1 {
+		t.Fatalf("expected the tool output replayed once, got %d times: %+v", seen, history)
+	}
+	if calls := history[1]; calls.Role != core.RoleAssistant || calls.Text != "checking" || len(calls.Blocks) != 2 || calls.Blocks[1].ToolCallID != "tc-1" {
+		t.Fatalf("expected the step text and its call in one assistant message, got: %+v", calls)
+	}
+	if reply := history[3]; reply.Role != core.RoleAssistant || reply.Text != "done" {
+		t.Fatalf("expected the closing reply on its own, got: %+v", reply)
+	}
+}
+
+func TestApproveToolCallCommandUnblocksPendingTool(t *testing.T) {
+	base := testWorkDir(t)
+	socket := filepath.Join(base, "core.sock")
+	srv := NewServer(socket)
+
+	e := core.NewEngine(core.NewRuntime(), toolLogProvider{})
+	e.SetTools([]core.Tool{
+		core.ToolFunc{ToolName: "echo", Run: func(_ context.Context, args map[string]any) (string, error) {
+			text, _ := args["text"].(string)
+			return "echoed " + text, nil
+		}},
+	})
+	if err := e.SetToolApproval(core.ApprovalConfig{Enabled: true, Tools: []string{"echo"}}); err != nil {
+		t.Fatalf("set tool approval failed: %v", err)
+	}
+	loop := core.NewCommandLoop(e)
+	srv.SetEngine(e, loop)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Serve(ctx) }()
+	if err := waitForSocket(socket, 2*time.Second); err != nil {
+		t.Fatalf("server not ready: %v", err)
+	}
+
+	resp, err := SendCommand(socket, protocol.Envelope{
+		ID:      "approval-prompt",
+		Type:    string(protocol.CmdPrompt),
+		Payload: map[string]any{"text": "use a tool", "wait": false},
+	})
+	if err != nil || !resp.OK {
+		t.Fatalf("prompt failed: resp=%+v err=%v", resp, err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stateResp, err := SendCommand(socket, protocol.Envelope{ID: "approval-state", Type: string(protocol.CmdGetState), Payload: map[string]any{}})
+		if err != nil || !stateResp.OK {
+			t.Fatalf("get_state failed: resp=%+v err=%v", stateResp, err)
+		}
+		if pending, _ := stateResp.Payload["pending_approvals"].([]any); len(pending) == 1 {
+			first, _ := pending[0].(map[string]any)
+			if first["tool_call_id"] != "tc-1" || first["tool_name"] != "echo" {
+				t.Fatalf("unexpected pending approval: %+v", first)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("approval request never became pending")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	badResp, err := SendCommand(socket, protocol.Envelope{
+		ID:      "approval-bad-scope",
+		Type:    string(protocol.CmdApproveToolCall),
+		Payload: map[string]any{"tool_call_id": "tc-1", "scope": "forever"},
+	})
+	if err != nil || badResp.OK || badResp.Error == nil || badResp.Error.Code != "invalid_payload" {
+		t.Fatalf("expected invalid_payload for bad scope, got resp=%+v err=%v", badResp, err)
+	}
+
+	approveResp, err := SendCommand(socket, protocol.Envelope{
+		ID:      "approval-approve",
+		Type:    string(protocol.CmdApproveToolCall),
+		Payload: map[string]any{"tool_call_id": "tc-1"},
+	})
+	if err != nil || !approveResp.OK || approveResp.Type != "accepted" {
+		t.Fatalf("approve failed: resp=%+v err=%v", approveResp, err)
+	}
+	if approveResp.Payload["decision"] != string(core.ApprovalAllowOnce) {
+		t.Fatalf("unexpected approve payload: %+v", approveResp.Payload)
+	}
+	waitForLoopState(t, loop, core.StateIdle, 2*time.Second)
+
+	againResp, err := SendCommand(socket, protocol.Envelope{
+		ID:      "approval-again",
+		Type:    string(protocol.CmdRejectToolCall),
+		Payload: map[string]any{"tool_call_id": "tc-1"},
+	})
+	if err != nil || againResp.OK || againResp.Error == nil || againResp.Error.Code != "command_rejected" {
+		t.Fatalf("expected command_rejected for answered approval, got resp=%+v err=%v", againResp, err)
+	}
+}
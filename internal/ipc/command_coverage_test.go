@@ -45,6 +45,9 @@ func TestDispatchDoesNotReturnNotImplementedForKnownCommands(t *testing.T) {
 		{ID: "c-switch", Type: string(protocol.CmdSwitchSession), Payload: map[string]any{"session_id": parentID}},
 		{ID: "c-branch", Type: string(protocol.CmdBranchSession), Payload: map[string]any{"session_id": parentID}},
 		{ID: "c-ext", Type: string(protocol.CmdExtensionCmd), Payload: map[string]any{"name": "missing", "payload": map[string]any{}}},
+		{ID: "c-approve", Type: string(protocol.CmdApproveToolCall), Payload: map[string]any{"tool_call_id": "missing"}},
+		{ID: "c-policy", Type: string(protocol.CmdGetPolicy), Payload: map[string]any{}},
+		{ID: "c-reject", Type: string(protocol.CmdRejectToolCall), Payload: map[string]any{"tool_call_id": "missing"}},
 	}
 
 	for _, tc := range cases {
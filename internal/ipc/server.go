@@ -124,6 +124,7 @@ func (s *Server) Serve(ctx context.Context) error {
 			}
 		})
 	}
+	s.loop.SetHistoryProvider(s.runHistory)
 	s.loop.SetOnTurnEnd(func(r core.TurnResult) {
 		sessionID, parentID := s.runContextFor(r.RunID)
 		if r.Err != nil {
@@ -138,7 +139,7 @@ func (s *Server) Serve(ctx context.Context) error {
 			}
 			return
 		}
-		nextParentID, err := s.appendTurnRecord(sessionID, r.RunID, string(r.Kind), r.Input, r.Output, parentID)
+		nextParentID, err := s.appendTurnRecord(sessionID, r.RunID, string(r.Kind), r.Input, r.Output, parentID, r.Messages)
 		if err != nil {
 			return
 		}
@@ -184,6 +185,7 @@ func (s *Server) Serve(ctx context.Context) error {
 	}
 
 	s.wg.Wait()
+	s.engine.StopBackgroundTools()
 	return nil
 }
 
@@ -303,6 +305,7 @@ func (s *Server) dispatch(env protocol.Envelope) protocol.ResponseEnvelope {
 		if err != nil {
 			return responseErr(env.ID, "session_error", err.Error())
 		}
+		s.resetSessionScopedState()
 		return responseOK(protocol.Envelope{
 			V:    protocol.Version,
 			ID:   env.ID,
@@ -329,6 +332,7 @@ func (s *Server) dispatch(env protocol.Envelope) protocol.ResponseEnvelope {
 		if err := s.sessions.SwitchSession(rawID); err != nil {
 			return responseErr(env.ID, "session_not_found", err.Error())
 		}
+		s.resetSessionScopedState()
 		return responseOK(protocol.Envelope{
 			V:    protocol.Version,
 			ID:   env.ID,
@@ -359,6 +363,7 @@ func (s *Server) dispatch(env protocol.Envelope) protocol.ResponseEnvelope {
 		if err != nil {
 			return responseErr(env.ID, "session_not_found", err.Error())
 		}
+		s.resetSessionScopedState()
 		return responseOK(protocol.Envelope{
 			V:    protocol.Version,
 			ID:   env.ID,
@@ -424,6 +429,9 @@ func (s *Server) dispatch(env protocol.Envelope) protocol.ResponseEnvelope {
 		if err := s.loop.Abort(); err != nil {
 			return responseErr(env.ID, "command_rejected", err.Error())
 		}
+		if s.engine != nil {
+			s.engine.StopBackgroundTools()
+		}
 		payload := map[string]any{"command": "abort"}
 		if runID := s.loop.CurrentRunID(); runID != "" {
 			payload["run_id"] = runID
@@ -491,6 +499,13 @@ func (s *Server) dispatch(env protocol.Envelope) protocol.ResponseEnvelope {
 			Type:    "state",
 			Payload: s.statePayload(),
 		})
+	case protocol.CmdGetPolicy:
+		return responseOK(protocol.Envelope{
+			V:       protocol.Version,
+			ID:      env.ID,
+			Type:    "policy",
+			Payload: s.policyPayload(),
+		})
 	case protocol.CmdGetMessages:
 		sessionID, _ := env.Payload["session_id"].(string)
 		if sessionID == "" && s.sessions != nil {
@@ -609,12 +624,18 @@ func (s *Server) dispatch(env protocol.Envelope) protocol.ResponseEnvelope {
 		}
 		nodes := make([]map[string]any, 0, len(entries))
 		for _, rec := range entries {
-			nodes = append(nodes, map[string]any{
+			node := map[string]any{
 				"id":        rec.ID,
 				"parent_id": rec.ParentID,
+				"type":      rec.Type,
 				"role":      rec.Role,
 				"snippet":   snippet(rec.Text, 80),
-			})
+			}
+			if rec.ToolName != "" {
+				node["tool_name"] = rec.ToolName
+				node["tool_call_id"] = rec.ToolCallID
+			}
+			nodes = append(nodes, node)
 		}
 		payload := map[string]any{
 			"session_id": sessionID,
@@ -674,6 +695,68 @@ func (s *Server) dispatch(env protocol.Envelope) protocol.ResponseEnvelope {
 			Type:    "compaction",
 			Payload: payload,
 		})
+	case protocol.CmdApproveToolCall:
+		toolCallID, ok := env.Payload["tool_call_id"].(string)
+		if !ok || strings.TrimSpace(toolCallID) == "" {
+			return responseErr(env.ID, "invalid_payload", "tool_call_id is required")
+		}
+		decision := core.ApprovalAllowOnce
+		if raw, exists := env.Payload["scope"]; exists {
+			scope, ok := raw.(string)
+			if !ok {
+				return responseErr(env.ID, "invalid_payload", "scope must be a string")
+			}
+			switch scope {
+			case "", "once":
+			case "session":
+				decision = core.ApprovalAllowSession
+			default:
+				return responseErr(env.ID, "invalid_payload", "scope must be once or session")
+			}
+		}
+		if err := s.engine.ApproveToolCall(toolCallID, decision); err != nil {
+			return responseErr(env.ID, "command_rejected", err.Error())
+		}
+		return responseOK(protocol.Envelope{
+			V:    protocol.Version,
+			ID:   env.ID,
+			Type: "accepted",
+			Payload: map[string]any{
+				"command":      string(protocol.CmdApproveToolCall),
+				"tool_call_id": toolCallID,
+				"decision":     string(decision),
+			},
+		})
+	case protocol.CmdRejectToolCall:
+		toolCallID, ok := env.Payload["tool_call_id"].(string)
+		if !ok || strings.TrimSpace(toolCallID) == "" {
+			return responseErr(env.ID, "invalid_payload", "tool_call_id is required")
+		}
+		reason := ""
+		if raw, exists := env.Payload["reason"]; exists {
+			v, ok := raw.(string)
+			if !ok {
+				return responseErr(env.ID, "invalid_payload", "reason must be a string")
+			}
+			reason = v
+		}
+		if err := s.engine.RejectToolCall(toolCallID, reason); err != nil {
+			return responseErr(env.ID, "command_rejected", err.Error())
+		}
+		payload := map[string]any{
+			"command":      string(protocol.CmdRejectToolCall),
+			"tool_call_id": toolCallID,
+			"decision":     string(core.ApprovalDeny),
+		}
+		if strings.TrimSpace(reason) != "" {
+			payload["reason"] = strings.TrimSpace(reason)
+		}
+		return responseOK(protocol.Envelope{
+			V:       protocol.Version,
+			ID:      env.ID,
+			Type:    "accepted",
+			Payload: payload,
+		})
 	case protocol.CmdExtensionCmd:
 		name, ok := env.Payload["name"].(string)
 		if !ok || name == "" {
@@ -707,7 +790,7 @@ func (s *Server) promptSync(reqID, text, leafID string) protocol.ResponseEnvelop
 	if err != nil {
 		return responseErrWithCause(reqID, "session_error", "failed to resolve session leaf", err)
 	}
-	promptWithContext, err := s.promptWithSessionContext(sessionID, text, resolvedLeafID)
+	history, err := s.sessionHistory(sessionID, resolvedLeafID)
 	if err != nil {
 		return responseErrWithCause(reqID, "session_error", "failed to build session context", err)
 	}
@@ -719,27 +802,27 @@ func (s *Server) promptSync(reqID, text, leafID string) protocol.ResponseEnvelop
 	defer unsub()
 
 	runID := fmt.Sprintf("sync-%d", time.Now().UnixNano())
-	out, err := s.engine.Prompt(context.Background(), runID, promptWithContext)
+	turn, err := s.engine.RunTurn(context.Background(), runID, history, text)
 	if err != nil {
 		if isContextOverflowError(err) {
 			if _, _, compactErr := s.compactSession(sessionID, "", "overflow"); compactErr == nil {
-				retryPrompt, ctxErr := s.promptWithSessionContext(sessionID, text, resolvedLeafID)
+				retryHistory, ctxErr := s.sessionHistory(sessionID, resolvedLeafID)
 				if ctxErr != nil {
 					return responseErrWithCause(reqID, "session_error", "failed to build session context", ctxErr)
 				}
-				out, err = s.engine.Prompt(context.Background(), runID+"-retry", retryPrompt)
+				turn, err = s.engine.RunTurn(context.Background(), runID+"-retry", retryHistory, text)
 			}
 		}
 		if err != nil {
 			return responseErrWithCause(reqID, "provider_error", "provider request failed", err)
 		}
 	}
-	if _, err := s.appendTurnRecord(sessionID, runID, string(core.TurnPrompt), text, out, resolvedLeafID); err != nil {
+	if _, err := s.appendTurnRecord(sessionID, runID, string(core.TurnPrompt), text, turn.Text, resolvedLeafID, turn.Messages); err != nil {
 		return responseErrWithCause(reqID, "session_error", "failed to persist session records", err)
 	}
 	_, _, _ = s.compactSessionIfThreshold(sessionID)
 	payload := map[string]any{
-		"output":     out,
+		"output":     turn.Text,
 		"events":     events,
 		"session_id": sessionID,
 	}
@@ -763,12 +846,12 @@ func (s *Server) promptAsync(reqID, text, leafID string) protocol.ResponseEnvelo
 	if err != nil {
 		return responseErrWithCause(reqID, "session_error", "failed to resolve session leaf", err)
 	}
-	promptWithContext, err := s.promptWithSessionContext(sessionID, text, resolvedLeafID)
+	history, err := s.sessionHistory(sessionID, resolvedLeafID)
 	if err != nil {
 		return responseErrWithCause(reqID, "session_error", "failed to build session context", err)
 	}
 
-	runID, err := s.loop.PromptWithExecutionText(text, promptWithContext)
+	runID, err := s.loop.PromptWithHistory(text, history)
 	if err != nil {
 		return responseErr(reqID, "command_rejected", err.Error())
 	}
@@ -799,7 +882,7 @@ func (s *Server) ensureActiveSession() (string, error) {
 	return s.sessions.NewSession()
 }
 
-func (s *Server) appendTurnRecord(sessionID, runID, kind, input, output, parentID string) (string, error) {
+func (s *Server) appendTurnRecord(sessionID, runID, kind, input, output, parentID string, messages []core.Message) (string, error) {
 	if sessionID == "" {
 		var err error
 		sessionID, err = s.ensureActiveSession()
@@ -815,17 +898,81 @@ func (s *Server) appendTurnRecord(sessionID, runID, kind, input, output, parentI
 	if err != nil {
 		return "", err
 	}
-	assistant := session.NewMessageEntry("assistant", output, runID, kind)
-	assistant.ParentID = user.ID
-	assistant, err = s.sessions.AppendMessageToResolved(sessionID, assistant)
-	if err != nil {
-		return "", err
+	lastID := user.ID
+	entries, reply := turnEntriesFromMessages(messages, runID, kind)
+	if len(messages) == 0 {
+		reply = output
+	}
+	for _, entry := range entries {
+		entry.ParentID = lastID
+		entry, err = s.sessions.AppendMessageToResolved(sessionID, entry)
+		if err != nil {
+			return "", err
+		}
+		lastID = entry.ID
+	}
+	// A turn that ends on tool results, with no closing text, has no
+	// assistant entry; its last result is the new leaf.
+	if strings.TrimSpace(reply) != "" {
+		assistant := session.NewMessageEntry("assistant", reply, runID, kind)
+		assistant.ParentID = lastID
+		assistant, err = s.sessions.AppendMessageToResolved(sessionID, assistant)
+		if err != nil {
+			return "", err
+		}
+		lastID = assistant.ID
+	}
+	_ = s.sessions.SetActiveLeaf(sessionID, lastID)
+	return lastID, nil
+}
+
+// turnEntriesFromMessages turns a turn transcript into session entries in
+// transcript order: each step's assistant text, tool calls and tool results.
+// The assistant text of the final step is returned as reply instead, for the
+// entry that closes the turn.
+func turnEntriesFromMessages(messages []core.Message, runID, kind string) (entries []session.MessageEntry, reply string) {
+	entries = make([]session.MessageEntry, 0, len(messages))
+	for i, msg := range messages {
+		switch msg.Role {
+		case core.RoleAssistant:
+			calls := make([]session.MessageEntry, 0, len(msg.Blocks))
+			for _, block := range msg.Blocks {
+				if block.Type != core.BlockTypeToolCall || strings.TrimSpace(block.ToolName) == "" {
+					continue
+				}
+				entry := session.NewToolCallEntry(block.ToolCallID, block.ToolName, block.Arguments, runID, kind)
+				entry.ThoughtSignature = block.ThoughtSignature
+				calls = append(calls, entry)
+			}
+			text := strings.TrimSpace(msg.Text)
+			if len(calls) == 0 && i == len(messages)-1 {
+				reply = text
+				continue
+			}
+			if text != "" {
+				entries = append(entries, session.NewMessageEntry("assistant", text, runID, kind))
+			}
+			entries = append(entries, calls...)
+		case core.RoleToolResult:
+			toolName := ""
+			for _, block := range msg.Blocks {
+				if block.Type == core.BlockTypeToolResult && block.ToolName != "" {
+					toolName = block.ToolName
+					break
+				}
+			}
+			if toolName == "" || strings.TrimSpace(msg.Text) == "" {
+				continue
+			}
+			entries = append(entries, session.NewToolResultEntry(msg.ToolCallID, toolName, msg.Text, runID, kind))
+		}
 	}
-	_ = s.sessions.SetActiveLeaf(sessionID, assistant.ID)
-	return assistant.ID, nil
+	return entries, reply
 }
 
-func (s *Server) promptWithSessionContext(sessionID, prompt, leafID string) (string, error) {
+// sessionHistory rebuilds the conversation along the path ending at leafID
+// (or the whole session when leafID is empty) as engine messages.
+func (s *Server) sessionHistory(sessionID, leafID string) ([]core.Message, error) {
 	var (
 		records []session.MessageEntry
 		err     error
@@ -836,9 +983,61 @@ func (s *Server) promptWithSessionContext(sessionID, prompt, leafID string) (str
 		records, err = s.sessions.BuildMessageContext(sessionID)
 	}
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	return sessionHistoryMessages(records), nil
+}
+
+// runHistory loads the history for a queued steer/follow-up turn of runID.
+func (s *Server) runHistory(runID string) ([]core.Message, error) {
+	sessionID, parentID := s.runContextFor(runID)
+	if sessionID == "" {
+		return nil, nil
+	}
+	return s.sessionHistory(sessionID, parentID)
+}
+
+func sessionHistoryMessages(records []session.MessageEntry) []core.Message {
+	out := make([]core.Message, 0, len(records))
+	calls := map[string]struct{}{}
+	for _, rec := range records {
+		if strings.TrimSpace(rec.Text) == "" {
+			continue
+		}
+		switch rec.Type {
+		case session.EntryTypeToolCall:
+			block := core.MessageBlock{
+				Type:             core.BlockTypeToolCall,
+				ToolCallID:       rec.ToolCallID,
+				ToolName:         rec.ToolName,
+				Arguments:        rec.Arguments,
+				ThoughtSignature: rec.ThoughtSignature,
+			}
+			calls[rec.ToolCallID] = struct{}{}
+			// Calls belong to the assistant message they follow, which holds
+			// the step's text, if any, and the calls before them.
+			if n := len(out); n > 0 && out[n-1].Role == core.RoleAssistant {
+				out[n-1].Blocks = append(out[n-1].Blocks, block)
+				continue
+			}
+			out = append(out, core.Message{Role: core.RoleAssistant, Blocks: []core.MessageBlock{block}})
+			continue
+		case session.EntryTypeToolResult:
+			// Results whose call was compacted away cannot be replayed.
+			if _, ok := calls[rec.ToolCallID]; !ok {
+				continue
+			}
+			out = append(out, core.NewToolResultMessage(rec.ToolCallID, rec.ToolName, rec.Text))
+			continue
+		}
+		switch rec.Role {
+		case "user":
+			out = append(out, core.NewTextMessage(core.RoleUser, rec.Text))
+		case "assistant":
+			out = append(out, core.NewTextMessage(core.RoleAssistant, rec.Text))
+		}
 	}
-	return session.BuildPromptContext(records, prompt, 20), nil
+	return out
 }
 
 func (s *Server) resolveLeafID(sessionID, explicitLeafID string) (string, error) {
@@ -975,6 +1174,12 @@ func runtimeEventPayload(ev core.Event) map[string]any {
 	if ev.ToolName != "" {
 		payload["tool_name"] = ev.ToolName
 	}
+	if ev.Arguments != nil {
+		payload["arguments"] = ev.Arguments
+	}
+	if ev.Details != nil {
+		payload["details"] = ev.Details
+	}
 	if ev.Message != "" {
 		payload["message"] = ev.Message
 	}
@@ -1054,6 +1259,12 @@ func (s *Server) statePayload() map[string]any {
 	if s.sessions != nil {
 		sessionID = s.sessions.ActiveSession()
 	}
+	pendingApprovals := []core.PendingApproval{}
+	toolState := map[string]map[string]any{}
+	if s.engine != nil {
+		pendingApprovals = s.engine.PendingApprovals()
+		toolState = s.engine.ToolStates()
+	}
 
 	return map[string]any{
 		"run_state":      runState,
@@ -1065,6 +1276,43 @@ func (s *Server) statePayload() map[string]any {
 			"steer":     pendingSteers,
 			"follow_up": pendingFollowUps,
 		},
+		"pending_approvals": pendingApprovals,
+		"tool_state":        toolState,
+	}
+}
+
+func (s *Server) policyPayload() map[string]any {
+	var policy *core.Policy
+	if s.engine != nil {
+		policy = s.engine.Policy()
+	}
+	if policy == nil {
+		return map[string]any{
+			"enabled": false,
+			"default": string(core.PolicyAllow),
+			"rules":   []core.PolicyRule{},
+		}
+	}
+	rules := policy.Rules
+	if rules == nil {
+		rules = []core.PolicyRule{}
+	}
+	return map[string]any{
+		"enabled": true,
+		"source":  policy.Source,
+		"workdir": policy.Workdir,
+		"default": string(policy.Default),
+		"rules":   rules,
+	}
+}
+
+// resetSessionScopedState drops state that only applies to the session being
+// left: allow-for-session tool approvals and persistent tool state such as
+// the bash shell.
+func (s *Server) resetSessionScopedState() {
+	if s.engine != nil {
+		s.engine.ClearSessionApprovals()
+		s.engine.ResetToolSessions()
 	}
 }
 
@@ -13,6 +13,9 @@ import (
 	"time"
 
 	"nous/internal/core"
+	"nous/internal/protocol"
+	"nous/internal/provider"
+	"nous/internal/session"
 )
 
 func TestSetCommandTimeoutValidation(t *testing.T) {
@@ -163,3 +166,134 @@ func TestPublishRuntimeEventDropsSlowSubscriber(t *testing.T) {
 		t.Fatalf("expected drop warning log, got logs=%q", logs.String())
 	}
 }
+
+func TestSessionHistoryMessagesKeepsRoleBoundaries(t *testing.T) {
+	got := sessionHistoryMessages([]session.MessageEntry{
+		{Type: session.EntryTypeMessage, Role: "user", Text: "first"},
+		{Type: session.EntryTypeMessage, Role: "assistant", Text: "second"},
+		{Type: session.EntryTypeMessage, Role: "user", Text: "  "},
+		{Type: session.EntryTypeMessage, Role: "user", Text: "third"},
+	})
+	if len(got) != 3 {
+		t.Fatalf("expected three history messages, got: %+v", got)
+	}
+	wantRoles := []core.MessageRole{core.RoleUser, core.RoleAssistant, core.RoleUser}
+	for i, msg := range got {
+		if msg.Role != wantRoles[i] {
+			t.Fatalf("unexpected role at %d: %+v", i, msg)
+		}
+	}
+	if got[1].Text != "second" || len(got[1].Blocks) != 1 {
+		t.Fatalf("unexpected assistant message: %+v", got[1])
+	}
+}
+
+func TestSessionHistoryMessagesRebuildsToolCalls(t *testing.T) {
+	signed := session.NewToolCallEntry("c1", "read", map[string]any{"path": "a"}, "run-1", "prompt")
+	signed.ThoughtSignature = "sig-1"
+	got := sessionHistoryMessages([]session.MessageEntry{
+		{Type: session.EntryTypeMessage, Role: "user", Text: "go"},
+		signed,
+		session.NewToolCallEntry("c2", "ls", nil, "run-1", "prompt"),
+		session.NewToolResultEntry("c1", "read", "read => a", "run-1", "prompt"),
+		session.NewToolResultEntry("c2", "ls", "ls => b", "run-1", "prompt"),
+		session.NewToolResultEntry("gone", "ls", "ls => orphan", "run-1", "prompt"),
+		{Type: session.EntryTypeMessage, Role: "assistant", Text: "done"},
+	})
+	if len(got) != 5 {
+		t.Fatalf("expected user, assistant calls, two results, assistant; got: %+v", got)
+	}
+	calls := got[1]
+	if calls.Role != core.RoleAssistant || len(calls.Blocks) != 2 || calls.Blocks[1].ToolCallID != "c2" {
+		t.Fatalf("expected consecutive calls merged into one assistant message, got: %+v", calls)
+	}
+	if calls.Blocks[0].ThoughtSignature != "sig-1" || calls.Blocks[1].ThoughtSignature != "" {
+		t.Fatalf("expected thought signature carried on its own call, got: %+v", calls.Blocks)
+	}
+	if got[2].Role != core.RoleToolResult || got[2].ToolCallID != "c1" || got[3].ToolCallID != "c2" {
+		t.Fatalf("unexpected tool results: %+v", got[2:4])
+	}
+}
+
+func TestGetPolicyReportsEffectiveRules(t *testing.T) {
+	srv := NewServer(filepath.Join(testWorkDir(t), "core.sock"))
+	e := core.NewEngine(core.NewRuntime(), provider.NewMockAdapter())
+	srv.SetEngine(e, core.NewCommandLoop(e))
+
+	resp := srv.dispatch(protocol.Envelope{ID: "policy-off", Type: string(protocol.CmdGetPolicy), Payload: map[string]any{}})
+	if !resp.OK || resp.Type != "policy" || resp.Payload["enabled"] != false || resp.Payload["default"] != "allow" {
+		t.Fatalf("unexpected policy response without policy: %+v", resp)
+	}
+
+	p, err := core.ParsePolicy([]byte(`{"default":"deny","rules":[{"id":"read-src","tool":"read","paths":["src/**"],"action":"allow"}]}`))
+	if err != nil {
+		t.Fatalf("parse policy failed: %v", err)
+	}
+	p.Source = "policy.json"
+	e.SetPolicy(p)
+
+	resp = srv.dispatch(protocol.Envelope{ID: "policy-on", Type: string(protocol.CmdGetPolicy), Payload: map[string]any{}})
+	if !resp.OK || resp.Payload["enabled"] != true || resp.Payload["default"] != "deny" || resp.Payload["source"] != "policy.json" {
+		t.Fatalf("unexpected policy response: %+v", resp)
+	}
+	raw, err := json.Marshal(resp.Payload["rules"])
+	if err != nil {
+		t.Fatalf("marshal rules failed: %v", err)
+	}
+	if string(raw) != `[{"id":"read-src","tool":"read","paths":["src/**"],"action":"allow"}]` {
+		t.Fatalf("unexpected rules payload: %s", raw)
+	}
+}
+
+type stateTool struct {
+	core.ToolFunc
+}
+
+func (stateTool) ToolState() map[string]any {
+	return map[string]any{"shell": "/bin/sh", "login": false}
+}
+
+func TestGetStateIncludesToolState(t *testing.T) {
+	srv := NewServer(filepath.Join(testWorkDir(t), "core.sock"))
+	e := core.NewEngine(core.NewRuntime(), provider.NewMockAdapter())
+	e.SetTools([]core.Tool{stateTool{ToolFunc: core.ToolFunc{ToolName: "bash"}}})
+	srv.SetEngine(e, core.NewCommandLoop(e))
+
+	resp := srv.dispatch(protocol.Envelope{ID: "state", Type: string(protocol.CmdGetState), Payload: map[string]any{}})
+	toolState, _ := resp.Payload["tool_state"].(map[string]map[string]any)
+	if !resp.OK || toolState["bash"]["shell"] != "/bin/sh" {
+		t.Fatalf("expected bash tool state in get_state, got: %+v", resp.Payload)
+	}
+}
+
+type backgroundTool struct {
+	core.ToolFunc
+	stopped chan struct{}
+}
+
+func (t backgroundTool) StopBackground() { close(t.stopped) }
+
+func TestServeStopsBackgroundToolsOnShutdown(t *testing.T) {
+	socket := testSocketPath(t)
+	srv := NewServer(socket)
+	e := core.NewEngine(core.NewRuntime(), provider.NewMockAdapter())
+	tool := backgroundTool{ToolFunc: core.ToolFunc{ToolName: "process"}, stopped: make(chan struct{})}
+	e.SetTools([]core.Tool{tool})
+	srv.SetEngine(e, core.NewCommandLoop(e))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Serve(ctx) }()
+	if err := waitForSocket(socket, 2*time.Second); err != nil {
+		t.Fatalf("server not ready: %v", err)
+	}
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("server returned error: %v", err)
+	}
+	select {
+	case <-tool.stopped:
+	default:
+		t.Fatal("expected background tools to be stopped on shutdown")
+	}
+}
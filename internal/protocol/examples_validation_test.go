@@ -47,7 +47,7 @@ func TestProtocolExamplesCommandsNDJSON(t *testing.T) {
 func assertCommandPayloadSemantics(t *testing.T, env Envelope, line int) {
 	t.Helper()
 	switch CommandType(env.Type) {
-	case CmdPing, CmdAbort, CmdNewSession, CmdGetState, CmdGetMessages, CmdCompactSession, CmdGetTree:
+	case CmdPing, CmdAbort, CmdNewSession, CmdGetState, CmdGetMessages, CmdCompactSession, CmdGetTree, CmdGetPolicy:
 		return
 	case CmdSetLeaf:
 		if leafID, _ := env.Payload["leaf_id"].(string); leafID == "" {
@@ -74,6 +74,10 @@ func assertCommandPayloadSemantics(t *testing.T, env Envelope, line int) {
 		if name, _ := env.Payload["name"].(string); name == "" {
 			t.Fatalf("command line %d (%s) requires payload.name", line, env.Type)
 		}
+	case CmdApproveToolCall, CmdRejectToolCall:
+		if id, _ := env.Payload["tool_call_id"].(string); id == "" {
+			t.Fatalf("command line %d (%s) requires payload.tool_call_id", line, env.Type)
+		}
 	default:
 		t.Fatalf("unsupported command in examples: %s", env.Type)
 	}
@@ -51,6 +51,9 @@ func TestProtocolSchemaValidation(t *testing.T) {
 	assertRequiredField(t, reqs, "branch_session", "session_id")
 	assertRequiredField(t, reqs, "extension_command", "name")
 	assertNotRequiredField(t, reqs, "branch_session", "parent_id")
+	assertRequiredField(t, reqs, "approve_tool_call", "tool_call_id")
+	assertRequiredField(t, reqs, "reject_tool_call", "tool_call_id")
+	assertCommandKeyExists(t, reqs, "get_policy")
 	respReqs, ok := doc["x-response-payload-requirements"].(map[string]any)
 	if !ok {
 		t.Fatalf("x-response-payload-requirements is missing or invalid")
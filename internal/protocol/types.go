@@ -29,6 +29,9 @@ const (
 	CmdSwitchSession   CommandType = "switch_session"
 	CmdBranchSession   CommandType = "branch_session"
 	CmdExtensionCmd    CommandType = "extension_command"
+	CmdApproveToolCall CommandType = "approve_tool_call"
+	CmdRejectToolCall  CommandType = "reject_tool_call"
+	CmdGetPolicy       CommandType = "get_policy"
 )
 
 const (
@@ -42,6 +45,7 @@ const (
 	EvToolExecutionStart  EventType = "tool_execution_start"
 	EvToolExecutionUpdate EventType = "tool_execution_update"
 	EvToolExecutionEnd    EventType = "tool_execution_end"
+	EvToolApprovalRequest EventType = "tool_approval_requested"
 	EvStatus              EventType = "status"
 	EvWarning             EventType = "warning"
 	EvError               EventType = "error"
@@ -86,11 +90,14 @@ var validCommands = map[CommandType]struct{}{
 	CmdSwitchSession:   {},
 	CmdBranchSession:   {},
 	CmdExtensionCmd:    {},
+	CmdApproveToolCall: {},
+	CmdRejectToolCall:  {},
+	CmdGetPolicy:       {},
 }
 
 var validEvents = map[EventType]struct{}{
 	EvAgentStart: {}, EvAgentEnd: {}, EvTurnStart: {}, EvTurnEnd: {}, EvMessageStart: {}, EvMessageUpdate: {}, EvMessageEnd: {},
-	EvToolExecutionStart: {}, EvToolExecutionUpdate: {}, EvToolExecutionEnd: {}, EvStatus: {}, EvWarning: {}, EvError: {},
+	EvToolExecutionStart: {}, EvToolExecutionUpdate: {}, EvToolExecutionEnd: {}, EvToolApprovalRequest: {}, EvStatus: {}, EvWarning: {}, EvError: {},
 }
 
 func DecodeCommand(line []byte) (Envelope, error) {
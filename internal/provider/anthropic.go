@@ -0,0 +1,566 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	anthropicAPIVersion       = "2023-06-01"
+	anthropicDefaultMaxTokens = 4096
+)
+
+type AnthropicAdapter struct {
+	apiKey    string
+	model     string
+	baseURL   string
+	maxTokens int
+	client    *http.Client
+}
+
+func NewAnthropicAdapter(apiKey, model, baseURL string) (*AnthropicAdapter, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("missing_anthropic_api_key")
+	}
+	if model == "" {
+		return nil, fmt.Errorf("missing_anthropic_model")
+	}
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
+	}
+	return &AnthropicAdapter{
+		apiKey:    apiKey,
+		model:     model,
+		baseURL:   normalizeAPIBase(baseURL),
+		maxTokens: anthropicDefaultMaxTokens,
+		client:    &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+func (a *AnthropicAdapter) Stream(ctx context.Context, req Request) <-chan Event {
+	out := make(chan Event, 8)
+	go func() {
+		defer close(out)
+		out <- Event{Type: EventStart}
+
+		system, messages := buildAnthropicMessages(requestMessages(req))
+		payload := map[string]any{
+			"model":      a.model,
+			"max_tokens": a.maxTokens,
+			"messages":   messages,
+			"stream":     true,
+		}
+		if system != "" {
+			payload["system"] = system
+		}
+		if len(req.Tools) > 0 {
+			payload["tools"] = buildAnthropicTools(req.Tools)
+		}
+		b, err := json.Marshal(payload)
+		if err != nil {
+			out <- Event{Type: EventError, Err: err}
+			return
+		}
+
+		policy := defaultRetryPolicy()
+		var lastErr error
+		for attempt := 1; attempt <= policy.maxAttempts; attempt++ {
+			httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/messages", bytes.NewReader(b))
+			if err != nil {
+				out <- Event{Type: EventError, Err: err}
+				return
+			}
+			httpReq.Header.Set("x-api-key", a.apiKey)
+			httpReq.Header.Set("anthropic-version", anthropicAPIVersion)
+			httpReq.Header.Set("Content-Type", "application/json")
+			httpReq.Header.Set("Accept", "text/event-stream")
+
+			resp, err := a.client.Do(httpReq)
+			if err != nil {
+				if ctx.Err() != nil {
+					out <- Event{Type: EventError, Err: NewAbortedError("request_aborted", ctx.Err())}
+					return
+				}
+				lastErr = err
+				if shouldRetryTransportError(err) && attempt < policy.maxAttempts {
+					delay := retryDelayForAttempt(policy, attempt)
+					out <- Event{
+						Type:    EventWarning,
+						Code:    "provider_retry",
+						Message: fmt.Sprintf("anthropic retry attempt %d/%d after transport failure: %v", attempt, policy.maxAttempts, err),
+					}
+					if waitErr := waitRetry(ctx, delay); waitErr != nil {
+						out <- Event{Type: EventError, Err: NewAbortedError("request_aborted", waitErr)}
+						return
+					}
+					continue
+				}
+				if shouldRetryTransportError(err) {
+					out <- Event{Type: EventError, Err: &RetryExhaustedError{Attempts: attempt, LastErr: err}}
+					return
+				}
+				out <- Event{Type: EventError, Err: err}
+				return
+			}
+
+			if resp.StatusCode >= 400 {
+				body, readErr := io.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+				if readErr != nil {
+					out <- Event{Type: EventError, Err: readErr}
+					return
+				}
+				httpErr := fmt.Errorf("anthropic_http_%d: %s", resp.StatusCode, string(body))
+				lastErr = httpErr
+				if shouldRetryAnthropicHTTPStatus(resp.StatusCode) && attempt < policy.maxAttempts {
+					delay := retryDelayForAttempt(policy, attempt)
+					out <- Event{
+						Type:    EventWarning,
+						Code:    "provider_retry",
+						Message: fmt.Sprintf("anthropic retry attempt %d/%d after http %d", attempt, policy.maxAttempts, resp.StatusCode),
+					}
+					if waitErr := waitRetry(ctx, delay); waitErr != nil {
+						out <- Event{Type: EventError, Err: NewAbortedError("request_aborted", waitErr)}
+						return
+					}
+					continue
+				}
+				if shouldRetryAnthropicHTTPStatus(resp.StatusCode) {
+					out <- Event{Type: EventError, Err: &RetryExhaustedError{Attempts: attempt, LastErr: httpErr}}
+					return
+				}
+				out <- Event{Type: EventError, Err: httpErr}
+				return
+			}
+
+			if err := a.handleSuccessResponse(ctx, resp, out); err != nil {
+				if ctx.Err() != nil {
+					out <- Event{Type: EventError, Err: NewAbortedError("request_aborted", ctx.Err())}
+					return
+				}
+				out <- Event{Type: EventError, Err: err}
+				return
+			}
+			return
+		}
+		if lastErr != nil {
+			out <- Event{Type: EventError, Err: &RetryExhaustedError{Attempts: policy.maxAttempts, LastErr: lastErr}}
+		}
+	}()
+	return out
+}
+
+// shouldRetryAnthropicHTTPStatus also retries 529, which Anthropic uses for
+// "overloaded" responses.
+func shouldRetryAnthropicHTTPStatus(status int) bool {
+	return status == 529 || shouldRetryHTTPStatus(status)
+}
+
+func (a *AnthropicAdapter) handleSuccessResponse(ctx context.Context, resp *http.Response, out chan<- Event) error {
+	defer resp.Body.Close()
+	contentType := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Type")))
+	if strings.HasPrefix(contentType, "text/event-stream") {
+		return emitAnthropicStreamEvents(ctx, resp.Body, out)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return emitAnthropicJSONEvents(body, out)
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+func (u anthropicUsage) toUsage() *Usage {
+	if u.InputTokens == 0 && u.OutputTokens == 0 {
+		return nil
+	}
+	return &Usage{
+		InputTokens:  u.InputTokens,
+		OutputTokens: u.OutputTokens,
+		TotalTokens:  u.InputTokens + u.OutputTokens,
+	}
+}
+
+type anthropicJSONResponse struct {
+	StopReason string `json:"stop_reason"`
+	Content    []struct {
+		Type  string         `json:"type"`
+		Text  string         `json:"text"`
+		ID    string         `json:"id"`
+		Name  string         `json:"name"`
+		Input map[string]any `json:"input"`
+	} `json:"content"`
+	Usage anthropicUsage `json:"usage"`
+}
+
+func emitAnthropicJSONEvents(body []byte, out chan<- Event) error {
+	var decoded anthropicJSONResponse
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return err
+	}
+	if len(decoded.Content) == 0 && strings.TrimSpace(decoded.StopReason) == "" {
+		return fmt.Errorf("anthropic_empty_content")
+	}
+	for _, block := range decoded.Content {
+		switch block.Type {
+		case "text":
+			if block.Text != "" {
+				out <- Event{Type: EventTextDelta, Delta: block.Text}
+			}
+		case "tool_use":
+			args := block.Input
+			if args == nil {
+				args = map[string]any{}
+			}
+			out <- Event{
+				Type: EventToolCall,
+				ToolCall: ToolCall{
+					ID:        block.ID,
+					Name:      block.Name,
+					Arguments: args,
+				},
+			}
+		}
+	}
+	if decoded.StopReason == "tool_use" {
+		out <- Event{Type: EventAwaitNext}
+	}
+	out <- Event{
+		Type:       EventDone,
+		StopReason: mapAnthropicStopReason(decoded.StopReason),
+		Usage:      decoded.Usage.toUsage(),
+	}
+	return nil
+}
+
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	ContentBlock struct {
+		Type  string         `json:"type"`
+		ID    string         `json:"id"`
+		Name  string         `json:"name"`
+		Input map[string]any `json:"input"`
+	} `json:"content_block"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage anthropicUsage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type partialAnthropicToolUse struct {
+	partialToolCall
+	input map[string]any
+}
+
+var errAnthropicStreamDone = fmt.Errorf("anthropic_stream_done")
+
+func emitAnthropicStreamEvents(ctx context.Context, r io.Reader, out chan<- Event) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 8*1024*1024)
+
+	toolUses := map[int]*partialAnthropicToolUse{}
+	stopReason := ""
+	usage := anthropicUsage{}
+	done := false
+	dataLines := make([]string, 0, 4)
+
+	flush := func() error {
+		if len(dataLines) == 0 {
+			return nil
+		}
+		payload := strings.TrimSpace(strings.Join(dataLines, "\n"))
+		dataLines = dataLines[:0]
+		if payload == "" {
+			return nil
+		}
+		var ev anthropicStreamEvent
+		if err := json.Unmarshal([]byte(payload), &ev); err != nil {
+			return fmt.Errorf("anthropic_bad_stream_chunk: %w", err)
+		}
+		switch ev.Type {
+		case "message_start":
+			if ev.Message.Usage.InputTokens > 0 {
+				usage.InputTokens = ev.Message.Usage.InputTokens
+			}
+			if ev.Message.Usage.OutputTokens > 0 {
+				usage.OutputTokens = ev.Message.Usage.OutputTokens
+			}
+		case "content_block_start":
+			if ev.ContentBlock.Type == "tool_use" {
+				state := &partialAnthropicToolUse{input: ev.ContentBlock.Input}
+				state.id = strings.TrimSpace(ev.ContentBlock.ID)
+				state.name = strings.TrimSpace(ev.ContentBlock.Name)
+				toolUses[ev.Index] = state
+			}
+		case "content_block_delta":
+			switch ev.Delta.Type {
+			case "text_delta":
+				if ev.Delta.Text != "" {
+					out <- Event{Type: EventTextDelta, Delta: ev.Delta.Text}
+				}
+			case "input_json_delta":
+				state, ok := toolUses[ev.Index]
+				if !ok {
+					return fmt.Errorf("anthropic_bad_stream_chunk: input_json_delta for unknown block %d", ev.Index)
+				}
+				state.argsRaw.WriteString(ev.Delta.PartialJSON)
+			}
+		case "message_delta":
+			if strings.TrimSpace(ev.Delta.StopReason) != "" {
+				stopReason = strings.TrimSpace(ev.Delta.StopReason)
+			}
+			if ev.Usage.InputTokens > 0 {
+				usage.InputTokens = ev.Usage.InputTokens
+			}
+			if ev.Usage.OutputTokens > 0 {
+				usage.OutputTokens = ev.Usage.OutputTokens
+			}
+		case "message_stop":
+			done = true
+			return errAnthropicStreamDone
+		case "error":
+			return fmt.Errorf("anthropic_stream_error: %s: %s", ev.Error.Type, ev.Error.Message)
+		}
+		return nil
+	}
+
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return NewAbortedError("request_aborted", ctx.Err())
+		}
+		line := scanner.Text()
+		if line == "" {
+			err := flush()
+			if err == errAnthropicStreamDone {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		if strings.HasPrefix(line, "data:") {
+			dataLines = append(dataLines, strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if !done {
+		if err := flush(); err == errAnthropicStreamDone {
+			done = true
+		} else if err != nil {
+			return err
+		}
+	}
+	if !done {
+		return fmt.Errorf("anthropic_stream_eof_before_done")
+	}
+
+	if len(toolUses) > 0 {
+		indices := make([]int, 0, len(toolUses))
+		for idx := range toolUses {
+			indices = append(indices, idx)
+		}
+		sort.Ints(indices)
+		for _, idx := range indices {
+			state := toolUses[idx]
+			args := state.input
+			if args == nil {
+				args = map[string]any{}
+			}
+			if raw := strings.TrimSpace(state.argsRaw.String()); raw != "" {
+				args = map[string]any{}
+				if err := json.Unmarshal([]byte(raw), &args); err != nil {
+					return fmt.Errorf("anthropic_bad_tool_args: %w", err)
+				}
+			}
+			out <- Event{
+				Type: EventToolCall,
+				ToolCall: ToolCall{
+					ID:        state.id,
+					Name:      state.name,
+					Arguments: args,
+				},
+			}
+		}
+	}
+
+	if stopReason == "tool_use" {
+		out <- Event{Type: EventAwaitNext}
+	}
+	out <- Event{
+		Type:       EventDone,
+		StopReason: mapAnthropicStopReason(stopReason),
+		Usage:      usage.toUsage(),
+	}
+	return nil
+}
+
+func mapAnthropicStopReason(reason string) StopReason {
+	switch strings.TrimSpace(reason) {
+	case "end_turn", "stop_sequence":
+		return StopReasonStop
+	case "max_tokens":
+		return StopReasonLength
+	case "tool_use":
+		return StopReasonToolUse
+	case "":
+		return StopReasonUnknown
+	default:
+		return StopReasonUnknown
+	}
+}
+
+// buildAnthropicMessages splits system messages out into the top-level system
+// prompt and merges consecutive same-role messages, since the Messages API
+// requires user/assistant turns to alternate.
+func buildAnthropicMessages(messages []Message) (string, []map[string]any) {
+	systemParts := make([]string, 0, 1)
+	out := make([]map[string]any, 0, len(messages))
+	appendBlocks := func(role string, blocks []map[string]any) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(out); n > 0 && out[n-1]["role"] == role {
+			prev, _ := out[n-1]["content"].([]map[string]any)
+			out[n-1]["content"] = append(prev, blocks...)
+			return
+		}
+		out = append(out, map[string]any{
+			"role":    role,
+			"content": blocks,
+		})
+	}
+
+	for _, msg := range messages {
+		role := strings.TrimSpace(msg.Role)
+		content := strings.TrimSpace(msg.Content)
+		if content == "" {
+			content = strings.TrimSpace(renderProviderBlocksAsText(msg.Blocks))
+		}
+		if role == "" {
+			continue
+		}
+		switch role {
+		case "system":
+			if content != "" {
+				systemParts = append(systemParts, content)
+			}
+		case "assistant":
+			blocks := make([]map[string]any, 0, 1+len(msg.ToolCalls))
+			if content != "" {
+				blocks = append(blocks, map[string]any{"type": "text", "text": content})
+			}
+			blocks = append(blocks, anthropicToolUseBlocks(msg.ToolCalls)...)
+			appendBlocks("assistant", blocks)
+		case "tool_result":
+			images := anthropicImageBlocks(imageBlocks(msg.Blocks))
+			if id := strings.TrimSpace(msg.ToolCallID); id != "" {
+				if content == "" {
+					content = EmptyToolResult
+				}
+				var result any = content
+				if len(images) > 0 {
+					result = append([]map[string]any{{"type": "text", "text": content}}, images...)
+				}
+				appendBlocks("user", []map[string]any{{
+					"type":        "tool_result",
+					"tool_use_id": id,
+					"content":     result,
+				}})
+				continue
+			}
+			if content == "" {
+				continue
+			}
+			appendBlocks("user", append([]map[string]any{{"type": "text", "text": "Tool result:\n" + content}}, images...))
+		default:
+			if content == "" {
+				continue
+			}
+			appendBlocks("user", []map[string]any{{"type": "text", "text": content}})
+		}
+	}
+	return strings.Join(systemParts, "\n\n"), out
+}
+
+func anthropicImageBlocks(images []ContentBlock) []map[string]any {
+	out := make([]map[string]any, 0, len(images))
+	for _, img := range images {
+		out = append(out, map[string]any{
+			"type": "image",
+			"source": map[string]any{
+				"type":       "base64",
+				"media_type": img.MediaType,
+				"data":       img.Data,
+			},
+		})
+	}
+	return out
+}
+
+func anthropicToolUseBlocks(toolCalls []ToolCall) []map[string]any {
+	out := make([]map[string]any, 0, len(toolCalls))
+	for _, call := range toolCalls {
+		id := strings.TrimSpace(call.ID)
+		name := strings.TrimSpace(call.Name)
+		if id == "" || name == "" {
+			continue
+		}
+		input := call.Arguments
+		if input == nil {
+			input = map[string]any{}
+		}
+		out = append(out, map[string]any{
+			"type":  "tool_use",
+			"id":    id,
+			"name":  name,
+			"input": input,
+		})
+	}
+	return out
+}
+
+func buildAnthropicTools(specs []ToolSpec) []map[string]any {
+	defs := buildOpenAITools(specs)
+	tools := make([]map[string]any, 0, len(defs))
+	for _, def := range defs {
+		fn, ok := def["function"].(map[string]any)
+		if !ok {
+			continue
+		}
+		tools = append(tools, map[string]any{
+			"name":         fn["name"],
+			"description":  fn["description"],
+			"input_schema": fn["parameters"],
+		})
+	}
+	return tools
+}
@@ -13,6 +13,8 @@ func Build(name, model, baseURL string) (Adapter, error) {
 		return NewOpenAIAdapter(os.Getenv("OPENAI_API_KEY"), model, baseURL)
 	case "gemini":
 		return NewGeminiAdapter(os.Getenv("GEMINI_API_KEY"), model, baseURL)
+	case "anthropic":
+		return NewAnthropicAdapter(os.Getenv("ANTHROPIC_API_KEY"), model, baseURL)
 	default:
 		return nil, fmt.Errorf("unknown_provider: %s", name)
 	}
@@ -31,3 +31,10 @@ func TestBuildGeminiRequiresKey(t *testing.T) {
 		t.Fatalf("expected gemini build without key to fail")
 	}
 }
+
+func TestBuildAnthropicRequiresKey(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	if _, err := Build("anthropic", "claude-test", "http://localhost"); err == nil {
+		t.Fatalf("expected anthropic build without key to fail")
+	}
+}
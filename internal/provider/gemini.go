@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -8,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -37,27 +39,17 @@ func NewGeminiAdapter(apiKey, model, baseURL string) (*GeminiAdapter, error) {
 }
 
 func (a *GeminiAdapter) Stream(ctx context.Context, req Request) <-chan Event {
-	out := make(chan Event, 4)
+	out := make(chan Event, 8)
 	go func() {
 		defer close(out)
 		out <- Event{Type: EventStart}
-		prompt := RenderMessages(req.Messages)
-
-		payload := map[string]any{
-			"contents": []map[string]any{
-				{
-					"parts": []map[string]string{
-						{"text": prompt},
-					},
-				},
-			},
-		}
+		payload := buildGeminiPayload(req)
 		b, err := json.Marshal(payload)
 		if err != nil {
 			out <- Event{Type: EventError, Err: err}
 			return
 		}
-		url := fmt.Sprintf("%s/v1beta/models/%s:generateContent?key=%s", a.baseURL, a.model, a.apiKey)
+		url := fmt.Sprintf("%s/v1beta/models/%s:streamGenerateContent?alt=sse&key=%s", a.baseURL, a.model, a.apiKey)
 		policy := defaultRetryPolicy()
 		var lastErr error
 		for attempt := 1; attempt <= policy.maxAttempts; attempt++ {
@@ -67,6 +59,7 @@ func (a *GeminiAdapter) Stream(ctx context.Context, req Request) <-chan Event {
 				return
 			}
 			httpReq.Header.Set("Content-Type", "application/json")
+			httpReq.Header.Set("Accept", "text/event-stream")
 
 			resp, err := a.client.Do(httpReq)
 			if err != nil {
@@ -96,13 +89,13 @@ func (a *GeminiAdapter) Stream(ctx context.Context, req Request) <-chan Event {
 				return
 			}
 
-			body, readErr := io.ReadAll(resp.Body)
-			_ = resp.Body.Close()
-			if readErr != nil {
-				out <- Event{Type: EventError, Err: readErr}
-				return
-			}
 			if resp.StatusCode >= 400 {
+				body, readErr := io.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+				if readErr != nil {
+					out <- Event{Type: EventError, Err: readErr}
+					return
+				}
 				httpErr := fmt.Errorf("gemini_http_%d: %s", resp.StatusCode, string(body))
 				lastErr = httpErr
 				if shouldRetryHTTPStatus(resp.StatusCode) && attempt < policy.maxAttempts {
@@ -126,48 +119,14 @@ func (a *GeminiAdapter) Stream(ctx context.Context, req Request) <-chan Event {
 				return
 			}
 
-			var decoded struct {
-				Candidates []struct {
-					FinishReason string `json:"finishReason"`
-					Content      struct {
-						Parts []struct {
-							Text string `json:"text"`
-						} `json:"parts"`
-					} `json:"content"`
-				} `json:"candidates"`
-				UsageMetadata struct {
-					PromptTokenCount     int `json:"promptTokenCount"`
-					CandidatesTokenCount int `json:"candidatesTokenCount"`
-					TotalTokenCount      int `json:"totalTokenCount"`
-				} `json:"usageMetadata"`
-			}
-			if err := json.Unmarshal(body, &decoded); err != nil {
+			if err := a.handleSuccessResponse(ctx, resp, out); err != nil {
+				if ctx.Err() != nil {
+					out <- Event{Type: EventError, Err: NewAbortedError("request_aborted", ctx.Err())}
+					return
+				}
 				out <- Event{Type: EventError, Err: err}
 				return
 			}
-			if len(decoded.Candidates) == 0 {
-				out <- Event{Type: EventError, Err: fmt.Errorf("gemini_empty_candidates")}
-				return
-			}
-
-			var text strings.Builder
-			for _, p := range decoded.Candidates[0].Content.Parts {
-				text.WriteString(p.Text)
-			}
-			out <- Event{Type: EventTextDelta, Delta: text.String()}
-			var usage *Usage
-			if decoded.UsageMetadata.PromptTokenCount > 0 || decoded.UsageMetadata.CandidatesTokenCount > 0 || decoded.UsageMetadata.TotalTokenCount > 0 {
-				usage = &Usage{
-					InputTokens:  decoded.UsageMetadata.PromptTokenCount,
-					OutputTokens: decoded.UsageMetadata.CandidatesTokenCount,
-					TotalTokens:  decoded.UsageMetadata.TotalTokenCount,
-				}
-			}
-			out <- Event{
-				Type:       EventDone,
-				StopReason: mapGeminiStopReason(decoded.Candidates[0].FinishReason),
-				Usage:      usage,
-			}
 			return
 		}
 		if lastErr != nil {
@@ -177,6 +136,19 @@ func (a *GeminiAdapter) Stream(ctx context.Context, req Request) <-chan Event {
 	return out
 }
 
+func (a *GeminiAdapter) handleSuccessResponse(ctx context.Context, resp *http.Response, out chan<- Event) error {
+	defer resp.Body.Close()
+	contentType := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Type")))
+	if strings.HasPrefix(contentType, "text/event-stream") {
+		return emitGeminiStreamEvents(ctx, resp.Body, out)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return emitGeminiJSONEvents(body, out)
+}
+
 func mapGeminiStopReason(reason string) StopReason {
 	switch strings.TrimSpace(strings.ToUpper(reason)) {
 	case "STOP":
@@ -189,3 +161,445 @@ func mapGeminiStopReason(reason string) StopReason {
 		return StopReasonUnknown
 	}
 }
+
+type geminiPart struct {
+	Text         string `json:"text"`
+	FunctionCall *struct {
+		ID   string         `json:"id"`
+		Name string         `json:"name"`
+		Args map[string]any `json:"args"`
+	} `json:"functionCall"`
+	ThoughtSignature string `json:"thoughtSignature"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		FinishReason string `json:"finishReason"`
+		Content      struct {
+			Parts []geminiPart `json:"parts"`
+		} `json:"content"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (r geminiResponse) usage() *Usage {
+	if r.UsageMetadata.PromptTokenCount == 0 && r.UsageMetadata.CandidatesTokenCount == 0 && r.UsageMetadata.TotalTokenCount == 0 {
+		return nil
+	}
+	return &Usage{
+		InputTokens:  r.UsageMetadata.PromptTokenCount,
+		OutputTokens: r.UsageMetadata.CandidatesTokenCount,
+		TotalTokens:  r.UsageMetadata.TotalTokenCount,
+	}
+}
+
+func emitGeminiJSONEvents(body []byte, out chan<- Event) error {
+	var decoded geminiResponse
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var chunks []geminiResponse
+		if err := json.Unmarshal(trimmed, &chunks); err != nil {
+			return err
+		}
+		decoded = mergeGeminiResponses(chunks)
+	} else if err := json.Unmarshal(body, &decoded); err != nil {
+		return err
+	}
+	if len(decoded.Candidates) == 0 {
+		return fmt.Errorf("gemini_empty_candidates")
+	}
+
+	var text strings.Builder
+	toolCalls := make([]ToolCall, 0, 1)
+	for _, p := range decoded.Candidates[0].Content.Parts {
+		if p.FunctionCall != nil {
+			toolCalls = append(toolCalls, geminiToolCall(p))
+			continue
+		}
+		text.WriteString(p.Text)
+	}
+	if text.Len() > 0 || len(toolCalls) == 0 {
+		out <- Event{Type: EventTextDelta, Delta: text.String()}
+	}
+	for _, call := range toolCalls {
+		out <- Event{Type: EventToolCall, ToolCall: call}
+	}
+	stopReason := mapGeminiStopReason(decoded.Candidates[0].FinishReason)
+	if len(toolCalls) > 0 {
+		out <- Event{Type: EventAwaitNext}
+		stopReason = StopReasonToolUse
+	}
+	out <- Event{
+		Type:       EventDone,
+		StopReason: stopReason,
+		Usage:      decoded.usage(),
+	}
+	return nil
+}
+
+// mergeGeminiResponses folds a non-SSE streamGenerateContent array into a
+// single response so it can share the JSON emission path.
+func mergeGeminiResponses(chunks []geminiResponse) geminiResponse {
+	var merged geminiResponse
+	for _, chunk := range chunks {
+		if chunk.UsageMetadata.TotalTokenCount > 0 || chunk.UsageMetadata.PromptTokenCount > 0 || chunk.UsageMetadata.CandidatesTokenCount > 0 {
+			merged.UsageMetadata = chunk.UsageMetadata
+		}
+		if len(chunk.Candidates) == 0 {
+			continue
+		}
+		if len(merged.Candidates) == 0 {
+			merged.Candidates = chunk.Candidates[:1]
+			continue
+		}
+		merged.Candidates[0].Content.Parts = append(merged.Candidates[0].Content.Parts, chunk.Candidates[0].Content.Parts...)
+		if chunk.Candidates[0].FinishReason != "" {
+			merged.Candidates[0].FinishReason = chunk.Candidates[0].FinishReason
+		}
+	}
+	return merged
+}
+
+// emitGeminiStreamEvents consumes streamGenerateContent?alt=sse output. Gemini
+// has no [DONE] sentinel, so a stream that ends before any candidate reports a
+// finishReason is treated as truncated.
+func emitGeminiStreamEvents(ctx context.Context, r io.Reader, out chan<- Event) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 8*1024*1024)
+
+	toolCalls := make([]ToolCall, 0, 1)
+	finishReason := ""
+	var usage *Usage
+	dataLines := make([]string, 0, 4)
+
+	flush := func() error {
+		if len(dataLines) == 0 {
+			return nil
+		}
+		payload := strings.TrimSpace(strings.Join(dataLines, "\n"))
+		dataLines = dataLines[:0]
+		if payload == "" {
+			return nil
+		}
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+			return fmt.Errorf("gemini_bad_stream_chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("gemini_stream_error: %s: %s", chunk.Error.Status, chunk.Error.Message)
+		}
+		if u := chunk.usage(); u != nil {
+			usage = u
+		}
+		if len(chunk.Candidates) == 0 {
+			return nil
+		}
+		candidate := chunk.Candidates[0]
+		var text strings.Builder
+		for _, p := range candidate.Content.Parts {
+			if p.FunctionCall != nil {
+				toolCalls = append(toolCalls, geminiToolCall(p))
+				continue
+			}
+			text.WriteString(p.Text)
+		}
+		if text.Len() > 0 {
+			out <- Event{Type: EventTextDelta, Delta: text.String()}
+		}
+		if strings.TrimSpace(candidate.FinishReason) != "" {
+			finishReason = strings.TrimSpace(candidate.FinishReason)
+		}
+		return nil
+	}
+
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return NewAbortedError("request_aborted", ctx.Err())
+		}
+		line := scanner.Text()
+		if line == "" {
+			if err := flush(); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		if strings.HasPrefix(line, "data:") {
+			dataLines = append(dataLines, strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	if finishReason == "" {
+		return fmt.Errorf("gemini_stream_eof_before_done")
+	}
+
+	for _, call := range toolCalls {
+		out <- Event{Type: EventToolCall, ToolCall: call}
+	}
+	stopReason := mapGeminiStopReason(finishReason)
+	if len(toolCalls) > 0 {
+		out <- Event{Type: EventAwaitNext}
+		stopReason = StopReasonToolUse
+	}
+	out <- Event{
+		Type:       EventDone,
+		StopReason: stopReason,
+		Usage:      usage,
+	}
+	return nil
+}
+
+// geminiCallSeq numbers function calls when the API does not assign an id,
+// so tool results can still be correlated by the engine.
+var geminiCallSeq atomic.Uint64
+
+func geminiToolCall(p geminiPart) ToolCall {
+	id := strings.TrimSpace(p.FunctionCall.ID)
+	if id == "" {
+		id = fmt.Sprintf("gemini-call-%d", geminiCallSeq.Add(1))
+	}
+	args := p.FunctionCall.Args
+	if args == nil {
+		args = map[string]any{}
+	}
+	return ToolCall{
+		ID:               id,
+		Name:             strings.TrimSpace(p.FunctionCall.Name),
+		Arguments:        args,
+		ThoughtSignature: p.ThoughtSignature,
+	}
+}
+
+func buildGeminiPayload(req Request) map[string]any {
+	system, contents := buildGeminiContents(requestMessages(req))
+	payload := map[string]any{
+		"contents": contents,
+	}
+	if system != "" {
+		payload["systemInstruction"] = map[string]any{
+			"parts": []map[string]any{{"text": system}},
+		}
+	}
+	if len(req.Tools) > 0 {
+		payload["tools"] = []map[string]any{
+			{"functionDeclarations": buildGeminiFunctionDeclarations(req.Tools)},
+		}
+	}
+	return payload
+}
+
+// buildGeminiContents maps messages onto Gemini's user/model roles. Consecutive
+// messages with the same role are merged into one content entry, and tool
+// results become functionResponse parts addressed by the originating call name.
+func buildGeminiContents(messages []Message) (string, []map[string]any) {
+	systemParts := make([]string, 0, 1)
+	callNames := map[string]string{}
+	out := make([]map[string]any, 0, len(messages))
+	appendParts := func(role string, parts []map[string]any) {
+		if len(parts) == 0 {
+			return
+		}
+		if n := len(out); n > 0 && out[n-1]["role"] == role {
+			prev, _ := out[n-1]["parts"].([]map[string]any)
+			out[n-1]["parts"] = append(prev, parts...)
+			return
+		}
+		out = append(out, map[string]any{
+			"role":  role,
+			"parts": parts,
+		})
+	}
+
+	for _, msg := range messages {
+		role := strings.TrimSpace(msg.Role)
+		content := strings.TrimSpace(msg.Content)
+		if content == "" {
+			content = strings.TrimSpace(renderProviderBlocksAsText(msg.Blocks))
+		}
+		if role == "" {
+			continue
+		}
+		switch role {
+		case "system":
+			if content != "" {
+				systemParts = append(systemParts, content)
+			}
+		case "assistant":
+			parts := make([]map[string]any, 0, 1+len(msg.ToolCalls))
+			if content != "" {
+				parts = append(parts, map[string]any{"text": content})
+			}
+			for _, call := range msg.ToolCalls {
+				name := strings.TrimSpace(call.Name)
+				if name == "" {
+					continue
+				}
+				callNames[strings.TrimSpace(call.ID)] = name
+				args := call.Arguments
+				if args == nil {
+					args = map[string]any{}
+				}
+				part := map[string]any{
+					"functionCall": map[string]any{"name": name, "args": args},
+				}
+				if call.ThoughtSignature != "" {
+					part["thoughtSignature"] = call.ThoughtSignature
+				}
+				parts = append(parts, part)
+			}
+			appendParts("model", parts)
+		case "tool_result":
+			name := callNames[strings.TrimSpace(msg.ToolCallID)]
+			if name == "" {
+				name = toolNameFromBlocks(msg.Blocks)
+			}
+			if name == "" || strings.TrimSpace(msg.ToolCallID) == "" {
+				if content == "" {
+					continue
+				}
+				appendParts("user", append([]map[string]any{{"text": "Tool result:\n" + content}}, geminiImageParts(imageBlocks(msg.Blocks))...))
+				continue
+			}
+			if content == "" {
+				content = EmptyToolResult
+			}
+			appendParts("user", append([]map[string]any{{
+				"functionResponse": map[string]any{
+					"name":     name,
+					"response": map[string]any{"content": content},
+				},
+			}}, geminiImageParts(imageBlocks(msg.Blocks))...))
+		default:
+			if content == "" {
+				continue
+			}
+			appendParts("user", []map[string]any{{"text": content}})
+		}
+	}
+	return strings.Join(systemParts, "\n\n"), out
+}
+
+func geminiImageParts(images []ContentBlock) []map[string]any {
+	out := make([]map[string]any, 0, len(images))
+	for _, img := range images {
+		out = append(out, map[string]any{
+			"inlineData": map[string]any{"mimeType": img.MediaType, "data": img.Data},
+		})
+	}
+	return out
+}
+
+func toolNameFromBlocks(blocks []ContentBlock) string {
+	for _, block := range blocks {
+		if name := strings.TrimSpace(block.ToolName); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+func buildGeminiFunctionDeclarations(specs []ToolSpec) []map[string]any {
+	defs := buildOpenAITools(specs)
+	decls := make([]map[string]any, 0, len(defs))
+	for _, def := range defs {
+		fn, ok := def["function"].(map[string]any)
+		if !ok {
+			continue
+		}
+		decl := map[string]any{
+			"name":        fn["name"],
+			"description": fn["description"],
+		}
+		if params, ok := fn["parameters"].(map[string]any); ok {
+			if schema := geminiSchema(params); len(schema) > 0 {
+				decl["parameters"] = schema
+			}
+		}
+		decls = append(decls, decl)
+	}
+	return decls
+}
+
+var geminiSchemaKeys = map[string]bool{
+	"type":        true,
+	"format":      true,
+	"title":       true,
+	"description": true,
+	"nullable":    true,
+	"enum":        true,
+	"items":       true,
+	"properties":  true,
+	"required":    true,
+	"minimum":     true,
+	"maximum":     true,
+	"minItems":    true,
+	"maxItems":    true,
+	"minLength":   true,
+	"maxLength":   true,
+	"pattern":     true,
+	"anyOf":       true,
+}
+
+// geminiSchema reduces a JSON schema to the OpenAPI subset accepted by
+// functionDeclarations. Object schemas without properties are dropped, since
+// Gemini rejects empty OBJECT parameter types.
+func geminiSchema(schema map[string]any) map[string]any {
+	out := map[string]any{}
+	for key, value := range schema {
+		if !geminiSchemaKeys[key] {
+			continue
+		}
+		switch key {
+		case "properties":
+			props, ok := value.(map[string]any)
+			if !ok {
+				continue
+			}
+			converted := map[string]any{}
+			for name, prop := range props {
+				if propSchema, ok := prop.(map[string]any); ok {
+					converted[name] = geminiSchema(propSchema)
+				}
+			}
+			value = converted
+		case "items":
+			if itemSchema, ok := value.(map[string]any); ok {
+				value = geminiSchema(itemSchema)
+			}
+		case "anyOf":
+			variants, ok := value.([]any)
+			if !ok {
+				continue
+			}
+			converted := make([]any, 0, len(variants))
+			for _, variant := range variants {
+				if variantSchema, ok := variant.(map[string]any); ok {
+					converted = append(converted, geminiSchema(variantSchema))
+				}
+			}
+			value = converted
+		}
+		out[key] = value
+	}
+	if out["type"] == "object" {
+		if props, _ := out["properties"].(map[string]any); len(props) == 0 {
+			return nil
+		}
+	}
+	return out
+}
@@ -24,6 +24,23 @@ func writeSSE(w http.ResponseWriter, chunks ...string) {
 	}
 }
 
+func testToolSpec(name, description string, required ...string) ToolSpec {
+	properties := map[string]any{}
+	for _, field := range required {
+		properties[field] = map[string]any{"type": "string"}
+	}
+	return ToolSpec{
+		Name:        name,
+		Description: description,
+		Parameters: map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": true,
+		},
+	}
+}
+
 func collectEvents(ch <-chan Event) []Event {
 	out := make([]Event, 0, 4)
 	for ev := range ch {
@@ -334,8 +351,8 @@ func TestOpenAIAdapterTextToolCallStaysAsText(t *testing.T) {
 		t.Fatalf("new openai adapter failed: %v", err)
 	}
 	evs := collectEvents(a.Stream(context.Background(), Request{
-		Messages:    []Message{{Role: "user", Content: "read file"}},
-		ActiveTools: []string{"read", "ls"},
+		Messages: []Message{{Role: "user", Content: "read file"}},
+		Tools:    []ToolSpec{testToolSpec("read", "Read a file.", "path"), {Name: "ls"}},
 	}))
 	if len(evs) < 3 {
 		t.Fatalf("unexpected event count: %d", len(evs))
@@ -509,8 +526,11 @@ func TestOpenAIAdapterSendsActiveTools(t *testing.T) {
 		t.Fatalf("new openai adapter failed: %v", err)
 	}
 	evs := collectEvents(a.Stream(context.Background(), Request{
-		Messages:    []Message{{Role: "user", Content: "hi"}},
-		ActiveTools: []string{"read", "grep"},
+		Messages: []Message{{Role: "user", Content: "hi"}},
+		Tools: []ToolSpec{
+			testToolSpec("read", "Read a file.", "path"),
+			testToolSpec("grep", "Search files.", "pattern"),
+		},
 	}))
 	if len(evs) < 3 || evs[1].Type != EventTextDelta {
 		t.Fatalf("unexpected events: %+v", evs)
@@ -668,7 +688,7 @@ func TestOpenAIAdapterMapsToolResultWithToolCallIDToToolRole(t *testing.T) {
 	}
 }
 
-func TestOpenAIAdapterWriteToolSchemaRequiresPathAndContent(t *testing.T) {
+func TestOpenAIAdapterDefaultsSchemaForUndeclaredTool(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
@@ -682,21 +702,14 @@ func TestOpenAIAdapterWriteToolSchemaRequiresPathAndContent(t *testing.T) {
 		if !ok || len(rawTools) != 1 {
 			t.Fatalf("expected one tool in request, got: %#v", req["tools"])
 		}
-		writeTool, ok := rawTools[0].(map[string]any)
-		if !ok {
-			t.Fatalf("tool payload must be object: %#v", rawTools[0])
-		}
-		fn, ok := writeTool["function"].(map[string]any)
-		if !ok {
-			t.Fatalf("function payload missing: %#v", writeTool)
+		tool, _ := rawTools[0].(map[string]any)
+		fn, _ := tool["function"].(map[string]any)
+		if fn["name"] != "demo.echo" || fn["description"] != "registered runtime tool" {
+			t.Fatalf("unexpected default tool definition: %#v", fn)
 		}
-		params, ok := fn["parameters"].(map[string]any)
-		if !ok {
-			t.Fatalf("parameters missing: %#v", fn)
-		}
-		required, ok := params["required"].([]any)
-		if !ok || len(required) != 2 || required[0] != "path" || required[1] != "content" {
-			t.Fatalf("expected required path+content for write schema, got: %#v", params["required"])
+		params, _ := fn["parameters"].(map[string]any)
+		if params["type"] != "object" || params["additionalProperties"] != true {
+			t.Fatalf("expected open object schema, got: %#v", params)
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -713,15 +726,263 @@ func TestOpenAIAdapterWriteToolSchemaRequiresPathAndContent(t *testing.T) {
 		t.Fatalf("new openai adapter failed: %v", err)
 	}
 	evs := collectEvents(a.Stream(context.Background(), Request{
-		Messages:    []Message{{Role: "user", Content: "hi"}},
-		ActiveTools: []string{"write"},
+		Messages: []Message{{Role: "user", Content: "hi"}},
+		Tools:    []ToolSpec{{Name: "demo.echo"}},
 	}))
 	if len(evs) < 3 || evs[1].Type != EventTextDelta {
 		t.Fatalf("unexpected events: %+v", evs)
 	}
 }
 
-func TestOpenAIAdapterEditToolSchemaRequiresPathOldNew(t *testing.T) {
+func writeAnthropicSSE(w http.ResponseWriter, events ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, ev := range events {
+		var decoded struct {
+			Type string `json:"type"`
+		}
+		_ = json.Unmarshal([]byte(ev), &decoded)
+		_, _ = io.WriteString(w, "event: "+decoded.Type+"\ndata: "+ev+"\n\n")
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+}
+
+func TestAnthropicAdapterStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+		if got := r.Header.Get("x-api-key"); got != "test-key" {
+			t.Fatalf("unexpected api key header: %q", got)
+		}
+		if got := r.Header.Get("anthropic-version"); got == "" {
+			t.Fatalf("missing anthropic-version header")
+		}
+		writeAnthropicSSE(
+			w,
+			`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":9,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"ping"}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hello "}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"from anthropic"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":4}}`,
+			`{"type":"message_stop"}`,
+		)
+	}))
+	defer srv.Close()
+
+	a, err := NewAnthropicAdapter("test-key", "claude-test", srv.URL)
+	if err != nil {
+		t.Fatalf("new anthropic adapter failed: %v", err)
+	}
+	evs := collectEvents(a.Stream(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}}))
+	if len(evs) != 4 {
+		t.Fatalf("unexpected events: %+v", evs)
+	}
+	if evs[1].Type != EventTextDelta || evs[1].Delta != "hello " {
+		t.Fatalf("unexpected first text event: %+v", evs[1])
+	}
+	if evs[2].Type != EventTextDelta || evs[2].Delta != "from anthropic" {
+		t.Fatalf("unexpected second text event: %+v", evs[2])
+	}
+	done := evs[3]
+	if done.Type != EventDone || done.StopReason != StopReasonStop {
+		t.Fatalf("expected done/stop event, got %+v", done)
+	}
+	if done.Usage == nil || done.Usage.InputTokens != 9 || done.Usage.OutputTokens != 4 || done.Usage.TotalTokens != 13 {
+		t.Fatalf("unexpected usage on done event: %+v", done.Usage)
+	}
+}
+
+func TestAnthropicAdapterStreamToolUseFromInputJSONDelta(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeAnthropicSSE(
+			w,
+			`{"type":"message_start","message":{"usage":{"input_tokens":12}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"checking"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"read","input":{}}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"path\":"}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"README.md\"}"}}`,
+			`{"type":"content_block_stop","index":1}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":7}}`,
+			`{"type":"message_stop"}`,
+		)
+	}))
+	defer srv.Close()
+
+	a, err := NewAnthropicAdapter("test-key", "claude-test", srv.URL)
+	if err != nil {
+		t.Fatalf("new anthropic adapter failed: %v", err)
+	}
+	evs := collectEvents(a.Stream(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}}))
+	if len(evs) != 5 {
+		t.Fatalf("unexpected events: %+v", evs)
+	}
+	if evs[1].Type != EventTextDelta || evs[1].Delta != "checking" {
+		t.Fatalf("unexpected text event: %+v", evs[1])
+	}
+	if evs[2].Type != EventToolCall || evs[2].ToolCall.ID != "toolu_1" || evs[2].ToolCall.Name != "read" {
+		t.Fatalf("unexpected tool call event: %+v", evs[2])
+	}
+	if got, _ := evs[2].ToolCall.Arguments["path"].(string); got != "README.md" {
+		t.Fatalf("unexpected tool call args: %+v", evs[2].ToolCall.Arguments)
+	}
+	if evs[3].Type != EventAwaitNext {
+		t.Fatalf("expected await-next event, got %+v", evs[3])
+	}
+	if evs[4].Type != EventDone || evs[4].StopReason != StopReasonToolUse {
+		t.Fatalf("expected done tool_use event, got %+v", evs[4])
+	}
+}
+
+func TestAnthropicAdapterMapsMaxTokensToLength(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeAnthropicSSE(
+			w,
+			`{"type":"message_start","message":{"usage":{"input_tokens":3}}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"trunc"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"max_tokens"},"usage":{"output_tokens":1}}`,
+			`{"type":"message_stop"}`,
+		)
+	}))
+	defer srv.Close()
+
+	a, err := NewAnthropicAdapter("test-key", "claude-test", srv.URL)
+	if err != nil {
+		t.Fatalf("new anthropic adapter failed: %v", err)
+	}
+	evs := collectEvents(a.Stream(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}}))
+	done := evs[len(evs)-1]
+	if done.Type != EventDone || done.StopReason != StopReasonLength {
+		t.Fatalf("expected done/length event, got %+v", done)
+	}
+}
+
+func TestAnthropicAdapterStreamMalformedChunkReturnsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeSSE(w, `{"type":"message_start"`, `{"type":"message_stop"}`)
+	}))
+	defer srv.Close()
+
+	a, err := NewAnthropicAdapter("test-key", "claude-test", srv.URL)
+	if err != nil {
+		t.Fatalf("new anthropic adapter failed: %v", err)
+	}
+	evs := collectEvents(a.Stream(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}}))
+	last := evs[len(evs)-1]
+	if last.Type != EventError {
+		t.Fatalf("expected final error event, got %+v", last)
+	}
+	if last.Err == nil || !strings.Contains(last.Err.Error(), "anthropic_bad_stream_chunk") {
+		t.Fatalf("expected malformed stream chunk error, got %+v", last.Err)
+	}
+}
+
+func TestAnthropicAdapterStreamEOFBeforeStopReturnsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeAnthropicSSE(
+			w,
+			`{"type":"message_start","message":{"usage":{"input_tokens":3}}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"partial"}}`,
+		)
+	}))
+	defer srv.Close()
+
+	a, err := NewAnthropicAdapter("test-key", "claude-test", srv.URL)
+	if err != nil {
+		t.Fatalf("new anthropic adapter failed: %v", err)
+	}
+	evs := collectEvents(a.Stream(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}}))
+	last := evs[len(evs)-1]
+	if last.Type != EventError {
+		t.Fatalf("expected final error event, got %+v", last)
+	}
+	if last.Err == nil || !strings.Contains(last.Err.Error(), "anthropic_stream_eof_before_done") {
+		t.Fatalf("expected stream eof error, got %+v", last.Err)
+	}
+}
+
+func TestAnthropicAdapterStreamErrorEventReturnsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeAnthropicSSE(w, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`)
+	}))
+	defer srv.Close()
+
+	a, err := NewAnthropicAdapter("test-key", "claude-test", srv.URL)
+	if err != nil {
+		t.Fatalf("new anthropic adapter failed: %v", err)
+	}
+	evs := collectEvents(a.Stream(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}}))
+	last := evs[len(evs)-1]
+	if last.Type != EventError || last.Err == nil || !strings.Contains(last.Err.Error(), "overloaded_error") {
+		t.Fatalf("expected stream error event, got %+v", last)
+	}
+}
+
+func TestAnthropicAdapterRetriesOverloadedThenSucceeds(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(529)
+			_, _ = w.Write([]byte(`{"type":"error","error":{"type":"overloaded_error"}}`))
+			return
+		}
+		writeAnthropicSSE(
+			w,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"recovered"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"}}`,
+			`{"type":"message_stop"}`,
+		)
+	}))
+	defer srv.Close()
+
+	a, err := NewAnthropicAdapter("test-key", "claude-test", srv.URL)
+	if err != nil {
+		t.Fatalf("new anthropic adapter failed: %v", err)
+	}
+	evs := collectEvents(a.Stream(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}}))
+	if attempts != 2 {
+		t.Fatalf("expected one retry attempt, got attempts=%d", attempts)
+	}
+	foundRetryWarning := false
+	foundText := false
+	for _, ev := range evs {
+		if ev.Type == EventWarning && ev.Code == "provider_retry" {
+			foundRetryWarning = true
+		}
+		if ev.Type == EventTextDelta && ev.Delta == "recovered" {
+			foundText = true
+		}
+	}
+	if !foundRetryWarning || !foundText {
+		t.Fatalf("expected retry warning and recovered text, got events=%+v", evs)
+	}
+}
+
+func TestAnthropicAdapterAbortReturnsTypedError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	a, err := NewAnthropicAdapter("test-key", "claude-test", "http://127.0.0.1:9")
+	if err != nil {
+		t.Fatalf("new anthropic adapter failed: %v", err)
+	}
+	evs := collectEvents(a.Stream(ctx, Request{Messages: []Message{{Role: "user", Content: "hi"}}}))
+	last := evs[len(evs)-1]
+	if last.Type != EventError {
+		t.Fatalf("expected final error event, got %+v", last)
+	}
+	if !IsAbortedError(last.Err) {
+		t.Fatalf("expected aborted error type, got %+v", last.Err)
+	}
+}
+
+func TestAnthropicAdapterBuildsNativeMessages(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
@@ -731,50 +992,118 @@ func TestOpenAIAdapterEditToolSchemaRequiresPathOldNew(t *testing.T) {
 		if err := json.Unmarshal(body, &req); err != nil {
 			t.Fatalf("decode request failed: %v", err)
 		}
-		rawTools, ok := req["tools"].([]any)
-		if !ok || len(rawTools) != 1 {
-			t.Fatalf("expected one tool in request, got: %#v", req["tools"])
+		if system, _ := req["system"].(string); system != "be brief" {
+			t.Fatalf("expected top-level system prompt, got: %#v", req["system"])
 		}
-		editTool, ok := rawTools[0].(map[string]any)
-		if !ok {
-			t.Fatalf("tool payload must be object: %#v", rawTools[0])
+		if maxTokens, _ := req["max_tokens"].(float64); maxTokens <= 0 {
+			t.Fatalf("expected max_tokens in request, got: %#v", req["max_tokens"])
 		}
-		fn, ok := editTool["function"].(map[string]any)
-		if !ok {
-			t.Fatalf("function payload missing: %#v", editTool)
+		rawMsgs, ok := req["messages"].([]any)
+		if !ok || len(rawMsgs) != 3 {
+			t.Fatalf("expected three messages, got: %#v", req["messages"])
 		}
-		params, ok := fn["parameters"].(map[string]any)
-		if !ok {
-			t.Fatalf("parameters missing: %#v", fn)
+		assistant, _ := rawMsgs[1].(map[string]any)
+		if role, _ := assistant["role"].(string); role != "assistant" {
+			t.Fatalf("unexpected second role: %#v", assistant)
 		}
-		required, ok := params["required"].([]any)
-		if !ok || len(required) != 3 || required[0] != "path" || required[1] != "oldText" || required[2] != "newText" {
-			t.Fatalf("expected required path+oldText+newText for edit schema, got: %#v", params["required"])
+		blocks, _ := assistant["content"].([]any)
+		toolUse, _ := blocks[len(blocks)-1].(map[string]any)
+		if toolUse["type"] != "tool_use" || toolUse["id"] != "toolu_1" || toolUse["name"] != "read" {
+			t.Fatalf("expected tool_use block, got: %#v", assistant)
 		}
+		toolMsg, _ := rawMsgs[2].(map[string]any)
+		if role, _ := toolMsg["role"].(string); role != "user" {
+			t.Fatalf("expected tool result in user message, got: %#v", toolMsg)
+		}
+		resultBlocks, _ := toolMsg["content"].([]any)
+		result, _ := resultBlocks[0].(map[string]any)
+		if result["type"] != "tool_result" || result["tool_use_id"] != "toolu_1" {
+			t.Fatalf("expected tool_result block, got: %#v", toolMsg)
+		}
+		rawTools, ok := req["tools"].([]any)
+		if !ok || len(rawTools) != 1 {
+			t.Fatalf("expected one tool, got: %#v", req["tools"])
+		}
+		tool, _ := rawTools[0].(map[string]any)
+		schema, _ := tool["input_schema"].(map[string]any)
+		required, _ := schema["required"].([]any)
+		if tool["name"] != "read" || len(required) == 0 || required[0] != "path" {
+			t.Fatalf("unexpected tool definition: %#v", tool)
+		}
+
+		writeAnthropicSSE(
+			w,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"ok"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"}}`,
+			`{"type":"message_stop"}`,
+		)
+	}))
+	defer srv.Close()
 
+	a, err := NewAnthropicAdapter("test-key", "claude-test", srv.URL)
+	if err != nil {
+		t.Fatalf("new anthropic adapter failed: %v", err)
+	}
+	evs := collectEvents(a.Stream(context.Background(), Request{
+		Messages: []Message{
+			{Role: "system", Content: "be brief"},
+			{Role: "user", Content: "please summarize"},
+			{Role: "assistant", Content: "reading", ToolCalls: []ToolCall{{ID: "toolu_1", Name: "read", Arguments: map[string]any{"path": "/tmp/a"}}}},
+			{Role: "tool_result", Content: "read => 1", ToolCallID: "toolu_1"},
+		},
+		Tools: []ToolSpec{testToolSpec("read", "Read a file.", "path")},
+	}))
+	if len(evs) < 3 || evs[1].Type != EventTextDelta {
+		t.Fatalf("unexpected events: %+v", evs)
+	}
+}
+
+func TestGeminiAdapterEmitsFunctionCalls(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(map[string]any{
-			"choices": []map[string]any{
-				{"message": map[string]any{"content": "ok"}},
+			"candidates": []map[string]any{
+				{
+					"finishReason": "STOP",
+					"content": map[string]any{
+						"role": "model",
+						"parts": []map[string]any{
+							{"functionCall": map[string]any{"name": "read", "args": map[string]any{"path": "README.md"}}, "thoughtSignature": "sig-1"},
+						},
+					},
+				},
 			},
 		})
 	}))
 	defer srv.Close()
 
-	a, err := NewOpenAIAdapter("test-key", "gpt-test", srv.URL)
+	a, err := NewGeminiAdapter("test-key", "gemini-test", srv.URL)
 	if err != nil {
-		t.Fatalf("new openai adapter failed: %v", err)
+		t.Fatalf("new gemini adapter failed: %v", err)
 	}
-	evs := collectEvents(a.Stream(context.Background(), Request{
-		Messages:    []Message{{Role: "user", Content: "hi"}},
-		ActiveTools: []string{"edit"},
-	}))
-	if len(evs) < 3 || evs[1].Type != EventTextDelta {
+	evs := collectEvents(a.Stream(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}}))
+	if len(evs) != 4 {
 		t.Fatalf("unexpected events: %+v", evs)
 	}
+	call := evs[1]
+	if call.Type != EventToolCall || call.ToolCall.Name != "read" || call.ToolCall.ID == "" {
+		t.Fatalf("expected tool call event with id, got %+v", call)
+	}
+	if got, _ := call.ToolCall.Arguments["path"].(string); got != "README.md" {
+		t.Fatalf("unexpected tool call args: %+v", call.ToolCall.Arguments)
+	}
+	if call.ToolCall.ThoughtSignature != "sig-1" {
+		t.Fatalf("expected thought signature on tool call, got %+v", call.ToolCall)
+	}
+	if evs[2].Type != EventAwaitNext {
+		t.Fatalf("expected await-next event, got %+v", evs[2])
+	}
+	if evs[3].Type != EventDone || evs[3].StopReason != StopReasonToolUse {
+		t.Fatalf("expected done tool_use event, got %+v", evs[3])
+	}
 }
 
-func TestOpenAIAdapterBashToolSchemaRequiresCommand(t *testing.T) {
+func TestGeminiAdapterSendsStructuredContentsAndFunctionDeclarations(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
@@ -784,45 +1113,329 @@ func TestOpenAIAdapterBashToolSchemaRequiresCommand(t *testing.T) {
 		if err := json.Unmarshal(body, &req); err != nil {
 			t.Fatalf("decode request failed: %v", err)
 		}
-		rawTools, ok := req["tools"].([]any)
-		if !ok || len(rawTools) != 1 {
-			t.Fatalf("expected one tool in request, got: %#v", req["tools"])
+		system, _ := req["systemInstruction"].(map[string]any)
+		if system == nil {
+			t.Fatalf("expected systemInstruction, got: %#v", req)
 		}
-		bashTool, ok := rawTools[0].(map[string]any)
-		if !ok {
-			t.Fatalf("tool payload must be object: %#v", rawTools[0])
+		contents, ok := req["contents"].([]any)
+		if !ok || len(contents) != 3 {
+			t.Fatalf("expected three contents, got: %#v", req["contents"])
 		}
-		fn, ok := bashTool["function"].(map[string]any)
-		if !ok {
-			t.Fatalf("function payload missing: %#v", bashTool)
+		wantRoles := []string{"user", "model", "user"}
+		for i, raw := range contents {
+			content, _ := raw.(map[string]any)
+			if role, _ := content["role"].(string); role != wantRoles[i] {
+				t.Fatalf("unexpected role at %d: %#v", i, content)
+			}
 		}
-		params, ok := fn["parameters"].(map[string]any)
-		if !ok {
-			t.Fatalf("parameters missing: %#v", fn)
+		modelParts, _ := contents[1].(map[string]any)["parts"].([]any)
+		callPart, _ := modelParts[len(modelParts)-1].(map[string]any)
+		fnCall, _ := callPart["functionCall"].(map[string]any)
+		if fnCall["name"] != "read" {
+			t.Fatalf("expected functionCall part, got: %#v", modelParts)
 		}
-		required, ok := params["required"].([]any)
-		if !ok || len(required) != 1 || required[0] != "command" {
-			t.Fatalf("expected required command for bash schema, got: %#v", params["required"])
+		if callPart["thoughtSignature"] != "sig-1" {
+			t.Fatalf("expected thought signature echoed on functionCall part, got: %#v", callPart)
+		}
+		userParts, _ := contents[2].(map[string]any)["parts"].([]any)
+		fnResp, _ := userParts[0].(map[string]any)["functionResponse"].(map[string]any)
+		if fnResp["name"] != "read" {
+			t.Fatalf("expected functionResponse part named after call, got: %#v", userParts)
+		}
+		tools, _ := req["tools"].([]any)
+		if len(tools) != 1 {
+			t.Fatalf("expected one tools entry, got: %#v", req["tools"])
+		}
+		decls, _ := tools[0].(map[string]any)["functionDeclarations"].([]any)
+		if len(decls) != 2 {
+			t.Fatalf("expected two function declarations, got: %#v", tools[0])
+		}
+		readDecl, _ := decls[0].(map[string]any)
+		params, _ := readDecl["parameters"].(map[string]any)
+		if readDecl["name"] != "read" || params == nil {
+			t.Fatalf("unexpected read declaration: %#v", readDecl)
+		}
+		if _, ok := params["additionalProperties"]; ok {
+			t.Fatalf("gemini schema must not include additionalProperties: %#v", params)
+		}
+		required, _ := params["required"].([]any)
+		if len(required) == 0 || required[0] != "path" {
+			t.Fatalf("expected required path, got: %#v", params)
+		}
+		openDecl, _ := decls[1].(map[string]any)
+		if _, ok := openDecl["parameters"]; ok {
+			t.Fatalf("schema-less tool should omit parameters: %#v", openDecl)
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(map[string]any{
-			"choices": []map[string]any{
-				{"message": map[string]any{"content": "ok"}},
+			"candidates": []map[string]any{
+				{"finishReason": "STOP", "content": map[string]any{"parts": []map[string]any{{"text": "ok"}}}},
 			},
 		})
 	}))
 	defer srv.Close()
 
-	a, err := NewOpenAIAdapter("test-key", "gpt-test", srv.URL)
+	a, err := NewGeminiAdapter("test-key", "gemini-test", srv.URL)
 	if err != nil {
-		t.Fatalf("new openai adapter failed: %v", err)
+		t.Fatalf("new gemini adapter failed: %v", err)
 	}
 	evs := collectEvents(a.Stream(context.Background(), Request{
-		Messages:    []Message{{Role: "user", Content: "hi"}},
-		ActiveTools: []string{"bash"},
+		Messages: []Message{
+			{Role: "system", Content: "be brief"},
+			{Role: "user", Content: "please summarize"},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "gemini-call-1", Name: "read", Arguments: map[string]any{"path": "/tmp/a"}, ThoughtSignature: "sig-1"}}},
+			{Role: "tool_result", Content: "read => 1", ToolCallID: "gemini-call-1"},
+		},
+		Tools: []ToolSpec{
+			testToolSpec("read", "Read a file.", "path"),
+			{Name: "demo.echo"},
+		},
 	}))
-	if len(evs) < 3 || evs[1].Type != EventTextDelta {
+	if len(evs) < 3 || evs[1].Type != EventTextDelta || evs[1].Delta != "ok" {
 		t.Fatalf("unexpected events: %+v", evs)
 	}
 }
+
+func TestGeminiAdapterStreamsSSEChunks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, ":streamGenerateContent") || r.URL.Query().Get("alt") != "sse" {
+			t.Fatalf("expected streamGenerateContent sse request, got %s?%s", r.URL.Path, r.URL.RawQuery)
+		}
+		writeSSE(
+			w,
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"hello "}]}}]}`,
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"from gemini"}]}}],"usageMetadata":{"promptTokenCount":9,"candidatesTokenCount":2,"totalTokenCount":11}}`,
+			`{"candidates":[{"finishReason":"STOP","content":{"role":"model","parts":[{"text":""}]}}],"usageMetadata":{"promptTokenCount":9,"candidatesTokenCount":4,"totalTokenCount":13}}`,
+		)
+	}))
+	defer srv.Close()
+
+	a, err := NewGeminiAdapter("test-key", "gemini-test", srv.URL)
+	if err != nil {
+		t.Fatalf("new gemini adapter failed: %v", err)
+	}
+	evs := collectEvents(a.Stream(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}}))
+	if len(evs) != 4 {
+		t.Fatalf("unexpected events: %+v", evs)
+	}
+	if evs[1].Type != EventTextDelta || evs[1].Delta != "hello " {
+		t.Fatalf("unexpected first text event: %+v", evs[1])
+	}
+	if evs[2].Type != EventTextDelta || evs[2].Delta != "from gemini" {
+		t.Fatalf("unexpected second text event: %+v", evs[2])
+	}
+	done := evs[3]
+	if done.Type != EventDone || done.StopReason != StopReasonStop {
+		t.Fatalf("expected done/stop event, got %+v", done)
+	}
+	if done.Usage == nil || done.Usage.InputTokens != 9 || done.Usage.OutputTokens != 4 || done.Usage.TotalTokens != 13 {
+		t.Fatalf("unexpected usage on done event: %+v", done.Usage)
+	}
+}
+
+func TestGeminiAdapterStreamsFunctionCallFromSSE(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeSSE(
+			w,
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"checking"}]}}]}`,
+			`{"candidates":[{"finishReason":"STOP","content":{"role":"model","parts":[{"functionCall":{"id":"fc-1","name":"read","args":{"path":"a.txt"}}}]}}]}`,
+		)
+	}))
+	defer srv.Close()
+
+	a, err := NewGeminiAdapter("test-key", "gemini-test", srv.URL)
+	if err != nil {
+		t.Fatalf("new gemini adapter failed: %v", err)
+	}
+	evs := collectEvents(a.Stream(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}}))
+	if len(evs) != 5 {
+		t.Fatalf("unexpected events: %+v", evs)
+	}
+	if evs[2].Type != EventToolCall || evs[2].ToolCall.ID != "fc-1" || evs[2].ToolCall.Name != "read" {
+		t.Fatalf("unexpected tool call event: %+v", evs[2])
+	}
+	if evs[3].Type != EventAwaitNext || evs[4].StopReason != StopReasonToolUse {
+		t.Fatalf("expected await-next and tool_use stop, got %+v", evs)
+	}
+}
+
+func TestGeminiAdapterStreamMalformedChunkReturnsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeSSE(w, `{"candidates":[`)
+	}))
+	defer srv.Close()
+
+	a, err := NewGeminiAdapter("test-key", "gemini-test", srv.URL)
+	if err != nil {
+		t.Fatalf("new gemini adapter failed: %v", err)
+	}
+	evs := collectEvents(a.Stream(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}}))
+	last := evs[len(evs)-1]
+	if last.Type != EventError {
+		t.Fatalf("expected final error event, got %+v", last)
+	}
+	if last.Err == nil || !strings.Contains(last.Err.Error(), "gemini_bad_stream_chunk") {
+		t.Fatalf("expected malformed stream chunk error, got %+v", last.Err)
+	}
+}
+
+func TestGeminiAdapterStreamEOFBeforeFinishReturnsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeSSE(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"partial"}]}}]}`)
+	}))
+	defer srv.Close()
+
+	a, err := NewGeminiAdapter("test-key", "gemini-test", srv.URL)
+	if err != nil {
+		t.Fatalf("new gemini adapter failed: %v", err)
+	}
+	evs := collectEvents(a.Stream(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}}))
+	last := evs[len(evs)-1]
+	if last.Type != EventError {
+		t.Fatalf("expected final error event, got %+v", last)
+	}
+	if last.Err == nil || !strings.Contains(last.Err.Error(), "gemini_stream_eof_before_done") {
+		t.Fatalf("expected stream eof error, got %+v", last.Err)
+	}
+}
+
+func TestAdaptersSendSystemPromptNatively(t *testing.T) {
+	var captured map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("read body failed: %v", err)
+		}
+		captured = nil
+		if err := json.Unmarshal(body, &captured); err != nil {
+			t.Fatalf("decode request failed: %v", err)
+		}
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/v1/messages"):
+			writeAnthropicSSE(
+				w,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"ok"}}`,
+				`{"type":"message_delta","delta":{"stop_reason":"end_turn"}}`,
+				`{"type":"message_stop"}`,
+			)
+		case strings.Contains(r.URL.Path, ":streamGenerateContent"):
+			writeSSE(w, `{"candidates":[{"finishReason":"STOP","content":{"role":"model","parts":[{"text":"ok"}]}}]}`)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"choices": []map[string]any{
+					{"message": map[string]any{"content": "ok"}},
+				},
+			})
+		}
+	}))
+	defer srv.Close()
+
+	req := Request{
+		System:   "follow AGENTS.md",
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	}
+
+	openai, err := NewOpenAIAdapter("test-key", "gpt-test", srv.URL)
+	if err != nil {
+		t.Fatalf("new openai adapter failed: %v", err)
+	}
+	collectEvents(openai.Stream(context.Background(), req))
+	rawMsgs, _ := captured["messages"].([]any)
+	if len(rawMsgs) != 2 {
+		t.Fatalf("expected system and user messages, got: %#v", captured["messages"])
+	}
+	first, _ := rawMsgs[0].(map[string]any)
+	if first["role"] != "system" || first["content"] != "follow AGENTS.md" {
+		t.Fatalf("expected leading system message, got: %#v", first)
+	}
+
+	anthropic, err := NewAnthropicAdapter("test-key", "claude-test", srv.URL)
+	if err != nil {
+		t.Fatalf("new anthropic adapter failed: %v", err)
+	}
+	collectEvents(anthropic.Stream(context.Background(), req))
+	if system, _ := captured["system"].(string); system != "follow AGENTS.md" {
+		t.Fatalf("expected anthropic system field, got: %#v", captured["system"])
+	}
+	if rawMsgs, _ := captured["messages"].([]any); len(rawMsgs) != 1 {
+		t.Fatalf("system prompt must not be sent as a message, got: %#v", captured["messages"])
+	}
+
+	gemini, err := NewGeminiAdapter("test-key", "gemini-test", srv.URL)
+	if err != nil {
+		t.Fatalf("new gemini adapter failed: %v", err)
+	}
+	collectEvents(gemini.Stream(context.Background(), req))
+	instruction, _ := captured["systemInstruction"].(map[string]any)
+	parts, _ := instruction["parts"].([]any)
+	if len(parts) != 1 {
+		t.Fatalf("expected gemini systemInstruction, got: %#v", captured)
+	}
+	if part, _ := parts[0].(map[string]any); part["text"] != "follow AGENTS.md" {
+		t.Fatalf("unexpected systemInstruction part: %#v", parts[0])
+	}
+}
+
+func TestAdaptersSendToolResultImages(t *testing.T) {
+	image := ContentBlock{Type: "image", MediaType: "image/png", Data: "aGk="}
+	messages := []Message{
+		{Role: "user", Content: "look"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "c1", Name: "read"}, {ID: "c2", Name: "read"}}},
+		{Role: "tool_result", Content: "read => image a.png", ToolCallID: "c1", Blocks: []ContentBlock{{Type: "tool_result", Text: "read => image a.png"}, image}},
+		{Role: "tool_result", Content: "read => b", ToolCallID: "c2"},
+	}
+
+	_, anthropic := buildAnthropicMessages(messages)
+	blocks, _ := anthropic[2]["content"].([]map[string]any)
+	parts, _ := blocks[0]["content"].([]map[string]any)
+	if len(parts) != 2 || parts[1]["type"] != "image" || parts[1]["source"].(map[string]any)["data"] != "aGk=" {
+		t.Fatalf("expected anthropic tool_result with image, got %#v", blocks)
+	}
+
+	openai := buildOpenAIMessages(messages)
+	if len(openai) != 5 || openai[2]["role"] != "tool" || openai[3]["role"] != "tool" || openai[4]["role"] != "user" {
+		t.Fatalf("expected images after the run of tool messages, got %#v", openai)
+	}
+	userParts, _ := openai[4]["content"].([]map[string]any)
+	if url, _ := userParts[1]["image_url"].(map[string]any)["url"].(string); url != "data:image/png;base64,aGk=" {
+		t.Fatalf("unexpected openai image part: %#v", userParts)
+	}
+
+	_, gemini := buildGeminiContents(messages)
+	geminiParts, _ := gemini[2]["parts"].([]map[string]any)
+	if len(geminiParts) < 2 || geminiParts[1]["inlineData"] == nil {
+		t.Fatalf("expected gemini inlineData after functionResponse, got %#v", gemini[2])
+	}
+}
+
+func TestAdaptersAnswerEmptyToolResults(t *testing.T) {
+	messages := []Message{
+		{Role: "user", Content: "search"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "c1", Name: "grep"}}},
+		{Role: "tool_result", Content: " ", ToolCallID: "c1"},
+		{Role: "tool_result", Content: ""},
+	}
+
+	_, anthropic := buildAnthropicMessages(messages)
+	blocks, _ := anthropic[len(anthropic)-1]["content"].([]map[string]any)
+	if len(anthropic) != 3 || len(blocks) != 1 || blocks[0]["type"] != "tool_result" || blocks[0]["tool_use_id"] != "c1" || blocks[0]["content"] != EmptyToolResult {
+		t.Fatalf("expected anthropic tool_result answering c1, got %#v", anthropic)
+	}
+
+	openai := buildOpenAIMessages(messages)
+	if len(openai) != 3 || openai[2]["role"] != "tool" || openai[2]["tool_call_id"] != "c1" || openai[2]["content"] != EmptyToolResult {
+		t.Fatalf("expected openai tool message answering c1, got %#v", openai)
+	}
+
+	_, gemini := buildGeminiContents(messages)
+	parts, _ := gemini[len(gemini)-1]["parts"].([]map[string]any)
+	if len(gemini) != 3 || len(parts) != 1 {
+		t.Fatalf("expected one gemini functionResponse, got %#v", gemini)
+	}
+	resp, _ := parts[0]["functionResponse"].(map[string]any)
+	if resp["name"] != "grep" || resp["response"].(map[string]any)["content"] != EmptyToolResult {
+		t.Fatalf("unexpected gemini functionResponse: %#v", parts[0])
+	}
+}
@@ -52,7 +52,7 @@ func (a *openAIChatAdapter) Stream(ctx context.Context, req Request) <-chan Even
 		defer close(out)
 		out <- Event{Type: EventStart}
 
-		messages := buildOpenAIMessages(req.Messages)
+		messages := buildOpenAIMessages(requestMessages(req))
 		payload := map[string]any{
 			"model":    a.model,
 			"messages": messages,
@@ -61,8 +61,8 @@ func (a *openAIChatAdapter) Stream(ctx context.Context, req Request) <-chan Even
 				"include_usage": true,
 			},
 		}
-		if len(req.ActiveTools) > 0 {
-			payload["tools"] = buildOpenAITools(req.ActiveTools)
+		if len(req.Tools) > 0 {
+			payload["tools"] = buildOpenAITools(req.Tools)
 		}
 		b, err := json.Marshal(payload)
 		if err != nil {
@@ -427,8 +427,20 @@ func mapOpenAIStopReason(reason string) StopReason {
 	}
 }
 
+// buildOpenAIMessages maps messages to chat completions messages. Tool
+// messages cannot carry images, so images from a run of tool results follow
+// it in one user message.
 func buildOpenAIMessages(messages []Message) []map[string]any {
 	out := make([]map[string]any, 0, len(messages))
+	var pendingImages []map[string]any
+	flushImages := func() {
+		if len(pendingImages) == 0 {
+			return
+		}
+		parts := append([]map[string]any{{"type": "text", "text": "Images returned by the tool calls above."}}, pendingImages...)
+		out = append(out, map[string]any{"role": "user", "content": parts})
+		pendingImages = nil
+	}
 	for _, msg := range messages {
 		role := strings.TrimSpace(msg.Role)
 		content := strings.TrimSpace(msg.Content)
@@ -438,23 +450,34 @@ func buildOpenAIMessages(messages []Message) []map[string]any {
 		if role == "" {
 			continue
 		}
+		if role != "tool_result" || strings.TrimSpace(msg.ToolCallID) == "" {
+			flushImages()
+		}
 		switch role {
 		case "assistant", "system", "user":
 		case "tool_result":
 			if strings.TrimSpace(msg.ToolCallID) != "" {
 				if content == "" {
-					continue
+					content = EmptyToolResult
 				}
 				out = append(out, map[string]any{
 					"role":         "tool",
 					"tool_call_id": strings.TrimSpace(msg.ToolCallID),
 					"content":      content,
 				})
+				pendingImages = append(pendingImages, openAIImageParts(imageBlocks(msg.Blocks))...)
 				continue
 			}
 			if content == "" {
 				continue
 			}
+			if images := openAIImageParts(imageBlocks(msg.Blocks)); len(images) > 0 {
+				out = append(out, map[string]any{
+					"role":    "user",
+					"content": append([]map[string]any{{"type": "text", "text": "Tool result:\n" + content}}, images...),
+				})
+				continue
+			}
 			out = append(out, map[string]any{
 				"role":    "user",
 				"content": "Tool result:\n" + content,
@@ -480,6 +503,18 @@ func buildOpenAIMessages(messages []Message) []map[string]any {
 			"content": content,
 		})
 	}
+	flushImages()
+	return out
+}
+
+func openAIImageParts(images []ContentBlock) []map[string]any {
+	out := make([]map[string]any, 0, len(images))
+	for _, img := range images {
+		out = append(out, map[string]any{
+			"type":      "image_url",
+			"image_url": map[string]any{"url": "data:" + img.MediaType + ";base64," + img.Data},
+		})
+	}
 	return out
 }
 
@@ -509,155 +544,22 @@ func openAIToolCalls(toolCalls []ToolCall) []map[string]any {
 	return out
 }
 
-func buildOpenAITools(names []string) []map[string]any {
-	tools := make([]map[string]any, 0, len(names))
-	for _, name := range names {
-		if strings.TrimSpace(name) == "" {
+func buildOpenAITools(specs []ToolSpec) []map[string]any {
+	tools := make([]map[string]any, 0, len(specs))
+	for _, spec := range specs {
+		name := strings.TrimSpace(spec.Name)
+		if name == "" {
 			continue
 		}
-		description := "registered runtime tool"
-		parameters := map[string]any{
-			"type":                 "object",
-			"properties":           map[string]any{},
-			"additionalProperties": true,
+		description := strings.TrimSpace(spec.Description)
+		if description == "" {
+			description = "registered runtime tool"
 		}
-		switch name {
-		case "read":
-			description = "Read file contents by path. Supports optional offset and limit."
-			parameters = map[string]any{
-				"type": "object",
-				"properties": map[string]any{
-					"path": map[string]any{
-						"type":        "string",
-						"description": "Path to the file to read (relative or absolute)",
-					},
-					"offset": map[string]any{
-						"type":        "number",
-						"description": "Optional line offset (0-based)",
-					},
-					"limit": map[string]any{
-						"type":        "number",
-						"description": "Optional max number of lines to read",
-					},
-				},
-				"required":             []string{"path"},
-				"additionalProperties": true,
-			}
-		case "ls":
-			description = "List directory contents."
-			parameters = map[string]any{
-				"type": "object",
-				"properties": map[string]any{
-					"path": map[string]any{
-						"type":        "string",
-						"description": "Directory path to list. Defaults to current directory.",
-					},
-				},
-				"additionalProperties": true,
-			}
-		case "find":
-			description = "Find files under a path using substring match."
-			parameters = map[string]any{
-				"type": "object",
-				"properties": map[string]any{
-					"path": map[string]any{
-						"type":        "string",
-						"description": "Root directory to search. Defaults to current directory.",
-					},
-					"query": map[string]any{
-						"type":        "string",
-						"description": "Substring to match in relative file paths.",
-					},
-					"max_results": map[string]any{
-						"type":        "number",
-						"description": "Maximum number of matches to return.",
-					},
-					"max_depth": map[string]any{
-						"type":        "number",
-						"description": "Maximum depth to walk. -1 means unlimited.",
-					},
-				},
-				"required":             []string{"query"},
-				"additionalProperties": true,
-			}
-		case "grep":
-			description = "Search file contents with a pattern and return matching lines."
-			parameters = map[string]any{
-				"type": "object",
-				"properties": map[string]any{
-					"pattern": map[string]any{
-						"type":        "string",
-						"description": "Pattern to search (regex).",
-					},
-					"path": map[string]any{
-						"type":        "string",
-						"description": "File or directory path to search. Defaults to current directory.",
-					},
-					"ignore_case": map[string]any{
-						"type":        "boolean",
-						"description": "Case-insensitive search.",
-					},
-					"limit": map[string]any{
-						"type":        "number",
-						"description": "Maximum number of matching lines to return.",
-					},
-				},
-				"required":             []string{"pattern"},
-				"additionalProperties": true,
-			}
-		case "write":
-			description = "Write text content into a file path."
-			parameters = map[string]any{
-				"type": "object",
-				"properties": map[string]any{
-					"path": map[string]any{
-						"type":        "string",
-						"description": "Path to the file to write (relative or absolute).",
-					},
-					"content": map[string]any{
-						"type":        "string",
-						"description": "Text content to write.",
-					},
-				},
-				"required":             []string{"path", "content"},
-				"additionalProperties": true,
-			}
-		case "edit":
-			description = "Edit a file by replacing exact oldText with newText."
+		parameters := spec.Parameters
+		if len(parameters) == 0 {
 			parameters = map[string]any{
-				"type": "object",
-				"properties": map[string]any{
-					"path": map[string]any{
-						"type":        "string",
-						"description": "Path to file to edit.",
-					},
-					"oldText": map[string]any{
-						"type":        "string",
-						"description": "Exact old text to replace.",
-					},
-					"newText": map[string]any{
-						"type":        "string",
-						"description": "Replacement text.",
-					},
-				},
-				"required":             []string{"path", "oldText", "newText"},
-				"additionalProperties": true,
-			}
-		case "bash":
-			description = "Execute a shell command in current working directory."
-			parameters = map[string]any{
-				"type": "object",
-				"properties": map[string]any{
-					"command": map[string]any{
-						"type":        "string",
-						"description": "Shell command to execute.",
-					},
-					"timeout": map[string]any{
-						"type":        "number",
-						"description": "Optional timeout in seconds.",
-					},
-				},
-				"required":             []string{"command"},
+				"type":                 "object",
+				"properties":           map[string]any{},
 				"additionalProperties": true,
 			}
 		}
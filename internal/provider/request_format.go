@@ -2,6 +2,10 @@ package provider
 
 import "strings"
 
+// EmptyToolResult stands in for a tool call that produced no output: every
+// tool call must be answered by a result, and the APIs reject empty ones.
+const EmptyToolResult = "(no output)"
+
 func RenderMessages(messages []Message) string {
 	lines := make([]string, 0, len(messages))
 	for _, msg := range messages {
@@ -18,6 +22,18 @@ func RenderMessages(messages []Message) string {
 	return strings.Join(lines, "\n")
 }
 
+// requestMessages returns the request messages with System, when set, as a
+// leading system-role message.
+func requestMessages(req Request) []Message {
+	system := strings.TrimSpace(req.System)
+	if system == "" {
+		return req.Messages
+	}
+	out := make([]Message, 0, len(req.Messages)+1)
+	out = append(out, Message{Role: "system", Content: system})
+	return append(out, req.Messages...)
+}
+
 func renderProviderBlocksAsText(blocks []ContentBlock) string {
 	if len(blocks) == 0 {
 		return ""
@@ -31,3 +47,15 @@ func renderProviderBlocksAsText(blocks []ContentBlock) string {
 	}
 	return strings.Join(lines, "\n")
 }
+
+// imageBlocks returns the image blocks of a message, such as images a tool
+// returned.
+func imageBlocks(blocks []ContentBlock) []ContentBlock {
+	var out []ContentBlock
+	for _, block := range blocks {
+		if block.Type == "image" && block.Data != "" && block.MediaType != "" {
+			out = append(out, block)
+		}
+	}
+	return out
+}
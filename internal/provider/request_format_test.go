@@ -1,6 +1,9 @@
 package provider
 
-import "testing"
+import (
+	"context"
+	"testing"
+)
 
 func TestResolvePromptPrefersStructuredMessages(t *testing.T) {
 	got := RenderMessages([]Message{
@@ -24,3 +27,19 @@ func TestRenderMessagesSkipsEmptyFields(t *testing.T) {
 		t.Fatalf("unexpected rendered messages\nwant=%q\ngot=%q", want, got)
 	}
 }
+
+func TestMockAdapterRendersSystemPromptFirst(t *testing.T) {
+	var text string
+	for ev := range NewMockAdapter().Stream(context.Background(), Request{
+		System:   "be brief",
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	}) {
+		if ev.Type == EventTextDelta {
+			text += ev.Delta
+		}
+	}
+	want := "mock response: system: be brief\nuser: hi"
+	if text != want {
+		t.Fatalf("unexpected mock text\nwant=%q\ngot=%q", want, text)
+	}
+}
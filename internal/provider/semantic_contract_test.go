@@ -62,6 +62,27 @@ func TestAdapterContractGeminiText(t *testing.T) {
 	assertKnownEventTypes(t, evs)
 }
 
+func TestAdapterContractAnthropicText(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"hello \"}}\n\n")
+		_, _ = io.WriteString(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"from anthropic\"}}\n\n")
+		_, _ = io.WriteString(w, "event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"}}\n\n")
+		_, _ = io.WriteString(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	}))
+	defer srv.Close()
+
+	a, err := NewAnthropicAdapter("test-key", "claude-test", srv.URL)
+	if err != nil {
+		t.Fatalf("new anthropic adapter failed: %v", err)
+	}
+	evs := collectEvents(a.Stream(context.Background(), Request{
+		Messages: []Message{{Role: "user", Content: "hello"}},
+	}))
+	assertTextResponseContract(t, evs)
+	assertKnownEventTypes(t, evs)
+}
+
 func assertTextResponseContract(t *testing.T, evs []Event) {
 	t.Helper()
 	if len(evs) < 3 {
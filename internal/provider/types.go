@@ -29,6 +29,9 @@ type ToolCall struct {
 	ID        string
 	Name      string
 	Arguments map[string]any
+	// ThoughtSignature is Gemini's opaque signature for the call; thinking
+	// models require it to be sent back with the call on later turns.
+	ThoughtSignature string
 }
 
 type ContentBlock struct {
@@ -37,6 +40,9 @@ type ContentBlock struct {
 	ToolCallID string         `json:"tool_call_id,omitempty"`
 	ToolName   string         `json:"tool_name,omitempty"`
 	Arguments  map[string]any `json:"arguments,omitempty"`
+	// MediaType and Data (base64) are set on "image" blocks.
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
 }
 
 type Message struct {
@@ -47,9 +53,20 @@ type Message struct {
 	ToolCalls  []ToolCall
 }
 
+// ToolSpec describes a callable tool to the model. Parameters is a JSON
+// schema object; an empty schema is sent as an open object.
+type ToolSpec struct {
+	Name        string
+	Description string
+	Parameters  map[string]any
+}
+
+// Request is one model call. System is the system prompt; adapters send it in
+// their native form ahead of any system-role messages.
 type Request struct {
-	Messages    []Message
-	ActiveTools []string
+	System   string
+	Messages []Message
+	Tools    []ToolSpec
 }
 
 type Usage struct {
@@ -7,11 +7,13 @@ import (
 	"time"
 )
 
-const CurrentSchemaVersion = 3
+const CurrentSchemaVersion = 4
 
 const (
 	EntryTypeMessage    = "message"
 	EntryTypeCompaction = "compaction"
+	EntryTypeToolCall   = "tool_call"
+	EntryTypeToolResult = "tool_result"
 )
 
 type MessageEntry struct {
@@ -23,6 +25,14 @@ type MessageEntry struct {
 	RunID     string `json:"run_id,omitempty"`
 	TurnKind  string `json:"turn_kind,omitempty"`
 	CreatedAt string `json:"created_at"`
+
+	// Set on tool_call and tool_result entries (schema version 4).
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+	ToolName   string         `json:"tool_name,omitempty"`
+	Arguments  map[string]any `json:"arguments,omitempty"`
+	// ThoughtSignature is the provider's opaque signature for a tool call
+	// (Gemini), replayed with the call when the session resumes.
+	ThoughtSignature string `json:"thought_signature,omitempty"`
 }
 
 type CompactionEntry struct {
@@ -47,6 +57,42 @@ func NewMessageEntry(role, text, runID, turnKind string) MessageEntry {
 	}
 }
 
+// NewToolCallEntry records a tool call made by the assistant. Text holds a
+// readable rendering of the call so listings and compaction can show it.
+func NewToolCallEntry(callID, toolName string, args map[string]any, runID, turnKind string) MessageEntry {
+	text := toolName
+	if len(args) > 0 {
+		if b, err := json.Marshal(args); err == nil {
+			text += " " + string(b)
+		}
+	}
+	return MessageEntry{
+		Type:       EntryTypeToolCall,
+		Role:       "assistant",
+		Text:       text,
+		RunID:      runID,
+		TurnKind:   turnKind,
+		CreatedAt:  time.Now().UTC().Format(time.RFC3339Nano),
+		ToolCallID: callID,
+		ToolName:   toolName,
+		Arguments:  args,
+	}
+}
+
+// NewToolResultEntry records the result returned for a tool call.
+func NewToolResultEntry(callID, toolName, result, runID, turnKind string) MessageEntry {
+	return MessageEntry{
+		Type:       EntryTypeToolResult,
+		Role:       "tool_result",
+		Text:       result,
+		RunID:      runID,
+		TurnKind:   turnKind,
+		CreatedAt:  time.Now().UTC().Format(time.RFC3339Nano),
+		ToolCallID: callID,
+		ToolName:   toolName,
+	}
+}
+
 func NewCompactionEntry(summary, firstKeptEntryID, instruction string, tokensBefore int, trigger string) CompactionEntry {
 	return CompactionEntry{
 		Type:             EntryTypeCompaction,
@@ -68,7 +114,16 @@ func DecodeMessageEntry(raw json.RawMessage) (MessageEntry, bool) {
 		// Legacy compatibility: treat legacy role/text lines as message entry.
 		rec.Type = EntryTypeMessage
 	}
-	if rec.Type != EntryTypeMessage || rec.Role == "" || strings.TrimSpace(rec.Text) == "" {
+	switch rec.Type {
+	case EntryTypeMessage:
+	case EntryTypeToolCall, EntryTypeToolResult:
+		if strings.TrimSpace(rec.ToolName) == "" {
+			return MessageEntry{}, false
+		}
+	default:
+		return MessageEntry{}, false
+	}
+	if rec.Role == "" || strings.TrimSpace(rec.Text) == "" {
 		return MessageEntry{}, false
 	}
 	return rec, true
@@ -153,32 +208,3 @@ func BuildMessagePath(entries []MessageEntry, leafID string) []MessageEntry {
 	}
 	return out
 }
-
-func BuildPromptContext(entries []MessageEntry, prompt string, maxLines int) string {
-	if len(entries) == 0 {
-		return prompt
-	}
-	lines := make([]string, 0, len(entries))
-	for _, rec := range entries {
-		if rec.Role == "" || strings.TrimSpace(rec.Text) == "" {
-			continue
-		}
-		lines = append(lines, rec.Role+": "+rec.Text)
-	}
-	if len(lines) == 0 {
-		return prompt
-	}
-	if maxLines > 0 && len(lines) > maxLines {
-		lines = lines[len(lines)-maxLines:]
-	}
-
-	var b strings.Builder
-	b.WriteString("Conversation so far:\n")
-	for _, line := range lines {
-		b.WriteString(line)
-		b.WriteByte('\n')
-	}
-	b.WriteString("user: ")
-	b.WriteString(prompt)
-	return b.String()
-}
@@ -16,17 +16,6 @@ func TestDecodeMessageEntrySupportsLegacyShape(t *testing.T) {
 	}
 }
 
-func TestBuildPromptContext(t *testing.T) {
-	got := BuildPromptContext([]MessageEntry{
-		{Type: EntryTypeMessage, Role: "user", Text: "first"},
-		{Type: EntryTypeMessage, Role: "assistant", Text: "second"},
-	}, "next", 20)
-	want := "Conversation so far:\nuser: first\nassistant: second\nuser: next"
-	if got != want {
-		t.Fatalf("unexpected prompt context\nwant=%q\ngot=%q", want, got)
-	}
-}
-
 func TestNormalizeMessageChainBackfillsLegacyIDsAndParents(t *testing.T) {
 	entries := NormalizeMessageChain([]MessageEntry{
 		{Type: EntryTypeMessage, Role: "user", Text: "one"},
@@ -60,3 +49,29 @@ func TestBuildMessagePathByLeaf(t *testing.T) {
 		t.Fatalf("unexpected branch path: %+v", path)
 	}
 }
+
+func TestDecodeMessageEntrySupportsToolEntries(t *testing.T) {
+	call := NewToolCallEntry("c1", "read", map[string]any{"path": "a.txt"}, "run-1", "prompt")
+	raw, err := json.Marshal(call)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	rec, ok := DecodeMessageEntry(raw)
+	if !ok {
+		t.Fatalf("expected tool_call entry to decode")
+	}
+	if rec.Type != EntryTypeToolCall || rec.ToolCallID != "c1" || rec.Arguments["path"] != "a.txt" || rec.Text != `read {"path":"a.txt"}` {
+		t.Fatalf("unexpected decoded tool_call: %+v", rec)
+	}
+
+	result := NewToolResultEntry("c1", "read", "read => hello", "run-1", "prompt")
+	raw, _ = json.Marshal(result)
+	rec, ok = DecodeMessageEntry(raw)
+	if !ok || rec.Type != EntryTypeToolResult || rec.Role != "tool_result" || rec.ToolName != "read" {
+		t.Fatalf("unexpected decoded tool_result: %+v ok=%v", rec, ok)
+	}
+
+	if _, ok := DecodeMessageEntry(json.RawMessage(`{"type":"tool_call","role":"assistant","text":"x"}`)); ok {
+		t.Fatalf("expected tool entry without tool_name to be rejected")
+	}
+}
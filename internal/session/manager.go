@@ -189,7 +189,13 @@ func (m *Manager) AppendMessageToResolved(sessionID string, entry MessageEntry)
 	if entry.Type == "" {
 		entry.Type = EntryTypeMessage
 	}
-	if entry.Type != EntryTypeMessage {
+	switch entry.Type {
+	case EntryTypeMessage:
+	case EntryTypeToolCall, EntryTypeToolResult:
+		if strings.TrimSpace(entry.ToolName) == "" {
+			return MessageEntry{}, fmt.Errorf("invalid_message_entry")
+		}
+	default:
 		return MessageEntry{}, fmt.Errorf("invalid_message_entry_type")
 	}
 	if strings.TrimSpace(entry.Text) == "" || entry.Role == "" {